@@ -0,0 +1,121 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package casbintest provides helpers to test policies, e.g. to check in CI that a policy change
+// does not alter the decisions of a recorded set of requests.
+package casbintest
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// TB is the part of testing.TB used by AssertDecisionsUnchanged.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Divergence describes a request decided differently by two enforcers.
+type Divergence struct {
+	Request    []interface{}
+	OldResult  bool
+	NewResult  bool
+	OldExplain []string
+	NewExplain []string
+	OldErr     error
+	NewErr     error
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("request %v: %s, now %s", d.Request,
+		formatDecision(d.OldResult, d.OldExplain, d.OldErr), formatDecision(d.NewResult, d.NewExplain, d.NewErr))
+}
+
+func formatDecision(result bool, explain []string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error %q", err)
+	}
+	if explain != nil {
+		return fmt.Sprintf("%t by %v", result, explain)
+	}
+	return fmt.Sprintf("%t", result)
+}
+
+// Option configures how the decisions are compared.
+type Option func(*options)
+
+type options struct {
+	compareExplanations bool
+}
+
+// CompareExplanations makes the decisions be compared with EnforceEx, so that a request decided the same
+// by a different rule diverges too.
+func CompareExplanations() Option {
+	return func(o *options) {
+		o.compareExplanations = true
+	}
+}
+
+// CompareDecisions enforces the requests with both enforcers and returns the requests decided differently,
+// in the order of the requests. A request failing with one enforcer only diverges, while a request failing
+// with both does not.
+func CompareDecisions(oldEnforcer, newEnforcer casbin.IEnforcer, requests [][]interface{}, opts ...Option) []Divergence {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var res []Divergence
+	for _, request := range requests {
+		d := Divergence{Request: request}
+		if o.compareExplanations {
+			d.OldResult, d.OldExplain, d.OldErr = oldEnforcer.EnforceEx(request...)
+			d.NewResult, d.NewExplain, d.NewErr = newEnforcer.EnforceEx(request...)
+		} else {
+			d.OldResult, d.OldErr = oldEnforcer.Enforce(request...)
+			d.NewResult, d.NewErr = newEnforcer.Enforce(request...)
+		}
+		if diverges(d) {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func diverges(d Divergence) bool {
+	if d.OldErr != nil || d.NewErr != nil {
+		return (d.OldErr == nil) != (d.NewErr == nil)
+	}
+	return d.OldResult != d.NewResult || !util.ArrayEquals(d.OldExplain, d.NewExplain)
+}
+
+// AssertDecisionsUnchanged enforces the requests with both enforcers, e.g. before and after a policy change,
+// and reports every request decided differently as an error of t. It returns whether no request diverged.
+//
+//	func TestPolicyChange(t *testing.T) {
+//		before, _ := casbin.NewEnforcer("model.conf", "policy_old.csv")
+//		after, _ := casbin.NewEnforcer("model.conf", "policy_new.csv")
+//		casbintest.AssertDecisionsUnchanged(t, before, after, recordedRequests)
+//	}
+func AssertDecisionsUnchanged(t TB, oldEnforcer, newEnforcer casbin.IEnforcer, requests [][]interface{}, opts ...Option) bool {
+	t.Helper()
+	divergences := CompareDecisions(oldEnforcer, newEnforcer, requests, opts...)
+	for _, d := range divergences {
+		t.Errorf("decision changed: %s", d)
+	}
+	return len(divergences) == 0
+}
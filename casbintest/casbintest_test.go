@@ -0,0 +1,94 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbintest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// recordingTB records the errors reported by AssertDecisionsUnchanged.
+type recordingTB struct {
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+var testRequests = [][]interface{}{
+	{"alice", "data1", "read"},
+	{"alice", "data2", "read"},
+	{"bob", "data2", "write"},
+	{"bob", "data1", "read"},
+}
+
+func TestAssertDecisionsUnchanged(t *testing.T) {
+	before, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	after, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+
+	if !AssertDecisionsUnchanged(t, before, after, testRequests) {
+		t.Error("the same policy should decide the same")
+	}
+
+	_, _ = after.AddPolicy("bob", "data1", "read")
+	tb := &recordingTB{}
+	if AssertDecisionsUnchanged(tb, before, after, testRequests) {
+		t.Error("the changed decision should be reported")
+	}
+	if len(tb.errors) != 1 {
+		t.Fatalf("errors: %v, supposed to be 1 error", tb.errors)
+	}
+	if tb.errors[0] != "decision changed: request [bob data1 read]: false, now true" {
+		t.Errorf("unexpected error: %s", tb.errors[0])
+	}
+}
+
+func TestCompareExplanations(t *testing.T) {
+	before, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	after, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	// alice reads data2 through her role, and now by a rule of her own too, which comes first.
+	_, _ = after.RemovePolicy("data2_admin", "data2", "read")
+	_, _ = after.AddPolicies([][]string{{"alice", "data2", "read"}, {"data2_admin", "data2", "read"}})
+
+	if divergences := CompareDecisions(before, after, testRequests); len(divergences) != 0 {
+		t.Errorf("divergences: %v, supposed to be none", divergences)
+	}
+	divergences := CompareDecisions(before, after, testRequests, CompareExplanations())
+	if len(divergences) != 1 {
+		t.Fatalf("divergences: %v, supposed to be 1", divergences)
+	}
+	if divergences[0].String() != "request [alice data2 read]: true by [data2_admin data2 read], now true by [alice data2 read]" {
+		t.Errorf("unexpected divergence: %s", divergences[0])
+	}
+}
+
+func TestCompareDecisionsErrors(t *testing.T) {
+	before, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	after, _ := casbin.NewEnforcer("../examples/rbac_with_domains_model.conf", "../examples/rbac_with_domains_policy.csv")
+
+	divergences := CompareDecisions(before, after, [][]interface{}{{"alice", "data1", "read"}})
+	if len(divergences) != 1 || divergences[0].NewErr == nil {
+		t.Errorf("divergences: %v, supposed to be an error of the new enforcer", divergences)
+	}
+	divergences = CompareDecisions(before, after, [][]interface{}{{"alice", "data1"}})
+	if len(divergences) != 0 {
+		t.Errorf("divergences: %v, supposed to be none as both enforcers fail", divergences)
+	}
+}
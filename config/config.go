@@ -51,12 +51,15 @@ type ConfigInterface interface {
 type Config struct {
 	// Section:key=value
 	data map[string]map[string]string
+	// Section:key=comment, the comment lines immediately preceding the key
+	comments map[string]map[string]string
 }
 
 // NewConfig create an empty configuration representation from file.
 func NewConfig(confName string) (ConfigInterface, error) {
 	c := &Config{
-		data: make(map[string]map[string]string),
+		data:     make(map[string]map[string]string),
+		comments: make(map[string]map[string]string),
 	}
 	err := c.parse(confName)
 	return c, err
@@ -65,7 +68,8 @@ func NewConfig(confName string) (ConfigInterface, error) {
 // NewConfigFromText create an empty configuration representation from text.
 func NewConfigFromText(text string) (ConfigInterface, error) {
 	c := &Config{
-		data: make(map[string]map[string]string),
+		data:     make(map[string]map[string]string),
+		comments: make(map[string]map[string]string),
 	}
 	err := c.parseBuffer(bufio.NewReader(strings.NewReader(text)))
 	return c, err
@@ -103,9 +107,13 @@ func (c *Config) parseBuffer(buf *bufio.Reader) error {
 	var lineNum int
 	var buffer bytes.Buffer
 	var canWrite bool
+	// comments holds the comment lines read since the last key, blank line or section,
+	// comment is the part of them preceding the key being read.
+	var comments []string
+	var comment string
 	for {
 		if canWrite {
-			if err := c.write(section, lineNum, &buffer); err != nil {
+			if err := c.write(section, lineNum, &buffer, comment); err != nil {
 				return err
 			} else {
 				canWrite = false
@@ -116,7 +124,7 @@ func (c *Config) parseBuffer(buf *bufio.Reader) error {
 		if err == io.EOF {
 			// force write when buffer is not flushed yet
 			if buffer.Len() > 0 {
-				if err = c.write(section, lineNum, &buffer); err != nil {
+				if err = c.write(section, lineNum, &buffer, comment); err != nil {
 					return err
 				}
 			}
@@ -127,20 +135,30 @@ func (c *Config) parseBuffer(buf *bufio.Reader) error {
 
 		line = bytes.TrimSpace(line)
 		switch {
-		case bytes.Equal(line, []byte{}), bytes.HasPrefix(line, DEFAULT_COMMENT_SEM),
-			bytes.HasPrefix(line, DEFAULT_COMMENT):
+		case bytes.Equal(line, []byte{}):
+			comments = nil
+			canWrite = true
+			continue
+		case bytes.HasPrefix(line, DEFAULT_COMMENT_SEM), bytes.HasPrefix(line, DEFAULT_COMMENT):
+			comments = append(comments, string(bytes.TrimSpace(line[1:])))
 			canWrite = true
 			continue
 		case bytes.HasPrefix(line, []byte{'['}) && bytes.HasSuffix(line, []byte{']'}):
 			// force write when buffer is not flushed yet
 			if buffer.Len() > 0 {
-				if err := c.write(section, lineNum, &buffer); err != nil {
+				if err := c.write(section, lineNum, &buffer, comment); err != nil {
 					return err
 				}
 				canWrite = false
 			}
 			section = string(line[1 : len(line)-1])
+			comments = nil
 		default:
+			if buffer.Len() == 0 {
+				comment = strings.Join(comments, "\n")
+				comments = nil
+			}
+
 			var p []byte
 			if bytes.HasSuffix(line, DEFAULT_MULTI_LINE_SEPARATOR) {
 				p = bytes.TrimSpace(line[:len(line)-1])
@@ -166,7 +184,7 @@ func (c *Config) parseBuffer(buf *bufio.Reader) error {
 	return nil
 }
 
-func (c *Config) write(section string, lineNum int, b *bytes.Buffer) error {
+func (c *Config) write(section string, lineNum int, b *bytes.Buffer, comment string) error {
 	if b.Len() <= 0 {
 		return nil
 	}
@@ -178,6 +196,9 @@ func (c *Config) write(section string, lineNum int, b *bytes.Buffer) error {
 	option := bytes.TrimSpace(optionVal[0])
 	value := bytes.TrimSpace(optionVal[1])
 	c.AddConfig(section, string(option), string(value))
+	if comment != "" {
+		c.setComment(section, string(option), comment)
+	}
 
 	// flush buffer after adding
 	b.Reset()
@@ -185,6 +206,25 @@ func (c *Config) write(section string, lineNum int, b *bytes.Buffer) error {
 	return nil
 }
 
+func (c *Config) setComment(section string, option string, comment string) {
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if _, ok := c.comments[section]; !ok {
+		c.comments[section] = make(map[string]string)
+	}
+	c.comments[section][option] = comment
+}
+
+// Comment returns the comment lines immediately preceding the provided key, without the comment characters.
+func (c *Config) Comment(key string) string {
+	section, option := DEFAULT_SECTION, strings.ToLower(key)
+	if keys := strings.Split(option, "::"); len(keys) >= 2 {
+		section, option = keys[0], keys[1]
+	}
+	return c.comments[section][option]
+}
+
 // Bool lookups up the value using the provided key and converts the value to a bool.
 func (c *Config) Bool(key string) (bool, error) {
 	return strconv.ParseBool(c.get(key))
@@ -88,3 +88,30 @@ func TestGet(t *testing.T) {
 		t.Errorf("Get failure: expected different value for multi5::name (expected: [%#v] got: [%#v])", "r.sub==p.sub&&r.obj==p.obj", v)
 	}
 }
+
+func TestComment(t *testing.T) {
+	text := `
+# not attached to any key
+
+[matchers]
+# first line
+; second line
+m = r.sub == p.sub \
+  && r.obj == p.obj
+n = r.act == p.act
+`
+	config, err := NewConfigFromText(text)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c := config.(*Config)
+	if v := c.Comment("matchers::m"); v != "first line\nsecond line" {
+		t.Errorf("Comment failure: expected different value for matchers::m (expected: [%#v] got: [%#v])", "first line\nsecond line", v)
+	}
+	if v := c.Comment("matchers::n"); v != "" {
+		t.Errorf("Comment failure: expected different value for matchers::n (expected: [%#v] got: [%#v])", "", v)
+	}
+	if v := config.String("matchers::m"); v != "r.sub == p.sub && r.obj == p.obj" {
+		t.Errorf("Get failure: expected different value for matchers::m (expected: [%#v] got: [%#v])", "r.sub == p.sub && r.obj == p.obj", v)
+	}
+}
@@ -20,6 +20,8 @@ const (
 	SubjectIndex  = "sub"
 	ObjectIndex   = "obj"
 	PriorityIndex = "priority"
+	ExpireIndex   = "expire"
+	EffectIndex   = "eft"
 )
 
 const (
@@ -0,0 +1,105 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// diagnostics is the snapshot of an enforcer produced by DumpDiagnostics.
+type diagnostics struct {
+	// Model is the model definition, in the CONF format.
+	Model string `json:"model"`
+	// Policy maps the sections and then the policy types to their rules.
+	Policy map[string]map[string][][]string `json:"policy"`
+	// Functions are the names of the functions available to the matchers.
+	Functions []string `json:"functions"`
+	// RoleManagers maps the role definitions to the types of their role managers.
+	RoleManagers map[string]string `json:"roleManagers"`
+	Adapter      string            `json:"adapter"`
+	Watcher      string            `json:"watcher"`
+	Effector     string            `json:"effector"`
+	Settings     map[string]bool   `json:"settings"`
+}
+
+// DumpDiagnostics returns a JSON snapshot of the enforcer to attach to a bug report: the model definition,
+// the whole policy, the names of the matcher functions, the types of the adapter, watcher, effector and role managers,
+// and the main settings. Only the types of the adapter and the watcher are included, so that no credential leaks,
+// but the policy is included as is, see DumpDiagnosticsRedacted.
+func (e *Enforcer) DumpDiagnostics() ([]byte, error) {
+	return e.DumpDiagnosticsRedacted(nil)
+}
+
+// DumpDiagnosticsRedacted returns a JSON snapshot of the enforcer like DumpDiagnostics, with each rule of the policy
+// replaced by the one returned by redact, e.g. to mask personal data. The model definition is not redacted.
+func (e *Enforcer) DumpDiagnosticsRedacted(redact RuleCodecFunc) ([]byte, error) {
+	d := diagnostics{
+		Model:        e.model.ToText(),
+		Policy:       map[string]map[string][][]string{},
+		RoleManagers: map[string]string{},
+		Adapter:      typeName(unwrapRuleCodec(e.adapter)),
+		Watcher:      typeName(e.watcher),
+		Effector:     typeName(e.eft),
+		Settings: map[string]bool{
+			"enabled":            e.enabled,
+			"autoSave":           e.autoSave,
+			"autoBuildRoleLinks": e.autoBuildRoleLinks,
+			"autoNotifyWatcher":  e.autoNotifyWatcher,
+			"acceptJsonRequest":  e.acceptJsonRequest,
+			"policyMatchers":     e.policyMatchers,
+		},
+	}
+
+	for _, sec := range []string{"p", "g"} {
+		for ptype, assertion := range e.model[sec] {
+			rules := make([][]string, 0, len(assertion.Policy))
+			for _, rule := range assertion.Policy {
+				rule = append([]string(nil), rule...)
+				if redact != nil {
+					rule = redact(sec, ptype, rule)
+				}
+				rules = append(rules, rule)
+			}
+			if d.Policy[sec] == nil {
+				d.Policy[sec] = map[string][][]string{}
+			}
+			d.Policy[sec][ptype] = rules
+		}
+	}
+
+	for name := range e.fm.GetFunctions() {
+		d.Functions = append(d.Functions, name)
+	}
+	sort.Strings(d.Functions)
+
+	for ptype, rm := range e.rmMap {
+		d.RoleManagers[ptype] = typeName(rm)
+	}
+	for ptype, rm := range e.condRmMap {
+		d.RoleManagers[ptype] = typeName(rm)
+	}
+
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// typeName returns the name of the dynamic type of v, or "" if v is nil.
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestDumpDiagnostics(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	data, err := e.DumpDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d diagnostics
+	if err = json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Model != e.GetModel().ToText() {
+		t.Errorf("model: %q, expected %q", d.Model, e.GetModel().ToText())
+	}
+	policy, _ := e.GetPolicy()
+	if !util.Array2DEquals(d.Policy["p"]["p"], policy) {
+		t.Errorf("policy: %v, expected %v", d.Policy["p"]["p"], policy)
+	}
+	groupingPolicy, _ := e.GetGroupingPolicy()
+	if !util.Array2DEquals(d.Policy["g"]["g"], groupingPolicy) {
+		t.Errorf("grouping policy: %v, expected %v", d.Policy["g"]["g"], groupingPolicy)
+	}
+	if d.Adapter != "*fileadapter.Adapter" {
+		t.Errorf("adapter: %q, expected %q", d.Adapter, "*fileadapter.Adapter")
+	}
+	if d.RoleManagers["g"] != "*defaultrolemanager.RoleManagerImpl" {
+		t.Errorf("role manager: %q, expected %q", d.RoleManagers["g"], "*defaultrolemanager.RoleManagerImpl")
+	}
+	hasKeyMatch := false
+	for _, name := range d.Functions {
+		hasKeyMatch = hasKeyMatch || name == "keyMatch"
+	}
+	if !hasKeyMatch {
+		t.Errorf("functions %v should contain keyMatch", d.Functions)
+	}
+
+	data, err = e.DumpDiagnosticsRedacted(func(sec string, ptype string, rule []string) []string {
+		rule[0] = "***"
+		return rule
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = diagnostics{}
+	if err = json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range d.Policy["p"]["p"] {
+		if rule[0] != "***" {
+			t.Errorf("rule %v should be redacted", rule)
+		}
+	}
+	policy, _ = e.GetPolicy()
+	if policy[0][0] != "alice" {
+		t.Errorf("redacting should not modify the policy, got %v", policy[0])
+	}
+}
@@ -15,12 +15,18 @@
 package casbin
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/casbin/casbin/v2/constant"
 	"github.com/casbin/casbin/v2/effector"
 	"github.com/casbin/casbin/v2/log"
 	"github.com/casbin/casbin/v2/model"
@@ -40,6 +46,9 @@ type Enforcer struct {
 	fm        model.FunctionMap
 	eft       effector.Effector
 
+	// contextFunctions are the matcher functions receiving the context of the enforcement, see AddContextFunction.
+	contextFunctions map[string]ContextFunction
+
 	adapter    persist.Adapter
 	watcher    persist.Watcher
 	dispatcher persist.Dispatcher
@@ -47,14 +56,78 @@ type Enforcer struct {
 	condRmMap  map[string]rbac.ConditionalRoleManager
 	matcherMap sync.Map
 
+	// escapedMatchers memoizes the most recently escaped custom matchers, see escapeMatcher.
+	escapedMatchers *util.SyncLRUCache
+	// namedMatchers are the matchers added by AddNamedMatcher, by name.
+	namedMatchers sync.Map
+
+	requestTypes sync.Map
+
 	enabled              bool
 	autoSave             bool
 	autoBuildRoleLinks   bool
 	autoNotifyWatcher    bool
 	autoNotifyDispatcher bool
 	acceptJsonRequest    bool
+	validateOnAdd        bool
+	skipExpiredPolicy    bool
+	shadowDetection      bool
+
+	// failurePolicy is the decision when evaluating a request fails, see SetFailurePolicy.
+	failurePolicy FailurePolicy
+
+	// clock returns the current time of the timeMatch function and of the expiry of rules, see SetClock.
+	clock func() time.Time
+
+	// policyMatchers is set when each policy type is evaluated with its own matcher, see EnablePolicyMatchers.
+	policyMatchers bool
+
+	// policyJoin is set when the matcher may reference the tokens of other policy types, see EnablePolicyJoin.
+	policyJoin bool
+
+	// skippedExpiredPolicyCount is the number of expired rules skipped by the last load, accessed atomically.
+	skippedExpiredPolicyCount int32
+
+	// changeTracking is set when policy changes are recorded for SavePolicyIncremental, see EnableChangeTracking.
+	changeTracking bool
+	// pendingChanges holds the policy changes made since the last load or save
+	// that have not been written to the adapter, see SavePolicyIncremental.
+	pendingChanges []persist.PolicyOperation
+	// pendingFullSave is set when the policy changed in a way that cannot be
+	// described by pendingChanges, e.g. by ClearPolicy or while change tracking is disabled.
+	pendingFullSave bool
+
+	// denyOverrideSets maps escaped request tokens, e.g. "r_sub", to the values denied regardless of the policy,
+	// see SetDenyOverrideSet.
+	denyOverrideSets map[string]map[string]struct{}
+
+	// preMatcher decides the requests it can before the matcher runs, see SetPreMatcher.
+	preMatcher func(rvals []interface{}) (decided bool, allow bool)
+
+	// policyVersion is the version of the storage the policy was loaded or saved at, see SavePolicyVersioned.
+	policyVersion string
+
+	// ruleDecoder and ruleEncoder transform the rules loaded from and written to the adapter, see SetRuleCodec.
+	ruleDecoder RuleCodecFunc
+	ruleEncoder RuleCodecFunc
+
+	// policySubscribers are called on each change of the policy, see SubscribePolicyChanges.
+	policySubscribers []policySubscriber
+	nextSubscriberID  int
+	subscribersMutex  sync.Mutex
+
+	// subjectIndex indexes the "p" policy by subject when enabled, see EnableSubjectIndex.
+	subjectIndex *subjectIndex
+
+	// policyRevision is incremented on each change of the policy or of the role managers.
+	policyRevision uint64
+	// permissionsCache caches the implicit permissions of the users when enabled, see EnableImplicitPermissionsCache.
+	permissionsCache     *util.SyncLRUCache
+	permissionsCacheSize int
 
 	logger log.Logger
+	// modelLogger is the logger of the model if set apart from logger, see SetModelLogger.
+	modelLogger log.Logger
 }
 
 // EnforceContext is used as the first element of the parameter "rvals" in method "enforce".
@@ -149,6 +222,21 @@ func NewEnforcer(params ...interface{}) (*Enforcer, error) {
 	return e, nil
 }
 
+// NewEnforcerFromFiles creates an enforcer via a model split across several files, see model.NewModelFromFiles,
+// and an optional adapter.
+//
+//	e := casbin.NewEnforcerFromFiles([]string{"path/to/definitions.conf", "path/to/matchers.conf"}, a)
+func NewEnforcerFromFiles(modelPaths []string, adapter persist.Adapter) (*Enforcer, error) {
+	m, err := model.NewModelFromFiles(modelPaths...)
+	if err != nil {
+		return nil, err
+	}
+	if adapter == nil {
+		return NewEnforcer(m)
+	}
+	return NewEnforcer(m, adapter)
+}
+
 // InitWithFile initializes an enforcer with a model file and a policy file.
 func (e *Enforcer) InitWithFile(modelPath string, policyPath string) error {
 	a := fileadapter.NewAdapter(policyPath)
@@ -173,17 +261,20 @@ func (e *Enforcer) InitWithAdapter(modelPath string, adapter persist.Adapter) er
 
 // InitWithModelAndAdapter initializes an enforcer with a model and a database adapter.
 func (e *Enforcer) InitWithModelAndAdapter(m model.Model, adapter persist.Adapter) error {
-	e.adapter = adapter
+	e.adapter = e.wrapRuleCodec(adapter)
 
 	e.model = m
-	m.SetLogger(e.logger)
+	m.SetLogger(e.getModelLogger())
 	e.model.PrintModel()
 	e.fm = model.LoadFunctionMap()
 
 	e.initialize()
+	if err := e.checkMatcherFunctions(); err != nil {
+		return err
+	}
 
 	// Do not initialize the full policy when using a filtered adapter
-	fa, ok := e.adapter.(persist.FilteredAdapter)
+	fa, ok := asFilteredAdapter(e.adapter)
 	if e.adapter != nil && (!ok || ok && !fa.IsFiltered()) {
 		err := e.LoadPolicy()
 		if err != nil {
@@ -195,9 +286,10 @@ func (e *Enforcer) InitWithModelAndAdapter(m model.Model, adapter persist.Adapte
 }
 
 // SetLogger changes the current enforcer's logger.
+// The model keeps the logger set by SetModelLogger, if any.
 func (e *Enforcer) SetLogger(logger log.Logger) {
 	e.logger = logger
-	e.model.SetLogger(e.logger)
+	e.model.SetLogger(e.getModelLogger())
 	for k := range e.rmMap {
 		e.rmMap[k].SetLogger(e.logger)
 	}
@@ -206,37 +298,69 @@ func (e *Enforcer) SetLogger(logger log.Logger) {
 	}
 }
 
+// SetModelLogger sets a logger for the model, e.g. for PrintModel and PrintPolicy, apart from
+// the logger of the enforce decisions. A nil logger makes the model share the enforcer's logger again.
+func (e *Enforcer) SetModelLogger(logger log.Logger) {
+	e.modelLogger = logger
+	e.model.SetLogger(e.getModelLogger())
+}
+
+// GetModelLogger returns the logger of the model.
+func (e *Enforcer) GetModelLogger() log.Logger {
+	return e.getModelLogger()
+}
+
+func (e *Enforcer) getModelLogger() log.Logger {
+	if e.modelLogger != nil {
+		return e.modelLogger
+	}
+	return e.logger
+}
+
 func (e *Enforcer) initialize() {
 	e.rmMap = map[string]rbac.RoleManager{}
 	e.condRmMap = map[string]rbac.ConditionalRoleManager{}
 	e.eft = effector.NewDefaultEffector()
 	e.watcher = nil
 	e.matcherMap = sync.Map{}
+	e.escapedMatchers = util.NewSyncLRUCache(escapedMatcherCacheSize)
 
 	e.enabled = true
 	e.autoSave = true
 	e.autoBuildRoleLinks = true
 	e.autoNotifyWatcher = true
 	e.autoNotifyDispatcher = true
+	e.invalidatePolicyIndexes()
 	e.initRmMap()
 }
 
 // LoadModel reloads the model from the model CONF file.
 // Because the policy is attached to a model, so the policy is invalidated and needs to be reloaded by calling LoadPolicy().
+// The options of the previous model, e.g. its matching options, are kept, see model.SetOptionsFrom.
 func (e *Enforcer) LoadModel() error {
-	var err error
-	e.model, err = model.NewModelFromFile(e.modelPath)
+	newModel, err := model.NewModelFromFile(e.modelPath)
 	if err != nil {
 		return err
 	}
-	e.model.SetLogger(e.logger)
+	// Keep the options set on the previous model, e.g. its matching options.
+	if err = newModel.SetOptionsFrom(e.model); err != nil {
+		return err
+	}
+	e.model = newModel
+	e.model.SetLogger(e.getModelLogger())
 
 	e.model.PrintModel()
 	e.fm = model.LoadFunctionMap()
 
 	e.initialize()
 
-	return nil
+	if err = e.checkMatcherFunctions(); err != nil {
+		return err
+	}
+	if e.policyMatchers {
+		_, err = e.policyMatcherTypes()
+	}
+	return err
 }
 
 // GetModel gets the current model.
@@ -249,18 +373,18 @@ func (e *Enforcer) SetModel(m model.Model) {
 	e.model = m
 	e.fm = model.LoadFunctionMap()
 
-	e.model.SetLogger(e.logger)
+	e.model.SetLogger(e.getModelLogger())
 	e.initialize()
 }
 
 // GetAdapter gets the current adapter.
 func (e *Enforcer) GetAdapter() persist.Adapter {
-	return e.adapter
+	return unwrapRuleCodec(e.adapter)
 }
 
 // SetAdapter sets the current adapter.
 func (e *Enforcer) SetAdapter(adapter persist.Adapter) {
-	e.adapter = adapter
+	e.adapter = e.wrapRuleCodec(adapter)
 }
 
 // SetWatcher sets the current watcher.
@@ -299,14 +423,24 @@ func (e *Enforcer) GetNamedRoleManager(ptype string) rbac.RoleManager {
 
 // SetRoleManager sets the current role manager.
 func (e *Enforcer) SetRoleManager(rm rbac.RoleManager) {
-	e.invalidateMatcherMap()
-	e.rmMap["g"] = rm
+	e.SetNamedRoleManager("g", rm)
 }
 
-// SetNamedRoleManager sets the role manager for the named policy.
+// SetNamedRoleManager sets the role manager for the named policy, e.g. "g2", so that each role definition
+// can have its own, e.g. a hierarchical one for users and a flat one for resources:
+//
+//	e.SetNamedRoleManager("g2", defaultrolemanager.NewRoleManagerImpl(1))
+//	err := e.RebuildRoleLinks()
+//
+// The matcher function of the role definition, e.g. g2(r.obj, p.obj), uses the new role manager at once,
+// but its links are only built by RebuildRoleLinks, LoadPolicy or BuildRoleLinks. LoadModel restores
+// the default role managers.
 func (e *Enforcer) SetNamedRoleManager(ptype string, rm rbac.RoleManager) {
 	e.invalidateMatcherMap()
 	e.rmMap[ptype] = rm
+	if assertion, ok := e.model["g"][ptype]; ok {
+		assertion.RM = rm
+	}
 }
 
 // SetEffector sets the current effector.
@@ -323,11 +457,14 @@ func (e *Enforcer) ClearPolicy() {
 		return
 	}
 	e.model.ClearPolicy()
+	e.invalidatePolicyIndexes()
+	e.pendingFullSave = true
+	e.publishEvent(PolicyChangeEvent{Reset: true})
 }
 
 // LoadPolicy reloads the policy from file/database.
 func (e *Enforcer) LoadPolicy() error {
-	newModel, err := e.loadPolicyFromAdapter(e.model)
+	newModel, version, err := e.loadPolicyFromAdapter(e.model)
 	if err != nil {
 		return err
 	}
@@ -335,26 +472,101 @@ func (e *Enforcer) LoadPolicy() error {
 	if err != nil {
 		return err
 	}
+	e.policyVersion = version
+	return nil
+}
+
+// ReloadPolicyAtomicSwap reloads the policy from file/database like LoadPolicy, but builds the new policy
+// and its role links aside, in a copy of the model and fresh role managers, and then swaps them in at once.
+// With a SyncedEnforcer, Enforce calls keep using the previous policy meanwhile instead of being blocked
+// by the reload, and only wait for the swap itself. It takes twice the memory of the policy during the reload.
+// Role managers must implement rbac.RoleManagerCloner to be rebuilt aside, otherwise, as with conditional
+// role managers, the role links are rebuilt in place on swap as LoadPolicy does.
+// GetRoleManager returns the new role managers after the swap.
+func (e *Enforcer) ReloadPolicyAtomicSwap() error {
+	newModel, rmMap, version, err := e.loadPolicyAside()
+	if err != nil {
+		return err
+	}
+	return e.swapPolicy(newModel, rmMap, version)
+}
+
+// loadPolicyAside loads the policy into a copy of the model, and builds its role links into clones
+// of the role managers if they all support it, see ReloadPolicyAtomicSwap.
+func (e *Enforcer) loadPolicyAside() (model.Model, map[string]rbac.RoleManager, string, error) {
+	newModel, version, err := e.loadPolicyFromAdapter(e.model)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !e.autoBuildRoleLinks || len(e.condRmMap) != 0 {
+		return newModel, nil, version, nil
+	}
+
+	rmMap := make(map[string]rbac.RoleManager, len(e.rmMap))
+	for ptype, rm := range e.rmMap {
+		if _, ok := rm.(rbac.ConditionalRoleManager); ok {
+			return newModel, nil, version, nil
+		}
+		cloner, ok := rm.(rbac.RoleManagerCloner)
+		if !ok {
+			return newModel, nil, version, nil
+		}
+		rmMap[ptype] = cloner.CloneEmpty()
+	}
+	if err := newModel.BuildRoleLinks(rmMap); err != nil {
+		return nil, nil, "", err
+	}
+	return newModel, rmMap, version, nil
+}
+
+// swapPolicy replaces the model by newModel and the role managers by rmMap, whose role links are already built.
+// If rmMap is nil, the role links are rebuilt in place.
+func (e *Enforcer) swapPolicy(newModel model.Model, rmMap map[string]rbac.RoleManager, version string) error {
+	if rmMap == nil {
+		if err := e.applyModifiedModel(newModel); err != nil {
+			return err
+		}
+	} else {
+		e.model = newModel
+		e.rmMap = rmMap
+		e.invalidateMatcherMap()
+		e.resetPendingChanges()
+		e.publishReset()
+	}
+	e.policyVersion = version
 	return nil
 }
 
-func (e *Enforcer) loadPolicyFromAdapter(baseModel model.Model) (model.Model, error) {
+// loadPolicyFromAdapter loads the policy into a copy of baseModel, along with the version of the storage
+// if the adapter is a VersionedAdapter.
+func (e *Enforcer) loadPolicyFromAdapter(baseModel model.Model) (model.Model, string, error) {
+	// The loaded policy replaces the whole policy, its rules are not reported as added.
 	newModel := baseModel.Copy()
+	newModel.SetPolicyChangedMuted(true)
+	defer newModel.SetPolicyChangedMuted(false)
 	newModel.ClearPolicy()
 
-	if err := e.adapter.LoadPolicy(newModel); err != nil && err.Error() != "invalid file path, file path cannot be empty" {
-		return nil, err
+	var version string
+	var err error
+	if a, ok := asVersionedAdapter(e.adapter); ok {
+		version, err = a.LoadPolicyVersioned(newModel)
+	} else {
+		err = e.adapter.LoadPolicy(newModel)
+	}
+	if err != nil && err.Error() != "invalid file path, file path cannot be empty" {
+		return nil, "", err
 	}
+	e.removeExpiredPolicy(newModel)
 
 	if err := newModel.SortPoliciesBySubjectHierarchy(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if err := newModel.SortPoliciesByPriority(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return newModel, nil
+	return newModel, version, nil
 }
 
 func (e *Enforcer) applyModifiedModel(newModel model.Model) error {
@@ -382,6 +594,8 @@ func (e *Enforcer) applyModifiedModel(newModel model.Model) error {
 
 	e.model = newModel
 	e.invalidateMatcherMap()
+	e.resetPendingChanges()
+	e.publishReset()
 	return nil
 }
 
@@ -422,19 +636,17 @@ func (e *Enforcer) rebuildConditionalRoleLinks(newModel model.Model) error {
 
 func (e *Enforcer) loadFilteredPolicy(filter interface{}) error {
 	e.invalidateMatcherMap()
-
-	var filteredAdapter persist.FilteredAdapter
+	e.invalidatePolicyIndexes()
 
 	// Attempt to cast the Adapter as a FilteredAdapter
-	switch adapter := e.adapter.(type) {
-	case persist.FilteredAdapter:
-		filteredAdapter = adapter
-	default:
+	fa, ok := asFilteredAdapter(e.adapter)
+	if !ok {
 		return errors.New("filtered policies are not supported by this adapter")
 	}
-	if err := filteredAdapter.LoadFilteredPolicy(e.model, filter); err != nil && err.Error() != "invalid file path, file path cannot be empty" {
+	if err := fa.LoadFilteredPolicy(e.model, filter); err != nil && err.Error() != "invalid file path, file path cannot be empty" {
 		return err
 	}
+	e.removeExpiredPolicy(e.model)
 
 	if err := e.model.SortPoliciesBySubjectHierarchy(); err != nil {
 		return err
@@ -459,7 +671,12 @@ func (e *Enforcer) loadFilteredPolicy(filter interface{}) error {
 func (e *Enforcer) LoadFilteredPolicy(filter interface{}) error {
 	e.model.ClearPolicy()
 
-	return e.loadFilteredPolicy(filter)
+	if err := e.loadFilteredPolicy(filter); err != nil {
+		return err
+	}
+	e.resetPendingChanges()
+	e.publishEvent(PolicyChangeEvent{Reset: true})
+	return nil
 }
 
 // LoadIncrementalFilteredPolicy append a filtered policy from file/database.
@@ -469,11 +686,11 @@ func (e *Enforcer) LoadIncrementalFilteredPolicy(filter interface{}) error {
 
 // IsFiltered returns true if the loaded policy has been filtered.
 func (e *Enforcer) IsFiltered() bool {
-	filteredAdapter, ok := e.adapter.(persist.FilteredAdapter)
+	fa, ok := asFilteredAdapter(e.adapter)
 	if !ok {
 		return false
 	}
-	return filteredAdapter.IsFiltered()
+	return fa.IsFiltered()
 }
 
 // SavePolicy saves the current policy (usually after changed with Casbin API) back to file/database.
@@ -484,6 +701,82 @@ func (e *Enforcer) SavePolicy() error {
 	if err := e.adapter.SavePolicy(e.model); err != nil {
 		return err
 	}
+	e.resetPendingChanges()
+	return e.notifySavePolicy()
+}
+
+// SavePolicyVersioned saves the current policy back to file/database like SavePolicy, unless the storage changed
+// since the policy was loaded or last saved, in which case it fails with a *persist.VersionConflictError
+// so that the caller can reload the policy and retry. The adapter must implement persist.VersionedAdapter.
+func (e *Enforcer) SavePolicyVersioned() error {
+	if e.IsFiltered() {
+		return errors.New("cannot save a filtered policy")
+	}
+	a, ok := asVersionedAdapter(e.adapter)
+	if !ok {
+		return errors.New("the adapter does not support versioned saving")
+	}
+	version, err := a.SavePolicyVersioned(e.model, e.policyVersion)
+	if err != nil {
+		return err
+	}
+	e.policyVersion = version
+	e.resetPendingChanges()
+	return e.notifySavePolicy()
+}
+
+// GetPolicyVersion returns the version of the storage the policy was loaded or saved at
+// with a persist.VersionedAdapter, or an empty string otherwise.
+func (e *Enforcer) GetPolicyVersion() string {
+	return e.policyVersion
+}
+
+// SavePolicyIncremental saves only the policy changes made since the last load or save
+// back to file/database, at once with a persist.ChangeAdapter, or else rule by rule with a persist.IncrementalAdapter,
+// or else using the batch and update methods of the adapter. It falls back to SavePolicy if the adapter does not
+// support incremental writes, see persist.IncrementalAdapter.
+// Changes are only tracked while change tracking is enabled and auto-save is disabled, see EnableChangeTracking.
+func (e *Enforcer) SavePolicyIncremental() error {
+	if e.pendingFullSave {
+		return e.SavePolicy()
+	}
+	if a, ok := asChangeAdapter(e.adapter); ok {
+		return e.applyPendingChanges(a)
+	}
+	_, incremental := asIncrementalAdapter(e.adapter)
+	if _, ok := e.adapter.(persist.BatchAdapter); !ok && !incremental {
+		return e.SavePolicy()
+	}
+	if len(e.pendingChanges) == 0 {
+		return nil
+	}
+
+	for len(e.pendingChanges) != 0 {
+		op := e.pendingChanges[0]
+		var err error
+		switch op.Type {
+		case persist.OperationAdd:
+			err = applyAddOperation(e.adapter, op)
+		case persist.OperationRemove:
+			err = applyRemoveOperation(e.adapter, op)
+		case persist.OperationUpdate:
+			err = applyUpdateOperation(e.adapter, op)
+		}
+		if err != nil {
+			if err.Error() == notImplemented {
+				return e.SavePolicy()
+			}
+			return err
+		}
+		e.pendingChanges = e.pendingChanges[1:]
+	}
+
+	e.resetPendingChanges()
+	return e.notifySavePolicy()
+}
+
+// notifySavePolicy notifies the watcher that the policy has been saved.
+func (e *Enforcer) notifySavePolicy() error {
 	if e.watcher != nil {
 		var err error
 		if watcher, ok := e.watcher.(persist.WatcherEx); ok {
@@ -496,10 +789,47 @@ func (e *Enforcer) SavePolicy() error {
 	return nil
 }
 
+// applyPendingChanges persists the pending changes with a single call to the adapter.
+func (e *Enforcer) applyPendingChanges(a persist.ChangeAdapter) error {
+	if len(e.pendingChanges) == 0 {
+		return nil
+	}
+	if err := a.ApplyChanges(e.GetPendingChanges()); err != nil {
+		if err.Error() == notImplemented {
+			return e.SavePolicy()
+		}
+		return err
+	}
+	e.resetPendingChanges()
+	return e.notifySavePolicy()
+}
+
+// GetPendingChanges returns the policy changes made since the last load or save, in order,
+// which SavePolicyIncremental persists. They are only tracked while change tracking is enabled
+// and auto-save is disabled.
+// The changes are copies, they can be modified.
+func (e *Enforcer) GetPendingChanges() []persist.PolicyChange {
+	changes := make([]persist.PolicyChange, len(e.pendingChanges))
+	for i, change := range e.pendingChanges {
+		change.Rules = copyRules(change.Rules)
+		if change.OldRules != nil {
+			change.OldRules = copyRules(change.OldRules)
+		}
+		changes[i] = change
+	}
+	return changes
+}
+
+func (e *Enforcer) resetPendingChanges() {
+	e.pendingChanges = nil
+	e.pendingFullSave = false
+}
+
 func (e *Enforcer) initRmMap() {
 	for ptype, assertion := range e.model["g"] {
 		if rm, ok := e.rmMap[ptype]; ok {
 			_ = rm.Clear()
+			assertion.RM = rm
 			continue
 		}
 		if len(assertion.Tokens) <= 2 && len(assertion.ParamsTokens) == 0 {
@@ -556,6 +886,18 @@ func (e *Enforcer) EnableAutoSave(autoSave bool) {
 	e.autoSave = autoSave
 }
 
+// EnableChangeTracking controls whether the policy changes that are not persisted by auto-save are recorded,
+// so that SavePolicyIncremental writes only them to the adapter. It is disabled by default, as the recorded
+// changes are kept in memory until the next load or save. While it is disabled, or if any change was made
+// before enabling it, SavePolicyIncremental saves the whole policy.
+func (e *Enforcer) EnableChangeTracking(enable bool) {
+	e.changeTracking = enable
+	if !enable && len(e.pendingChanges) != 0 {
+		e.pendingChanges = nil
+		e.pendingFullSave = true
+	}
+}
+
 // EnableAutoBuildRoleLinks controls whether to rebuild the role inheritance relations when a role is added or deleted.
 func (e *Enforcer) EnableAutoBuildRoleLinks(autoBuildRoleLinks bool) {
 	e.autoBuildRoleLinks = autoBuildRoleLinks
@@ -566,8 +908,149 @@ func (e *Enforcer) EnableAcceptJsonRequest(acceptJsonRequest bool) {
 	e.acceptJsonRequest = acceptJsonRequest
 }
 
+// EnableSkipExpiredPolicy controls whether expired rules are skipped when loading the policy.
+// A policy type opts in to expiry by defining an "expire" field, e.g. "p = sub, obj, act, expire",
+// whose value is an RFC 3339 time such as "2026-01-02T15:04:05Z", or empty for a rule that never expires.
+// Rules with an unparsable expiry are treated as expired and logged as errors. The current time is the one of SetClock.
+// Rules expired at load time are dropped before entering the model, loading does not remove them from the storage.
+// However, SavePolicy writes the whole model and so deletes them from the storage.
+func (e *Enforcer) EnableSkipExpiredPolicy(enable bool) {
+	e.skipExpiredPolicy = enable
+}
+
+// GetSkippedExpiredPolicyCount returns the number of expired rules skipped by the last policy load.
+func (e *Enforcer) GetSkippedExpiredPolicyCount() int {
+	return int(atomic.LoadInt32(&e.skippedExpiredPolicyCount))
+}
+
+func (e *Enforcer) removeExpiredPolicy(m model.Model) {
+	if !e.skipExpiredPolicy {
+		return
+	}
+	removed, err := m.RemoveExpiredPolicy(e.now())
+	if err != nil {
+		e.logger.LogError(err, "skipping expired rules")
+	}
+	atomic.StoreInt32(&e.skippedExpiredPolicyCount, int32(removed))
+}
+
+// SetClock sets the source of the current time of the enforcer, e.g. a fake clock in tests. It is used by
+// the timeMatch function of the matchers, by inTimeRange and weekdayMatch when their time argument is empty,
+// by temporal roles, see EnableNamedTemporalRoles, and to skip expired rules. A nil clock restores time.Now.
+func (e *Enforcer) SetClock(clock func() time.Time) {
+	e.clock = clock
+	e.invalidateMatcherMap()
+}
+
+// now returns the current time of the enforcer, see SetClock.
+func (e *Enforcer) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
+	}
+	return e.clock()
+}
+
+// SetValidateOnAdd controls whether added policy rules are checked against the model.
+// When enabled, adding a rule that sets a field which is referenced by neither a matcher nor the policy effect
+// fails, as such a rule can never behave as intended.
+func (e *Enforcer) SetValidateOnAdd(validateOnAdd bool) {
+	e.validateOnAdd = validateOnAdd
+}
+
+// SetPreMatcher sets a function called with the request values before the matcher runs, to decide the requests
+// it can cheaply, e.g. to reject a subject that is not in any policy when the matcher is expensive.
+// If it returns decided, Enforce returns allow without evaluating the policy, otherwise the evaluation proceeds.
+// The values do not include the EnforceContext, if any. A request allowed by fn is still denied by a deny override
+// set, see SetDenyOverrideSet. A nil fn removes the pre-matcher.
+func (e *Enforcer) SetPreMatcher(fn func(rvals []interface{}) (decided bool, allow bool)) {
+	e.preMatcher = fn
+}
+
+// preMatch runs the pre-matcher on the request, see SetPreMatcher.
+func (e *Enforcer) preMatch(rvals []interface{}) (decided bool, allow bool) {
+	rType := "r"
+	if len(rvals) != 0 {
+		if enforceContext, ok := rvals[0].(EnforceContext); ok {
+			rType = enforceContext.RType
+			rvals = rvals[1:]
+		}
+	}
+
+	decided, allow = e.preMatcher(rvals)
+	if !decided {
+		return false, false
+	}
+	if allow && len(e.denyOverrideSets) != 0 {
+		if assertion, ok := e.model["r"][rType]; ok && len(assertion.Tokens) == len(rvals) {
+			rTokens := make(map[string]int, len(assertion.Tokens))
+			for i, token := range assertion.Tokens {
+				rTokens[token] = i
+			}
+			allow = !e.isDenyOverridden(rTokens, rvals)
+		}
+	}
+	e.logger.LogEnforce("pre-matcher", rvals, allow, nil)
+	return decided, allow
+}
+
+// SetDenyOverrideSet sets the values of a request field that are denied regardless of any allow rule,
+// e.g. a blocklist of users with SetDenyOverrideSet("r.sub", []string{"mallory"}). A request whose field is in the set
+// is denied before the matcher runs, by a lookup instead of a scan of the policy. token is a field of a request
+// definition such as "r.sub", or "sub" for the definition "r". An empty set removes the override of the field.
+func (e *Enforcer) SetDenyOverrideSet(token string, values []string) error {
+	if !strings.Contains(token, ".") {
+		token = "r." + token
+	}
+	rType := token[:strings.Index(token, ".")]
+	field := token
+	token = rType + "_" + token[len(rType)+1:]
+
+	assertion, err := e.model.GetAssertion("r", rType)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, t := range assertion.Tokens {
+		found = found || t == token
+	}
+	if !found {
+		return fmt.Errorf("%s is not a field of the request definition %s", field, rType)
+	}
+
+	if len(values) == 0 {
+		delete(e.denyOverrideSets, token)
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	if e.denyOverrideSets == nil {
+		e.denyOverrideSets = make(map[string]map[string]struct{})
+	}
+	e.denyOverrideSets[token] = set
+	return nil
+}
+
+// isDenyOverridden checks whether a request field is in its deny override set.
+func (e *Enforcer) isDenyOverridden(rTokens map[string]int, rvals []interface{}) bool {
+	for token, set := range e.denyOverrideSets {
+		i, ok := rTokens[token]
+		if !ok {
+			continue
+		}
+		if value, ok := rvals[i].(string); ok {
+			if _, denied := set[value]; denied {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // BuildRoleLinks manually rebuild the role inheritance relations.
 func (e *Enforcer) BuildRoleLinks() error {
+	e.invalidatePolicyIndexes()
 	if e.rmMap == nil {
 		return errors.New("rmMap is nil")
 	}
@@ -581,6 +1064,18 @@ func (e *Enforcer) BuildRoleLinks() error {
 	return e.model.BuildRoleLinks(e.rmMap)
 }
 
+// RebuildRoleLinks recomputes the role inheritance relations of all the role managers, conditional ones included,
+// from the grouping policy in memory, e.g. after setting a role manager or changing its matching functions,
+// without reloading the policy from the adapter.
+func (e *Enforcer) RebuildRoleLinks() error {
+	e.invalidateMatcherMap()
+	e.invalidatePolicyIndexes()
+	if err := e.rebuildRoleLinks(e.model); err != nil {
+		return err
+	}
+	return e.rebuildConditionalRoleLinks(e.model)
+}
+
 // BuildIncrementalRoleLinks provides incremental build the role inheritance relations.
 func (e *Enforcer) BuildIncrementalRoleLinks(op model.PolicyOp, ptype string, rules [][]string) error {
 	e.invalidateMatcherMap()
@@ -604,36 +1099,60 @@ func NewEnforceContext(suffix string) EnforceContext {
 }
 
 func (e *Enforcer) invalidateMatcherMap() {
+	// The role managers or their matching functions may have changed too.
+	e.policyRevision++
 	e.matcherMap = sync.Map{}
 }
 
 // enforce use a custom matcher to decides whether a "subject" can access a "object" with the operation "action", input parameters are usually: (matcher, sub, obj, act), use model matcher by default when matcher is "".
-func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interface{}) (ok bool, err error) { //nolint:funlen,cyclop,gocyclo // TODO: reduce function complexity
+func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interface{}) (ok bool, err error) {
+	return e.enforceWithMatched(context.Background(), matcher, explains, nil, nil, rvals...)
+}
+
+// enforceWithMatched is enforce, also collecting into matched every rule matching the request if it is not nil,
+// in which case the matcher is run over all rules instead of stopping at the decisive one.
+// If effects is not nil, the effect of every rule evaluated until the decision is appended to it, see EnforceExVerbose.
+// It stops with ctx.Err() once ctx is done, see EnforceWithContext.
+// A failed evaluation is decided by the failure policy, see SetFailurePolicy.
+func (e *Enforcer) enforceWithMatched(ctx context.Context, matcher string, explains *[]string, matched *[][]string, effects *[]effector.Effect, rvals ...interface{}) (ok bool, err error) { //nolint:funlen,cyclop,gocyclo // TODO: reduce function complexity
+	defer func() {
+		if err != nil {
+			ok, err = e.applyFailurePolicy(ctx, err)
+		}
+	}()
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			err = &evaluationError{fmt.Errorf("panic: %v\n%s", r, debug.Stack())}
 		}
 	}()
 
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+
 	if !e.enabled {
 		return true, nil
 	}
 
-	functions := e.fm.GetFunctions()
-	if _, ok := e.model["g"]; ok {
-		for key, ast := range e.model["g"] {
-			// g must be a normal role definition (ast.RM != nil)
-			//   or a conditional role definition (ast.CondRM != nil)
-			// ast.RM and ast.CondRM shouldn't be nil at the same time
-			if ast.RM != nil {
-				functions[key] = util.GenerateGFunction(ast.RM)
-			}
-			if ast.CondRM != nil {
-				functions[key] = util.GenerateConditionalGFunction(ast.CondRM)
-			}
+	if e.preMatcher != nil {
+		if decided, allow := e.preMatch(rvals); decided {
+			return allow, nil
 		}
 	}
 
+	if e.policyMatchers && matcher == "" {
+		// An EnforceContext selects the definitions explicitly, bypassing the pairing.
+		hasContext := false
+		if len(rvals) != 0 {
+			_, hasContext = rvals[0].(EnforceContext)
+		}
+		if !hasContext {
+			return e.enforceWithPolicyMatchers(ctx, explains, matched, effects, rvals...)
+		}
+	}
+
+	functions := e.matcherFunctions()
+
 	var (
 		rType = "r"
 		pType = "p"
@@ -653,12 +1172,21 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 			break
 		}
 	}
+	definitions := map[string]string{"r": rType, "p": pType, "e": eType}
+	if matcher == "" {
+		definitions["m"] = mType
+	}
+	for sec, key := range definitions {
+		if _, err = e.model.GetAssertion(sec, key); err != nil {
+			return false, err
+		}
+	}
 
 	var expString string
 	if matcher == "" {
 		expString = e.model["m"][mType].Value
 	} else {
-		expString = util.RemoveComments(util.EscapeAssertion(matcher))
+		expString = e.escapeMatcher(matcher)
 	}
 
 	rTokens := make(map[string]int, len(e.model["r"][rType].Tokens))
@@ -670,34 +1198,50 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 		pTokens[token] = i
 	}
 
-	if e.acceptJsonRequest {
-		// try to parse all request values from json to map[string]interface{}
-		// skip if there is an error
-		for i, rval := range rvals {
-			switch rval := rval.(type) {
-			case string:
-				var mapValue map[string]interface{}
-				mapValue, err = util.JsonToMap(rval)
-				if err == nil {
-					rvals[i] = mapValue
-				}
+	// Tokens of the other policy types referenced by the matcher follow the ones of pType,
+	// so that each rule of pType can be combined with their rules, see evalWithJoinedPolicies.
+	var joinedPolicies [][][]string
+	var joinedTypes []string
+	if e.policyJoin {
+		joinedTypes = e.getJoinedPolicyTypes(expString, pType)
+	}
+	for _, joinedType := range joinedTypes {
+		assertion := e.model["p"][joinedType]
+		for _, pvals := range assertion.Policy {
+			if len(assertion.Tokens) != len(pvals) {
+				return false, fmt.Errorf(
+					"invalid policy size: expected %d, got %d, pvals: %v",
+					len(assertion.Tokens),
+					len(pvals),
+					pvals)
 			}
 		}
+		offset := len(pTokens)
+		for i, token := range assertion.Tokens {
+			pTokens[token] = offset + i
+		}
+		joinedPolicies = append(joinedPolicies, assertion.Policy)
 	}
 
+	e.parseJsonRequest(rvals)
+
+	hasEval := util.HasEval(expString)
 	parameters := enforceParameters{
+		ctx: ctx,
+
 		rTokens: rTokens,
 		rVals:   rvals,
 
 		pTokens: pTokens,
+
+		subjectRoles: e.newSubjectRoles(expString, hasEval, rType, rTokens, rvals),
 	}
 
-	hasEval := util.HasEval(expString)
 	if hasEval {
 		functions["eval"] = generateEvalFunction(functions, &parameters)
 	}
 	var expression *govaluate.EvaluableExpression
-	expression, err = e.getAndStoreMatcherExpression(hasEval, expString, functions)
+	expression, err = e.getContextMatcherExpression(ctx, hasEval, expString, functions)
 	if err != nil {
 		return false, err
 	}
@@ -710,17 +1254,39 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 			rvals)
 	}
 
+	if e.isDenyOverridden(rTokens, rvals) {
+		e.logger.LogEnforce(expString, rvals, false, nil)
+		return false, nil
+	}
+
 	var policyEffects []effector.Effect
 	var matcherResults []float64
 
 	var effect effector.Effect
 	var explainIndex int
+	decided := false
 
-	if policyLen := len(e.model["p"][pType].Policy); policyLen != 0 && strings.Contains(expString, pType+"_") { //nolint:nestif // TODO: reduce function complexity
-		policyEffects = make([]effector.Effect, policyLen)
-		matcherResults = make([]float64, policyLen)
+	policyLen := len(e.model["p"][pType].Policy)
+	if policyLen != 0 && strings.Contains(expString, pType+"_") && matched == nil && effects == nil && e.isDenyFirstEffect(eType, pType, parameters.pTokens) {
+		effect, explainIndex, err = e.evalDenyFirst(expression, &parameters, pType, joinedPolicies, e.model["e"][eType].Value)
+		if err != nil {
+			return false, err
+		}
+	} else if policyLen != 0 && strings.Contains(expString, pType+"_") { //nolint:nestif // TODO: reduce function complexity
+		// An allow-override decision is made on the first matched allow rule, usually one of the first rules,
+		// so the effects are grown with the evaluated rules instead of being allocated for the whole policy.
+		size := policyLen
+		if e.isAllowOverrideEffect(eType) {
+			size = 0
+		}
+		policyEffects = make([]effector.Effect, size)
+		matcherResults = make([]float64, size)
 
 		for policyIndex, pvals := range e.model["p"][pType].Policy {
+			if policyIndex == len(policyEffects) {
+				policyEffects = append(policyEffects, 0)
+				matcherResults = append(matcherResults, 0)
+			}
 			// log.LogPrint("Policy Rule: ", pvals)
 			if len(e.model["p"][pType].Tokens) != len(pvals) {
 				return false, fmt.Errorf(
@@ -730,9 +1296,7 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 					pvals)
 			}
 
-			parameters.pVals = pvals
-
-			result, err := expression.Eval(parameters)
+			result, err := evalWithJoinedPolicies(expression, &parameters, pvals, joinedPolicies)
 			// log.LogPrint("Result: ", result)
 
 			if err != nil {
@@ -754,6 +1318,15 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 				return false, errors.New("matcher result should be bool, int or float")
 			}
 
+			if matched != nil && matcherResults[policyIndex] == 1 {
+				// pvals is the rule of the policy, the caller gets a copy it may modify.
+				*matched = append(*matched, append([]string(nil), pvals...))
+			}
+			if decided {
+				// Only reached when collecting all matched rules, the effect must not change anymore.
+				continue
+			}
+
 			if j, ok := parameters.pTokens[pType+"_eft"]; ok {
 				eft := parameters.pVals[j]
 				if eft == "allow" {
@@ -766,6 +1339,9 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 			} else {
 				policyEffects[policyIndex] = effector.Allow
 			}
+			if effects != nil {
+				*effects = append(*effects, matchedEffect(policyEffects[policyIndex], matcherResults[policyIndex]))
+			}
 
 			// if e.model["e"]["e"].Value == "priority(p_eft) || deny" {
 			//	break
@@ -776,7 +1352,10 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 				return false, err
 			}
 			if effect != effector.Indeterminate {
-				break
+				decided = true
+				if matched == nil {
+					break
+				}
 			}
 		}
 	} else {
@@ -790,10 +1369,13 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 
 		parameters.pVals = make([]string, len(parameters.pTokens))
 
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
 		result, err := expression.Eval(parameters)
 
 		if err != nil {
-			return false, err
+			return false, &evaluationError{err}
 		}
 
 		if result.(bool) {
@@ -801,6 +1383,9 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 		} else {
 			policyEffects[0] = effector.Indeterminate
 		}
+		if effects != nil {
+			*effects = append(*effects, policyEffects[0])
+		}
 
 		effect, explainIndex, err = e.eft.MergeEffects(e.model["e"][eType].Value, policyEffects, matcherResults, 0, 1)
 		if err != nil {
@@ -831,6 +1416,141 @@ func (e *Enforcer) enforce(matcher string, explains *[]string, rvals ...interfac
 	return result, nil
 }
 
+// matcherFunctions returns the functions available to the matchers, the g functions of the role definitions included.
+func (e *Enforcer) matcherFunctions() map[string]govaluate.ExpressionFunction {
+	functions := e.fm.GetFunctions()
+	for key, ast := range e.model["g"] {
+		// g must be a normal role definition (ast.RM != nil)
+		//   or a conditional role definition (ast.CondRM != nil)
+		// ast.RM and ast.CondRM shouldn't be nil at the same time
+		if ast.RM != nil {
+			functions[key] = generateGFunction(ast)
+		}
+		if ast.CondRM != nil {
+			functions[key] = util.GenerateConditionalGFunction(ast.CondRM)
+		}
+	}
+	if e.clock != nil {
+		functions["timeMatch"] = util.GenerateTimeWindowMatchFunc(e.clock)
+		functions["inTimeRange"] = util.GenerateInTimeRangeFunc(e.clock)
+		functions["weekdayMatch"] = util.GenerateWeekdayMatchFunc(e.clock)
+	}
+	e.bindContextFunctions(context.Background(), functions)
+	return functions
+}
+
+// parseJsonRequest parses the JSON request values into maps if enabled, see EnableAcceptJsonRequest.
+func (e *Enforcer) parseJsonRequest(rvals []interface{}) {
+	if !e.acceptJsonRequest {
+		return
+	}
+	// try to parse all request values from json to map[string]interface{}
+	// skip if there is an error
+	for i, rval := range rvals {
+		switch rval := rval.(type) {
+		case string:
+			mapValue, err := util.JsonToMap(rval)
+			if err == nil {
+				rvals[i] = mapValue
+			}
+		}
+	}
+}
+
+// isDenyFirstEffect reports whether the rules of pType can be evaluated deny rules first, see evalDenyFirst.
+func (e *Enforcer) isDenyFirstEffect(eType string, pType string, pTokens map[string]int) bool {
+	if _, ok := e.eft.(*effector.DefaultEffector); !ok {
+		return false
+	}
+	if _, ok := pTokens[pType+"_eft"]; !ok {
+		return false
+	}
+	expr := e.model["e"][eType].Value
+	return expr == constant.AllowAndDenyEffect || expr == constant.DenyOverrideEffect
+}
+
+// isAllowOverrideEffect reports whether the default effector decides on the first matched allow rule
+// by only reading the effect of the current rule.
+func (e *Enforcer) isAllowOverrideEffect(eType string) bool {
+	if _, ok := e.eft.(*effector.DefaultEffector); !ok {
+		return false
+	}
+	return e.model["e"][eType].Value == constant.AllowOverrideEffect
+}
+
+// evalDenyFirst evaluates the rules of pType for a deny-override effect expr, deny rules first, so that
+// the decision is made on the first matched deny rule without evaluating the other rules.
+// The effect and the explain index are the ones the default effector gives evaluating the rules in order.
+func (e *Enforcer) evalDenyFirst(expression *govaluate.EvaluableExpression, parameters *enforceParameters, pType string, joinedPolicies [][][]string, expr string) (effector.Effect, int, error) {
+	policy := e.model["p"][pType].Policy
+	tokenLen := len(e.model["p"][pType].Tokens)
+	eftIndex := parameters.pTokens[pType+"_eft"]
+
+	for _, pvals := range policy {
+		if tokenLen != len(pvals) {
+			return effector.Indeterminate, -1, fmt.Errorf(
+				"invalid policy size: expected %d, got %d, pvals: %v",
+				tokenLen,
+				len(pvals),
+				pvals)
+		}
+	}
+
+	// matches evaluates the matcher for the rule at index i.
+	matches := func(i int) (bool, error) {
+		result, err := evalWithJoinedPolicies(expression, parameters, policy[i], joinedPolicies)
+		if err != nil {
+			return false, err
+		}
+		switch result := result.(type) {
+		case bool:
+			return result, nil
+		case float64:
+			return result != 0, nil
+		default:
+			return false, errors.New("matcher result should be bool, int or float")
+		}
+	}
+
+	for i, pvals := range policy {
+		if pvals[eftIndex] != "deny" {
+			continue
+		}
+		ok, err := matches(i)
+		if err != nil {
+			return effector.Indeterminate, -1, err
+		}
+		if ok {
+			return effector.Deny, i, nil
+		}
+	}
+
+	if expr == constant.DenyOverrideEffect {
+		return effector.Allow, -1, nil
+	}
+	for i, pvals := range policy {
+		if pvals[eftIndex] != "allow" {
+			continue
+		}
+		ok, err := matches(i)
+		if err != nil {
+			return effector.Indeterminate, -1, err
+		}
+		if ok {
+			return effector.Allow, i, nil
+		}
+	}
+	return effector.Indeterminate, -1, nil
+}
+
+// generateGFunction generates the g(_, _[, _]) function of a role definition with a role manager.
+func generateGFunction(ast *model.Assertion) govaluate.ExpressionFunction {
+	if ast.IsDomainOptional() {
+		return util.GenerateDomainOptionalGFunction(ast.RM)
+	}
+	return util.GenerateGFunction(ast.RM)
+}
+
 func (e *Enforcer) getAndStoreMatcherExpression(hasEval bool, expString string, functions map[string]govaluate.ExpressionFunction) (*govaluate.EvaluableExpression, error) {
 	var expression *govaluate.EvaluableExpression
 	var err error
@@ -839,7 +1559,7 @@ func (e *Enforcer) getAndStoreMatcherExpression(hasEval bool, expString string,
 	if !hasEval && isPresent {
 		expression = cachedExpression.(*govaluate.EvaluableExpression)
 	} else {
-		expression, err = govaluate.NewEvaluableExpressionWithFunctions(expString, functions)
+		expression, err = govaluate.NewEvaluableExpressionWithFunctions(escapeSubjectRoles(expString), functions)
 		if err != nil {
 			return nil, err
 		}
@@ -850,7 +1570,7 @@ func (e *Enforcer) getAndStoreMatcherExpression(hasEval bool, expString string,
 
 // Enforce decides whether a "subject" can access a "object" with the operation "action", input parameters are usually: (sub, obj, act).
 func (e *Enforcer) Enforce(rvals ...interface{}) (bool, error) {
-	return e.enforce("", nil, rvals...)
+	return e.EnforceWithContext(context.Background(), rvals...)
 }
 
 // EnforceWithMatcher use a custom matcher to decides whether a "subject" can access a "object" with the operation "action", input parameters are usually: (matcher, sub, obj, act), use model matcher by default when matcher is "".
@@ -865,6 +1585,122 @@ func (e *Enforcer) EnforceEx(rvals ...interface{}) (bool, []string, error) {
 	return result, explain, err
 }
 
+// EnforceExVerbose decides like EnforceEx, also returning the effect of every rule evaluated until the decision,
+// in evaluation order: the effect of the rule if its matcher matched the request, effector.Indeterminate otherwise.
+// Without any rule, the only effect is the one of the matcher evaluated alone.
+func (e *Enforcer) EnforceExVerbose(rvals ...interface{}) (bool, []string, []effector.Effect, error) {
+	explain := []string{}
+	effects := []effector.Effect{}
+	result, err := e.enforceWithMatched(context.Background(), "", &explain, nil, &effects, rvals...)
+	return result, explain, effects, err
+}
+
+// matchedEffect is the effect a rule contributes to the decision, effector.Indeterminate if its matcher did not match.
+func matchedEffect(effect effector.Effect, matcherResult float64) effector.Effect {
+	if matcherResult == 0 {
+		return effector.Indeterminate
+	}
+	return effect
+}
+
+// ExplainAll returns every policy rule matching the request, in policy order and regardless of its effect,
+// while EnforceEx only returns the decisive one. It helps understanding overlapping rules.
+func (e *Enforcer) ExplainAll(rvals ...interface{}) ([][]string, error) {
+	matched := [][]string{}
+	if _, err := e.enforceWithMatched(context.Background(), "", nil, &matched, nil, rvals...); err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// TestRuleAgainstRequest decides whether the matcher matches the request with the given policy rule,
+// which does not have to be in the policy, e.g. to test a rule before adding it. The effect of the rule is ignored.
+// An EnforceContext may be passed as the first request value to select the request, policy and matcher definitions.
+func (e *Enforcer) TestRuleAgainstRequest(rule []string, rvals ...interface{}) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	rType, pType, mType := "r", "p", "m"
+	if len(rvals) != 0 {
+		if enforceContext, ok := rvals[0].(EnforceContext); ok {
+			rType = enforceContext.RType
+			pType = enforceContext.PType
+			mType = enforceContext.MType
+			rvals = rvals[1:]
+		}
+	}
+
+	rAssertion, err := e.model.GetAssertion("r", rType)
+	if err != nil {
+		return false, err
+	}
+	pAssertion, err := e.model.GetAssertion("p", pType)
+	if err != nil {
+		return false, err
+	}
+	mAssertion, err := e.model.GetAssertion("m", mType)
+	if err != nil {
+		return false, err
+	}
+	if len(rAssertion.Tokens) != len(rvals) {
+		return false, fmt.Errorf(
+			"invalid request size: expected %d, got %d, rvals: %v",
+			len(rAssertion.Tokens),
+			len(rvals),
+			rvals)
+	}
+	if len(pAssertion.Tokens) != len(rule) {
+		return false, fmt.Errorf(
+			"invalid policy size: expected %d, got %d, pvals: %v",
+			len(pAssertion.Tokens),
+			len(rule),
+			rule)
+	}
+
+	functions := e.matcherFunctions()
+
+	rTokens := make(map[string]int, len(rAssertion.Tokens))
+	for i, token := range rAssertion.Tokens {
+		rTokens[token] = i
+	}
+	pTokens := make(map[string]int, len(pAssertion.Tokens))
+	for i, token := range pAssertion.Tokens {
+		pTokens[token] = i
+	}
+	expString := mAssertion.Value
+	hasEval := util.HasEval(expString)
+	parameters := enforceParameters{
+		rTokens:      rTokens,
+		rVals:        rvals,
+		pTokens:      pTokens,
+		pVals:        rule,
+		subjectRoles: e.newSubjectRoles(expString, hasEval, rType, rTokens, rvals),
+	}
+	if hasEval {
+		functions["eval"] = generateEvalFunction(functions, &parameters)
+	}
+	expression, err := e.getAndStoreMatcherExpression(hasEval, expString, functions)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := expression.Eval(parameters)
+	if err != nil {
+		return false, err
+	}
+	switch result := result.(type) {
+	case bool:
+		return result, nil
+	case float64:
+		return result != 0, nil
+	default:
+		return false, errors.New("matcher result should be bool, int or float")
+	}
+}
+
 // EnforceExWithMatcher use a custom matcher and explain enforcement by informing matched rules.
 func (e *Enforcer) EnforceExWithMatcher(matcher string, rvals ...interface{}) (bool, []string, error) {
 	explain := []string{}
@@ -885,6 +1721,60 @@ func (e *Enforcer) BatchEnforce(requests [][]interface{}) ([]bool, error) {
 	return results, nil
 }
 
+// BatchEnforceConcurrent enforce in batches like BatchEnforce, evaluating the requests with a pool of workers.
+// The results are in the order of the requests. If workers is not positive, the number of CPUs is used.
+// Enforcement only reads the model, the role managers and the concurrency-safe matcher cache, so the batch is safe
+// as long as the policy is not modified meanwhile, which SyncedEnforcer guarantees by holding its read lock.
+// Custom functions and role manager matching functions must be safe for concurrent use as well.
+func (e *Enforcer) BatchEnforceConcurrent(requests [][]interface{}, workers int) ([]bool, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	results := make([]bool, len(requests))
+	errs := make([]error, len(requests))
+	var next, failed int32 = -1, 0
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(requests) {
+					return
+				}
+				request := requests[i]
+				if e.acceptJsonRequest {
+					// JSON request values are parsed in place, the caller may share a request between several entries.
+					request = append([]interface{}(nil), request...)
+				}
+				results[i], errs[i] = e.enforce("", nil, request...)
+				if errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return results[:i], err
+		}
+	}
+	return results, nil
+}
+
+// BatchEnforceParallel is BatchEnforceConcurrent with concurrency as the number of workers.
+func (e *Enforcer) BatchEnforceParallel(concurrency int, requests [][]interface{}) ([]bool, error) {
+	return e.BatchEnforceConcurrent(requests, concurrency)
+}
+
 // BatchEnforceWithMatcher enforce with matcher in batches.
 func (e *Enforcer) BatchEnforceWithMatcher(matcher string, requests [][]interface{}) ([]bool, error) {
 	var results []bool
@@ -907,6 +1797,18 @@ func (e *Enforcer) AddNamedMatchingFunc(ptype, name string, fn rbac.MatchingFunc
 	return false
 }
 
+// SetNamedDomainCaptureFunc sets the DomainCaptureFunc of the RoleManager of ptype, so that g(r.sub, p.sub, r.dom)
+// only holds through roles whose name encodes the domain r.dom. It returns false if the RoleManager doesn't support it.
+func (e *Enforcer) SetNamedDomainCaptureFunc(ptype string, fn rbac.DomainCaptureFunc) bool {
+	if rm, ok := e.rmMap[ptype].(interface {
+		SetDomainCaptureFunc(fn rbac.DomainCaptureFunc)
+	}); ok {
+		rm.SetDomainCaptureFunc(fn)
+		return true
+	}
+	return false
+}
+
 // AddNamedDomainMatchingFunc add MatchingFunc by ptype to RoleManager.
 func (e *Enforcer) AddNamedDomainMatchingFunc(ptype, name string, fn rbac.MatchingFunc) bool {
 	if rm, ok := e.rmMap[ptype]; ok {
@@ -959,13 +1861,112 @@ func (e *Enforcer) SetNamedDomainLinkConditionFuncParams(ptype, user, role, doma
 	return false
 }
 
+// SetNamedDefaultLinkConditionFunc sets the condition function applied to every link of the
+// ptype role manager that carries condition parameters but has no condition function of its own.
+// It returns false if ptype has no conditional role manager supporting it.
+func (e *Enforcer) SetNamedDefaultLinkConditionFunc(ptype string, fn rbac.LinkConditionFunc) bool {
+	if rm, ok := e.condRmMap[ptype]; ok {
+		if drm, ok := rm.(interface {
+			SetDefaultLinkConditionFunc(fn rbac.LinkConditionFunc)
+		}); ok {
+			drm.SetDefaultLinkConditionFunc(fn)
+			return true
+		}
+	}
+	return false
+}
+
+// EnableNamedTemporalRoles makes the links of ptype active only within the time window given by
+// their condition columns, e.g. "g = _, _, (_, _)" with "g, alice, admin, 2026-01-01T00:00:00Z, ".
+// Bounds are in RFC 3339 and an empty bound leaves the window open, as for timeMatch. The current
+// time is the one of SetClock.
+func (e *Enforcer) EnableNamedTemporalRoles(ptype string) bool {
+	return e.SetNamedDefaultLinkConditionFunc(ptype, util.GenerateTimeWindowLinkConditionFunc(e.now))
+}
+
+// EnablePolicyJoin controls whether a matcher may reference the tokens of other policy types than the enforced one,
+// e.g. "r.obj == p.obj && r.obj == p2.obj && r.act == p2.act" when enforcing p. It is disabled by default,
+// in which case such a matcher fails to evaluate as the tokens of p2 are unknown.
+//
+// When enabled, each rule of the enforced policy type is combined with every rule of the referenced policy types
+// in turn, and the rule matches if the matcher holds for at least one combination. Its effect is the one of the
+// enforced rule. This iterates the cross product of the policy sets, so the cost of a request grows with the
+// product of their sizes, and a referenced policy type without rules matches nothing.
+func (e *Enforcer) EnablePolicyJoin(enable bool) {
+	e.policyJoin = enable
+}
+
+// getJoinedPolicyTypes returns the policy types other than pType whose tokens are referenced by the matcher.
+func (e *Enforcer) getJoinedPolicyTypes(expString string, pType string) []string {
+	var res []string
+	for ptype, assertion := range e.model["p"] {
+		if ptype == pType {
+			continue
+		}
+		for _, token := range assertion.Tokens {
+			if util.HasToken(expString, token) {
+				res = append(res, ptype)
+				break
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// evalWithJoinedPolicies evaluates the matcher for a rule of the enforced policy type.
+// If the matcher references other policy types, the rule is combined with each rule of those types in turn,
+// see EnablePolicyJoin.
+func evalWithJoinedPolicies(expression *govaluate.EvaluableExpression, parameters *enforceParameters, pvals []string, joinedPolicies [][][]string) (interface{}, error) {
+	if parameters.ctx != nil {
+		if err := parameters.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(joinedPolicies) == 0 {
+		parameters.pVals = pvals
+		result, err := expression.Eval(*parameters)
+		if err != nil {
+			return nil, &evaluationError{err}
+		}
+		return result, nil
+	}
+
+	var result interface{} = false
+	for _, joinedPvals := range joinedPolicies[0] {
+		combined := append(pvals[:len(pvals):len(pvals)], joinedPvals...)
+		res, err := evalWithJoinedPolicies(expression, parameters, combined, joinedPolicies[1:])
+		if err != nil {
+			return nil, err
+		}
+		switch res := res.(type) {
+		case bool:
+			if res {
+				return res, nil
+			}
+		case float64:
+			if res != 0 {
+				return res, nil
+			}
+		}
+		result = res
+	}
+	return result, nil
+}
+
 // assumes bounds have already been checked.
 type enforceParameters struct {
+	// ctx is the context of the enforcement, checked before evaluating the matcher on each rule.
+	ctx context.Context
+
 	rTokens map[string]int
 	rVals   []interface{}
 
 	pTokens map[string]int
 	pVals   []string
+
+	// subjectRoles resolves r.sub.roles, nil if the matcher cannot reference it.
+	subjectRoles *subjectRoles
 }
 
 // implements govaluate.Parameters.
@@ -984,6 +1985,9 @@ func (p enforceParameters) Get(name string) (interface{}, error) {
 	case 'r':
 		i, ok := p.rTokens[name]
 		if !ok {
+			if p.subjectRoles != nil && name == p.subjectRoles.token {
+				return p.subjectRoles.get()
+			}
 			return nil, errors.New("No parameter '" + name + "' found.")
 		}
 		return p.rVals[i], nil
@@ -1002,7 +2006,7 @@ func generateEvalFunction(functions map[string]govaluate.ExpressionFunction, par
 		if !ok {
 			return nil, errors.New("argument of eval(subrule string) must be a string")
 		}
-		expression = util.EscapeAssertion(expression)
+		expression = escapeSubjectRoles(util.EscapeAssertion(expression))
 		expr, err := govaluate.NewEvaluableExpressionWithFunctions(expression, functions)
 		if err != nil {
 			return nil, fmt.Errorf("error while parsing eval parameter: %s, %s", expression, err.Error())
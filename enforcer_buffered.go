@@ -0,0 +1,568 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// FlushErrorFunc is called when buffered operations could not be written to the adapter.
+// ops contains the operations that were not persisted, in the order they were made.
+type FlushErrorFunc func(ops []persist.PolicyOperation, err error)
+
+// BufferedEnforcer wraps SyncedEnforcer and buffers Auto-Save writes.
+// Policy changes are applied to the in-memory model immediately, while the
+// corresponding adapter writes are queued and flushed periodically, when the
+// buffer reaches its maximum size, or when Flush() / Close() is called.
+type BufferedEnforcer struct {
+	*SyncedEnforcer
+	buffer *bufferedAdapter
+
+	maxBufferSize   int32
+	rollbackOnError int32
+	onFlushError    FlushErrorFunc
+	callbackMutex   sync.RWMutex
+
+	flushMutex sync.Mutex
+	flushCh    chan struct{}
+	intervalCh chan time.Duration
+	stopCh     chan struct{}
+	closed     int32
+}
+
+// bufferedOperation is a queued adapter write.
+// fieldValues is only set for filtered removals and updates, in which case Rules and OldRules
+// hold the rules that were changed in the model so the change can be rolled back.
+type bufferedOperation struct {
+	persist.PolicyOperation
+	fieldIndex  int
+	fieldValues []string
+}
+
+// bufferedAdapter queues the Auto-Save calls of the enforcer instead of
+// forwarding them to the underlying adapter. The calls are staged until the
+// enforcer reports the change of the model, and only then queued.
+type bufferedAdapter struct {
+	persist.Adapter
+	enforcer *BufferedEnforcer
+	staged   []bufferedOperation
+	ops      []bufferedOperation
+	mutex    sync.Mutex
+}
+
+// NewBufferedEnforcer creates a buffered enforcer via file or DB.
+// It accepts the same parameters as NewEnforcer.
+func NewBufferedEnforcer(params ...interface{}) (*BufferedEnforcer, error) {
+	e := &BufferedEnforcer{}
+	var err error
+	e.SyncedEnforcer, err = NewSyncedEnforcer(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	e.flushCh = make(chan struct{}, 1)
+	e.intervalCh = make(chan time.Duration)
+	e.stopCh = make(chan struct{})
+	e.wrapAdapter(e.adapter)
+	e.SubscribePolicyChanges(e.policyChanged)
+
+	go e.runFlusher()
+	return e, nil
+}
+
+// policyChanged queues the adapter write staged for a change of the model,
+// so that writes whose change of the model failed are never flushed.
+func (e *BufferedEnforcer) policyChanged(ev PolicyChangeEvent) {
+	if e.buffer == nil {
+		return
+	}
+	if ev.Reset {
+		e.buffer.unstage()
+		return
+	}
+	e.buffer.commit(ev)
+}
+
+// SetFlushInterval sets how often the buffer is flushed in the background.
+// A duration of 0 or less disables periodic flushing. It does nothing after Close.
+func (e *BufferedEnforcer) SetFlushInterval(d time.Duration) {
+	select {
+	case e.intervalCh <- d:
+	case <-e.stopCh:
+	}
+}
+
+// SetMaxBufferSize sets the number of queued operations that triggers a background flush.
+// A size of 0 or less disables size-triggered flushing.
+func (e *BufferedEnforcer) SetMaxBufferSize(size int) {
+	atomic.StoreInt32(&e.maxBufferSize, int32(size))
+}
+
+// SetFlushErrorCallback sets the function called when a flush fails.
+func (e *BufferedEnforcer) SetFlushErrorCallback(fn FlushErrorFunc) {
+	e.callbackMutex.Lock()
+	defer e.callbackMutex.Unlock()
+	e.onFlushError = fn
+}
+
+// EnableRollbackOnFlushError controls whether operations that failed to be
+// persisted are reverted in the in-memory model.
+func (e *BufferedEnforcer) EnableRollbackOnFlushError(enable bool) {
+	var enabled int32
+	if enable {
+		enabled = 1
+	}
+	atomic.StoreInt32(&e.rollbackOnError, enabled)
+}
+
+// BufferedOperationCount returns the number of operations waiting to be flushed.
+func (e *BufferedEnforcer) BufferedOperationCount() int {
+	e.flushMutex.Lock()
+	defer e.flushMutex.Unlock()
+	if e.buffer == nil {
+		return 0
+	}
+	e.buffer.mutex.Lock()
+	defer e.buffer.mutex.Unlock()
+	return len(e.buffer.ops)
+}
+
+// SetAdapter flushes the pending operations to the current adapter and then sets the new adapter.
+// A failed flush is reported through the flush error callback.
+func (e *BufferedEnforcer) SetAdapter(adapter persist.Adapter) {
+	e.flushMutex.Lock()
+	defer e.flushMutex.Unlock()
+	_ = e.flush()
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.wrapAdapter(adapter)
+}
+
+// GetAdapter gets the underlying adapter the buffered operations are written to.
+func (e *BufferedEnforcer) GetAdapter() persist.Adapter {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	if e.buffer == nil {
+		return nil
+	}
+	return unwrapRuleCodec(e.buffer.Adapter)
+}
+
+// SetRuleCodec flushes the pending operations and then sets the functions transforming the rules
+// between their encoding in the storage and the model.
+// A failed flush is reported through the flush error callback.
+func (e *BufferedEnforcer) SetRuleCodec(decode RuleCodecFunc, encode RuleCodecFunc) {
+	e.flushMutex.Lock()
+	defer e.flushMutex.Unlock()
+	// flush passes its error to the flush error callback.
+	_ = e.flush()
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.ruleDecoder = decode
+	e.ruleEncoder = encode
+	if e.buffer != nil {
+		e.buffer.Adapter = e.wrapRuleCodec(unwrapRuleCodec(e.buffer.Adapter))
+	}
+}
+
+// Flush writes all queued operations to the adapter.
+// If the adapter does not implement a write, the whole model is saved instead.
+// On failure, the unwritten operations are passed to the flush error callback
+// and either rolled back from the in-memory model, if enabled, or put back at
+// the head of the buffer so that the next flush retries them.
+func (e *BufferedEnforcer) Flush() error {
+	e.flushMutex.Lock()
+	defer e.flushMutex.Unlock()
+	return e.flush()
+}
+
+func (e *BufferedEnforcer) flush() error {
+	if e.buffer == nil {
+		return nil
+	}
+	ops := e.buffer.drain()
+
+	for i, op := range ops {
+		err := e.buffer.apply(op)
+		if err != nil && err.Error() == notImplemented {
+			// the adapter cannot write this operation, persist the whole model instead
+			if err = e.savePolicy(); err == nil {
+				return nil
+			}
+		}
+		if err != nil {
+			failed := ops[i:]
+			if atomic.LoadInt32(&e.rollbackOnError) != 0 {
+				e.m.Lock()
+				rollbackErr := e.rollback(failed)
+				e.m.Unlock()
+				if rollbackErr != nil {
+					err = errors.New(err.Error() + ", rollback failed: " + rollbackErr.Error())
+				}
+			} else {
+				e.buffer.requeue(failed)
+			}
+			e.callbackMutex.RLock()
+			fn := e.onFlushError
+			e.callbackMutex.RUnlock()
+			if fn != nil {
+				fn(toPolicyOperations(failed), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// savePolicy saves the in-memory model to the underlying adapter and discards
+// the pending operations, which the saved model already contains.
+func (e *BufferedEnforcer) savePolicy() error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	if err := e.buffer.Adapter.SavePolicy(e.model); err != nil {
+		return err
+	}
+	e.buffer.drain()
+	return nil
+}
+
+// Close stops background flushing and drains the buffer.
+func (e *BufferedEnforcer) Close() error {
+	if !atomic.CompareAndSwapInt32(&e.closed, 0, 1) {
+		return nil
+	}
+	close(e.stopCh)
+	return e.Flush()
+}
+
+// LoadPolicy flushes the pending operations and then reloads the policy from file/database.
+func (e *BufferedEnforcer) LoadPolicy() error {
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	return e.SyncedEnforcer.LoadPolicy()
+}
+
+// ReloadPolicyAtomicSwap flushes the pending operations and then reloads the policy aside and swaps it in.
+func (e *BufferedEnforcer) ReloadPolicyAtomicSwap() error {
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	return e.SyncedEnforcer.ReloadPolicyAtomicSwap()
+}
+
+func (e *BufferedEnforcer) wrapAdapter(adapter persist.Adapter) {
+	if adapter == nil {
+		e.buffer = nil
+		e.adapter = nil
+		return
+	}
+	e.buffer = &bufferedAdapter{Adapter: e.wrapRuleCodec(adapter), enforcer: e}
+	e.adapter = e.buffer
+}
+
+func (e *BufferedEnforcer) runFlusher() {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-tick:
+		case <-e.flushCh:
+		case d := <-e.intervalCh:
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+			if d > 0 {
+				ticker = time.NewTicker(d)
+				tick = ticker.C
+			}
+			continue
+		case <-e.stopCh:
+			return
+		}
+		// errors are reported through the flush error callback
+		_ = e.Flush()
+	}
+}
+
+// rollback reverts the given operations in the in-memory model, newest first.
+func (e *BufferedEnforcer) rollback(ops []bufferedOperation) error {
+	needToRebuild := false
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		var err error
+		switch op.Type {
+		case persist.OperationAdd:
+			_, err = e.model.RemovePolicies(op.Section, op.PolicyType, op.Rules)
+		case persist.OperationRemove:
+			err = e.model.AddPolicies(op.Section, op.PolicyType, op.Rules)
+		case persist.OperationUpdate:
+			_, err = e.model.RemovePolicies(op.Section, op.PolicyType, op.Rules)
+			if err == nil {
+				err = e.model.AddPolicies(op.Section, op.PolicyType, op.OldRules)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if op.Section == "g" {
+			needToRebuild = true
+		}
+	}
+
+	e.invalidateMatcherMap()
+	e.invalidatePolicyIndexes()
+	if needToRebuild && e.autoBuildRoleLinks {
+		return e.Enforcer.BuildRoleLinks()
+	}
+	return nil
+}
+
+func toPolicyOperations(ops []bufferedOperation) []persist.PolicyOperation {
+	res := make([]persist.PolicyOperation, len(ops))
+	for i, op := range ops {
+		res[i] = op.PolicyOperation
+	}
+	return res
+}
+
+// stage records an adapter write until the change of the model it belongs to is committed.
+func (a *bufferedAdapter) stage(op bufferedOperation) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.staged = append(a.staged, op)
+}
+
+// unstage discards the staged writes, e.g. when the whole policy was replaced.
+func (a *bufferedAdapter) unstage() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.staged = nil
+}
+
+// commit queues the last staged write of the kind of the change ev, with the rules that changed.
+// The writes staged before it belong to changes of the model that failed and are discarded.
+func (a *bufferedAdapter) commit(ev PolicyChangeEvent) {
+	a.mutex.Lock()
+	var op *bufferedOperation
+	for i := len(a.staged) - 1; i >= 0; i-- {
+		staged := a.staged[i]
+		if staged.Type == ev.Op && staged.Section == ev.Sec && staged.PolicyType == ev.Ptype {
+			op = &staged
+			a.staged = a.staged[i+1:]
+			break
+		}
+	}
+	a.mutex.Unlock()
+	if op == nil {
+		return
+	}
+
+	op.Rules = ev.Rules
+	op.OldRules = ev.OldRules
+	a.enqueue(*op)
+}
+
+func (a *bufferedAdapter) enqueue(op bufferedOperation) {
+	a.mutex.Lock()
+	a.ops = append(a.ops, op)
+	size := len(a.ops)
+	a.mutex.Unlock()
+
+	if maxSize := atomic.LoadInt32(&a.enforcer.maxBufferSize); maxSize > 0 && size >= int(maxSize) {
+		select {
+		case a.enforcer.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *bufferedAdapter) drain() []bufferedOperation {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	ops := a.ops
+	a.ops = nil
+	return ops
+}
+
+// requeue puts operations that could not be written back at the head of the buffer.
+func (a *bufferedAdapter) requeue(ops []bufferedOperation) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.ops = append(append([]bufferedOperation{}, ops...), a.ops...)
+}
+
+func (a *bufferedAdapter) apply(op bufferedOperation) error {
+	var err error
+	switch {
+	case op.fieldValues != nil:
+		err = a.Adapter.RemoveFilteredPolicy(op.Section, op.PolicyType, op.fieldIndex, op.fieldValues...)
+		if err == nil && op.Type == persist.OperationUpdate {
+			err = applyAddOperation(a.Adapter, persist.PolicyOperation{Section: op.Section, PolicyType: op.PolicyType, Rules: op.Rules})
+		}
+	case op.Type == persist.OperationAdd:
+		err = applyAddOperation(a.Adapter, op.PolicyOperation)
+	case op.Type == persist.OperationRemove:
+		err = applyRemoveOperation(a.Adapter, op.PolicyOperation)
+	case op.Type == persist.OperationUpdate:
+		err = applyUpdateOperation(a.Adapter, op.PolicyOperation)
+	}
+	return err
+}
+
+// missingRules returns the rules that are not in the model yet.
+func (a *bufferedAdapter) missingRules(sec string, ptype string, rules [][]string) [][]string {
+	var res [][]string
+	for _, rule := range rules {
+		if ok, _ := a.enforcer.model.HasPolicy(sec, ptype, rule); !ok {
+			res = append(res, rule)
+		}
+	}
+	return res
+}
+
+// existingRules returns the rules that are already in the model.
+func (a *bufferedAdapter) existingRules(sec string, ptype string, rules [][]string) [][]string {
+	var res [][]string
+	for _, rule := range rules {
+		if ok, _ := a.enforcer.model.HasPolicy(sec, ptype, rule); ok {
+			res = append(res, rule)
+		}
+	}
+	return res
+}
+
+// LoadPolicy loads all policy rules from the underlying adapter.
+func (a *bufferedAdapter) LoadPolicy(model model.Model) error {
+	return a.Adapter.LoadPolicy(model)
+}
+
+// SavePolicy discards the pending operations and saves all policy rules to the underlying adapter.
+func (a *bufferedAdapter) SavePolicy(model model.Model) error {
+	a.drain()
+	return a.Adapter.SavePolicy(model)
+}
+
+// AddPolicy stages a policy rule to be added to the storage.
+func (a *bufferedAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+// AddPolicies stages policy rules to be added to the storage.
+func (a *bufferedAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	rules = a.missingRules(sec, ptype, rules)
+	if len(rules) == 0 {
+		return nil
+	}
+	a.stage(bufferedOperation{PolicyOperation: persist.PolicyOperation{
+		Type:       persist.OperationAdd,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      rules,
+	}})
+	return nil
+}
+
+// RemovePolicy stages a policy rule to be removed from the storage.
+func (a *bufferedAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+// RemovePolicies stages policy rules to be removed from the storage.
+func (a *bufferedAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	rules = a.existingRules(sec, ptype, rules)
+	if len(rules) == 0 {
+		return nil
+	}
+	a.stage(bufferedOperation{PolicyOperation: persist.PolicyOperation{
+		Type:       persist.OperationRemove,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      rules,
+	}})
+	return nil
+}
+
+// RemoveFilteredPolicy stages the removal of policy rules that match the filter from the storage.
+func (a *bufferedAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	rules, err := a.enforcer.model.GetFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return err
+	}
+	a.stage(bufferedOperation{
+		PolicyOperation: persist.PolicyOperation{
+			Type:       persist.OperationRemove,
+			Section:    sec,
+			PolicyType: ptype,
+			Rules:      rules,
+		},
+		fieldIndex:  fieldIndex,
+		fieldValues: append([]string{}, fieldValues...),
+	})
+	return nil
+}
+
+// UpdatePolicy stages a policy rule to be updated in the storage.
+func (a *bufferedAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return a.UpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+// UpdatePolicies stages policy rules to be updated in the storage.
+func (a *bufferedAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	if len(a.existingRules(sec, ptype, oldRules)) != len(oldRules) {
+		return nil
+	}
+	a.stage(bufferedOperation{PolicyOperation: persist.PolicyOperation{
+		Type:       persist.OperationUpdate,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      newRules,
+		OldRules:   oldRules,
+	}})
+	return nil
+}
+
+// UpdateFilteredPolicies stages the replacement of the rules that match the filter with newRules.
+func (a *bufferedAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	oldRules, err := a.enforcer.model.GetFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	if err != nil || len(oldRules) == 0 {
+		return nil, err
+	}
+	a.stage(bufferedOperation{
+		PolicyOperation: persist.PolicyOperation{
+			Type:       persist.OperationUpdate,
+			Section:    sec,
+			PolicyType: ptype,
+			Rules:      newRules,
+			OldRules:   oldRules,
+		},
+		fieldIndex:  fieldIndex,
+		fieldValues: append([]string{}, fieldValues...),
+	})
+	return oldRules, nil
+}
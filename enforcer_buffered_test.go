@@ -0,0 +1,273 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// recordingAdapter records the rules written to it and can be made to fail.
+type recordingAdapter struct {
+	mutex   sync.Mutex
+	added   [][]string
+	removed [][]string
+	saved   int
+	fail    bool
+}
+
+func (a *recordingAdapter) LoadPolicy(model model.Model) error { return nil }
+
+func (a *recordingAdapter) SavePolicy(model model.Model) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.saved++
+	return nil
+}
+
+func (a *recordingAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.fail {
+		return errors.New("storage unavailable")
+	}
+	a.added = append(a.added, rule)
+	return nil
+}
+
+func (a *recordingAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.fail {
+		return errors.New("storage unavailable")
+	}
+	a.removed = append(a.removed, rule)
+	return nil
+}
+
+func (a *recordingAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errors.New(notImplemented)
+}
+
+func (a *recordingAdapter) addedCount() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.added)
+}
+
+func TestBufferedEnforcerFlush(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/rbac_model.conf", a)
+	defer e.Close()
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	_, _ = e.AddGroupingPolicy("bob", "admin")
+	_, _ = e.AddPolicy("alice", "data1", "read")
+
+	if a.addedCount() != 0 {
+		t.Errorf("adapter should not be written before flush, got %d rules", a.addedCount())
+	}
+	if e.BufferedOperationCount() != 2 {
+		t.Errorf("buffered operation count: %d, expected 2", e.BufferedOperationCount())
+	}
+
+	ok, _ := e.Enforce("alice", "data1", "read")
+	if !ok {
+		t.Error("buffered rule should be applied in memory immediately")
+	}
+
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if a.addedCount() != 2 {
+		t.Errorf("adapter rule count: %d, expected 2", a.addedCount())
+	}
+	if e.BufferedOperationCount() != 0 {
+		t.Errorf("buffered operation count: %d, expected 0", e.BufferedOperationCount())
+	}
+}
+
+func TestBufferedEnforcerMaxBufferSize(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+	e.SetMaxBufferSize(2)
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	_, _ = e.AddPolicy("alice", "data2", "read")
+
+	deadline := time.Now().Add(time.Second)
+	for a.addedCount() != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if a.addedCount() != 2 {
+		t.Errorf("adapter rule count: %d, expected 2", a.addedCount())
+	}
+}
+
+func TestBufferedEnforcerFlushInterval(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+	e.SetFlushInterval(10 * time.Millisecond)
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+
+	deadline := time.Now().Add(time.Second)
+	for a.addedCount() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if a.addedCount() != 1 {
+		t.Errorf("adapter rule count: %d, expected 1", a.addedCount())
+	}
+}
+
+func TestBufferedEnforcerSetFlushIntervalAfterClose(t *testing.T) {
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", &recordingAdapter{})
+	_ = e.Close()
+
+	done := make(chan struct{})
+	go func() {
+		e.SetFlushInterval(10 * time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetFlushInterval should not block after Close")
+	}
+}
+
+func TestBufferedEnforcerFailedChange(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+
+	// An adapter write whose change of the model then fails is not queued.
+	_ = e.buffer.AddPolicy("p", "p", []string{"eve", "data1", "read"})
+	if e.BufferedOperationCount() != 0 {
+		t.Errorf("buffered operation count: %d, expected 0", e.BufferedOperationCount())
+	}
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(a.added, [][]string{{"alice", "data1", "read"}}) {
+		t.Errorf("adapter rules: %v, expected [[alice data1 read]]", a.added)
+	}
+}
+
+func TestBufferedEnforcerFlushError(t *testing.T) {
+	a := &recordingAdapter{fail: true}
+	e, _ := NewBufferedEnforcer("examples/rbac_model.conf", a)
+	defer e.Close()
+
+	var failed []persist.PolicyOperation
+	e.SetFlushErrorCallback(func(ops []persist.PolicyOperation, err error) {
+		failed = ops
+	})
+	e.EnableRollbackOnFlushError(true)
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	_, _ = e.AddGroupingPolicy("bob", "alice")
+
+	if err := e.Flush(); err == nil {
+		t.Error("flush should fail")
+	}
+	if len(failed) != 2 {
+		t.Errorf("failed operation count: %d, expected 2", len(failed))
+	}
+
+	ok, _ := e.HasPolicy("alice", "data1", "read")
+	if ok {
+		t.Error("failed rule should be rolled back")
+	}
+	ok, _ = e.HasRoleForUser("bob", "alice")
+	if ok {
+		t.Error("failed role link should be rolled back")
+	}
+}
+
+func TestBufferedEnforcerFlushErrorRequeue(t *testing.T) {
+	a := &recordingAdapter{fail: true}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	_, _ = e.AddPolicy("bob", "data2", "write")
+	if err := e.Flush(); err == nil {
+		t.Error("flush should fail")
+	}
+	if ok, _ := e.HasPolicy("alice", "data1", "read"); !ok {
+		t.Error("failed rule should be kept without rollback")
+	}
+
+	_, _ = e.AddPolicy("cathy", "data3", "read")
+	a.mutex.Lock()
+	a.fail = false
+	a.mutex.Unlock()
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if !util.Array2DEquals(a.added, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"cathy", "data3", "read"}}) {
+		t.Errorf("adapter rules: %v, expected the failed rules to be retried first", a.added)
+	}
+}
+
+func TestBufferedEnforcerFlushNotImplemented(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	_, _ = e.RemoveFilteredPolicy(0, "alice")
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	a.mutex.Lock()
+	saved := a.saved
+	a.mutex.Unlock()
+	if saved != 1 {
+		t.Errorf("adapter save count: %d, expected 1", saved)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if a.saved != 1 {
+		t.Error("operations covered by the saved model should not be written again")
+	}
+}
+
+func TestBufferedEnforcerClose(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a.addedCount() != 1 {
+		t.Errorf("adapter rule count: %d, expected 1", a.addedCount())
+	}
+}
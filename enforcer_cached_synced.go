@@ -55,6 +55,15 @@ func (e *SyncedCachedEnforcer) EnableCache(enableCache bool) {
 	atomic.StoreInt32(&e.enableCache, enabled)
 }
 
+// EnforceTyped decides whether the request built from a struct registered by RegisterRequestType is allowed.
+func (e *SyncedCachedEnforcer) EnforceTyped(req interface{}) (bool, error) {
+	rvals, err := e.typedRequestValues(req)
+	if err != nil {
+		return false, err
+	}
+	return e.Enforce(rvals...)
+}
+
 // Enforce decides whether a "subject" can access a "object" with the operation "action", input parameters are usually: (sub, obj, act).
 // if rvals is not string , ignore the cache.
 func (e *SyncedCachedEnforcer) Enforce(rvals ...interface{}) (bool, error) {
@@ -82,6 +91,24 @@ func (e *SyncedCachedEnforcer) Enforce(rvals ...interface{}) (bool, error) {
 	return res, err
 }
 
+// EnforceExNoCache explains enforcement like EnforceEx, bypassing the decision cache: the result is neither
+// read from nor written to the cache, so what-if queries of admin tooling don't pollute the hot request path.
+func (e *SyncedCachedEnforcer) EnforceExNoCache(rvals ...interface{}) (bool, []string, error) {
+	return e.SyncedEnforcer.EnforceEx(rvals...)
+}
+
+// SetDenyOverrideSet sets the values of a request field that are denied regardless of any allow rule,
+// and clears the cached decisions, which may allow a newly denied value.
+func (e *SyncedCachedEnforcer) SetDenyOverrideSet(token string, values []string) error {
+	if err := e.SyncedEnforcer.SetDenyOverrideSet(token, values); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&e.enableCache) != 0 {
+		return e.cache.Clear()
+	}
+	return nil
+}
+
 func (e *SyncedCachedEnforcer) LoadPolicy() error {
 	if atomic.LoadInt32(&e.enableCache) != 0 {
 		if err := e.cache.Clear(); err != nil {
@@ -91,6 +118,18 @@ func (e *SyncedCachedEnforcer) LoadPolicy() error {
 	return e.SyncedEnforcer.LoadPolicy()
 }
 
+// ReloadPolicyAtomicSwap reloads the policy aside and swaps it in, then clears the decision cache,
+// so that no decision cached from the previous policy is served afterwards.
+func (e *SyncedCachedEnforcer) ReloadPolicyAtomicSwap() error {
+	if err := e.SyncedEnforcer.ReloadPolicyAtomicSwap(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&e.enableCache) != 0 {
+		return e.cache.Clear()
+	}
+	return nil
+}
+
 func (e *SyncedCachedEnforcer) AddPolicy(params ...interface{}) (bool, error) {
 	if ok, err := e.checkOneAndRemoveCache(params...); !ok {
 		return ok, err
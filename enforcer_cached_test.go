@@ -71,3 +71,22 @@ func TestCache(t *testing.T) {
 	testEnforceCache(t, e, "alice", "data2", "read", false)
 	testEnforceCache(t, e, "alice", "data2", "write", false)
 }
+
+func TestEnforceExNoCache(t *testing.T) {
+	e, _ := NewCachedEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+
+	// A stale cached decision must not be served to the no-cache path.
+	key, _ := e.getKey("alice", "data1", "read")
+	_ = e.setCachedResult(key, false, e.expireTime)
+	res, explain, err := e.EnforceExNoCache("alice", "data1", "read")
+	if err != nil || !res || len(explain) == 0 {
+		t.Errorf("EnforceExNoCache: %t, %v, %v, supposed to be true with an explanation", res, explain, err)
+	}
+
+	// The no-cache path must not populate the cache either.
+	_, _, _ = e.EnforceExNoCache("bob", "data2", "write")
+	key, _ = e.getKey("bob", "data2", "write")
+	if _, err = e.getCachedResult(key); err == nil {
+		t.Error("EnforceExNoCache should not write the cache")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+
+	"github.com/casbin/govaluate"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// ContextFunction is a matcher function receiving the context of the enforcement,
+// so that slow lookups, e.g. calls to remote services, can honor its cancellation and deadline.
+type ContextFunction func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// AddContextFunction adds a customized function receiving the context of the enforcement to the matchers.
+// Enforce and the other methods without a context call it with context.Background().
+func (e *Enforcer) AddContextFunction(name string, function ContextFunction) {
+	if e.contextFunctions == nil {
+		e.contextFunctions = make(map[string]ContextFunction)
+	}
+	e.contextFunctions[name] = function
+	e.invalidateMatcherMap()
+}
+
+// EnforceWithContext decides whether a "subject" can access a "object" with the operation "action" like Enforce,
+// returning ctx.Err() as soon as ctx is cancelled or its deadline is exceeded, checked before the matcher
+// is evaluated against each rule. The context is also passed to the functions added by AddContextFunction.
+func (e *Enforcer) EnforceWithContext(ctx context.Context, rvals ...interface{}) (bool, error) {
+	return e.enforceWithMatched(ctx, "", nil, nil, nil, rvals...)
+}
+
+// bindContextFunctions adds the context functions to functions, bound to ctx.
+func (e *Enforcer) bindContextFunctions(ctx context.Context, functions map[string]govaluate.ExpressionFunction) {
+	for name, function := range e.contextFunctions {
+		function := function
+		functions[name] = func(args ...interface{}) (interface{}, error) {
+			return function(ctx, args...)
+		}
+	}
+}
+
+// getContextMatcherExpression is getAndStoreMatcherExpression, except that the matcher is compiled
+// without being cached when it calls context functions that must be bound to a context other than
+// context.Background().
+func (e *Enforcer) getContextMatcherExpression(ctx context.Context, hasEval bool, expString string, functions map[string]govaluate.ExpressionFunction) (*govaluate.EvaluableExpression, error) {
+	if ctx == context.Background() || !e.usesContextFunctions(hasEval, expString) {
+		return e.getAndStoreMatcherExpression(hasEval, expString, functions)
+	}
+	e.bindContextFunctions(ctx, functions)
+	return govaluate.NewEvaluableExpressionWithFunctions(escapeSubjectRoles(expString), functions)
+}
+
+// usesContextFunctions determines whether the matcher may call a context function.
+func (e *Enforcer) usesContextFunctions(hasEval bool, expString string) bool {
+	if len(e.contextFunctions) == 0 {
+		return false
+	}
+	if hasEval {
+		return true
+	}
+	for name := range e.contextFunctions {
+		if util.HasToken(expString, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+)
+
+func TestEnforceWithContext(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+
+	ok, err := e.EnforceWithContext(context.Background(), "alice", "data1", "read")
+	if err != nil || !ok {
+		t.Errorf("EnforceWithContext: %v, %v, expected true", ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = e.EnforceWithContext(ctx, "alice", "data1", "read"); !errors.Is(err, context.Canceled) {
+		t.Errorf("EnforceWithContext error: %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestEnforceWithContextFunction(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = slowCheck(r.sub) && r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	e, err := NewEnforcer(m, fileadapter.NewAdapter("examples/basic_policy.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddContextFunction("slowCheck", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return true, nil
+		}
+	})
+
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "alice", "data2", "read", false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := e.EnforceWithContext(ctx, "alice", "data1", "read"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("EnforceWithContext error: %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
@@ -31,6 +31,7 @@ func (d *DistributedEnforcer) SetDispatcher(dispatcher persist.Dispatcher) {
 func (d *DistributedEnforcer) AddPoliciesSelf(shouldPersist func() bool, sec string, ptype string, rules [][]string) (affected [][]string, err error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		var noExistsPolicy [][]string
 		for _, rule := range rules {
@@ -69,6 +70,7 @@ func (d *DistributedEnforcer) AddPoliciesSelf(shouldPersist func() bool, sec str
 func (d *DistributedEnforcer) RemovePoliciesSelf(shouldPersist func() bool, sec string, ptype string, rules [][]string) (affected [][]string, err error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		if err = d.adapter.(persist.BatchAdapter).RemovePolicies(sec, ptype, rules); err != nil {
 			if err.Error() != notImplemented {
@@ -97,6 +99,7 @@ func (d *DistributedEnforcer) RemovePoliciesSelf(shouldPersist func() bool, sec
 func (d *DistributedEnforcer) RemoveFilteredPolicySelf(shouldPersist func() bool, sec string, ptype string, fieldIndex int, fieldValues ...string) (affected [][]string, err error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		if err = d.adapter.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...); err != nil {
 			if err.Error() != notImplemented {
@@ -124,6 +127,7 @@ func (d *DistributedEnforcer) RemoveFilteredPolicySelf(shouldPersist func() bool
 func (d *DistributedEnforcer) ClearPolicySelf(shouldPersist func() bool) error {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		err := d.adapter.SavePolicy(nil)
 		if err != nil {
@@ -140,6 +144,7 @@ func (d *DistributedEnforcer) ClearPolicySelf(shouldPersist func() bool) error {
 func (d *DistributedEnforcer) UpdatePolicySelf(shouldPersist func() bool, sec string, ptype string, oldRule, newRule []string) (affected bool, err error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		err = d.adapter.(persist.UpdatableAdapter).UpdatePolicy(sec, ptype, oldRule, newRule)
 		if err != nil {
@@ -170,6 +175,7 @@ func (d *DistributedEnforcer) UpdatePolicySelf(shouldPersist func() bool, sec st
 func (d *DistributedEnforcer) UpdatePoliciesSelf(shouldPersist func() bool, sec string, ptype string, oldRules, newRules [][]string) (affected bool, err error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	if shouldPersist != nil && shouldPersist() {
 		err = d.adapter.(persist.UpdatableAdapter).UpdatePolicies(sec, ptype, oldRules, newRules)
 		if err != nil {
@@ -200,6 +206,7 @@ func (d *DistributedEnforcer) UpdatePoliciesSelf(shouldPersist func() bool, sec
 func (d *DistributedEnforcer) UpdateFilteredPoliciesSelf(shouldPersist func() bool, sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) (bool, error) {
 	d.m.Lock()
 	defer d.m.Unlock()
+	d.invalidatePolicyIndexes()
 	var (
 		oldRules [][]string
 		err      error
@@ -15,6 +15,8 @@
 package casbin
 
 import (
+	"context"
+
 	"github.com/casbin/casbin/v2/effector"
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
@@ -37,32 +39,67 @@ type IEnforcer interface {
 	SetModel(m model.Model)
 	GetAdapter() persist.Adapter
 	SetAdapter(adapter persist.Adapter)
+	SetRuleCodec(decode RuleCodecFunc, encode RuleCodecFunc)
+	SubscribePolicyChanges(fn func(ev PolicyChangeEvent)) func()
+	ValidateMatchers() error
 	SetWatcher(watcher persist.Watcher) error
 	GetRoleManager() rbac.RoleManager
 	SetRoleManager(rm rbac.RoleManager)
+	GetNamedRoleManager(ptype string) rbac.RoleManager
+	SetNamedRoleManager(ptype string, rm rbac.RoleManager)
 	SetEffector(eft effector.Effector)
 	ClearPolicy()
 	LoadPolicy() error
+	ReloadPolicyAtomicSwap() error
 	LoadFilteredPolicy(filter interface{}) error
 	LoadIncrementalFilteredPolicy(filter interface{}) error
 	IsFiltered() bool
 	SavePolicy() error
+	SavePolicyIncremental() error
+	GetPendingChanges() []persist.PolicyChange
+	EnableChangeTracking(enable bool)
+	SavePolicyVersioned() error
+	GetPolicyVersion() string
 	EnableEnforce(enable bool)
 	EnableLog(enable bool)
 	EnableAutoNotifyWatcher(enable bool)
 	EnableAutoSave(autoSave bool)
+	EnableSubjectIndex(enable bool)
+	EnablePolicyMatchers(enable bool) error
+	EnablePolicyJoin(enable bool)
+	SetPreMatcher(fn func(rvals []interface{}) (decided bool, allow bool))
+	DumpDiagnostics() ([]byte, error)
+	DumpDiagnosticsRedacted(redact RuleCodecFunc) ([]byte, error)
+	EnableImplicitPermissionsCache(enable bool)
+	SetImplicitPermissionsCacheSize(size int)
 	EnableAutoBuildRoleLinks(autoBuildRoleLinks bool)
 	BuildRoleLinks() error
+	RebuildRoleLinks() error
 	Enforce(rvals ...interface{}) (bool, error)
+	EnforceWithContext(ctx context.Context, rvals ...interface{}) (bool, error)
 	EnforceWithMatcher(matcher string, rvals ...interface{}) (bool, error)
+	AddNamedMatcher(name string, matcher string) error
+	EnforceWithNamedMatcher(name string, rvals ...interface{}) (bool, error)
 	EnforceEx(rvals ...interface{}) (bool, []string, error)
+	EnforceExVerbose(rvals ...interface{}) (bool, []string, []effector.Effect, error)
+	EnforceWithExplanationJSON(rvals ...interface{}) ([]byte, error)
+	EnforceWithPolicyOverride(rules map[string][][]string, rvals ...interface{}) (bool, error)
+	ExplainAll(rvals ...interface{}) ([][]string, error)
+	TestRuleAgainstRequest(rule []string, rvals ...interface{}) (bool, error)
 	EnforceExWithMatcher(matcher string, rvals ...interface{}) (bool, []string, error)
 	BatchEnforce(requests [][]interface{}) ([]bool, error)
+	BatchEnforceConcurrent(requests [][]interface{}, workers int) ([]bool, error)
+	BatchEnforceParallel(concurrency int, requests [][]interface{}) ([]bool, error)
 	BatchEnforceWithMatcher(matcher string, requests [][]interface{}) ([]bool, error)
+	RegisterRequestType(rType string, req interface{}) error
+	RegisterRequestTypeWithContext(ctx EnforceContext, req interface{}) error
+	EnforceTyped(req interface{}) (bool, error)
 
 	/* RBAC API */
 	GetRolesForUser(name string, domain ...string) ([]string, error)
 	GetUsersForRole(name string, domain ...string) ([]string, error)
+	CountRolesForUser(name string, domain ...string) (int, error)
+	CountUsersForRole(name string, domain ...string) (int, error)
 	HasRoleForUser(name string, role string, domain ...string) (bool, error)
 	AddRoleForUser(user string, role string, domain ...string) (bool, error)
 	AddPermissionForUser(user string, permission ...string) (bool, error)
@@ -72,12 +109,17 @@ type IEnforcer interface {
 	GetPermissionsForUser(user string, domain ...string) ([][]string, error)
 	HasPermissionForUser(user string, permission ...string) (bool, error)
 	GetImplicitRolesForUser(name string, domain ...string) ([]string, error)
+	GetNamedImplicitRolesForUser(ptype string, name string, domain ...string) ([]string, error)
 	GetImplicitPermissionsForUser(user string, domain ...string) ([][]string, error)
+	GetImplicitPermissionsForUserWithDepth(user string, maxDepth int, domain ...string) ([][]string, error)
+	RangeImplicitPermissionsForUser(user string, fn func(perm []string) bool, domain ...string) error
 	GetImplicitUsersForPermission(permission ...string) ([]string, error)
+	GetImplicitResourcesForRole(role string, domain ...string) ([][]string, error)
 	DeleteRoleForUser(user string, role string, domain ...string) (bool, error)
 	DeleteRolesForUser(user string, domain ...string) (bool, error)
 	DeleteUser(user string) (bool, error)
 	DeleteRole(role string) (bool, error)
+	RenameRole(oldRole string, newRole string, domain ...string) (bool, error)
 	DeletePermission(permission ...string) (bool, error)
 
 	/* RBAC API with domains*/
@@ -91,7 +133,10 @@ type IEnforcer interface {
 	DeleteAllUsersByDomain(domain string) (bool, error)
 	DeleteDomains(domains ...string) (bool, error)
 	GetAllDomains() ([]string, error)
+	GetDomainsForUser(user string) ([]string, error)
 	GetAllRolesByDomain(domain string) ([]string, error)
+	GetAllObjectsByDomain(domain string) ([]string, error)
+	GetAllActionsByDomain(domain string) ([]string, error)
 
 	/* Management API */
 	GetAllSubjects() ([]string, error)
@@ -104,6 +149,8 @@ type IEnforcer interface {
 	GetAllNamedRoles(ptype string) ([]string, error)
 	GetPolicy() ([][]string, error)
 	GetFilteredPolicy(fieldIndex int, fieldValues ...string) ([][]string, error)
+	GetFilteredPolicyLimit(sec string, ptype string, limit int, fieldIndex int, fieldValues ...string) ([][]string, error)
+	GetFilteredPolicyExcept(sec string, ptype string, fieldIndex int, fieldValues ...string) ([][]string, error)
 	GetNamedPolicy(ptype string) ([][]string, error)
 	GetFilteredNamedPolicy(ptype string, fieldIndex int, fieldValues ...string) ([][]string, error)
 	GetGroupingPolicy() ([][]string, error)
@@ -111,6 +158,8 @@ type IEnforcer interface {
 	GetNamedGroupingPolicy(ptype string) ([][]string, error)
 	GetFilteredNamedGroupingPolicy(ptype string, fieldIndex int, fieldValues ...string) ([][]string, error)
 	HasPolicy(params ...interface{}) (bool, error)
+	HasPolicyForSubject(subject string) bool
+	GetPolicyForSubject(subject string) [][]string
 	HasNamedPolicy(ptype string, params ...interface{}) (bool, error)
 	AddPolicy(params ...interface{}) (bool, error)
 	AddPolicies(rules [][]string) (bool, error)
@@ -137,8 +186,12 @@ type IEnforcer interface {
 	RemoveFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) (bool, error)
 	RemoveNamedGroupingPolicy(ptype string, params ...interface{}) (bool, error)
 	RemoveNamedGroupingPolicies(ptype string, rules [][]string) (bool, error)
+	RemoveGroupingPoliciesWithCascade(rules [][]string) (bool, error)
+	RemoveNamedGroupingPoliciesWithCascade(ptype string, rules [][]string) (bool, error)
 	RemoveFilteredNamedGroupingPolicy(ptype string, fieldIndex int, fieldValues ...string) (bool, error)
 	AddFunction(name string, function govaluate.ExpressionFunction)
+	RemoveFunction(name string) error
+	AddContextFunction(name string, function ContextFunction)
 
 	UpdatePolicy(oldPolicy []string, newPolicy []string) (bool, error)
 	UpdatePolicies(oldPolicies [][]string, newPolicies [][]string) (bool, error)
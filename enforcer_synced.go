@@ -15,17 +15,22 @@
 package casbin
 
 import (
+	"context"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/casbin/govaluate"
 
+	"github.com/casbin/casbin/v2/effector"
 	"github.com/casbin/casbin/v2/persist"
 	"github.com/casbin/casbin/v2/rbac"
 )
 
 // SyncedEnforcer wraps Enforcer and provides synchronized access.
+// Enforcement and the other reads hold the read lock, so that they run concurrently,
+// while the changes of the policy and of the model hold the write lock.
 type SyncedEnforcer struct {
 	*Enforcer
 	m               sync.RWMutex
@@ -145,20 +150,43 @@ func (e *SyncedEnforcer) ClearPolicy() {
 // LoadPolicy reloads the policy from file/database.
 func (e *SyncedEnforcer) LoadPolicy() error {
 	e.m.RLock()
-	newModel, err := e.loadPolicyFromAdapter(e.model)
+	newModel, version, err := e.loadPolicyFromAdapter(e.model)
 	e.m.RUnlock()
 	if err != nil {
 		return err
 	}
 	e.m.Lock()
+	defer e.m.Unlock()
 	err = e.applyModifiedModel(newModel)
-	e.m.Unlock()
 	if err != nil {
 		return err
 	}
+	e.policyVersion = version
 	return nil
 }
 
+// ReloadPolicyAtomicSwap reloads the policy from file/database aside while Enforce keeps using the previous one,
+// and only holds the write lock to swap it in.
+// If the policy is changed meanwhile, the reload is done again under the write lock,
+// so that the change persisted by the writer is not lost by the swap.
+func (e *SyncedEnforcer) ReloadPolicyAtomicSwap() error {
+	e.m.RLock()
+	revision := e.policyRevision
+	newModel, rmMap, version, err := e.loadPolicyAside()
+	e.m.RUnlock()
+	if err != nil {
+		return err
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	if e.policyRevision != revision {
+		if newModel, rmMap, version, err = e.loadPolicyAside(); err != nil {
+			return err
+		}
+	}
+	return e.swapPolicy(newModel, rmMap, version)
+}
+
 // LoadFilteredPolicy reloads a filtered policy from file/database.
 func (e *SyncedEnforcer) LoadFilteredPolicy(filter interface{}) error {
 	e.m.Lock()
@@ -180,6 +208,55 @@ func (e *SyncedEnforcer) SavePolicy() error {
 	return e.Enforcer.SavePolicy()
 }
 
+// SavePolicyIncremental saves only the policy changes made since the last load or save back to file/database.
+func (e *SyncedEnforcer) SavePolicyIncremental() error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.SavePolicyIncremental()
+}
+
+// EnableChangeTracking controls whether the policy changes that are not persisted by auto-save are recorded.
+func (e *SyncedEnforcer) EnableChangeTracking(enable bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.EnableChangeTracking(enable)
+}
+
+// GetPendingChanges returns the policy changes made since the last load or save, in order.
+func (e *SyncedEnforcer) GetPendingChanges() []persist.PolicyChange {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetPendingChanges()
+}
+
+// SavePolicyVersioned saves the current policy back to file/database unless the storage changed since it was loaded.
+func (e *SyncedEnforcer) SavePolicyVersioned() error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.SavePolicyVersioned()
+}
+
+// GetPolicyVersion returns the version of the storage the policy was loaded or saved at.
+func (e *SyncedEnforcer) GetPolicyVersion() string {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetPolicyVersion()
+}
+
+// SetDenyOverrideSet sets the values of a request field that are denied regardless of any allow rule.
+func (e *SyncedEnforcer) SetDenyOverrideSet(token string, values []string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.SetDenyOverrideSet(token, values)
+}
+
+// RebuildRoleLinks recomputes the role inheritance relations of all the role managers from the grouping policy in memory.
+func (e *SyncedEnforcer) RebuildRoleLinks() error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RebuildRoleLinks()
+}
+
 // BuildRoleLinks manually rebuild the role inheritance relations.
 func (e *SyncedEnforcer) BuildRoleLinks() error {
 	e.m.Lock()
@@ -194,6 +271,38 @@ func (e *SyncedEnforcer) Enforce(rvals ...interface{}) (bool, error) {
 	return e.Enforcer.Enforce(rvals...)
 }
 
+// EnforceWithContext decides whether a "subject" can access a "object" with the operation "action" like Enforce,
+// returning ctx.Err() as soon as ctx is done.
+func (e *SyncedEnforcer) EnforceWithContext(ctx context.Context, rvals ...interface{}) (bool, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.EnforceWithContext(ctx, rvals...)
+}
+
+// RegisterRequestType registers a struct type whose fields build the requests of the request definition rType.
+func (e *SyncedEnforcer) RegisterRequestType(rType string, req interface{}) error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.RegisterRequestType(rType, req)
+}
+
+// RegisterRequestTypeWithContext registers a struct type whose fields build the requests of the request definition
+// ctx.RType, evaluated with the definitions of ctx.
+func (e *SyncedEnforcer) RegisterRequestTypeWithContext(ctx EnforceContext, req interface{}) error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.RegisterRequestTypeWithContext(ctx, req)
+}
+
+// EnforceTyped decides whether the request built from a struct registered by RegisterRequestType is allowed.
+func (e *SyncedEnforcer) EnforceTyped(req interface{}) (bool, error) {
+	rvals, err := e.typedRequestValues(req)
+	if err != nil {
+		return false, err
+	}
+	return e.Enforce(rvals...)
+}
+
 // EnforceWithMatcher use a custom matcher to decides whether a "subject" can access a "object" with the operation "action", input parameters are usually: (matcher, sub, obj, act), use model matcher by default when matcher is "".
 func (e *SyncedEnforcer) EnforceWithMatcher(matcher string, rvals ...interface{}) (bool, error) {
 	e.m.RLock()
@@ -201,6 +310,21 @@ func (e *SyncedEnforcer) EnforceWithMatcher(matcher string, rvals ...interface{}
 	return e.Enforcer.EnforceWithMatcher(matcher, rvals...)
 }
 
+// AddNamedMatcher compiles the matcher and stores it under name for EnforceWithNamedMatcher.
+func (e *SyncedEnforcer) AddNamedMatcher(name string, matcher string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.AddNamedMatcher(name, matcher)
+}
+
+// EnforceWithNamedMatcher decides whether a "subject" can access a "object" with the operation "action"
+// using the matcher added by AddNamedMatcher under name.
+func (e *SyncedEnforcer) EnforceWithNamedMatcher(name string, rvals ...interface{}) (bool, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.EnforceWithNamedMatcher(name, rvals...)
+}
+
 // EnforceEx explain enforcement by informing matched rules.
 func (e *SyncedEnforcer) EnforceEx(rvals ...interface{}) (bool, []string, error) {
 	e.m.RLock()
@@ -208,6 +332,41 @@ func (e *SyncedEnforcer) EnforceEx(rvals ...interface{}) (bool, []string, error)
 	return e.Enforcer.EnforceEx(rvals...)
 }
 
+// EnforceExVerbose decides like EnforceEx, also returning the effect of every rule evaluated until the decision.
+func (e *SyncedEnforcer) EnforceExVerbose(rvals ...interface{}) (bool, []string, []effector.Effect, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.EnforceExVerbose(rvals...)
+}
+
+// EnforceWithExplanationJSON decides whether the request is allowed and returns the decision as a JSON object.
+func (e *SyncedEnforcer) EnforceWithExplanationJSON(rvals ...interface{}) ([]byte, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.EnforceWithExplanationJSON(rvals...)
+}
+
+// EnforceWithPolicyOverride decides whether the request would be allowed with the given rules instead of the loaded ones.
+func (e *SyncedEnforcer) EnforceWithPolicyOverride(rules map[string][][]string, rvals ...interface{}) (bool, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.EnforceWithPolicyOverride(rules, rvals...)
+}
+
+// ExplainAll returns every policy rule matching the request, in policy order and regardless of its effect.
+func (e *SyncedEnforcer) ExplainAll(rvals ...interface{}) ([][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.ExplainAll(rvals...)
+}
+
+// TestRuleAgainstRequest decides whether the matcher matches the request with the given policy rule.
+func (e *SyncedEnforcer) TestRuleAgainstRequest(rule []string, rvals ...interface{}) (bool, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.TestRuleAgainstRequest(rule, rvals...)
+}
+
 // EnforceExWithMatcher use a custom matcher and explain enforcement by informing matched rules.
 func (e *SyncedEnforcer) EnforceExWithMatcher(matcher string, rvals ...interface{}) (bool, []string, error) {
 	e.m.RLock()
@@ -222,6 +381,18 @@ func (e *SyncedEnforcer) BatchEnforce(requests [][]interface{}) ([]bool, error)
 	return e.Enforcer.BatchEnforce(requests)
 }
 
+// BatchEnforceConcurrent enforce in batches like BatchEnforce, evaluating the requests with a pool of workers.
+func (e *SyncedEnforcer) BatchEnforceConcurrent(requests [][]interface{}, workers int) ([]bool, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.BatchEnforceConcurrent(requests, workers)
+}
+
+// BatchEnforceParallel is BatchEnforceConcurrent with concurrency as the number of workers.
+func (e *SyncedEnforcer) BatchEnforceParallel(concurrency int, requests [][]interface{}) ([]bool, error) {
+	return e.BatchEnforceConcurrent(requests, concurrency)
+}
+
 // BatchEnforceWithMatcher enforce with matcher in batches.
 func (e *SyncedEnforcer) BatchEnforceWithMatcher(matcher string, requests [][]interface{}) ([]bool, error) {
 	e.m.RLock()
@@ -292,6 +463,13 @@ func (e *SyncedEnforcer) GetPolicy() ([][]string, error) {
 	return e.Enforcer.GetPolicy()
 }
 
+// FindRedundantPolicies gets the authorization rules that are already implied by another rule with the same effect.
+func (e *SyncedEnforcer) FindRedundantPolicies() ([][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.FindRedundantPolicies()
+}
+
 // GetFilteredPolicy gets all the authorization rules in the policy, field filters can be specified.
 func (e *SyncedEnforcer) GetFilteredPolicy(fieldIndex int, fieldValues ...string) ([][]string, error) {
 	e.m.RLock()
@@ -299,6 +477,20 @@ func (e *SyncedEnforcer) GetFilteredPolicy(fieldIndex int, fieldValues ...string
 	return e.Enforcer.GetFilteredPolicy(fieldIndex, fieldValues...)
 }
 
+// GetFilteredPolicyLimit gets the first limit rules of a policy matching the field filters.
+func (e *SyncedEnforcer) GetFilteredPolicyLimit(sec string, ptype string, limit int, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetFilteredPolicyLimit(sec, ptype, limit, fieldIndex, fieldValues...)
+}
+
+// GetFilteredPolicyExcept gets the rules of a policy that do not match the field filters.
+func (e *SyncedEnforcer) GetFilteredPolicyExcept(sec string, ptype string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetFilteredPolicyExcept(sec, ptype, fieldIndex, fieldValues...)
+}
+
 // GetNamedPolicy gets all the authorization rules in the named policy.
 func (e *SyncedEnforcer) GetNamedPolicy(ptype string) ([][]string, error) {
 	e.m.RLock()
@@ -489,6 +681,34 @@ func (e *SyncedEnforcer) RemoveFilteredNamedPolicy(ptype string, fieldIndex int,
 	return e.Enforcer.RemoveFilteredNamedPolicy(ptype, fieldIndex, fieldValues...)
 }
 
+// RemoveFilteredPolicyMatch removes authorization rules whose fields match glob patterns, field filters can be specified.
+func (e *SyncedEnforcer) RemoveFilteredPolicyMatch(fieldIndex int, fieldValues ...string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveFilteredPolicyMatch(fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredNamedPolicyMatch removes authorization rules whose fields match glob patterns from the current named policy.
+func (e *SyncedEnforcer) RemoveFilteredNamedPolicyMatch(ptype string, fieldIndex int, fieldValues ...string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveFilteredNamedPolicyMatch(ptype, fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredGroupingPolicyMatch removes role inheritance rules whose fields match glob patterns.
+func (e *SyncedEnforcer) RemoveFilteredGroupingPolicyMatch(fieldIndex int, fieldValues ...string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveFilteredGroupingPolicyMatch(fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredNamedGroupingPolicyMatch removes role inheritance rules whose fields match glob patterns from the current named policy.
+func (e *SyncedEnforcer) RemoveFilteredNamedGroupingPolicyMatch(ptype string, fieldIndex int, fieldValues ...string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveFilteredNamedGroupingPolicyMatch(ptype, fieldIndex, fieldValues...)
+}
+
 // HasGroupingPolicy determines whether a role inheritance rule exists.
 func (e *SyncedEnforcer) HasGroupingPolicy(params ...interface{}) (bool, error) {
 	e.m.RLock()
@@ -592,6 +812,22 @@ func (e *SyncedEnforcer) RemoveNamedGroupingPolicies(ptype string, rules [][]str
 	return e.Enforcer.RemoveNamedGroupingPolicies(ptype, rules)
 }
 
+// RemoveGroupingPoliciesWithCascade removes role inheritance rules from the current policy,
+// together with the rules of the roles that are left without any member, up the role hierarchy.
+func (e *SyncedEnforcer) RemoveGroupingPoliciesWithCascade(rules [][]string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveGroupingPoliciesWithCascade(rules)
+}
+
+// RemoveNamedGroupingPoliciesWithCascade removes role inheritance rules from the current named policy,
+// together with the rules of the roles that are left without any member, up the role hierarchy.
+func (e *SyncedEnforcer) RemoveNamedGroupingPoliciesWithCascade(ptype string, rules [][]string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveNamedGroupingPoliciesWithCascade(ptype, rules)
+}
+
 func (e *SyncedEnforcer) UpdateGroupingPolicy(oldRule []string, newRule []string) (bool, error) {
 	e.m.Lock()
 	defer e.m.Unlock()
@@ -630,6 +866,20 @@ func (e *SyncedEnforcer) AddFunction(name string, function govaluate.ExpressionF
 	e.Enforcer.AddFunction(name, function)
 }
 
+// RemoveFunction removes a customized or built-in function that no matcher calls.
+func (e *SyncedEnforcer) RemoveFunction(name string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RemoveFunction(name)
+}
+
+// AddContextFunction adds a customized function receiving the context of the enforcement.
+func (e *SyncedEnforcer) AddContextFunction(name string, function ContextFunction) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.AddContextFunction(name, function)
+}
+
 func (e *SyncedEnforcer) SelfAddPolicy(sec string, ptype string, rule []string) (bool, error) {
 	e.m.Lock()
 	defer e.m.Unlock()
@@ -677,3 +927,123 @@ func (e *SyncedEnforcer) SelfUpdatePolicies(sec string, ptype string, oldRules,
 	defer e.m.Unlock()
 	return e.Enforcer.SelfUpdatePolicies(sec, ptype, oldRules, newRules)
 }
+
+// ImportPolicyMerge merges the rules of several policy types into the current policy, resolving conflicts with onConflict.
+func (e *SyncedEnforcer) ImportPolicyMerge(rules map[string][][]string, onConflict func(existing, incoming []string) []string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.ImportPolicyMerge(rules, onConflict)
+}
+
+// ImportPolicyStream adds the rules read from r to the current policy in batches, see Enforcer.ImportPolicyStream.
+func (e *SyncedEnforcer) ImportPolicyStream(r io.Reader, opts ImportOptions) (int, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.ImportPolicyStream(r, opts)
+}
+
+// SetRuleCodec sets the functions transforming the rules between their encoding in the storage and the model.
+func (e *SyncedEnforcer) SetRuleCodec(decode RuleCodecFunc, encode RuleCodecFunc) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetRuleCodec(decode, encode)
+}
+
+// ValidateMatchers checks that every function called by the matchers of the model is defined.
+func (e *SyncedEnforcer) ValidateMatchers() error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.ValidateMatchers()
+}
+
+// EnableSubjectIndex enables or disables the index of the "p" policy by subject.
+func (e *SyncedEnforcer) EnableSubjectIndex(enable bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.EnableSubjectIndex(enable)
+}
+
+// HasPolicyForSubject determines whether the "p" policy has any rule for the subject.
+func (e *SyncedEnforcer) HasPolicyForSubject(subject string) bool {
+	// Lock, as the subject index may be rebuilt.
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.HasPolicyForSubject(subject)
+}
+
+// GetPolicyForSubject gets the rules of the "p" policy for the subject.
+func (e *SyncedEnforcer) GetPolicyForSubject(subject string) [][]string {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.GetPolicyForSubject(subject)
+}
+
+// SetPreMatcher sets a function deciding the requests it can before the matcher runs.
+func (e *SyncedEnforcer) SetPreMatcher(fn func(rvals []interface{}) (decided bool, allow bool)) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetPreMatcher(fn)
+}
+
+// DumpDiagnostics returns a JSON snapshot of the enforcer to attach to a bug report.
+func (e *SyncedEnforcer) DumpDiagnostics() ([]byte, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.DumpDiagnostics()
+}
+
+// DumpDiagnosticsRedacted returns a JSON snapshot of the enforcer with each policy rule passed through redact.
+func (e *SyncedEnforcer) DumpDiagnosticsRedacted(redact RuleCodecFunc) ([]byte, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.DumpDiagnosticsRedacted(redact)
+}
+
+// EnablePolicyMatchers enables or disables evaluating each policy type with its own matcher.
+func (e *SyncedEnforcer) EnablePolicyMatchers(enable bool) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.EnablePolicyMatchers(enable)
+}
+
+// EnablePolicyJoin controls whether a matcher may reference the tokens of other policy types than the enforced one.
+func (e *SyncedEnforcer) EnablePolicyJoin(enable bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.EnablePolicyJoin(enable)
+}
+
+// EnableImplicitPermissionsCache enables or disables caching the implicit permissions of the users.
+func (e *SyncedEnforcer) EnableImplicitPermissionsCache(enable bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.EnableImplicitPermissionsCache(enable)
+}
+
+// SetImplicitPermissionsCacheSize sets the maximum number of results kept by the implicit permissions cache.
+func (e *SyncedEnforcer) SetImplicitPermissionsCacheSize(size int) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetImplicitPermissionsCacheSize(size)
+}
+
+// SetClock sets the source of the current time of the enforcer, e.g. a fake clock in tests.
+func (e *SyncedEnforcer) SetClock(clock func() time.Time) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetClock(clock)
+}
+
+// SetFailurePolicy sets the decision of the enforcer when evaluating a request fails.
+func (e *SyncedEnforcer) SetFailurePolicy(policy FailurePolicy) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetFailurePolicy(policy)
+}
+
+// SetShadowDetection controls whether the rules added to a priority model are checked for being shadowed.
+func (e *SyncedEnforcer) SetShadowDetection(enable bool) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.Enforcer.SetShadowDetection(enable)
+}
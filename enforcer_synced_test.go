@@ -16,10 +16,13 @@ package casbin
 
 import (
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/casbin/casbin/v2/errors"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -531,3 +534,151 @@ func TestSyncedEnforcerAddNamedGroupingPoliciesEx(t *testing.T) {
 		testSyncedEnforcerGetUsers(t, e, []string{"user1", "user2", "user3", "user4", "user5", "user6"}, "member")
 	}
 }
+
+func TestSyncedEnforcerConcurrentEnforce(t *testing.T) {
+	e, _ := NewSyncedEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	testSyncedEnforcerConcurrentEnforce(t, e, [][]interface{}{
+		{"alice", "data2", "read"},
+		{"bob", "data2", "write"},
+		{"alice", "data1", "write"},
+		{"cathy", "data1", "read"},
+	}, []bool{true, true, false, false})
+
+	e, _ = NewSyncedEnforcer("examples/rbac_with_pattern_model.conf", "examples/rbac_with_pattern_policy.csv")
+	e.AddNamedMatchingFunc("g2", "KeyMatch2", util.KeyMatch2)
+	testSyncedEnforcerConcurrentEnforce(t, e, [][]interface{}{
+		{"alice", "/book/1", "GET"},
+		{"bob", "/pen/3", "GET"},
+		{"alice", "/pen/1", "GET"},
+		{"bob", "/book/1", "GET"},
+	}, []bool{true, true, true, false})
+}
+
+// testSyncedEnforcerConcurrentEnforce enforces the requests from several goroutines while the policy is modified
+// and reloaded, on rules the requests don't depend on. It is meant to be run with the race detector.
+func testSyncedEnforcerConcurrentEnforce(t *testing.T, e *SyncedEnforcer, requests [][]interface{}, expected []bool) {
+	t.Helper()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for k, request := range requests {
+					if ok, err := e.Enforce(request...); err != nil || ok != expected[k] {
+						t.Errorf("%v: %t, %v, supposed to be %t", request, ok, err, expected[k])
+					}
+				}
+				if _, err := e.BatchEnforce(requests); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(done)
+		for j := 0; j < 50; j++ {
+			_, _ = e.AddPolicy("dave", "/dave/1", "GET")
+			_, _ = e.AddGroupingPolicy("dave", "eve")
+			_, _ = e.RemovePolicy("dave", "/dave/1", "GET")
+			_, _ = e.RemoveGroupingPolicy("dave", "eve")
+			if err := e.LoadPolicy(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+}
+
+// reloadRaceAdapter loads the rules added to it, and calls onLoad once after reading them.
+type reloadRaceAdapter struct {
+	recordingAdapter
+	onLoad func()
+}
+
+func (a *reloadRaceAdapter) LoadPolicy(m model.Model) error {
+	a.mutex.Lock()
+	rules := append([][]string(nil), a.added...)
+	onLoad := a.onLoad
+	a.onLoad = nil
+	a.mutex.Unlock()
+
+	for _, rule := range rules {
+		if err := persist.LoadPolicyArray(append([]string{"p"}, rule...), m); err != nil {
+			return err
+		}
+	}
+	if onLoad != nil {
+		onLoad()
+	}
+	return nil
+}
+
+func TestSyncedReloadPolicyAtomicSwapRace(t *testing.T) {
+	a := &reloadRaceAdapter{}
+	e, _ := NewSyncedEnforcer("examples/basic_model.conf", a)
+	_, _ = e.AddPolicy("alice", "data1", "read")
+
+	// A rule is added and persisted after the reload read the adapter, but before it swaps the policy in.
+	done := make(chan struct{})
+	a.onLoad = func() {
+		go func() {
+			defer close(done)
+			if _, err := e.AddPolicy("bob", "data2", "write"); err != nil {
+				t.Error(err)
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := e.ReloadPolicyAtomicSwap(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	testEnforceSync(t, e, "alice", "data1", "read", true)
+	testEnforceSync(t, e, "bob", "data2", "write", true)
+}
+
+func TestSyncedEnforcerSetClock(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, start, end
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act && timeMatch(p.start, p.end)
+`)
+	e, _ := NewSyncedEnforcer(m)
+	_, _ = e.AddPolicy("alice", "data1", "read", "2020-01-01T09:00:00Z", "2020-01-01T17:00:00Z")
+	inWindow := func() time.Time { return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC) }
+	e.SetClock(inWindow)
+
+	// The clock is set while requests are enforced, meant to be run with the race detector.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := e.Enforce("alice", "data1", "read"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		e.SetClock(nil)
+		e.SetClock(inWindow)
+	}
+	wg.Wait()
+	testEnforceSync(t, e, "alice", "data1", "read", true)
+}
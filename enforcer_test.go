@@ -15,11 +15,23 @@
 package casbin
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/casbin/casbin/v2/effector"
+	"github.com/casbin/casbin/v2/log"
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	stringadapter "github.com/casbin/casbin/v2/persist/string-adapter"
+	defaultrolemanager "github.com/casbin/casbin/v2/rbac/default-role-manager"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -183,6 +195,51 @@ m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
 	testEnforce(t, e, "bob", "data2", "write", true)
 }
 
+func TestMatcherWithJoinedPolicyTypes(t *testing.T) {
+	text :=
+		`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, eft
+p2 = obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.obj == p2.obj && r.act == p2.act
+`
+	m, _ := model.NewModelFromString(text)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("alice", "data1", "allow")
+	_, _ = e.AddNamedPolicy("p2", "data1", "read")
+	if _, err := e.Enforce("alice", "data1", "read"); err == nil {
+		t.Error("matcher referencing p2 should fail without policy join")
+	}
+	e.EnablePolicyJoin(true)
+
+	// p grants coarse access to an object, p2 lists the actions the object supports.
+	_, _ = e.AddPolicy("alice", "data1", "allow")
+	_, _ = e.AddPolicy("bob", "data1", "deny")
+	_, _ = e.AddPolicy("bob", "data2", "allow")
+	_, _ = e.AddNamedPolicy("p2", "data1", "read")
+	_, _ = e.AddNamedPolicy("p2", "data1", "write")
+	_, _ = e.AddNamedPolicy("p2", "data2", "read")
+
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "alice", "data1", "write", true)
+	testEnforce(t, e, "alice", "data1", "delete", false)
+	testEnforce(t, e, "alice", "data2", "read", false)
+	testEnforce(t, e, "bob", "data1", "read", false)
+	testEnforce(t, e, "bob", "data2", "read", true)
+	testEnforce(t, e, "bob", "data2", "write", false)
+
+	_, _ = e.RemoveFilteredNamedPolicy("p2", 0, "data1")
+	testEnforce(t, e, "alice", "data1", "read", false)
+}
+
 func TestNotUsedRBACModelInMemory(t *testing.T) {
 	m := model.NewModel()
 	m.AddDef("r", "r", "sub, obj, act")
@@ -484,6 +541,49 @@ func TestEnforceEx(t *testing.T) {
 	testEnforceEx(t, e, "alice", obj, "write", []string{})
 }
 
+func testExplainAll(t *testing.T, e *Enforcer, sub, obj, act interface{}, res [][]string) {
+	t.Helper()
+	myRes, err := e.ExplainAll(sub, obj, act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(res, myRes) {
+		t.Errorf("ExplainAll %s, %v, %s: %v, supposed to be %v", sub, obj, act, myRes, res)
+	}
+}
+
+func TestExplainAll(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+
+	// The deny rule wins, but the allow rule inherited from data2_admin matched as well.
+	testEnforce(t, e, "alice", "data2", "write", false)
+	testExplainAll(t, e, "alice", "data2", "write", [][]string{
+		{"data2_admin", "data2", "write", "allow"},
+		{"alice", "data2", "write", "deny"},
+	})
+	testExplainAll(t, e, "alice", "data1", "read", [][]string{{"alice", "data1", "read", "allow"}})
+	testExplainAll(t, e, "bob", "data1", "read", [][]string{})
+
+	// The rules after the decisive one are matched as well, without changing the decision.
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	_, _ = e.AddPolicy("alice", "data2", "read")
+	testEnforceEx(t, e, "alice", "data2", "read", []string{"data2_admin", "data2", "read"})
+	testExplainAll(t, e, "alice", "data2", "read", [][]string{
+		{"data2_admin", "data2", "read"},
+		{"alice", "data2", "read"},
+	})
+
+	// The rules returned are copies, modifying them leaves the policy unchanged.
+	matched, _ := e.ExplainAll("alice", "data2", "read")
+	matched[0][0] = "bob"
+	testEnforce(t, e, "alice", "data2", "read", true)
+	testEnforce(t, e, "bob", "data2", "read", false)
+
+	if _, err := e.ExplainAll("alice", "data1"); err == nil {
+		t.Error("a request with a wrong number of values should fail")
+	}
+}
+
 func TestEnforceExLog(t *testing.T) {
 	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv", true)
 
@@ -516,6 +616,114 @@ func TestBatchEnforce(t *testing.T) {
 	testBatchEnforce(t, e, [][]interface{}{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"jack", "data3", "read"}}, results)
 }
 
+func testEnforceExVerbose(t *testing.T, e *Enforcer, sub, obj, act interface{}, res bool, explain []string, effects []effector.Effect) {
+	t.Helper()
+	myRes, myExplain, myEffects, err := e.EnforceExVerbose(sub, obj, act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if myRes != res || !util.ArrayEquals(explain, myExplain) {
+		t.Errorf("%s, %v, %s: %t, %v, supposed to be %t, %v", sub, obj, act, myRes, myExplain, res, explain)
+	}
+	if !reflect.DeepEqual(myEffects, effects) {
+		t.Errorf("%s, %v, %s: effects %v, supposed to be %v", sub, obj, act, myEffects, effects)
+	}
+}
+
+func TestEnforceExVerbose(t *testing.T) {
+	allow, deny, none := effector.Allow, effector.Deny, effector.Indeterminate
+
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	testEnforceExVerbose(t, e, "alice", "data1", "read", true, []string{"alice", "data1", "read"}, []effector.Effect{allow})
+	testEnforceExVerbose(t, e, "bob", "data2", "write", true, []string{"bob", "data2", "write"}, []effector.Effect{none, allow})
+	testEnforceExVerbose(t, e, "bob", "data1", "read", false, []string{}, []effector.Effect{none, none})
+
+	e, _ = NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+	testEnforceExVerbose(t, e, "alice", "data2", "write", false, []string{"alice", "data2", "write", "deny"},
+		[]effector.Effect{none, none, none, allow, deny})
+
+	e, _ = NewEnforcer("examples/priority_model.conf", "examples/priority_policy.csv")
+	testEnforceExVerbose(t, e, "bob", "data2", "read", true, []string{"data2_allow_group", "data2", "read", "allow"},
+		[]effector.Effect{none, none, none, none, allow})
+	testEnforceExVerbose(t, e, "bob", "data2", "write", false, []string{"bob", "data2", "write", "deny"},
+		[]effector.Effect{none, none, none, none, none, none, deny})
+
+	e, _ = NewEnforcer("examples/abac_model.conf")
+	testEnforceExVerbose(t, e, "alice", newTestResource("data1", "alice"), "read", true, []string{}, []effector.Effect{allow})
+}
+
+func TestBatchEnforceConcurrent(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	requests := make([][]interface{}, 0)
+	expected := make([]bool, 0)
+	for i := 0; i < 50; i++ {
+		requests = append(requests,
+			[]interface{}{"alice", "data2", "read"},
+			[]interface{}{"bob", "data1", "read"},
+			[]interface{}{"bob", "data2", "write"})
+		expected = append(expected, true, false, true)
+	}
+
+	for _, workers := range []int{0, 1, 4, 1000} {
+		results, err := e.BatchEnforceConcurrent(requests, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("workers %d: %d results, supposed to be %d", workers, len(results), len(expected))
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Errorf("workers %d: result %d is %t, supposed to be %t", workers, i, results[i], expected[i])
+			}
+		}
+	}
+
+	results, err := e.BatchEnforceConcurrent(nil, 4)
+	if err != nil || len(results) != 0 {
+		t.Errorf("empty batch: %v, %v", results, err)
+	}
+
+	_, err = e.BatchEnforceConcurrent([][]interface{}{{"alice", "data1", "read"}, {"alice", "data1"}}, 2)
+	if err == nil {
+		t.Error("a request with a wrong number of values should fail")
+	}
+
+	// JSON request values are parsed per request, a request shared by several entries is not modified.
+	e, _ = NewEnforcer("examples/abac_model.conf")
+	e.EnableAcceptJsonRequest(true)
+	request := []interface{}{"alice", `{ "Name": "data1", "Owner": "alice"}`, "read"}
+	requests = [][]interface{}{request, request, request, request}
+	results, err = e.BatchEnforceConcurrent(requests, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []bool{true, true, true, true}) {
+		t.Errorf("results: %v, supposed to be all true", results)
+	}
+	if _, ok := request[1].(string); !ok {
+		t.Errorf("request value is modified: %v", request[1])
+	}
+}
+
+func TestBatchEnforceParallel(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+
+	requests := [][]interface{}{
+		{"alice", "domain1", "data1", "read"},
+		{"alice", "domain2", "data2", "read"},
+		{"bob", "domain2", "data2", "write"},
+	}
+	results, err := e.BatchEnforceParallel(2, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []bool{true, false, true}) {
+		t.Errorf("results: %v, supposed to be [true false true]", results)
+	}
+}
+
 func TestSubjectPriority(t *testing.T) {
 	e, _ := NewEnforcer("examples/subject_priority_model.conf", "examples/subject_priority_policy.csv")
 	testBatchEnforce(t, e, [][]interface{}{
@@ -733,3 +941,1011 @@ func TestLinkConditionFunc(t *testing.T) {
 	testDomainEnforce(t, e, "alice", "domain5", "data5", "read", false)
 	testDomainEnforce(t, e, "alice", "domain5", "data5", "write", false)
 }
+
+func TestTemporalRoles(t *testing.T) {
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m, _ := model.NewModelFromFile("examples/rbac_with_temporal_roles_model.conf")
+	e, _ := NewEnforcer(m)
+	e.SetClock(func() time.Time { return clock })
+	if !e.EnableNamedTemporalRoles("g") {
+		t.Fatal("EnableNamedTemporalRoles() = false, want true")
+	}
+
+	_, _ = e.AddPolicies([][]string{
+		{"admin", "data1", "write"},
+		{"oncall", "data2", "write"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{
+		{"oncall", "admin", "", ""},
+		{"alice", "oncall", "2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z"},
+		{"bob", "oncall", "2026-04-01T00:00:00Z", ""},
+	})
+
+	testEnforce(t, e, "alice", "data1", "write", true)
+	testEnforce(t, e, "alice", "data2", "write", true)
+	testEnforce(t, e, "bob", "data2", "write", false)
+	testGetRoles(t, e, []string{"oncall"}, "alice")
+	testGetRoles(t, e, []string{}, "bob")
+	if roles, _ := e.GetImplicitRolesForUser("alice"); !util.SetEquals(roles, []string{"oncall", "admin"}) {
+		t.Errorf("alice implicit roles: %v, want [oncall admin]", roles)
+	}
+	e.EnableImplicitPermissionsCache(true)
+	if permissions, _ := e.GetImplicitPermissionsForUser("alice"); len(permissions) != 2 {
+		t.Errorf("alice implicit permissions: %v, want 2 permissions", permissions)
+	}
+
+	clock = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	// The links expire without any policy change, the permissions must not be cached.
+	if permissions, _ := e.GetImplicitPermissionsForUser("alice"); len(permissions) != 0 {
+		t.Errorf("alice implicit permissions: %v, want []", permissions)
+	}
+	testEnforce(t, e, "alice", "data1", "write", false)
+	testEnforce(t, e, "alice", "data2", "write", false)
+	testEnforce(t, e, "bob", "data1", "write", true)
+	testGetRoles(t, e, []string{}, "alice")
+	testGetRoles(t, e, []string{"oncall"}, "bob")
+	if roles, _ := e.GetImplicitRolesForUser("alice"); len(roles) != 0 {
+		t.Errorf("alice implicit roles: %v, want []", roles)
+	}
+
+	// The window is read from the policy, so it survives a reload.
+	if err := e.BuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "bob", "data1", "write", true)
+	testEnforce(t, e, "alice", "data1", "write", false)
+
+	// A link condition function of its own takes precedence.
+	e.AddNamedLinkConditionFunc("g", "alice", "oncall", func(args ...string) (bool, error) { return true, nil })
+	testEnforce(t, e, "alice", "data2", "write", true)
+
+	// Revoking the grant drops the link whatever its window.
+	_, _ = e.RemoveGroupingPolicy("bob", "oncall", "2026-04-01T00:00:00Z", "")
+	testEnforce(t, e, "bob", "data1", "write", false)
+
+	if e.EnableNamedTemporalRoles("g2") {
+		t.Error("EnableNamedTemporalRoles(g2) = true, want false")
+	}
+}
+
+func TestDomainTemporalRoles(t *testing.T) {
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m, _ := model.NewModelFromFile("examples/rbac_with_domain_temporal_roles_model.conf")
+	e, _ := NewEnforcer(m)
+	e.SetClock(func() time.Time { return clock })
+	e.EnableNamedTemporalRoles("g")
+
+	// Single rules go through the incremental conditional links as well.
+	_, _ = e.AddPolicy("admin", "domain1", "data1", "write")
+	_, _ = e.AddGroupingPolicy("alice", "admin", "domain1", "2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z")
+
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "write", true)
+	clock = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "write", false)
+	if roles, _ := e.GetImplicitRolesForUser("alice", "domain1"); len(roles) != 0 {
+		t.Errorf("alice implicit roles in domain1: %v, want []", roles)
+	}
+}
+
+// incrementalAdapter records the batch writes it receives and counts full saves.
+type incrementalAdapter struct {
+	added   [][]string
+	removed [][]string
+	updated [][]string
+	saves   int
+}
+
+func (a *incrementalAdapter) LoadPolicy(model model.Model) error { return nil }
+
+func (a *incrementalAdapter) SavePolicy(model model.Model) error {
+	a.saves++
+	return nil
+}
+
+func (a *incrementalAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+func (a *incrementalAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	a.added = append(a.added, rules...)
+	return nil
+}
+
+func (a *incrementalAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+func (a *incrementalAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	a.removed = append(a.removed, rules...)
+	return nil
+}
+
+func (a *incrementalAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errors.New(notImplemented)
+}
+
+func (a *incrementalAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return a.UpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+func (a *incrementalAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	a.updated = append(a.updated, newRules...)
+	return nil
+}
+
+func (a *incrementalAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return nil, errors.New(notImplemented)
+}
+
+func TestSavePolicyIncremental(t *testing.T) {
+	a := &incrementalAdapter{}
+	e, _ := NewEnforcer("examples/rbac_model.conf", a)
+	e.EnableAutoSave(false)
+	e.EnableChangeTracking(true)
+
+	_, _ = e.AddPolicies([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	_, _ = e.AddGroupingPolicy("alice", "admin")
+	_, _ = e.RemovePolicy("bob", "data2", "write")
+	_, _ = e.UpdatePolicy([]string{"alice", "data1", "read"}, []string{"alice", "data1", "write"})
+	_, _ = e.AddPolicy("alice", "data1", "write")
+
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(a.added, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"alice", "admin"}}) {
+		t.Errorf("added rules: %v", a.added)
+	}
+	if !util.Array2DEquals(a.removed, [][]string{{"bob", "data2", "write"}}) {
+		t.Errorf("removed rules: %v", a.removed)
+	}
+	if !util.Array2DEquals(a.updated, [][]string{{"alice", "data1", "write"}}) {
+		t.Errorf("updated rules: %v", a.updated)
+	}
+	if a.saves != 0 {
+		t.Errorf("full saves: %d, expected 0", a.saves)
+	}
+
+	// Nothing changed since the last save.
+	a.added = nil
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.added) != 0 {
+		t.Errorf("added rules: %v, expected none", a.added)
+	}
+
+	// Clearing the policy can only be persisted by a full save.
+	e.ClearPolicy()
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if a.saves != 1 {
+		t.Errorf("full saves: %d, expected 1", a.saves)
+	}
+
+	// Without change tracking, the changes can only be persisted by a full save.
+	e.EnableChangeTracking(false)
+	a.added = nil
+	_, _ = e.AddPolicy("bob", "data2", "read")
+	if changes := e.GetPendingChanges(); len(changes) != 0 {
+		t.Errorf("untracked changes: %v, expected none", changes)
+	}
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.added) != 0 || a.saves != 2 {
+		t.Errorf("added rules: %v, full saves: %d, expected none and 2", a.added, a.saves)
+	}
+}
+
+// changeAdapter is an incrementalAdapter persisting the pending changes at once.
+type changeAdapter struct {
+	incrementalAdapter
+	changes [][]persist.PolicyChange
+}
+
+func (a *changeAdapter) ApplyChanges(changes []persist.PolicyChange) error {
+	a.changes = append(a.changes, changes)
+	return nil
+}
+
+func TestGetPendingChanges(t *testing.T) {
+	a := &changeAdapter{}
+	e, _ := NewEnforcer("examples/rbac_model.conf", a)
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	if changes := e.GetPendingChanges(); len(changes) != 0 {
+		t.Errorf("changes with auto-save: %v, expected none", changes)
+	}
+	a.added = nil
+
+	e.EnableAutoSave(false)
+	e.EnableChangeTracking(true)
+	_, _ = e.AddPolicies([][]string{{"bob", "data2", "write"}})
+	_, _ = e.AddGroupingPolicy("alice", "admin")
+	_, _ = e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"})
+	_, _ = e.RemovePolicy("alice", "data1", "read")
+
+	changes := e.GetPendingChanges()
+	expected := []persist.PolicyChange{
+		{Type: persist.OperationAdd, Section: "p", PolicyType: "p", Rules: [][]string{{"bob", "data2", "write"}}},
+		{Type: persist.OperationAdd, Section: "g", PolicyType: "g", Rules: [][]string{{"alice", "admin"}}},
+		{Type: persist.OperationUpdate, Section: "p", PolicyType: "p", Rules: [][]string{{"bob", "data2", "read"}}, OldRules: [][]string{{"bob", "data2", "write"}}},
+		{Type: persist.OperationRemove, Section: "p", PolicyType: "p", Rules: [][]string{{"alice", "data1", "read"}}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("changes: %v, supposed to be %v", changes, expected)
+	}
+	changes[0].Rules[0][0] = "mallory"
+
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.changes) != 1 || !reflect.DeepEqual(a.changes[0], expected) {
+		t.Errorf("applied changes: %v, supposed to be %v", a.changes, expected)
+	}
+	if len(a.added) != 0 || a.saves != 0 {
+		t.Errorf("added rules: %v, full saves: %d, expected none", a.added, a.saves)
+	}
+	if changes := e.GetPendingChanges(); len(changes) != 0 {
+		t.Errorf("changes after save: %v, expected none", changes)
+	}
+
+	// Nothing changed since the last save.
+	if err := e.SavePolicyIncremental(); err != nil || len(a.changes) != 1 {
+		t.Errorf("saving without changes: %v, %d calls, expected 1", err, len(a.changes))
+	}
+}
+
+// ruleAdapter is a persist.IncrementalAdapter recording its calls, whose other write methods must not be called.
+type ruleAdapter struct {
+	calls []string
+	saves int
+}
+
+func (a *ruleAdapter) LoadPolicy(model model.Model) error { return nil }
+
+func (a *ruleAdapter) SavePolicy(model model.Model) error {
+	a.saves++
+	return nil
+}
+
+func (a *ruleAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return errors.New("AddPolicy should not be called")
+}
+
+func (a *ruleAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return errors.New("RemovePolicy should not be called")
+}
+
+func (a *ruleAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errors.New("RemoveFilteredPolicy should not be called")
+}
+
+func (a *ruleAdapter) AddPolicyRule(sec string, ptype string, rule []string) error {
+	a.calls = append(a.calls, fmt.Sprintf("add %s %v", ptype, rule))
+	return nil
+}
+
+func (a *ruleAdapter) RemovePolicyRule(sec string, ptype string, rule []string) error {
+	a.calls = append(a.calls, fmt.Sprintf("remove %s %v", ptype, rule))
+	return nil
+}
+
+func (a *ruleAdapter) UpdatePolicyRule(sec string, ptype string, oldRule []string, newRule []string) error {
+	a.calls = append(a.calls, fmt.Sprintf("update %s %v %v", ptype, oldRule, newRule))
+	return nil
+}
+
+func TestIncrementalAdapter(t *testing.T) {
+	a := &ruleAdapter{}
+	e, _ := NewEnforcer("examples/rbac_model.conf", a)
+
+	_, err := e.AddPolicy("alice", "data1", "read")
+	if err == nil {
+		_, err = e.AddPoliciesEx([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data2", "write"}})
+	}
+	if err == nil {
+		_, err = e.AddGroupingPolicy("alice", "admin")
+	}
+	if err == nil {
+		_, err = e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"})
+	}
+	if err == nil {
+		_, err = e.RemoveFilteredPolicy(0, "alice")
+	}
+	if err == nil {
+		_, err = e.UpdateFilteredPolicies([][]string{{"carol", "data3", "read"}}, 0, "bob")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"add p [alice data1 read]",
+		"add p [bob data2 write]",
+		"add g [alice admin]",
+		"update p [bob data2 write] [bob data2 read]",
+		"remove p [alice data1 read]",
+		"remove p [bob data2 read]",
+		"add p [carol data3 read]",
+	}
+	if !reflect.DeepEqual(a.calls, expected) {
+		t.Errorf("calls: %q, supposed to be %q", a.calls, expected)
+	}
+
+	a.calls = nil
+	e.EnableAutoSave(false)
+	e.EnableChangeTracking(true)
+	_, _ = e.RemovePolicy("carol", "data3", "read")
+	_, _ = e.AddPolicy("dave", "data4", "read")
+	if err = e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"remove p [carol data3 read]", "add p [dave data4 read]"}
+	if !reflect.DeepEqual(a.calls, expected) || a.saves != 0 {
+		t.Errorf("calls: %q, full saves: %d, supposed to be %q and none", a.calls, a.saves, expected)
+	}
+}
+
+func TestSkipExpiredPolicy(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, expire
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	a := stringadapter.NewAdapter(`
+p, alice, data1, read, 2000-01-01T00:00:00Z
+p, alice, data2, read, 2999-01-01T00:00:00Z
+p, bob, data1, read,
+p, bob, data2, read, 2000-01-01T00:00:00Z
+p, cathy, data1, read, tomorrow
+`)
+
+	e, _ := NewEnforcer(m, a)
+	logger := &errorLogger{}
+	e.SetLogger(logger)
+	testEnforce(t, e, "alice", "data1", "read", true)
+	if e.GetSkippedExpiredPolicyCount() != 0 {
+		t.Errorf("skipped expired rules: %d, supposed to be 0", e.GetSkippedExpiredPolicyCount())
+	}
+
+	e.EnableSkipExpiredPolicy(true)
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data2", "read", "2999-01-01T00:00:00Z"}, {"bob", "data1", "read", ""}})
+	if e.GetSkippedExpiredPolicyCount() != 3 {
+		t.Errorf("skipped expired rules: %d, supposed to be 3", e.GetSkippedExpiredPolicyCount())
+	}
+	if len(logger.errs) != 1 {
+		t.Errorf("errors: %v, supposed to report the malformed expiry", logger.errs)
+	}
+	testEnforce(t, e, "alice", "data1", "read", false)
+	testEnforce(t, e, "alice", "data2", "read", true)
+	testEnforce(t, e, "bob", "data1", "read", true)
+	testEnforce(t, e, "cathy", "data1", "read", false)
+
+	// The expiry is checked against the clock of the enforcer.
+	e.SetClock(func() time.Time { return time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC) })
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data1", "read", ""}})
+}
+
+func TestTimeMatchWithClock(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, start, end
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act && timeMatch(p.start, p.end)
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicies([][]string{
+		{"alice", "data1", "read", "2020-01-01T09:00:00Z", "2020-01-01T17:00:00Z"},
+		{"bob", "data1", "read", "2020-02-01T00:00:00Z", ""},
+		{"carol", "data1", "read", "", ""},
+	})
+
+	clock := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	e.SetClock(func() time.Time { return clock })
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "bob", "data1", "read", false)
+	testEnforce(t, e, "carol", "data1", "read", true)
+
+	clock = time.Date(2020, 2, 2, 12, 0, 0, 0, time.UTC)
+	testEnforce(t, e, "alice", "data1", "read", false)
+	testEnforce(t, e, "bob", "data1", "read", true)
+
+	// The real clock is past the window of alice too.
+	e.SetClock(nil)
+	testEnforce(t, e, "alice", "data1", "read", false)
+	testEnforce(t, e, "bob", "data1", "read", true)
+}
+
+func TestSetClockOfTimeRangeFunctions(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act && inTimeRange("", "09:00", "17:00") && weekdayMatch("", "Mon-Fri")
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("alice", "data1", "read")
+
+	// 2026-10-16 is a Friday.
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	e.SetClock(func() time.Time { return clock })
+	testEnforce(t, e, "alice", "data1", "read", true)
+
+	clock = time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	testEnforce(t, e, "alice", "data1", "read", false)
+
+	clock = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	testEnforce(t, e, "alice", "data1", "read", false)
+}
+
+func TestSavePolicyVersioned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	policy, err := ioutil.ReadFile("examples/basic_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "policy.csv")
+	if err = ioutil.WriteFile(path, policy, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e1, _ := NewEnforcer("examples/basic_model.conf", path)
+	e2, _ := NewEnforcer("examples/basic_model.conf", path)
+	e1.EnableAutoSave(false)
+	e2.EnableAutoSave(false)
+	if e1.GetPolicyVersion() == "" || e1.GetPolicyVersion() != e2.GetPolicyVersion() {
+		t.Fatalf("versions after load: %q and %q", e1.GetPolicyVersion(), e2.GetPolicyVersion())
+	}
+
+	_, _ = e1.AddPolicy("carol", "data1", "read")
+	if err = e1.SavePolicyVersioned(); err != nil {
+		t.Fatal(err)
+	}
+
+	// e2 loaded the policy before e1 saved, so its save must not clobber e1's change.
+	_, _ = e2.AddPolicy("dave", "data2", "read")
+	err = e2.SavePolicyVersioned()
+	var conflict *persist.VersionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("SavePolicyVersioned: %v, supposed to be a version conflict", err)
+	}
+	if conflict.Expected != e2.GetPolicyVersion() || conflict.Actual != e1.GetPolicyVersion() {
+		t.Errorf("conflict versions: %q and %q", conflict.Expected, conflict.Actual)
+	}
+
+	// Reload, reapply and retry.
+	if err = e2.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = e2.AddPolicy("dave", "data2", "read")
+	if err = e2.SavePolicyVersioned(); err != nil {
+		t.Fatal(err)
+	}
+
+	e3, _ := NewEnforcer("examples/basic_model.conf", path)
+	testEnforce(t, e3, "carol", "data1", "read", true)
+	testEnforce(t, e3, "dave", "data2", "read", true)
+
+	e4, _ := NewEnforcer("examples/basic_model.conf")
+	if err = e4.SavePolicyVersioned(); err == nil {
+		t.Error("SavePolicyVersioned should fail without a versioned adapter")
+	}
+}
+
+func TestDenyOverrideSet(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	if err := e.SetDenyOverrideSet("sub", []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	// The blocked subject is denied even though allow rules match.
+	testEnforce(t, e, "alice", "data1", "read", false)
+	testEnforce(t, e, "alice", "data2", "read", false)
+	testEnforce(t, e, "bob", "data2", "write", true)
+
+	if err := e.SetDenyOverrideSet("r.obj", []string{"data2"}); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "bob", "data2", "write", false)
+
+	// An empty set removes the override.
+	_ = e.SetDenyOverrideSet("sub", nil)
+	_ = e.SetDenyOverrideSet("obj", nil)
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "bob", "data2", "write", true)
+
+	if err := e.SetDenyOverrideSet("r.ip", []string{"10.0.0.1"}); err == nil {
+		t.Error("a field outside the request definition should be rejected")
+	}
+	if err := e.SetDenyOverrideSet("r2.sub", []string{"alice"}); err == nil {
+		t.Error("an unknown request definition should be rejected")
+	}
+
+	// Cached decisions must not bypass a newly denied value.
+	ce, _ := NewCachedEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	testEnforceCache(t, ce, "alice", "data1", "read", true)
+	_ = ce.SetDenyOverrideSet("sub", []string{"alice"})
+	testEnforceCache(t, ce, "alice", "data1", "read", false)
+}
+
+func TestJsonGetMatcher(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = field, value, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = jsonGet(r.sub, p.field) == p.value && r.obj == p.obj && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("dept.name", "finance", "ledger", "read")
+	_, _ = e.AddPolicy("$.roles[0]", "auditor", "ledger", "write")
+
+	testEnforce(t, e, `{"dept": {"name": "finance"}}`, "ledger", "read", true)
+	testEnforce(t, e, `{"dept": {"name": "sales"}}`, "ledger", "read", false)
+	testEnforce(t, e, `{"roles": ["auditor"]}`, "ledger", "write", true)
+	testEnforce(t, e, `{"roles": []}`, "ledger", "write", false)
+	testEnforce(t, e, `{}`, "ledger", "read", false)
+}
+
+func TestRebuildRoleLinks(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	rm := defaultrolemanager.NewRoleManagerImpl(10)
+	e.SetRoleManager(rm)
+	if ok, _ := rm.HasLink("alice", "data2_admin"); ok {
+		t.Fatal("a new role manager should not have links before they are rebuilt")
+	}
+
+	if err := e.RebuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := rm.HasLink("alice", "data2_admin"); !ok {
+		t.Error("the links should be rebuilt from the grouping policy in memory")
+	}
+	testEnforce(t, e, "alice", "data2", "read", true)
+
+	// Adding a grouping rule only in memory shows the links come from the model, not from the adapter.
+	e.EnableAutoSave(false)
+	_, _ = e.AddGroupingPolicy("bob", "data2_admin")
+	e.SetRoleManager(defaultrolemanager.NewRoleManagerImpl(10))
+	if err := e.RebuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "bob", "data2", "read", true)
+
+	e, _ = NewEnforcer("examples/rbac_with_domains_conditional_model.conf", "examples/rbac_with_domains_conditional_policy.csv")
+	if err := e.RebuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNumericRangeMatcher(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, amount, act
+
+[policy_definition]
+p = sub, min, max, tier, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && between(r.amount, p.min, p.max) && inRange(r.amount, p.tier) && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("alice", "0", "1000", "..500.5", "approve")
+
+	for _, test := range []struct {
+		amount interface{}
+		res    bool
+	}{{0, true}, {500.5, true}, {501, false}, {-1, false}, {"250", true}} {
+		res, err := e.Enforce("alice", test.amount, "approve")
+		if err != nil || res != test.res {
+			t.Errorf("amount %v: %t, %v, supposed to be %t", test.amount, res, err, test.res)
+		}
+	}
+
+	if _, err := e.Enforce("alice", "a lot", "approve"); err == nil {
+		t.Error("a non-numeric amount should fail")
+	}
+}
+
+// countingLogger counts the model, policy and enforce messages logged to it.
+type countingLogger struct {
+	log.DefaultLogger
+	models, policies, enforces int
+}
+
+func (l *countingLogger) LogModel(model [][]string) { l.models++ }
+
+func (l *countingLogger) LogPolicy(policy map[string][][]string) { l.policies++ }
+
+func (l *countingLogger) LogEnforce(matcher string, request []interface{}, result bool, explains [][]string) {
+	l.enforces++
+}
+
+func TestModelLogger(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	enforceLogger := &countingLogger{}
+	modelLogger := &countingLogger{}
+	enforceLogger.EnableLog(true)
+	modelLogger.EnableLog(true)
+	e.SetLogger(enforceLogger)
+	e.SetModelLogger(modelLogger)
+
+	if e.GetModelLogger() != modelLogger || e.GetModel().GetLogger() != modelLogger {
+		t.Fatal("the model should use the model logger")
+	}
+
+	e.SetLogger(enforceLogger)
+	if e.GetModel().GetLogger() != modelLogger {
+		t.Fatal("SetLogger should keep the model logger")
+	}
+
+	e.GetModel().PrintPolicy()
+	_, _ = e.Enforce("alice", "data1", "read")
+	if modelLogger.policies != 1 || modelLogger.enforces != 0 {
+		t.Errorf("model logger: %d policy and %d enforce messages, expected only 1 policy message", modelLogger.policies, modelLogger.enforces)
+	}
+	if enforceLogger.enforces != 1 || enforceLogger.policies != 0 {
+		t.Errorf("enforce logger: %d policy and %d enforce messages, expected only 1 enforce message", enforceLogger.policies, enforceLogger.enforces)
+	}
+
+	_ = e.LoadModel()
+	if e.GetModel().GetLogger() != modelLogger {
+		t.Error("a reloaded model should use the model logger")
+	}
+
+	e.SetModelLogger(nil)
+	if e.GetModelLogger() != enforceLogger || e.GetModel().GetLogger() != enforceLogger {
+		t.Error("the model should share the enforcer's logger after the model logger is unset")
+	}
+}
+
+func TestTestRuleAgainstRequest(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+
+	for _, test := range []struct {
+		rule    []string
+		request []interface{}
+		res     bool
+	}{
+		{[]string{"carol", "data3", "read", "allow"}, []interface{}{"carol", "data3", "read"}, true},
+		{[]string{"carol", "data3", "read", "allow"}, []interface{}{"carol", "data3", "write"}, false},
+		{[]string{"data2_admin", "data3", "read", "allow"}, []interface{}{"alice", "data3", "read"}, true},
+		{[]string{"alice", "data1", "read", "deny"}, []interface{}{"alice", "data1", "read"}, true},
+	} {
+		res, err := e.TestRuleAgainstRequest(test.rule, test.request...)
+		if err != nil || res != test.res {
+			t.Errorf("rule %v, request %v: %t, %v, supposed to be %t", test.rule, test.request, res, err, test.res)
+		}
+	}
+
+	if ok, _ := e.HasPolicy("carol", "data3", "read", "allow"); ok {
+		t.Error("the tested rule should not be added to the policy")
+	}
+
+	if _, err := e.TestRuleAgainstRequest([]string{"carol", "data3"}, "carol", "data3", "read"); err == nil {
+		t.Error("a rule of the wrong size should fail")
+	}
+	if _, err := e.TestRuleAgainstRequest([]string{"carol", "data3", "read", "allow"}, "carol", "data3"); err == nil {
+		t.Error("a request of the wrong size should fail")
+	}
+}
+
+func TestContainsScopesMatcher(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, scopes, obj
+
+[policy_definition]
+p = sub, anyOf, allOf, obj
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && containsAny(r.scopes, p.anyOf) && containsAll(r.scopes, p.allOf) && r.obj == p.obj
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("app", "repo:read,repo:admin", "user:email", "repo1")
+
+	for _, test := range []struct {
+		scopes interface{}
+		res    bool
+	}{
+		{[]string{"repo:read", "user:email"}, true},
+		{[]string{"repo:admin", "user:email", "gist"}, true},
+		{[]string{"repo:read"}, false},
+		{[]string{"user:email"}, false},
+		{"repo:read,user:email", true},
+	} {
+		res, err := e.Enforce("app", test.scopes, "repo1")
+		if err != nil || res != test.res {
+			t.Errorf("scopes %v: %t, %v, supposed to be %t", test.scopes, res, err, test.res)
+		}
+	}
+}
+
+// orderedEffector is the default effector, which the enforcer does not evaluate deny rules first for.
+type orderedEffector struct {
+	effector.DefaultEffector
+}
+
+func TestDenyFirstEvaluation(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+	ordered, _ := NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+	ordered.SetEffector(&orderedEffector{})
+
+	for _, sub := range []string{"alice", "bob", "data2_admin"} {
+		for _, obj := range []string{"data1", "data2"} {
+			for _, act := range []string{"read", "write"} {
+				res, explain, err := e.EnforceEx(sub, obj, act)
+				expected, expectedExplain, _ := ordered.EnforceEx(sub, obj, act)
+				if err != nil || res != expected || !util.ArrayEquals(explain, expectedExplain) {
+					t.Errorf("%s, %s, %s: %t, %v, %v, supposed to be %t, %v", sub, obj, act, res, explain, err, expected, expectedExplain)
+				}
+			}
+		}
+	}
+
+	evaluated := 0
+	e.AddFunction("counted", func(args ...interface{}) (interface{}, error) {
+		evaluated++
+		return true, nil
+	})
+	_, _ = e.AddPolicies([][]string{{"alice", "data3", "read", "allow"}, {"alice", "data3", "write", "allow"}, {"alice", "data3", "read", "deny"}})
+	ok, _ := e.EnforceWithMatcher("counted() && g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act", "alice", "data3", "read")
+	if ok {
+		t.Error("alice, data3, read should be denied")
+	}
+	if deny := 2; evaluated != deny {
+		t.Errorf("evaluated rules: %d, expected only the %d deny rules", evaluated, deny)
+	}
+}
+
+func TestNewEnforcerFromFiles(t *testing.T) {
+	e, err := NewEnforcerFromFiles([]string{"examples/rbac_model_definitions.conf", "examples/rbac_model_matchers.conf"},
+		fileadapter.NewAdapter("examples/rbac_policy.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "alice", "data2", "read", true)
+	testEnforce(t, e, "bob", "data1", "read", false)
+
+	e, err = NewEnforcerFromFiles([]string{"examples/rbac_model_definitions.conf", "examples/rbac_model_matchers.conf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	testEnforce(t, e, "alice", "data1", "read", true)
+
+	if _, err = NewEnforcerFromFiles([]string{"examples/rbac_model_definitions.conf", "examples/not_found.conf"}, nil); err == nil {
+		t.Error("a missing model file should fail")
+	}
+}
+
+func TestReloadPolicyAtomicSwap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := testWritePolicyFile(t, dir, "p, data2_admin, data2, read\ng, alice, data2_admin\n")
+
+	e, _ := NewSyncedEnforcer("examples/rbac_model.conf", path)
+	testEnforce(t, e.Enforcer, "alice", "data2", "read", true)
+	oldRm := e.GetRoleManager()
+
+	testWritePolicyFile(t, dir, "p, data2_admin, data2, read\ng, bob, data2_admin\n")
+	if err := e.ReloadPolicyAtomicSwap(); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e.Enforcer, "alice", "data2", "read", false)
+	testEnforce(t, e.Enforcer, "bob", "data2", "read", true)
+
+	// The role links were built into a new role manager, the previous one is left untouched.
+	if e.GetRoleManager() == oldRm {
+		t.Error("the role manager should be swapped")
+	}
+	if ok, _ := oldRm.HasLink("alice", "data2_admin"); !ok {
+		t.Error("the previous role manager should keep its links")
+	}
+
+	// Adding a rule after the swap updates the new role manager.
+	_, _ = e.AddGroupingPolicy("carol", "data2_admin")
+	testEnforce(t, e.Enforcer, "carol", "data2", "read", true)
+}
+
+func TestPolicyChangedCallbackAfterLoadPolicy(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	var added [][]string
+	e.GetModel().OnPolicyChanged(func(sec string, ptype string, op model.PolicyOp, rules [][]string) {
+		if op == model.PolicyAdd {
+			added = append(added, rules...)
+		}
+	})
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ReloadPolicyAtomicSwap(); err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 {
+		t.Errorf("loaded rules: %v, supposed not to be reported", added)
+	}
+
+	_, _ = e.AddPolicy("carol", "data3", "read")
+	if !util.Array2DEquals(added, [][]string{{"carol", "data3", "read"}}) {
+		t.Errorf("added rules: %v, the callback should be kept by LoadPolicy", added)
+	}
+}
+
+func TestPreMatcher(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	calls := 0
+	e.SetPreMatcher(func(rvals []interface{}) (bool, bool) {
+		calls++
+		switch rvals[0] {
+		case "guest":
+			return true, false
+		case "root":
+			return true, true
+		}
+		return false, false
+	})
+
+	testEnforce(t, e, "guest", "data1", "read", false)
+	testEnforce(t, e, "root", "data1", "read", true)
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "alice", "data1", "write", false)
+	if calls != 4 {
+		t.Errorf("pre-matcher calls: %d, supposed to be 4", calls)
+	}
+
+	ok, explain, _ := e.EnforceEx("root", "data3", "write")
+	if !ok || len(explain) != 0 {
+		t.Errorf("root: %t, %v, supposed to be allowed without explanation", ok, explain)
+	}
+
+	// A deny override set still applies to the requests allowed by the pre-matcher.
+	_ = e.SetDenyOverrideSet("sub", []string{"root"})
+	testEnforce(t, e, "root", "data1", "read", false)
+
+	e.SetPreMatcher(nil)
+	_ = e.SetDenyOverrideSet("sub", nil)
+	testEnforce(t, e, "root", "data1", "read", false)
+	testEnforce(t, e, "guest", "data1", "read", false)
+}
+
+func TestSetNamedRoleManager(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_resource_roles_model.conf", "examples/rbac_with_resource_roles_policy.csv")
+	_, _ = e.AddNamedGroupingPolicy("g2", "data3", "data1")
+
+	// A hierarchy of resources is followed by the default role manager.
+	testEnforce(t, e, "alice", "data3", "write", true)
+
+	// A flat role manager only follows the direct links of g2, while g stays hierarchical.
+	rm := defaultrolemanager.NewRoleManagerImpl(1)
+	e.SetNamedRoleManager("g2", rm)
+	if e.GetNamedRoleManager("g2") != rm {
+		t.Error("role manager of g2 should be set")
+	}
+	if err := e.RebuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "alice", "data1", "write", true)
+	testEnforce(t, e, "alice", "data3", "write", false)
+	if ok, _ := rm.HasLink("data1", "data_group"); !ok {
+		t.Error("links of g2 should be built into its role manager")
+	}
+	if ok, _ := e.GetNamedRoleManager("g").HasLink("data1", "data_group"); ok {
+		t.Error("links of g2 should not be built into the role manager of g")
+	}
+
+	// The role manager is kept by LoadPolicy.
+	_ = e.LoadPolicy()
+	if e.GetNamedRoleManager("g2") != rm {
+		t.Error("role manager of g2 should be kept by LoadPolicy")
+	}
+	testEnforce(t, e, "alice", "data1", "write", true)
+	testEnforce(t, e, "alice", "data3", "write", false)
+}
+
+// wrappedEffector is the default effector given the effects of the whole policy, as it is not a *DefaultEffector.
+type wrappedEffector struct {
+	*effector.DefaultEffector
+}
+
+func TestAllowOverrideShortCircuit(t *testing.T) {
+	for _, files := range [][2]string{
+		{"examples/rbac_model.conf", "examples/rbac_policy.csv"},
+		{"examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv"},
+	} {
+		e, _ := NewEnforcer(files[0], files[1])
+		e.GetModel()["e"]["e"].Value = "some(where (p_eft == allow))"
+		fullScan, _ := NewEnforcer(files[0], files[1])
+		fullScan.GetModel()["e"]["e"].Value = "some(where (p_eft == allow))"
+		fullScan.SetEffector(wrappedEffector{effector.NewDefaultEffector()})
+
+		for _, sub := range []string{"alice", "bob", "data2_admin", "carol"} {
+			for _, obj := range []string{"data1", "data2"} {
+				for _, act := range []string{"read", "write"} {
+					res, explain, err := e.EnforceEx(sub, obj, act)
+					if err != nil {
+						t.Fatal(err)
+					}
+					fullScanRes, fullScanExplain, _ := fullScan.EnforceEx(sub, obj, act)
+					if res != fullScanRes || !util.ArrayEquals(explain, fullScanExplain) {
+						t.Errorf("%s: %s, %s, %s: %t %v, supposed to be %t %v", files[0], sub, obj, act, res, explain, fullScanRes, fullScanExplain)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLoadModelKeepsOptions(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err := e.GetModel().SetMatchingOptions(model.MatchingOptions{CaseInsensitive: true, Separator: "\x1f"}); err != nil {
+		t.Fatal(err)
+	}
+	e.GetModel().SetRejectRoleCycles(true)
+
+	if err := e.LoadModel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.HasPolicy("ALICE", "data1", "READ"); !ok {
+		t.Error("the case-insensitive matching should be kept by LoadModel")
+	}
+	if key := e.GetModel().PolicyKey("p", "p", []string{"a", "b"}); key != "a\x1fb" {
+		t.Errorf("policy key: %q, supposed to be joined by the separator", key)
+	}
+	if ok, err := e.AddGroupingPolicy("data2_admin", "alice"); ok || err == nil {
+		t.Error("role cycles should still be rejected after LoadModel")
+	}
+}
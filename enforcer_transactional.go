@@ -18,8 +18,6 @@ import (
 	"context"
 	"errors"
 	"sync"
-
-	"github.com/casbin/casbin/v2/persist"
 )
 
 // TransactionalEnforcer extends Enforcer with transaction support.
@@ -54,7 +52,7 @@ func (te *TransactionalEnforcer) BeginTransaction(ctx context.Context) (*Transac
 	}
 
 	// Check if adapter supports transactions.
-	txAdapter, ok := te.adapter.(persist.TransactionalAdapter)
+	txAdapter, ok := asTransactionalAdapter(te.adapter)
 	if !ok {
 		return nil, errors.New("adapter does not support transactions")
 	}
@@ -23,6 +23,9 @@ var (
 	ErrLinkNotFound                = errors.New("error: link between name1 and name2 does not exist")
 	ErrUseDomainParameter          = errors.New("error: useDomain should be 1 parameter")
 	ErrInvalidFieldValuesParameter = errors.New("fieldValues requires at least one parameter")
+	ErrInvalidFieldIndex           = errors.New("fieldIndex should not be negative")
+	ErrUnusedPolicyField           = errors.New("the policy rule sets a field that is not used by the model")
+	ErrShadowedPolicy              = errors.New("the policy rule is shadowed by a rule of higher priority")
 
 	// GetAllowedObjectConditions errors.
 	ErrObjCondition   = errors.New("need to meet the prefix required by the object condition")
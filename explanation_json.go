@@ -0,0 +1,70 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"encoding/json"
+)
+
+// enforceExplanation is the JSON object produced by EnforceWithExplanationJSON.
+type enforceExplanation struct {
+	Allowed     bool     `json:"allowed"`
+	MatchedRule []string `json:"matchedRule"`
+	Effect      *string  `json:"effect"`
+	Ptype       string   `json:"ptype"`
+}
+
+// EnforceWithExplanationJSON decides whether the request is allowed like EnforceEx, and returns the decision
+// as a JSON object with the following schema, whose fields are always present and in this order:
+//
+//	{
+//		"allowed":     true,                        // the decision
+//		"matchedRule": ["alice", "data1", "read"],  // the rule deciding the request, null if no rule matched
+//		"effect":      "allow",                     // the effect of the matched rule, "allow" or "deny",
+//		                                            // "allow" if the policy has no eft field, null if no rule matched
+//		"ptype":       "p"                          // the policy type the request was enforced against
+//	}
+func (e *Enforcer) EnforceWithExplanationJSON(rvals ...interface{}) ([]byte, error) {
+	allowed, explain, err := e.EnforceEx(rvals...)
+	if err != nil {
+		return nil, err
+	}
+	return e.explanationJSON(allowed, explain, rvals...)
+}
+
+// explanationJSON builds the JSON object of EnforceWithExplanationJSON from the outputs of EnforceEx.
+func (e *Enforcer) explanationJSON(allowed bool, explain []string, rvals ...interface{}) ([]byte, error) {
+	pType := "p"
+	if len(rvals) != 0 {
+		if enforceContext, ok := rvals[0].(EnforceContext); ok {
+			pType = enforceContext.PType
+		}
+	}
+
+	res := enforceExplanation{Allowed: allowed, Ptype: pType}
+	if len(explain) != 0 {
+		res.MatchedRule = explain
+		effect := "allow"
+		if assertion, err := e.model.GetAssertion("p", pType); err == nil {
+			for i, token := range assertion.Tokens {
+				if token == pType+"_eft" && i < len(explain) && explain[i] != "" {
+					effect = explain[i]
+				}
+			}
+		}
+		res.Effect = &effect
+	}
+	return json.Marshal(res)
+}
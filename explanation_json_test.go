@@ -0,0 +1,46 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"testing"
+)
+
+func testEnforceWithExplanationJSON(t *testing.T, e IEnforcer, res string, rvals ...interface{}) {
+	t.Helper()
+	b, err := e.EnforceWithExplanationJSON(rvals...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != res {
+		t.Errorf("%v: %s, supposed to be %s", rvals, b, res)
+	}
+}
+
+func TestEnforceWithExplanationJSON(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	testEnforceWithExplanationJSON(t, e, `{"allowed":true,"matchedRule":["alice","data1","read"],"effect":"allow","ptype":"p"}`, "alice", "data1", "read")
+	testEnforceWithExplanationJSON(t, e, `{"allowed":false,"matchedRule":null,"effect":null,"ptype":"p"}`, "alice", "data2", "read")
+
+	e, _ = NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+	testEnforceWithExplanationJSON(t, e, `{"allowed":false,"matchedRule":["alice","data2","write","deny"],"effect":"deny","ptype":"p"}`, "alice", "data2", "write")
+
+	se, _ := NewSyncedEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	testEnforceWithExplanationJSON(t, se, `{"allowed":true,"matchedRule":["bob","data2","write"],"effect":"allow","ptype":"p"}`, "bob", "data2", "write")
+
+	if _, err := e.EnforceWithExplanationJSON("alice", "data1"); err == nil {
+		t.Error("invalid request size should be an error")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+)
+
+// FailurePolicy is the decision of the enforcer when evaluating a request fails, see SetFailurePolicy.
+type FailurePolicy int
+
+const (
+	// FailWithError returns the error with a false decision, the caller decides. It is the default.
+	FailWithError FailurePolicy = iota
+	// FailClosed denies the request without an error.
+	FailClosed
+	// FailOpen allows the request without an error.
+	FailOpen
+)
+
+// SetFailurePolicy sets the decision of the enforcer when evaluating a request fails, e.g. when a custom function
+// panics or the matcher errors at runtime, so that the security posture doesn't depend on how each caller handles
+// the error. With FailClosed or FailOpen, the error is logged and the enforcer returns false or true with a nil error.
+// The failure policy only applies to the evaluation of the matcher: an invalid request, e.g. of the wrong size,
+// a missing definition or a matcher that doesn't compile are always returned as errors, and so is the error
+// of a request cancelled by its context, see EnforceWithContext.
+func (e *Enforcer) SetFailurePolicy(policy FailurePolicy) {
+	e.failurePolicy = policy
+}
+
+// evaluationError is an error raised while evaluating the matcher, e.g. by a custom function,
+// to which the failure policy applies.
+type evaluationError struct {
+	err error
+}
+
+func (e *evaluationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *evaluationError) Unwrap() error {
+	return e.err
+}
+
+// applyFailurePolicy returns the decision for a request that failed with err.
+func (e *Enforcer) applyFailurePolicy(ctx context.Context, err error) (bool, error) {
+	var evalErr *evaluationError
+	if !errors.As(err, &evalErr) {
+		return false, err
+	}
+	if e.failurePolicy == FailWithError || ctx.Err() != nil {
+		return false, evalErr.err
+	}
+	e.logger.LogError(evalErr.err, "evaluating the request failed")
+	return e.failurePolicy == FailOpen, nil
+}
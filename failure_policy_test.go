@@ -0,0 +1,99 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+)
+
+func TestFailurePolicy(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = check(r.sub) && r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	e, err := NewEnforcer(m, fileadapter.NewAdapter("examples/basic_policy.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddFunction("check", func(args ...interface{}) (interface{}, error) {
+		switch args[0] {
+		case "panic":
+			panic("check failed")
+		case "error":
+			return nil, errors.New("check failed")
+		}
+		return true, nil
+	})
+
+	for _, sub := range []string{"panic", "error"} {
+		if ok, err := e.Enforce(sub, "data1", "read"); ok || err == nil {
+			t.Errorf("%s with FailWithError: %t, %v, supposed to be false with an error", sub, ok, err)
+		}
+	}
+
+	e.SetFailurePolicy(FailClosed)
+	for _, sub := range []string{"panic", "error"} {
+		if ok, err := e.Enforce(sub, "data1", "read"); ok || err != nil {
+			t.Errorf("%s with FailClosed: %t, %v, supposed to be false", sub, ok, err)
+		}
+	}
+	testEnforce(t, e, "alice", "data1", "read", true)
+
+	e.SetFailurePolicy(FailOpen)
+	for _, sub := range []string{"panic", "error"} {
+		if ok, err := e.Enforce(sub, "data1", "read"); !ok || err != nil {
+			t.Errorf("%s with FailOpen: %t, %v, supposed to be true", sub, ok, err)
+		}
+	}
+	testEnforce(t, e, "alice", "data2", "read", false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = e.EnforceWithContext(ctx, "alice", "data1", "read"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled request with FailOpen: %v, supposed to be %v", err, context.Canceled)
+	}
+}
+
+func TestFailurePolicyInvalidRequest(t *testing.T) {
+	e, err := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetFailurePolicy(FailOpen)
+
+	if ok, err := e.Enforce("alice", "data1"); ok || err == nil {
+		t.Errorf("invalid request size with FailOpen: %t, %v, supposed to be false with an error", ok, err)
+	}
+	if ok, err := e.Enforce(NewEnforceContext("2"), "alice", "data1", "read"); ok || err == nil {
+		t.Errorf("missing definitions with FailOpen: %t, %v, supposed to be false with an error", ok, err)
+	}
+	if ok, err := e.EnforceWithMatcher("r.sub == (", "alice", "data1", "read"); ok || err == nil {
+		t.Errorf("invalid matcher with FailOpen: %t, %v, supposed to be false with an error", ok, err)
+	}
+}
@@ -15,6 +15,9 @@
 package casbin
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
@@ -207,3 +210,66 @@ func TestFilteredAdapterInvalidFilepath(t *testing.T) {
 		t.Errorf("expected error in LoadFilteredPolicy, but got nil")
 	}
 }
+
+func TestFileAdapterSeparator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := testWritePolicyFile(t, dir, "p\tadmin\tdomain1\tdata1\tread\np\tadmin\tdomain2\tdata2, archived\tread\n"+
+		"g\talice\tadmin\tdomain1\ng\tbob\tadmin\tdomain2\n")
+
+	a := fileadapter.NewFilteredAdapter(path)
+	a.SetSeparator('\t')
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", a)
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "read", true)
+	testDomainEnforce(t, e, "bob", "domain2", "data2, archived", "read", true)
+
+	if err := e.LoadFilteredPolicy(&fileadapter.Filter{P: []string{"", "domain2"}, G: []string{"", "", "domain2"}}); err != nil {
+		t.Fatal(err)
+	}
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "read", false)
+	testDomainEnforce(t, e, "bob", "domain2", "data2, archived", "read", true)
+
+	_ = e.LoadPolicy()
+	_, _ = e.AddPolicy("admin", "domain1", "data3", "write")
+	if err := e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(data), "p\tadmin\tdomain1\tdata3\twrite\n") {
+		t.Errorf("the policy should be saved with tabs: %q", data)
+	}
+}
+
+func TestFilteredPolicyByPType(t *testing.T) {
+	e, _ := NewEnforcer()
+	adapter := fileadapter.NewFilteredAdapter("examples/rbac_with_pattern_policy.csv")
+	_ = e.InitWithAdapter("examples/rbac_with_pattern_model.conf", adapter)
+
+	if err := e.LoadFilteredPolicy(&fileadapter.Filter{PTypes: []string{"p", "g"}}); err != nil {
+		t.Fatal(err)
+	}
+	testHasPolicy(t, e, []string{"alice", "/pen/1", "GET"}, true)
+	testHasGroupingPolicy(t, e, []string{"alice", "book_admin"}, true)
+	if rules, _ := e.GetNamedGroupingPolicy("g2"); len(rules) != 0 {
+		t.Errorf("g2 rules: %v, supposed to be skipped", rules)
+	}
+
+	// The field filters apply to the included policy types only.
+	if err := e.LoadFilteredPolicy(&fileadapter.Filter{PTypes: []string{"g2"}, G2: []string{"", "book_group"}}); err != nil {
+		t.Fatal(err)
+	}
+	if rules, _ := e.GetPolicy(); len(rules) != 0 {
+		t.Errorf("p rules: %v, supposed to be skipped", rules)
+	}
+	rules, _ := e.GetNamedGroupingPolicy("g2")
+	expected := [][]string{{"/book/*", "book_group"}, {"/book/:id", "book_group"}, {"/book2/{id}", "book_group"}}
+	if !util.Array2DEquals(rules, expected) {
+		t.Errorf("g2 rules: %v, supposed to be %v", rules, expected)
+	}
+}
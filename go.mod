@@ -4,7 +4,7 @@ require (
 	github.com/bmatcuk/doublestar/v4 v4.6.1
 	github.com/casbin/govaluate v1.3.0
 	github.com/golang/mock v1.4.4
-	github.com/shirou/gopsutil/v3 v3.24.5 // indirect
+	github.com/shirou/gopsutil/v3 v3.24.5
 )
 
 go 1.13
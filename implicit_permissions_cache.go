@@ -0,0 +1,110 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"github.com/casbin/casbin/v2/util"
+)
+
+const defaultImplicitPermissionsCacheSize = 1000
+
+// implicitPermissionsKey identifies a call of GetNamedImplicitPermissionsForUser.
+type implicitPermissionsKey struct {
+	ptype  string
+	gtype  string
+	user   string
+	domain string
+}
+
+// implicitPermissionsEntry holds the implicit permissions of a user at a revision of the policy.
+type implicitPermissionsEntry struct {
+	revision   uint64
+	permission [][]string
+}
+
+// EnableImplicitPermissionsCache enables or disables caching the results of GetImplicitPermissionsForUser
+// and GetNamedImplicitPermissionsForUser by user and domain, for permission reports requested repeatedly.
+// A cached result is reused until the policy or a role manager changes through the enforcer: any change
+// of p or g, a reload or a new role manager discards all of them. Changes made to the model or to a role manager
+// directly are not noticed. The implicit permissions through a conditional role definition, e.g. temporal roles,
+// are not cached, as its links change with their conditions. The least recently used results are evicted beyond the size set by
+// SetImplicitPermissionsCacheSize, 1000 by default.
+func (e *Enforcer) EnableImplicitPermissionsCache(enable bool) {
+	if !enable {
+		e.permissionsCache = nil
+		return
+	}
+	if e.permissionsCache == nil {
+		e.permissionsCache = util.NewSyncLRUCache(e.getImplicitPermissionsCacheSize())
+	}
+}
+
+// SetImplicitPermissionsCacheSize sets the maximum number of results kept by the implicit permissions cache.
+// It clears the cache if enabled.
+func (e *Enforcer) SetImplicitPermissionsCacheSize(size int) {
+	e.permissionsCacheSize = size
+	if e.permissionsCache != nil {
+		e.permissionsCache = util.NewSyncLRUCache(e.getImplicitPermissionsCacheSize())
+	}
+}
+
+func (e *Enforcer) getImplicitPermissionsCacheSize() int {
+	if e.permissionsCacheSize <= 0 {
+		return defaultImplicitPermissionsCacheSize
+	}
+	return e.permissionsCacheSize
+}
+
+// getCachedImplicitPermissions returns a copy of the cached implicit permissions of key if they are up to date.
+func (e *Enforcer) getCachedImplicitPermissions(key implicitPermissionsKey) ([][]string, bool) {
+	if !e.isImplicitPermissionsCacheable(key) {
+		return nil, false
+	}
+	value, ok := e.permissionsCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := value.(implicitPermissionsEntry)
+	if entry.revision != e.policyRevision {
+		return nil, false
+	}
+	return copyRules(entry.permission), true
+}
+
+// cacheImplicitPermissions caches a copy of the implicit permissions of key at the current revision of the policy.
+func (e *Enforcer) cacheImplicitPermissions(key implicitPermissionsKey, permission [][]string) {
+	if !e.isImplicitPermissionsCacheable(key) {
+		return
+	}
+	e.permissionsCache.Put(key, implicitPermissionsEntry{revision: e.policyRevision, permission: copyRules(permission)})
+}
+
+// isImplicitPermissionsCacheable returns whether the implicit permissions of key can be cached, i.e. the cache
+// is enabled and the links of the role definition don't depend on conditions, which may change at any time.
+func (e *Enforcer) isImplicitPermissionsCacheable(key implicitPermissionsKey) bool {
+	if e.permissionsCache == nil {
+		return false
+	}
+	_, conditional := e.condRmMap[key.gtype]
+	return !conditional
+}
+
+func copyRules(rules [][]string) [][]string {
+	res := make([][]string, len(rules))
+	for i, rule := range rules {
+		res[i] = append([]string(nil), rule...)
+	}
+	return res
+}
@@ -16,10 +16,13 @@ package casbin
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/casbin/casbin/v2/constant"
 	Err "github.com/casbin/casbin/v2/errors"
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
 )
 
 const (
@@ -30,12 +33,91 @@ func (e *Enforcer) shouldPersist() bool {
 	return e.adapter != nil && e.autoSave
 }
 
+// persistChange writes a policy change with auto-save, rule by rule if the adapter is a persist.IncrementalAdapter,
+// or else with write, which calls the method of Adapter, BatchAdapter or UpdatableAdapter matching the change.
+// A "not implemented" error is ignored, the change is then only made in memory.
+func (e *Enforcer) persistChange(op persist.PolicyOperation, write func() error) error {
+	var err error
+	if a, ok := asIncrementalAdapter(e.adapter); ok {
+		if op.Type == persist.OperationAdd {
+			op.Rules = e.newRules(op.Section, op.PolicyType, op.Rules)
+		}
+		err = applyIncrementalOperation(a, op)
+	} else {
+		err = write()
+	}
+	if err != nil && err.Error() != notImplemented {
+		return err
+	}
+	return nil
+}
+
+// newRules returns the rules that are not in the policy yet, without repetition.
+func (e *Enforcer) newRules(sec string, ptype string, rules [][]string) [][]string {
+	var res [][]string
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		key := e.model.PolicyKey(sec, ptype, rule)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if ok, _ := e.model.HasPolicy(sec, ptype, rule); !ok {
+			res = append(res, rule)
+		}
+	}
+	return res
+}
+
 func (e *Enforcer) shouldNotify() bool {
 	return e.watcher != nil && e.autoNotifyWatcher
 }
 
+// validateRules checks that the rules only set fields that are used by the model, see SetValidateOnAdd.
+func (e *Enforcer) validateRules(sec string, ptype string, rules [][]string) error {
+	if !e.validateOnAdd || sec != "p" {
+		return nil
+	}
+
+	assertion, err := e.model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+
+	for i, token := range assertion.Tokens {
+		if token == ptype+"_"+constant.PriorityIndex || e.isTokenUsed(token) {
+			continue
+		}
+		for _, rule := range rules {
+			if i < len(rule) && rule[i] != "" {
+				return fmt.Errorf("%w: %s.%s of %v", Err.ErrUnusedPolicyField, ptype, strings.TrimPrefix(token, ptype+"_"), rule)
+			}
+		}
+	}
+	return nil
+}
+
+// isTokenUsed determines whether a token is referenced by any matcher or policy effect.
+func (e *Enforcer) isTokenUsed(token string) bool {
+	for _, sec := range []string{"m", "e"} {
+		for _, assertion := range e.model[sec] {
+			if util.HasToken(assertion.Value, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // addPolicy adds a rule to the current policy.
 func (e *Enforcer) addPolicyWithoutNotify(sec string, ptype string, rule []string) (bool, error) {
+	if err := e.validateRules(sec, ptype, [][]string{rule}); err != nil {
+		return false, err
+	}
+	if err := e.model.CheckRoleCycles(sec, ptype, [][]string{rule}); err != nil {
+		return false, err
+	}
+
 	if e.dispatcher != nil && e.autoNotifyDispatcher {
 		return true, e.dispatcher.AddPolicies(sec, ptype, [][]string{rule})
 	}
@@ -46,10 +128,9 @@ func (e *Enforcer) addPolicyWithoutNotify(sec string, ptype string, rule []strin
 	}
 
 	if e.shouldPersist() {
-		if err = e.adapter.AddPolicy(sec, ptype, rule); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationAdd, Section: sec, PolicyType: ptype, Rules: [][]string{rule}}
+		if err = e.persistChange(op, func() error { return e.adapter.AddPolicy(sec, ptype, rule) }); err != nil {
+			return false, err
 		}
 	}
 
@@ -57,12 +138,19 @@ func (e *Enforcer) addPolicyWithoutNotify(sec string, ptype string, rule []strin
 	if err != nil {
 		return false, err
 	}
+	defer e.publishChange(persist.OperationAdd, sec, ptype, [][]string{rule}, nil)
+	e.detectShadowedRules(sec, ptype, [][]string{rule})
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyAdd, ptype, [][]string{rule})
 		if err != nil {
 			return true, err
 		}
+
+		err = e.BuildIncrementalConditionalRoleLinks(model.PolicyAdd, ptype, [][]string{rule})
+		if err != nil {
+			return true, err
+		}
 	}
 
 	return true, nil
@@ -72,6 +160,13 @@ func (e *Enforcer) addPolicyWithoutNotify(sec string, ptype string, rule []strin
 // If autoRemoveRepeat == true, existing rules are automatically filtered
 // Otherwise, false is returned directly.
 func (e *Enforcer) addPoliciesWithoutNotify(sec string, ptype string, rules [][]string, autoRemoveRepeat bool) (bool, error) {
+	if err := e.validateRules(sec, ptype, rules); err != nil {
+		return false, err
+	}
+	if err := e.model.CheckRoleCycles(sec, ptype, rules); err != nil {
+		return false, err
+	}
+
 	if e.dispatcher != nil && e.autoNotifyDispatcher {
 		return true, e.dispatcher.AddPolicies(sec, ptype, rules)
 	}
@@ -84,17 +179,18 @@ func (e *Enforcer) addPoliciesWithoutNotify(sec string, ptype string, rules [][]
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.(persist.BatchAdapter).AddPolicies(sec, ptype, rules); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationAdd, Section: sec, PolicyType: ptype, Rules: rules}
+		if err := e.persistChange(op, func() error { return e.adapter.(persist.BatchAdapter).AddPolicies(sec, ptype, rules) }); err != nil {
+			return false, err
 		}
 	}
 
-	err := e.model.AddPolicies(sec, ptype, rules)
+	affected, err := e.model.AddPoliciesWithAffected(sec, ptype, rules)
+	defer e.publishChange(persist.OperationAdd, sec, ptype, affected, nil)
 	if err != nil {
 		return false, err
 	}
+	e.detectShadowedRules(sec, ptype, affected)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyAdd, ptype, rules)
@@ -118,10 +214,9 @@ func (e *Enforcer) removePolicyWithoutNotify(sec string, ptype string, rule []st
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.RemovePolicy(sec, ptype, rule); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationRemove, Section: sec, PolicyType: ptype, Rules: [][]string{rule}}
+		if err := e.persistChange(op, func() error { return e.adapter.RemovePolicy(sec, ptype, rule) }); err != nil {
+			return false, err
 		}
 	}
 
@@ -129,12 +224,18 @@ func (e *Enforcer) removePolicyWithoutNotify(sec string, ptype string, rule []st
 	if !ruleRemoved || err != nil {
 		return ruleRemoved, err
 	}
+	defer e.publishChange(persist.OperationRemove, sec, ptype, [][]string{rule}, nil)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, [][]string{rule})
 		if err != nil {
 			return ruleRemoved, err
 		}
+
+		err = e.BuildIncrementalConditionalRoleLinks(model.PolicyRemove, ptype, [][]string{rule})
+		if err != nil {
+			return ruleRemoved, err
+		}
 	}
 
 	return ruleRemoved, nil
@@ -146,16 +247,18 @@ func (e *Enforcer) updatePolicyWithoutNotify(sec string, ptype string, oldRule [
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.(persist.UpdatableAdapter).UpdatePolicy(sec, ptype, oldRule, newRule); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationUpdate, Section: sec, PolicyType: ptype, Rules: [][]string{newRule}, OldRules: [][]string{oldRule}}
+		if err := e.persistChange(op, func() error {
+			return e.adapter.(persist.UpdatableAdapter).UpdatePolicy(sec, ptype, oldRule, newRule)
+		}); err != nil {
+			return false, err
 		}
 	}
 	ruleUpdated, err := e.model.UpdatePolicy(sec, ptype, oldRule, newRule)
 	if !ruleUpdated || err != nil {
 		return ruleUpdated, err
 	}
+	defer e.publishChange(persist.OperationUpdate, sec, ptype, [][]string{newRule}, [][]string{oldRule})
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, [][]string{oldRule}) // remove the old rule
@@ -181,10 +284,11 @@ func (e *Enforcer) updatePoliciesWithoutNotify(sec string, ptype string, oldRule
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.(persist.UpdatableAdapter).UpdatePolicies(sec, ptype, oldRules, newRules); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationUpdate, Section: sec, PolicyType: ptype, Rules: newRules, OldRules: oldRules}
+		if err := e.persistChange(op, func() error {
+			return e.adapter.(persist.UpdatableAdapter).UpdatePolicies(sec, ptype, oldRules, newRules)
+		}); err != nil {
+			return false, err
 		}
 	}
 
@@ -192,6 +296,7 @@ func (e *Enforcer) updatePoliciesWithoutNotify(sec string, ptype string, oldRule
 	if !ruleUpdated || err != nil {
 		return ruleUpdated, err
 	}
+	defer e.publishChange(persist.OperationUpdate, sec, ptype, newRules, oldRules)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, oldRules) // remove the old rules
@@ -218,27 +323,91 @@ func (e *Enforcer) removePoliciesWithoutNotify(sec string, ptype string, rules [
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.(persist.BatchAdapter).RemovePolicies(sec, ptype, rules); err != nil {
-			if err.Error() != notImplemented {
-				return false, err
-			}
+		op := persist.PolicyOperation{Type: persist.OperationRemove, Section: sec, PolicyType: ptype, Rules: rules}
+		if err := e.persistChange(op, func() error { return e.adapter.(persist.BatchAdapter).RemovePolicies(sec, ptype, rules) }); err != nil {
+			return false, err
 		}
 	}
 
-	rulesRemoved, err := e.model.RemovePolicies(sec, ptype, rules)
+	affected, err := e.model.RemovePoliciesWithAffected(sec, ptype, rules)
+	rulesRemoved := len(affected) != 0
 	if !rulesRemoved || err != nil {
 		return rulesRemoved, err
 	}
+	defer e.publishChange(persist.OperationRemove, sec, ptype, affected, nil)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, rules)
 		if err != nil {
 			return rulesRemoved, err
 		}
+
+		err = e.BuildIncrementalConditionalRoleLinks(model.PolicyRemove, ptype, rules)
+		if err != nil {
+			return rulesRemoved, err
+		}
 	}
 	return rulesRemoved, nil
 }
 
+// applyPolicyOperation persists and applies an operation to the current policy, without building role links.
+func (e *Enforcer) applyPolicyOperation(op persist.PolicyOperation) error {
+	sec, ptype := op.Section, op.PolicyType
+	if e.dispatcher != nil && e.autoNotifyDispatcher {
+		switch op.Type {
+		case persist.OperationAdd:
+			return e.dispatcher.AddPolicies(sec, ptype, op.Rules)
+		case persist.OperationRemove:
+			return e.dispatcher.RemovePolicies(sec, ptype, op.Rules)
+		default:
+			return e.dispatcher.UpdatePolicies(sec, ptype, op.OldRules, op.Rules)
+		}
+	}
+
+	if e.shouldPersist() {
+		if err := e.persistChange(op, func() error {
+			switch op.Type {
+			case persist.OperationAdd:
+				return e.adapter.(persist.BatchAdapter).AddPolicies(sec, ptype, op.Rules)
+			case persist.OperationRemove:
+				return e.adapter.(persist.BatchAdapter).RemovePolicies(sec, ptype, op.Rules)
+			default:
+				return e.adapter.(persist.UpdatableAdapter).UpdatePolicies(sec, ptype, op.OldRules, op.Rules)
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch op.Type {
+	case persist.OperationAdd:
+		_, err = e.model.AddPoliciesWithAffected(sec, ptype, op.Rules)
+	case persist.OperationRemove:
+		_, err = e.model.RemovePoliciesWithAffected(sec, ptype, op.Rules)
+	default:
+		_, err = e.model.UpdatePolicies(sec, ptype, op.OldRules, op.Rules)
+	}
+	if err != nil {
+		return err
+	}
+	e.publishChange(op.Type, sec, ptype, op.Rules, op.OldRules)
+	return nil
+}
+
+// invertPolicyOperation returns the operation reverting op.
+func invertPolicyOperation(op persist.PolicyOperation) persist.PolicyOperation {
+	switch op.Type {
+	case persist.OperationAdd:
+		op.Type = persist.OperationRemove
+	case persist.OperationRemove:
+		op.Type = persist.OperationAdd
+	default:
+		op.Rules, op.OldRules = op.OldRules, op.Rules
+	}
+	return op
+}
+
 // removeFilteredPolicy removes rules based on field filters from the current policy.
 func (e *Enforcer) removeFilteredPolicyWithoutNotify(sec string, ptype string, fieldIndex int, fieldValues []string) (bool, error) {
 	if len(fieldValues) == 0 {
@@ -250,10 +419,17 @@ func (e *Enforcer) removeFilteredPolicyWithoutNotify(sec string, ptype string, f
 	}
 
 	if e.shouldPersist() {
-		if err := e.adapter.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...); err != nil {
-			if err.Error() != notImplemented {
+		op := persist.PolicyOperation{Type: persist.OperationRemove, Section: sec, PolicyType: ptype}
+		if _, ok := asIncrementalAdapter(e.adapter); ok {
+			// An IncrementalAdapter removes the rules matching the filter one by one.
+			rules, err := e.model.GetFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+			if err != nil {
 				return false, err
 			}
+			op.Rules = rules
+		}
+		if err := e.persistChange(op, func() error { return e.adapter.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...) }); err != nil {
+			return false, err
 		}
 	}
 
@@ -261,6 +437,7 @@ func (e *Enforcer) removeFilteredPolicyWithoutNotify(sec string, ptype string, f
 	if !ruleRemoved || err != nil {
 		return ruleRemoved, err
 	}
+	defer e.publishChange(persist.OperationRemove, sec, ptype, effects, nil)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, effects)
@@ -272,6 +449,52 @@ func (e *Enforcer) removeFilteredPolicyWithoutNotify(sec string, ptype string, f
 	return ruleRemoved, nil
 }
 
+// removeFilteredPolicyMatch removes the rules whose fields match the glob patterns in fieldValues.
+// The matched rules are removed as a batch, so the adapter receives explicit rules rather than patterns.
+func (e *Enforcer) removeFilteredPolicyMatch(sec string, ptype string, fieldIndex int, fieldValues []string) (bool, error) {
+	if len(fieldValues) == 0 {
+		return false, Err.ErrInvalidFieldValuesParameter
+	}
+	if fieldIndex < 0 {
+		return false, Err.ErrInvalidFieldIndex
+	}
+
+	policy, err := e.model.GetPolicy(sec, ptype)
+	if err != nil {
+		return false, err
+	}
+
+	var rules [][]string
+	for _, rule := range policy {
+		matched := true
+		for i, pattern := range fieldValues {
+			if pattern == "" {
+				continue
+			}
+			if fieldIndex+i >= len(rule) {
+				matched = false
+				break
+			}
+			ok, err := util.GlobMatch(rule[fieldIndex+i], pattern)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == 0 {
+		return false, nil
+	}
+	return e.removePolicies(sec, ptype, rules)
+}
+
 func (e *Enforcer) updateFilteredPoliciesWithoutNotify(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
 	var (
 		oldRules [][]string
@@ -282,7 +505,11 @@ func (e *Enforcer) updateFilteredPoliciesWithoutNotify(sec string, ptype string,
 		return oldRules, err
 	}
 
-	if e.shouldPersist() {
+	if a, ok := asIncrementalAdapter(e.adapter); ok && e.shouldPersist() {
+		if oldRules, err = e.persistFilteredUpdate(a, sec, ptype, newRules, fieldIndex, fieldValues...); err != nil {
+			return nil, err
+		}
+	} else if e.shouldPersist() {
 		if oldRules, err = e.adapter.(persist.UpdatableAdapter).UpdateFilteredPolicies(sec, ptype, newRules, fieldIndex, fieldValues...); err != nil {
 			if err.Error() != notImplemented {
 				return nil, err
@@ -312,6 +539,7 @@ func (e *Enforcer) updateFilteredPoliciesWithoutNotify(sec string, ptype string,
 	if !ruleChanged {
 		return make([][]string, 0), nil
 	}
+	defer e.publishChange(persist.OperationUpdate, sec, ptype, newRules, oldRules)
 
 	if sec == "g" {
 		err := e.BuildIncrementalRoleLinks(model.PolicyRemove, ptype, oldRules) // remove the old rules
@@ -327,6 +555,24 @@ func (e *Enforcer) updateFilteredPoliciesWithoutNotify(sec string, ptype string,
 	return oldRules, nil
 }
 
+// persistFilteredUpdate writes with auto-save the replacement of the rules matching the filter by newRules
+// to a persist.IncrementalAdapter, as the removal of the former followed by the addition of the latter.
+// It returns the replaced rules.
+func (e *Enforcer) persistFilteredUpdate(a persist.IncrementalAdapter, sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	oldRules, err := e.model.GetFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return nil, err
+	}
+	err = applyIncrementalOperation(a, persist.PolicyOperation{Type: persist.OperationRemove, Section: sec, PolicyType: ptype, Rules: oldRules})
+	if err == nil {
+		err = applyIncrementalOperation(a, persist.PolicyOperation{Type: persist.OperationAdd, Section: sec, PolicyType: ptype, Rules: newRules})
+	}
+	if err != nil && err.Error() != notImplemented {
+		return nil, err
+	}
+	return oldRules, nil
+}
+
 // addPolicy adds a rule to the current policy.
 func (e *Enforcer) addPolicy(sec string, ptype string, rule []string) (bool, error) {
 	ok, err := e.addPolicyWithoutNotify(sec, ptype, rule)
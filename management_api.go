@@ -17,9 +17,11 @@ package casbin
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/persist"
 	"github.com/casbin/casbin/v2/util"
 	"github.com/casbin/govaluate"
 )
@@ -86,6 +88,18 @@ func (e *Enforcer) GetFilteredPolicy(fieldIndex int, fieldValues ...string) ([][
 	return e.GetFilteredNamedPolicy("p", fieldIndex, fieldValues...)
 }
 
+// GetFilteredPolicyLimit gets the first limit rules of a policy matching the field filters, e.g. a sample of
+// the rules of a subject, without scanning the rest of the policy. A limit of 0 means no limit.
+func (e *Enforcer) GetFilteredPolicyLimit(sec string, ptype string, limit int, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return e.model.GetFilteredPolicyLimit(sec, ptype, limit, fieldIndex, fieldValues...)
+}
+
+// GetFilteredPolicyExcept gets the rules of a policy that do not match the field filters,
+// e.g. every rule that is not about the "read" action.
+func (e *Enforcer) GetFilteredPolicyExcept(sec string, ptype string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return e.model.GetFilteredPolicyExcept(sec, ptype, fieldIndex, fieldValues...)
+}
+
 // GetNamedPolicy gets all the authorization rules in the named policy.
 func (e *Enforcer) GetNamedPolicy(ptype string) ([][]string, error) {
 	return e.model.GetPolicy("p", ptype)
@@ -121,6 +135,10 @@ func (e *Enforcer) GetFilteredNamedPolicyWithMatcher(ptype string, matcher strin
 	var res [][]string
 	var err error
 
+	if _, err = e.model.GetAssertion("p", ptype); err != nil {
+		return res, err
+	}
+
 	functions := e.fm.GetFunctions()
 	if _, ok := e.model["g"]; ok {
 		for key, ast := range e.model["g"] {
@@ -128,7 +146,7 @@ func (e *Enforcer) GetFilteredNamedPolicyWithMatcher(ptype string, matcher strin
 			//   or a conditional role definition (ast.CondRM != nil)
 			// ast.RM and ast.CondRM shouldn't be nil at the same time
 			if ast.RM != nil {
-				functions[key] = util.GenerateGFunction(ast.RM)
+				functions[key] = generateGFunction(ast)
 			}
 			if ast.CondRM != nil {
 				functions[key] = util.GenerateConditionalGFunction(ast.CondRM)
@@ -194,6 +212,137 @@ func (e *Enforcer) GetFilteredNamedPolicyWithMatcher(ptype string, matcher strin
 	return res, nil
 }
 
+// FindRedundantPolicies gets the authorization rules that are already implied by another rule with the same effect.
+// Each rule is evaluated as a request against the other rules using the model's matcher, so shadowing through
+// functions like keyMatch or regexMatch is detected. As the values of the rule used as a request are taken
+// literally, the results are candidates to be reviewed before removal. The policy is not modified.
+// For example:
+// p, alice, /users/*, read
+// p, alice, /users/alice, read
+//
+// FindRedundantPolicies() will get: [["alice", "/users/alice", "read"]] for a keyMatch model.
+func (e *Enforcer) FindRedundantPolicies() ([][]string, error) {
+	var res [][]string
+
+	functions := e.fm.GetFunctions()
+	if _, ok := e.model["g"]; ok {
+		for key, ast := range e.model["g"] {
+			if ast.RM != nil {
+				functions[key] = generateGFunction(ast)
+			}
+			if ast.CondRM != nil {
+				functions[key] = util.GenerateConditionalGFunction(ast.CondRM)
+			}
+		}
+	}
+
+	rTokens := make(map[string]int, len(e.model["r"]["r"].Tokens))
+	for i, token := range e.model["r"]["r"].Tokens {
+		rTokens[token] = i
+	}
+	pTokens := make(map[string]int, len(e.model["p"]["p"].Tokens))
+	for i, token := range e.model["p"]["p"].Tokens {
+		pTokens[token] = i
+	}
+
+	// A rule is used as a request by taking the policy fields named like the request tokens.
+	requestFields := make([]int, len(e.model["r"]["r"].Tokens))
+	for i, token := range e.model["r"]["r"].Tokens {
+		index, ok := pTokens["p_"+strings.TrimPrefix(token, "r_")]
+		if !ok {
+			return res, fmt.Errorf("request token %s has no matching policy token", token)
+		}
+		requestFields[i] = index
+	}
+
+	parameters := enforceParameters{
+		rTokens: rTokens,
+		pTokens: pTokens,
+	}
+
+	expString := e.model["m"]["m"].Value
+	if util.HasEval(expString) {
+		functions["eval"] = generateEvalFunction(functions, &parameters)
+	}
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(expString, functions)
+	if err != nil {
+		return res, err
+	}
+
+	policy := e.model["p"]["p"].Policy
+	for _, pvals := range policy {
+		if len(pTokens) != len(pvals) {
+			return res, fmt.Errorf(
+				"invalid policy size: expected %d, got %d, pvals: %v",
+				len(pTokens),
+				len(pvals),
+				pvals)
+		}
+	}
+
+	// implies reports whether the rule at index j matches the rule at index i used as a request.
+	implies := func(i int, j int) (bool, error) {
+		rvals := make([]interface{}, len(requestFields))
+		for k, index := range requestFields {
+			rvals[k] = policy[i][index]
+		}
+		parameters.rVals = rvals
+		parameters.pVals = policy[j]
+
+		result, err := expression.Eval(parameters)
+		if err != nil {
+			return false, err
+		}
+		switch result := result.(type) {
+		case bool:
+			return result, nil
+		case float64:
+			return result != 0, nil
+		default:
+			return false, errors.New("matcher result should be bool, int or float")
+		}
+	}
+
+	effect := func(pvals []string) string {
+		if index, ok := pTokens["p_eft"]; ok {
+			return pvals[index]
+		}
+		return "allow"
+	}
+
+	for i := range policy {
+		for j := range policy {
+			if i == j || effect(policy[i]) != effect(policy[j]) {
+				continue
+			}
+
+			ok, err := implies(i, j)
+			if err != nil {
+				return res, err
+			}
+			if !ok {
+				continue
+			}
+
+			// Equivalent rules imply each other, only the later one is reported.
+			if j > i {
+				ok, err = implies(j, i)
+				if err != nil {
+					return res, err
+				}
+				if ok {
+					continue
+				}
+			}
+
+			res = append(res, policy[i])
+			break
+		}
+	}
+
+	return res, nil
+}
+
 // HasPolicy determines whether an authorization rule exists.
 func (e *Enforcer) HasPolicy(params ...interface{}) (bool, error) {
 	return e.HasNamedPolicy("p", params...)
@@ -328,6 +477,20 @@ func (e *Enforcer) RemoveFilteredNamedPolicy(ptype string, fieldIndex int, field
 	return e.removeFilteredPolicy("p", ptype, fieldIndex, fieldValues)
 }
 
+// RemoveFilteredPolicyMatch removes authorization rules whose fields match glob patterns, field filters can be specified.
+// An empty pattern matches any value. Patterns follow globMatch: "*" matches any sequence within a path segment,
+// "**" also crosses "/", e.g. "/tmp/**" matches every object under "/tmp/". A literal "*" is escaped as "\\*",
+// so "\\*" only matches the subject "*".
+func (e *Enforcer) RemoveFilteredPolicyMatch(fieldIndex int, fieldValues ...string) (bool, error) {
+	return e.RemoveFilteredNamedPolicyMatch("p", fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredNamedPolicyMatch removes authorization rules whose fields match glob patterns from the current named policy,
+// see RemoveFilteredPolicyMatch for the pattern syntax.
+func (e *Enforcer) RemoveFilteredNamedPolicyMatch(ptype string, fieldIndex int, fieldValues ...string) (bool, error) {
+	return e.removeFilteredPolicyMatch("p", ptype, fieldIndex, fieldValues)
+}
+
 // HasGroupingPolicy determines whether a role inheritance rule exists.
 func (e *Enforcer) HasGroupingPolicy(params ...interface{}) (bool, error) {
 	return e.HasNamedGroupingPolicy("g", params...)
@@ -440,6 +603,72 @@ func (e *Enforcer) RemoveNamedGroupingPolicies(ptype string, rules [][]string) (
 	return e.removePolicies("g", ptype, rules)
 }
 
+// RemoveGroupingPoliciesWithCascade removes role inheritance rules from the current policy,
+// together with the rules of the roles that are left without any member, up the role hierarchy.
+// For example:
+// g, alice, team_lead
+// g, team_lead, manager
+//
+// RemoveGroupingPoliciesWithCascade([][]string{{"alice", "team_lead"}}) will remove both rules.
+func (e *Enforcer) RemoveGroupingPoliciesWithCascade(rules [][]string) (bool, error) {
+	return e.RemoveNamedGroupingPoliciesWithCascade("g", rules)
+}
+
+// RemoveNamedGroupingPoliciesWithCascade removes role inheritance rules from the current named policy,
+// together with the rules of the roles that are left without any member, up the role hierarchy.
+// Membership is considered per domain. All the rules are removed at once, so role links are rebuilt once.
+func (e *Enforcer) RemoveNamedGroupingPoliciesWithCascade(ptype string, rules [][]string) (bool, error) {
+	policy, err := e.model.GetPolicy("g", ptype)
+	if err != nil {
+		return false, err
+	}
+
+	var res [][]string
+	removed := make(map[string]bool)
+	remove := func(rule []string) {
+		removed[e.model.PolicyKey("g", ptype, rule)] = true
+		res = append(res, rule)
+	}
+
+	for _, rule := range rules {
+		hasPolicy, err := e.model.HasPolicy("g", ptype, rule)
+		if err != nil {
+			return false, err
+		}
+		if hasPolicy && !removed[e.model.PolicyKey("g", ptype, rule)] && len(rule) >= 2 {
+			remove(rule)
+		}
+	}
+
+	// The role of each removed rule may have lost its last member, in which case the rules
+	// granting it parent roles in the same domain are removed as well.
+	for i := 0; i < len(res); i++ {
+		role, domain := res[i][1], res[i][2:]
+
+		orphaned := true
+		for _, rule := range policy {
+			if !removed[e.model.PolicyKey("g", ptype, rule)] && rule[1] == role && util.ArrayEquals(rule[2:], domain) {
+				orphaned = false
+				break
+			}
+		}
+		if !orphaned {
+			continue
+		}
+
+		for _, rule := range policy {
+			if !removed[e.model.PolicyKey("g", ptype, rule)] && rule[0] == role && util.ArrayEquals(rule[2:], domain) {
+				remove(rule)
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return false, nil
+	}
+	return e.removePolicies("g", ptype, res)
+}
+
 func (e *Enforcer) UpdateGroupingPolicy(oldRule []string, newRule []string) (bool, error) {
 	return e.UpdateNamedGroupingPolicy("g", oldRule, newRule)
 }
@@ -462,9 +691,34 @@ func (e *Enforcer) RemoveFilteredNamedGroupingPolicy(ptype string, fieldIndex in
 	return e.removeFilteredPolicy("g", ptype, fieldIndex, fieldValues)
 }
 
+// RemoveFilteredGroupingPolicyMatch removes role inheritance rules whose fields match glob patterns,
+// see RemoveFilteredPolicyMatch for the pattern syntax.
+func (e *Enforcer) RemoveFilteredGroupingPolicyMatch(fieldIndex int, fieldValues ...string) (bool, error) {
+	return e.RemoveFilteredNamedGroupingPolicyMatch("g", fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredNamedGroupingPolicyMatch removes role inheritance rules whose fields match glob patterns from the current named policy,
+// see RemoveFilteredPolicyMatch for the pattern syntax.
+func (e *Enforcer) RemoveFilteredNamedGroupingPolicyMatch(ptype string, fieldIndex int, fieldValues ...string) (bool, error) {
+	return e.removeFilteredPolicyMatch("g", ptype, fieldIndex, fieldValues)
+}
+
 // AddFunction adds a customized function.
+// The compiled matchers are dropped, they keep the functions they were compiled with.
 func (e *Enforcer) AddFunction(name string, function govaluate.ExpressionFunction) {
 	e.fm.AddFunction(name, function)
+	e.invalidateMatcherMap()
+}
+
+// RemoveFunction removes a customized or built-in function.
+// It fails, keeping the function, if a matcher of the model still calls it.
+func (e *Enforcer) RemoveFunction(name string) error {
+	if key := e.matcherCalling(name); key != "" {
+		return fmt.Errorf("matcher %s calls function %s, which cannot be removed", key, name)
+	}
+	e.fm.RemoveFunction(name)
+	e.invalidateMatcherMap()
+	return nil
 }
 
 func (e *Enforcer) SelfAddPolicy(sec string, ptype string, rule []string) (bool, error) {
@@ -498,3 +752,198 @@ func (e *Enforcer) SelfUpdatePolicy(sec string, ptype string, oldRule, newRule [
 func (e *Enforcer) SelfUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) (bool, error) {
 	return e.updatePoliciesWithoutNotify(sec, ptype, oldRules, newRules)
 }
+
+// policyMerge is a change of ImportPolicyMerge: the rule newRule is added if oldRule is nil, or replaces oldRule.
+type policyMerge struct {
+	oldRule []string
+	newRule []string
+}
+
+// ImportPolicyMerge merges the rules of several policy types into the current policy, e.g. rules imported
+// from another environment as {"p": ..., "g": ...}. A rule identical to an existing one is skipped.
+// A rule conflicting with an existing one, i.e. considered the same by the policy key (see model.SetPolicyKeyFunc)
+// or only differing by its priority, is passed to onConflict, and the existing rule is replaced with the returned rule,
+// or kept if it returns nil. A nil onConflict keeps all the existing rules. The other rules are added.
+// Only policy and grouping policy types can be merged. All the rules are validated before the policy is changed,
+// and role links are rebuilt once at the end.
+func (e *Enforcer) ImportPolicyMerge(rules map[string][][]string, onConflict func(existing, incoming []string) []string) error {
+	if onConflict == nil {
+		onConflict = func(existing, incoming []string) []string { return nil }
+	}
+	ptypes := make([]string, 0, len(rules))
+	for ptype, ptypeRules := range rules {
+		if err := e.validateMergedRules(ptype, ptypeRules); err != nil {
+			return err
+		}
+		ptypes = append(ptypes, ptype)
+	}
+	sort.Strings(ptypes)
+
+	merges := make(map[string][]policyMerge, len(ptypes))
+	for _, ptype := range ptypes {
+		ptypeMerges, err := e.mergePolicies(ptype, rules[ptype], onConflict)
+		if err != nil {
+			return err
+		}
+		merges[ptype] = ptypeMerges
+	}
+
+	rolesChanged := false
+	for _, ptype := range ptypes {
+		if err := e.applyPolicyMerges(ptype[:1], ptype, merges[ptype]); err != nil {
+			return err
+		}
+		rolesChanged = rolesChanged || (ptype[:1] == "g" && len(merges[ptype]) != 0)
+	}
+
+	if rolesChanged && e.autoBuildRoleLinks {
+		if err := e.BuildRoleLinks(); err != nil {
+			return err
+		}
+	}
+	if e.shouldNotify() {
+		return e.notifySavePolicy()
+	}
+	return nil
+}
+
+// validateMergedRules checks that ptype is a policy or grouping policy type and the size of rules merged into it.
+func (e *Enforcer) validateMergedRules(ptype string, rules [][]string) error {
+	if ptype == "" {
+		return errors.New("missing policy type")
+	}
+	sec := ptype[:1]
+	if sec != "p" && sec != "g" {
+		return fmt.Errorf("%s is not a policy or grouping policy type", ptype)
+	}
+	assertion, err := e.model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if len(rule) != len(assertion.Tokens) && (sec == "p" || len(rule) < len(assertion.Tokens)) {
+			return fmt.Errorf("invalid policy rule size: expected %d, got %d, rule: %v", len(assertion.Tokens), len(rule), rule)
+		}
+	}
+	return e.validateRules(sec, ptype, rules)
+}
+
+// mergePolicies computes the changes merging rules into ptype, resolving conflicts with onConflict.
+func (e *Enforcer) mergePolicies(ptype string, rules [][]string, onConflict func(existing, incoming []string) []string) ([]policyMerge, error) {
+	sec := ptype[:1]
+	mergeKey, hasPriority := e.model.PolicyConflictKey(sec, ptype, constant.PriorityIndex)
+
+	// Existing rules only differing by their priority.
+	existing := map[string][]string{}
+	if hasPriority {
+		for _, rule := range e.model[sec][ptype].Policy {
+			existing[mergeKey(rule)] = rule
+		}
+	}
+
+	var merges []policyMerge
+	merged := map[string]int{}
+	for _, rule := range rules {
+		key := mergeKey(rule)
+		if i, ok := merged[key]; ok {
+			// Conflict with a rule merged before.
+			if util.ArrayEquals(merges[i].newRule, rule) {
+				continue
+			}
+			if resolved := onConflict(merges[i].newRule, rule); resolved != nil {
+				if err := e.validateMergedRules(ptype, [][]string{resolved}); err != nil {
+					return nil, err
+				}
+				merges[i].newRule = resolved
+			}
+			continue
+		}
+
+		stored, ok, err := e.model.GetStoredPolicy(sec, ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			stored, ok = existing[key]
+		}
+		if !ok {
+			merges = append(merges, policyMerge{newRule: rule})
+			merged[key] = len(merges) - 1
+			continue
+		}
+		if util.ArrayEquals(stored, rule) {
+			continue
+		}
+		resolved := onConflict(stored, rule)
+		if resolved == nil || util.ArrayEquals(stored, resolved) {
+			continue
+		}
+		if err = e.validateMergedRules(ptype, [][]string{resolved}); err != nil {
+			return nil, err
+		}
+		merges = append(merges, policyMerge{oldRule: stored, newRule: resolved})
+		merged[key] = len(merges) - 1
+	}
+	return merges, nil
+}
+
+// applyPolicyMerges persists and applies the changes of ImportPolicyMerge, without building role links.
+func (e *Enforcer) applyPolicyMerges(sec string, ptype string, merges []policyMerge) error {
+	var addedRules, oldRules, newRules [][]string
+	for _, merge := range merges {
+		if merge.oldRule == nil {
+			addedRules = append(addedRules, merge.newRule)
+		} else {
+			oldRules = append(oldRules, merge.oldRule)
+			newRules = append(newRules, merge.newRule)
+		}
+	}
+
+	if e.dispatcher != nil && e.autoNotifyDispatcher {
+		if len(addedRules) != 0 {
+			if err := e.dispatcher.AddPolicies(sec, ptype, addedRules); err != nil {
+				return err
+			}
+		}
+		if len(oldRules) != 0 {
+			return e.dispatcher.UpdatePolicies(sec, ptype, oldRules, newRules)
+		}
+		return nil
+	}
+
+	if e.shouldPersist() {
+		if len(addedRules) != 0 {
+			op := persist.PolicyOperation{Type: persist.OperationAdd, Section: sec, PolicyType: ptype, Rules: addedRules}
+			if err := e.persistChange(op, func() error {
+				return e.adapter.(persist.BatchAdapter).AddPolicies(sec, ptype, addedRules)
+			}); err != nil {
+				return err
+			}
+		}
+		if len(oldRules) != 0 {
+			op := persist.PolicyOperation{Type: persist.OperationUpdate, Section: sec, PolicyType: ptype, Rules: newRules, OldRules: oldRules}
+			if err := e.persistChange(op, func() error {
+				return e.adapter.(persist.UpdatableAdapter).UpdatePolicies(sec, ptype, oldRules, newRules)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	affected, err := e.model.AddPoliciesWithAffected(sec, ptype, addedRules)
+	e.publishChange(persist.OperationAdd, sec, ptype, affected, nil)
+	if err != nil {
+		return err
+	}
+	if len(oldRules) != 0 {
+		if _, err = e.model.UpdatePolicies(sec, ptype, oldRules, newRules); err != nil {
+			return err
+		}
+		e.publishChange(persist.OperationUpdate, sec, ptype, newRules, oldRules)
+		if sec == "p" {
+			// Replacements may change the priority of rules.
+			return e.model.SortPoliciesByPriority()
+		}
+	}
+	return nil
+}
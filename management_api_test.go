@@ -15,8 +15,11 @@
 package casbin
 
 import (
+	"errors"
 	"testing"
 
+	Err "github.com/casbin/casbin/v2/errors"
+	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -173,6 +176,16 @@ func TestGetPolicyAPI(t *testing.T) {
 		{"bob", "data2", "write"},
 		{"data2_admin", "data2", "read"},
 		{"data2_admin", "data2", "write"}})
+	testGetFilteredNamedPolicyWithMatcher(t, e, "p", "regexMatch(p.obj, '^data[12]$') && p.act == 'write'", [][]string{
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "write"}})
+
+	if _, err := e.GetFilteredNamedPolicyWithMatcher("p", "p.sub == ("); err == nil {
+		t.Error("invalid matcher should fail")
+	}
+	if _, err := e.GetFilteredNamedPolicyWithMatcher("p2", "p2.sub == 'alice'"); err == nil {
+		t.Error("unknown policy type should fail")
+	}
 
 	testGetFilteredPolicy(t, e, 0, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}, "data2_admin", "data2")
 	// Note: "" (empty string) in fieldValues means matching all values.
@@ -197,6 +210,35 @@ func TestGetPolicyAPI(t *testing.T) {
 	testHasGroupingPolicy(t, e, []string{"bob", "data2_admin"}, false)
 }
 
+func TestFindRedundantPolicies(t *testing.T) {
+	e, _ := NewEnforcer("examples/keymatch_model.conf")
+	_, _ = e.AddPolicies([][]string{
+		{"alice", "/users/alice", "GET"},
+		{"alice", "/users/*", "GET"},
+		{"alice", "/orders/1", "(GET)|(POST)"},
+		{"bob", "/users/bob", "GET"},
+		{"alice", "/users/*", "(GET)"},
+	})
+
+	res, err := e.FindRedundantPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"alice", "/users/alice", "GET"}, {"alice", "/users/*", "(GET)"}}
+	if !util.Array2DEquals(expected, res) {
+		t.Error("Redundant policies: ", res, ", supposed to be ", expected)
+	}
+
+	e, _ = NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	res, err = e.FindRedundantPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 0 {
+		t.Error("Redundant policies: ", res, ", supposed to be none")
+	}
+}
+
 func TestModifyPolicyAPI(t *testing.T) {
 	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
 
@@ -360,3 +402,237 @@ func TestModifyGroupingPolicyAPI(t *testing.T) {
 	_, _ = e.AddNamedGroupingPoliciesEx("g", [][]string{{"user1", "member"}, {"user2", "member"}, {"user3", "member"}})
 	testGetUsers(t, e, []string{"user1", "user2", "user3"}, "member")
 }
+
+func TestRemoveGroupingPoliciesWithCascade(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf")
+	_, _ = e.AddGroupingPolicies([][]string{
+		{"alice", "team_lead"},
+		{"team_lead", "manager"},
+		{"manager", "director"},
+		{"bob", "team_lead2"},
+		{"team_lead2", "manager"},
+		{"team_lead", "staff"},
+	})
+
+	ok, err := e.RemoveGroupingPoliciesWithCascade([][]string{{"alice", "team_lead"}})
+	if err != nil || !ok {
+		t.Fatalf("RemoveGroupingPoliciesWithCascade: %t, %v", ok, err)
+	}
+	// manager keeps team_lead2 as a member, so its own rule is kept.
+	testGetGroupingPolicy(t, e, [][]string{{"manager", "director"}, {"bob", "team_lead2"}, {"team_lead2", "manager"}})
+	testGetRoles(t, e, []string{}, "alice")
+
+	ok, _ = e.RemoveGroupingPoliciesWithCascade([][]string{{"alice", "team_lead"}})
+	if ok {
+		t.Error("removing a missing rule should not be affected")
+	}
+
+	// Roles in a cycle are members of each other, so they are not pruned.
+	_, _ = e.AddGroupingPolicy("director", "team_lead2")
+	_, _ = e.RemoveGroupingPoliciesWithCascade([][]string{{"bob", "team_lead2"}})
+	testGetGroupingPolicy(t, e, [][]string{{"manager", "director"}, {"team_lead2", "manager"}, {"director", "team_lead2"}})
+}
+
+func TestValidateOnAdd(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, note
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+
+	// Without validation the rule is added although p.note has no effect.
+	if ok, err := e.AddPolicy("alice", "data1", "read", "owner"); !ok || err != nil {
+		t.Errorf("AddPolicy: %t, %v", ok, err)
+	}
+
+	e.SetValidateOnAdd(true)
+	if ok, err := e.AddPolicy("bob", "data1", "read", "owner"); ok || !errors.Is(err, Err.ErrUnusedPolicyField) {
+		t.Errorf("AddPolicy: %t, %v, supposed to fail with %v", ok, err, Err.ErrUnusedPolicyField)
+	}
+	if ok, err := e.AddPolicies([][]string{{"bob", "data2", "read", ""}, {"bob", "data3", "read", "owner"}}); ok || !errors.Is(err, Err.ErrUnusedPolicyField) {
+		t.Errorf("AddPolicies: %t, %v, supposed to fail with %v", ok, err, Err.ErrUnusedPolicyField)
+	}
+	if ok, err := e.AddPolicy("bob", "data1", "read", ""); !ok || err != nil {
+		t.Errorf("AddPolicy: %t, %v", ok, err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read", "owner"}, {"bob", "data1", "read", ""}})
+}
+
+func TestRemoveFilteredPolicyMatch(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf")
+	_, _ = e.AddPolicies([][]string{
+		{"alice", "/tmp/a", "read"},
+		{"alice", "/tmp/a/b", "write"},
+		{"bob", "/tmp/b", "read"},
+		{"bob", "/home/b", "read"},
+		{"*", "/tmp/c", "read"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{{"alice", "role:admin"}, {"bob", "role:user"}, {"bob", "admin"}})
+
+	ok, err := e.RemoveFilteredPolicyMatch(0, "\\*")
+	if !ok || err != nil {
+		t.Errorf("RemoveFilteredPolicyMatch: %t, %v", ok, err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "/tmp/a", "read"}, {"alice", "/tmp/a/b", "write"}, {"bob", "/tmp/b", "read"}, {"bob", "/home/b", "read"}})
+
+	_, _ = e.RemoveFilteredPolicyMatch(1, "/tmp/*", "read")
+	testGetPolicy(t, e, [][]string{{"alice", "/tmp/a/b", "write"}, {"bob", "/home/b", "read"}})
+
+	_, _ = e.RemoveFilteredPolicyMatch(0, "", "/tmp/**")
+	testGetPolicy(t, e, [][]string{{"bob", "/home/b", "read"}})
+
+	ok, _ = e.RemoveFilteredPolicyMatch(1, "/tmp/**")
+	if ok {
+		t.Error("removing without a match should not be affected")
+	}
+	if _, err = e.RemoveFilteredPolicyMatch(-1, "bob"); !errors.Is(err, Err.ErrInvalidFieldIndex) {
+		t.Errorf("RemoveFilteredPolicyMatch with a negative field index: %v, supposed to be %v", err, Err.ErrInvalidFieldIndex)
+	}
+
+	_, _ = e.RemoveFilteredGroupingPolicyMatch(1, "role:*")
+	testGetGroupingPolicy(t, e, [][]string{{"bob", "admin"}})
+	testGetRoles(t, e, []string{}, "alice")
+}
+
+func TestImportPolicyMerge(t *testing.T) {
+	e, _ := NewEnforcer("examples/priority_model_explicit.conf", "examples/priority_policy_explicit.csv")
+
+	var conflicts [][]string
+	keepIncoming := func(existing, incoming []string) []string {
+		conflicts = append(conflicts, existing, incoming)
+		return incoming
+	}
+
+	err := e.ImportPolicyMerge(map[string][][]string{
+		"p": {
+			{"1", "alice", "data1", "write", "allow"},
+			{"5", "bob", "data2", "read", "deny"},
+			{"1", "carol", "data1", "read", "allow"},
+		},
+		"g": {{"carol", "data2_allow_group"}},
+	}, keepIncoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the rule differing by its priority conflicts, identical rules are skipped.
+	if !util.Array2DEquals(conflicts, [][]string{{"1", "bob", "data2", "read", "deny"}, {"5", "bob", "data2", "read", "deny"}}) {
+		t.Errorf("conflicts: %v", conflicts)
+	}
+	policy, _ := e.GetPolicy()
+	if !util.Array2DEquals(policy, [][]string{
+		{"1", "alice", "data1", "write", "allow"},
+		{"1", "alice", "data1", "read", "allow"},
+		{"1", "carol", "data1", "read", "allow"},
+		{"5", "bob", "data2", "read", "deny"},
+		{"10", "data1_deny_group", "data1", "read", "deny"},
+		{"10", "data1_deny_group", "data1", "write", "deny"},
+		{"10", "data2_allow_group", "data2", "read", "allow"},
+		{"10", "data2_allow_group", "data2", "write", "allow"},
+	}) {
+		t.Errorf("policy: %v", policy)
+	}
+	testEnforce(t, e, "carol", "data1", "read", true)
+	testEnforce(t, e, "carol", "data2", "write", true)
+	testEnforce(t, e, "bob", "data2", "read", false)
+
+	// Returning nil, or a nil onConflict, keeps the existing rule.
+	err = e.ImportPolicyMerge(map[string][][]string{"p": {{"20", "bob", "data2", "read", "deny"}}},
+		func(existing, incoming []string) []string { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.ImportPolicyMerge(map[string][][]string{"p": {{"30", "bob", "data2", "read", "deny"}}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.HasPolicy("5", "bob", "data2", "read", "deny"); !ok {
+		t.Error("the existing rule should be kept")
+	}
+
+	// Invalid rules fail the whole import.
+	err = e.ImportPolicyMerge(map[string][][]string{
+		"p": {{"1", "dave", "data1", "read", "allow"}, {"1", "dave", "data1"}},
+	}, keepIncoming)
+	if err == nil {
+		t.Error("a rule with a wrong size should fail the import")
+	}
+	if ok, _ := e.HasPolicy("1", "dave", "data1", "read", "allow"); ok {
+		t.Error("no rule should be added by a failed import")
+	}
+	if err = e.ImportPolicyMerge(map[string][][]string{"p9": {{"a"}}}, keepIncoming); err == nil {
+		t.Error("an unknown ptype should fail the import")
+	}
+	for _, ptype := range []string{"", "r", "m"} {
+		if err = e.ImportPolicyMerge(map[string][][]string{ptype: {{"alice", "data1", "read"}}}, keepIncoming); err == nil {
+			t.Errorf("ptype %q should fail the import", ptype)
+		}
+	}
+	if len(e.GetModel()["r"]["r"].Policy) != 0 {
+		t.Errorf("request definition rules: %v, supposed to be empty", e.GetModel()["r"]["r"].Policy)
+	}
+}
+
+func TestGetFilteredPolicyLimit(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	testLimit := func(limit int, res [][]string, fieldIndex int, fieldValues ...string) {
+		t.Helper()
+		myRes, err := e.GetFilteredPolicyLimit("p", "p", limit, fieldIndex, fieldValues...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !util.Array2DEquals(res, myRes) {
+			t.Errorf("limit %d: %v, supposed to be %v", limit, myRes, res)
+		}
+	}
+	testLimit(1, [][]string{{"data2_admin", "data2", "read"}}, 0, "data2_admin")
+	testLimit(2, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}}, 1, "data2")
+	testLimit(0, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}, 1, "data2")
+	testLimit(10, [][]string{{"alice", "data1", "read"}}, 0, "alice")
+	testLimit(1, [][]string{}, 0, "carol")
+
+	if _, err := e.GetFilteredPolicyLimit("p", "p", -1, 0, "alice"); err == nil {
+		t.Error("negative limit should be an error")
+	}
+	if _, err := e.GetFilteredPolicyLimit("p", "p9", 1, 0, "alice"); err == nil {
+		t.Error("unknown policy type should be an error")
+	}
+}
+
+func TestGetFilteredPolicyExcept(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	testExcept := func(res [][]string, fieldIndex int, fieldValues ...string) {
+		t.Helper()
+		myRes, err := e.GetFilteredPolicyExcept("p", "p", fieldIndex, fieldValues...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !util.Array2DEquals(res, myRes) {
+			t.Errorf("except %v: %v, supposed to be %v", fieldValues, myRes, res)
+		}
+	}
+	testExcept([][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "write"}}, 2, "read")
+	testExcept([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}, 0, "data2_admin")
+	testExcept([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "write"}}, 1, "data2", "read")
+	testExcept([][]string{}, 1, "")
+
+	rules, _ := e.GetFilteredPolicyExcept("p", "p", 0, "bob")
+	rules[0][0] = "mallory"
+	if ok, _ := e.HasPolicy("alice", "data1", "read"); !ok {
+		t.Error("modifying the returned rules should not modify the policy")
+	}
+
+	if _, err := e.GetFilteredPolicyExcept("p", "p9", 0, "alice"); err == nil {
+		t.Error("unknown policy type should be an error")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// matcherStringRegex matches the string literals of a matcher.
+	matcherStringRegex = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+	// matcherCallRegex matches the function calls of a matcher, along with the character before the name.
+	matcherCallRegex = regexp.MustCompile(`(^|[^A-Za-z0-9_.])([A-Za-z_][A-Za-z0-9_]*)\s*\(`)
+)
+
+// ValidateMatchers checks that every function called by the matchers of the model is a built-in function,
+// a role definition like g, or a function registered by AddFunction or AddContextFunction. As custom functions are usually
+// registered after the enforcer is created, it should be called once they are, e.g. at startup,
+// to fail then rather than at the first Enforce.
+//
+// The construction of an enforcer and LoadModel only reject the calls whose name differs from a known function
+// by its case, see checkMatcherFunctions, as the functions registered by AddFunction are not known yet.
+// RemoveFunction refuses to remove a function that a matcher calls.
+func (e *Enforcer) ValidateMatchers() error {
+	return e.validateMatcherFunctions(false)
+}
+
+// checkMatcherFunctions checks that the matchers of the model do not call a function whose name
+// only differs from a known function by its case, e.g. keymatch instead of keyMatch,
+// as such a function is a typo rather than a custom function to be registered later.
+func (e *Enforcer) checkMatcherFunctions() error {
+	return e.validateMatcherFunctions(true)
+}
+
+func (e *Enforcer) validateMatcherFunctions(typosOnly bool) error {
+	known := make(map[string]struct{})
+	for name := range e.fm.GetFunctions() {
+		known[name] = struct{}{}
+	}
+	for name := range e.contextFunctions {
+		known[name] = struct{}{}
+	}
+	for name := range e.model["g"] {
+		known[name] = struct{}{}
+	}
+	known["eval"] = struct{}{}
+
+	keys := make([]string, 0, len(e.model["m"]))
+	for key := range e.model["m"] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, name := range matcherFunctionNames(e.model["m"][key].Value) {
+			if _, ok := known[name]; ok {
+				continue
+			}
+			if similar := similarFunctionName(name, known, !typosOnly); similar != "" {
+				return fmt.Errorf("matcher %s calls undefined function %s, did you mean %s?", key, name, similar)
+			}
+			if !typosOnly {
+				return fmt.Errorf("matcher %s calls undefined function %s", key, name)
+			}
+		}
+	}
+	return nil
+}
+
+// matcherCalling returns the key of the first matcher that calls the function name, or an empty string.
+func (e *Enforcer) matcherCalling(name string) string {
+	keys := make([]string, 0, len(e.model["m"]))
+	for key := range e.model["m"] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, called := range matcherFunctionNames(e.model["m"][key].Value) {
+			if called == name {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// matcherFunctionNames returns the names of the functions called by a matcher.
+func matcherFunctionNames(matcher string) []string {
+	matcher = matcherStringRegex.ReplaceAllString(matcher, "''")
+	var names []string
+	for _, match := range matcherCallRegex.FindAllStringSubmatch(matcher, -1) {
+		// "in" is the operator of a membership test, e.g. r.sub in ('alice', 'bob').
+		if name := match[2]; name != "in" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// similarFunctionName returns the known function whose name only differs from name by its case,
+// or also by one character if edits is true and they are long enough not to be unrelated, or an empty string.
+func similarFunctionName(name string, known map[string]struct{}, edits bool) string {
+	var res []string
+	for k := range known {
+		if strings.EqualFold(k, name) || edits && len(name) >= 5 && len(k) >= 5 && editDistanceAtMostOne(strings.ToLower(k), strings.ToLower(name)) {
+			res = append(res, k)
+		}
+	}
+	if len(res) == 0 {
+		return ""
+	}
+	sort.Strings(res)
+	return res[0]
+}
+
+// editDistanceAtMostOne reports whether s and t differ by at most one inserted, removed or replaced byte.
+func editDistanceAtMostOne(s string, t string) bool {
+	if len(s) > len(t) {
+		s, t = t, s
+	}
+	if len(t)-len(s) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(s) && s[i] == t[i] {
+		i++
+	}
+	if len(s) == len(t) {
+		return i == len(s) || s[i+1:] == t[i+1:]
+	}
+	return s[i:] == t[i+1:]
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+const matcherFunctionsModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = %s
+`
+
+func testMatcherModel(t *testing.T, matcher string) model.Model {
+	t.Helper()
+	m, err := model.NewModelFromString(strings.Replace(matcherFunctionsModel, "%s", matcher, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMatcherFunctionTypoAtConstruction(t *testing.T) {
+	_, err := NewEnforcer(testMatcherModel(t, "g(r.sub, p.sub) && keymatch(r.obj, p.obj)"))
+	if err == nil || !strings.Contains(err.Error(), "keymatch") || !strings.Contains(err.Error(), "keyMatch") {
+		t.Errorf("a misspelled function should fail the construction naming it, got %v", err)
+	}
+
+	_, err = NewEnforcer(testMatcherModel(t, "g(r.sub, p.sub) && myMatch(r.obj, p.obj) && r.act in ('read(', \"write\")"))
+	if err != nil {
+		t.Errorf("a custom function to be registered later should not fail the construction, got %v", err)
+	}
+}
+
+func TestValidateMatchers(t *testing.T) {
+	e, _ := NewEnforcer(testMatcherModel(t, "g(r.sub, p.sub) && myMatch(r.obj, p.obj) && r.act in ('read', 'write')"))
+
+	err := e.ValidateMatchers()
+	if err == nil || !strings.Contains(err.Error(), "myMatch") {
+		t.Errorf("an unregistered function should be reported, got %v", err)
+	}
+
+	e.AddFunction("myMatch", func(args ...interface{}) (interface{}, error) { return true, nil })
+	if err = e.ValidateMatchers(); err != nil {
+		t.Errorf("registered functions should be valid, got %v", err)
+	}
+
+	e, _ = NewEnforcer(testMatcherModel(t, "g(r.sub, p.sub) && keyMtch(r.obj, p.obj) && eval('true')"))
+	err = e.ValidateMatchers()
+	if err == nil || !strings.Contains(err.Error(), "did you mean keyMatch") {
+		t.Errorf("a misspelled function should be reported with a suggestion, got %v", err)
+	}
+}
+
+func TestRemoveFunction(t *testing.T) {
+	e, _ := NewEnforcer(testMatcherModel(t, "g(r.sub, p.sub) && myMatch(r.obj, p.obj)"))
+	e.AddFunction("myMatch", func(args ...interface{}) (interface{}, error) { return true, nil })
+	e.AddFunction("unused", func(args ...interface{}) (interface{}, error) { return true, nil })
+
+	if err := e.RemoveFunction("myMatch"); err == nil || !strings.Contains(err.Error(), "myMatch") {
+		t.Errorf("removing a function called by a matcher should fail, got %v", err)
+	}
+	if err := e.ValidateMatchers(); err != nil {
+		t.Errorf("a function that failed to be removed should be kept, got %v", err)
+	}
+
+	if err := e.RemoveFunction("unused"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.fm.GetFunctions()["unused"]; ok {
+		t.Error("the function should be removed")
+	}
+}
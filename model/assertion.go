@@ -16,6 +16,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -36,21 +37,55 @@ type Assertion struct {
 	CondRM          rbac.ConditionalRoleManager
 	FieldIndexMap   map[string]int
 	FieldIndexMutex sync.RWMutex
+	// Comment holds the comment lines preceding the assertion in the model text, written back by ToText.
+	Comment string
 
-	logger log.Logger
+	policyKeyFunc func([]string) string
+	// fieldIndexes maps the indexed fields to their index, see Model.BuildFieldIndex.
+	fieldIndexes map[int]valueIndex
+	logger       log.Logger
+	// options is only set on the assertion holding the options of the model, see Model.getOptions.
+	options *modelOptions
 }
 
-func (ast *Assertion) buildIncrementalRoleLinks(rm rbac.RoleManager, op PolicyOp, rules [][]string) error {
+// policyKey returns the key of a rule in PolicyMap, rules with the same key are considered the same.
+func (ast *Assertion) policyKey(rule []string) string {
+	if ast.policyKeyFunc != nil {
+		return ast.policyKeyFunc(rule)
+	}
+	return strings.Join(rule, DefaultSep)
+}
+
+// IsDomainOptional reports whether the role definition declares its domain optional by the "_?" token,
+// e.g. g = _, _, _?, so that g(r.sub, p.sub) without a domain holds if the link exists in any domain.
+func (ast *Assertion) IsDomainOptional() bool {
+	return len(ast.Tokens) > 2 && strings.TrimSpace(ast.Tokens[len(ast.Tokens)-1]) == "_?"
+}
+
+func (ast *Assertion) buildIncrementalRoleLinks(rm rbac.RoleManager, op PolicyOp, rules [][]string, rejectCycles bool) error {
 	ast.RM = rm
 	count := strings.Count(ast.Value, "_")
 	if count < 2 {
 		return errors.New("the number of \"_\" in role definition should be at least 2")
 	}
 
+	// Check all the rules first so that the role manager is not left half updated.
+	// Elements after the ones of the role definition are custom data, which is ignored.
 	for _, rule := range rules {
 		if len(rule) < count {
-			return errors.New("grouping policy elements do not meet role definition")
+			return fmt.Errorf("grouping policy rule %v has %d elements, the role definition %s = %s needs %d",
+				rule, len(rule), ast.Key, ast.Value, count)
 		}
+	}
+
+	if op == PolicyAdd && rejectCycles {
+		// The rules are already in the policy, they are checked against the other rules.
+		if err := findRoleCycle(policyWithout(ast.Policy, rules, count), rules, count); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range rules {
 		if len(rule) > count {
 			rule = rule[:count]
 		}
@@ -70,7 +105,7 @@ func (ast *Assertion) buildIncrementalRoleLinks(rm rbac.RoleManager, op PolicyOp
 	return nil
 }
 
-func (ast *Assertion) buildRoleLinks(rm rbac.RoleManager) error {
+func (ast *Assertion) buildRoleLinks(rm rbac.RoleManager, rejectCycles bool) error {
 	ast.RM = rm
 	count := strings.Count(ast.Value, "_")
 	if count < 2 {
@@ -80,6 +115,13 @@ func (ast *Assertion) buildRoleLinks(rm rbac.RoleManager) error {
 		if len(rule) < count {
 			return errors.New("grouping policy elements do not meet role definition")
 		}
+	}
+	if rejectCycles {
+		if err := findRoleCycle(nil, ast.Policy, count); err != nil {
+			return err
+		}
+	}
+	for _, rule := range ast.Policy {
 		if len(rule) > count {
 			rule = rule[:count]
 		}
@@ -92,6 +134,70 @@ func (ast *Assertion) buildRoleLinks(rm rbac.RoleManager) error {
 	return nil
 }
 
+// roleGraph holds the links of grouping rules, from the users to their roles, by domain.
+type roleGraph map[string]map[string][]string
+
+func (g roleGraph) addLink(user string, role string, domain string) {
+	if g[domain] == nil {
+		g[domain] = map[string][]string{}
+	}
+	g[domain][user] = append(g[domain][user], role)
+}
+
+// inherits returns whether user inherits role in domain, directly or through any number of roles.
+func (g roleGraph) inherits(user string, role string, domain string) bool {
+	links := g[domain]
+	visited := map[string]bool{user: true}
+	queue := []string{user}
+	for len(queue) != 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if name == role {
+			return true
+		}
+		for _, next := range links[name] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// findRoleCycle returns an error naming the first of the added rules whose link would make a cycle of inheritance,
+// that is whose role already inherits its user through the existing rules or the added rules before it,
+// see Model.SetRejectRoleCycles. The rules have count elements at least, as in their role definition.
+func findRoleCycle(existing [][]string, added [][]string, count int) error {
+	graph := roleGraph{}
+	for _, rule := range existing {
+		graph.addLink(rule[0], rule[1], strings.Join(rule[2:count], "\x00"))
+	}
+	for _, rule := range added {
+		domain := strings.Join(rule[2:count], "\x00")
+		if graph.inherits(rule[1], rule[0], domain) {
+			return fmt.Errorf("grouping policy rule %v makes a role cycle: %s already inherits %s", rule[:count], rule[1], rule[0])
+		}
+		graph.addLink(rule[0], rule[1], domain)
+	}
+	return nil
+}
+
+// policyWithout returns the rules of policy that are not in rules, comparing their first count elements.
+func policyWithout(policy [][]string, rules [][]string, count int) [][]string {
+	skip := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		skip[strings.Join(rule[:count], "\x00")] = true
+	}
+	res := make([][]string, 0, len(policy))
+	for _, rule := range policy {
+		if !skip[strings.Join(rule[:count], "\x00")] {
+			res = append(res, rule)
+		}
+	}
+	return res
+}
+
 func (ast *Assertion) buildIncrementalConditionalRoleLinks(condRM rbac.ConditionalRoleManager, op PolicyOp, rules [][]string) error {
 	ast.CondRM = condRM
 	count := strings.Count(ast.Value, "_")
@@ -114,7 +220,7 @@ func (ast *Assertion) buildIncrementalConditionalRoleLinks(condRM rbac.Condition
 		case PolicyAdd:
 			err = ast.addConditionalRoleLink(rule, domainRule)
 		case PolicyRemove:
-			err = ast.CondRM.DeleteLink(rule[0], rule[1], rule[2:]...)
+			err = ast.CondRM.DeleteLink(rule[0], rule[1], domainRule...)
 		}
 		if err != nil {
 			return err
@@ -193,10 +299,19 @@ func (ast *Assertion) copy() *Assertion {
 	newAst := &Assertion{
 		Key:           ast.Key,
 		Value:         ast.Value,
+		Comment:       ast.Comment,
 		PolicyMap:     policyMap,
 		Tokens:        tokens,
 		Policy:        policy,
 		FieldIndexMap: fieldIndexMap,
+		policyKeyFunc: ast.policyKeyFunc,
+	}
+	if ast.fieldIndexes != nil {
+		newAst.fieldIndexes = make(map[int]valueIndex, len(ast.fieldIndexes))
+		for fieldIndex := range ast.fieldIndexes {
+			newAst.fieldIndexes[fieldIndex] = nil
+		}
+		newAst.rebuildFieldIndexes()
 	}
 
 	return newAst
@@ -0,0 +1,166 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// valueIndex maps the values of a field to the keys of the rules having them, see Model.BuildFieldIndex.
+type valueIndex map[string]map[string]struct{}
+
+// BuildFieldIndex indexes the rules of a policy by the value of their field at fieldIndex, so that
+// GetFilteredPolicy and RemoveFilteredPolicy filtering on this field look the matching rules up
+// instead of scanning the whole policy. The index is kept up to date by the methods of Model,
+// and is rebuilt when the model is copied, e.g. by LoadPolicy, but it is not aware of the rules
+// written to Assertion.Policy directly, after which BuildFieldIndex should be called again.
+func (model Model) BuildFieldIndex(sec string, ptype string, fieldIndex int) error {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+	if fieldIndex < 0 {
+		return fmt.Errorf("invalid field index: %d", fieldIndex)
+	}
+
+	if ast.fieldIndexes == nil {
+		ast.fieldIndexes = map[int]valueIndex{}
+	}
+	ast.fieldIndexes[fieldIndex] = valueIndex{}
+	for _, rule := range ast.Policy {
+		ast.indexRuleField(fieldIndex, rule, ast.policyKey(rule))
+	}
+	return nil
+}
+
+// rebuildFieldIndexes rebuilds the indexes of the fields indexed by BuildFieldIndex.
+func (ast *Assertion) rebuildFieldIndexes() {
+	for fieldIndex := range ast.fieldIndexes {
+		ast.fieldIndexes[fieldIndex] = valueIndex{}
+	}
+	for _, rule := range ast.Policy {
+		ast.indexRule(rule)
+	}
+}
+
+// indexRule adds a rule to the field indexes.
+func (ast *Assertion) indexRule(rule []string) {
+	if len(ast.fieldIndexes) == 0 {
+		return
+	}
+	key := ast.policyKey(rule)
+	for fieldIndex := range ast.fieldIndexes {
+		ast.indexRuleField(fieldIndex, rule, key)
+	}
+}
+
+func (ast *Assertion) indexRuleField(fieldIndex int, rule []string, key string) {
+	if fieldIndex >= len(rule) {
+		return
+	}
+	index := ast.fieldIndexes[fieldIndex]
+	keys, ok := index[rule[fieldIndex]]
+	if !ok {
+		keys = map[string]struct{}{}
+		index[rule[fieldIndex]] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// unindexRule removes a rule from the field indexes.
+func (ast *Assertion) unindexRule(rule []string) {
+	if len(ast.fieldIndexes) == 0 {
+		return
+	}
+	key := ast.policyKey(rule)
+	for fieldIndex, index := range ast.fieldIndexes {
+		if fieldIndex >= len(rule) {
+			continue
+		}
+		keys := index[rule[fieldIndex]]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(index, rule[fieldIndex])
+		}
+	}
+}
+
+// filterIndexed returns the positions in the policy of the rules matching the field filters, in increasing order,
+// looking them up in the smallest index of the filtered fields. It returns false if none of them is indexed.
+func (ast *Assertion) filterIndexed(fieldIndex int, fieldValues []string) ([]int, bool) {
+	var candidates map[string]struct{}
+	indexed := false
+	for i, fieldValue := range fieldValues {
+		if fieldValue == "" {
+			continue
+		}
+		index, ok := ast.fieldIndexes[fieldIndex+i]
+		if !ok {
+			continue
+		}
+		if keys := index[fieldValue]; !indexed || len(keys) < len(candidates) {
+			candidates = keys
+			indexed = true
+		}
+	}
+	if !indexed {
+		return nil, false
+	}
+
+	positions := make([]int, 0, len(candidates))
+	for key := range candidates {
+		if i, ok := ast.PolicyMap[key]; ok && matchesFieldFilter(ast.Policy[i], fieldIndex, fieldValues) {
+			positions = append(positions, i)
+		}
+	}
+	sort.Ints(positions)
+	return positions, true
+}
+
+// rulesAt returns the rules at the given positions in the policy.
+func (ast *Assertion) rulesAt(positions []int) [][]string {
+	var rules [][]string
+	for _, i := range positions {
+		rules = append(rules, ast.Policy[i])
+	}
+	return rules
+}
+
+// removePositions removes the rules at the given positions, in increasing order, from the policy
+// into a new slice, only updating PolicyMap for the rules after the first removed one.
+// It returns whether any rule was removed.
+func (ast *Assertion) removePositions(positions []int) bool {
+	if len(positions) == 0 {
+		return false
+	}
+
+	policy := make([][]string, positions[0], len(ast.Policy)-len(positions))
+	copy(policy, ast.Policy[:positions[0]])
+	next := 0
+	for i := positions[0]; i < len(ast.Policy); i++ {
+		rule := ast.Policy[i]
+		if next < len(positions) && positions[next] == i {
+			next++
+			ast.unindexRule(rule)
+			delete(ast.PolicyMap, ast.policyKey(rule))
+			continue
+		}
+		ast.PolicyMap[ast.policyKey(rule)] = len(policy)
+		policy = append(policy, rule)
+	}
+	ast.Policy = policy
+	return true
+}
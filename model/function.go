@@ -33,6 +33,11 @@ func (fm *FunctionMap) AddFunction(name string, function govaluate.ExpressionFun
 	fm.fns.LoadOrStore(name, function)
 }
 
+// RemoveFunction removes an expression function.
+func (fm *FunctionMap) RemoveFunction(name string) {
+	fm.fns.Delete(name)
+}
+
 // LoadFunctionMap loads an initial function map.
 func LoadFunctionMap() FunctionMap {
 	fm := &FunctionMap{}
@@ -48,7 +53,18 @@ func LoadFunctionMap() FunctionMap {
 	fm.AddFunction("keyMatch5", util.KeyMatch5Func)
 	fm.AddFunction("regexMatch", util.RegexMatchFunc)
 	fm.AddFunction("ipMatch", util.IPMatchFunc)
+	fm.AddFunction("ipMatchCIDR", util.IPMatchCIDRFunc)
 	fm.AddFunction("globMatch", util.GlobMatchFunc)
+	fm.AddFunction("semverCompare", util.SemverCompareFunc)
+	fm.AddFunction("semverGte", util.SemverGteFunc)
+	fm.AddFunction("jsonGet", util.JsonGetFunc)
+	fm.AddFunction("between", util.BetweenFunc)
+	fm.AddFunction("inRange", util.InRangeFunc)
+	fm.AddFunction("containsAny", util.ContainsAnyFunc)
+	fm.AddFunction("containsAll", util.ContainsAllFunc)
+	fm.AddFunction("inTimeRange", util.InTimeRangeFunc)
+	fm.AddFunction("weekdayMatch", util.WeekdayMatchFunc)
+	fm.AddFunction("timeMatch", util.TimeWindowMatchFunc)
 
 	return *fm
 }
@@ -51,7 +51,13 @@ var requiredSections = []string{"r", "p", "e", "m"}
 
 func loadAssertion(model Model, cfg config.ConfigInterface, sec string, key string) bool {
 	value := cfg.String(sectionNameMap[sec] + "::" + key)
-	return model.AddDef(sec, key, value)
+	if !model.AddDef(sec, key, value) {
+		return false
+	}
+	if commentConfig, ok := cfg.(interface{ Comment(key string) string }); ok {
+		model[sec][key].Comment = commentConfig.Comment(sectionNameMap[sec] + "::" + key)
+	}
+	return true
 }
 
 var paramsRegex = regexp.MustCompile(`\((.*?)\)`)
@@ -131,7 +137,9 @@ func (model Model) SetLogger(logger log.Logger) {
 			ast.logger = logger
 		}
 	}
-	model["logger"] = AssertionMap{"logger": &Assertion{logger: logger}}
+	if model["logger"]["logger"] == nil {
+		model["logger"] = AssertionMap{"logger": &Assertion{logger: logger}}
+	}
 }
 
 // GetLogger returns the model's logger.
@@ -171,6 +179,64 @@ func NewModelFromString(text string) (Model, error) {
 	return m, nil
 }
 
+// NewModelFromFiles creates a model from the fragments of a model in several .CONF files, e.g. one defining
+// the request, policy and role definitions and another one the effect and the matcher.
+// A definition in a later file overrides the same definition in an earlier one, but the request,
+// policy and role definitions must keep their tokens, which the other definitions depend on.
+func NewModelFromFiles(paths ...string) (Model, error) {
+	m := NewModel()
+	definedIn := make(map[string]string)
+	for _, path := range paths {
+		cfg, err := config.NewConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		fragment := NewModel()
+		for sec := range sectionNameMap {
+			loadSection(fragment, cfg, sec)
+		}
+
+		for sec := range sectionNameMap {
+			for key, ast := range fragment[sec] {
+				if prev, ok := m[sec][key]; ok && !sameTokens(prev, ast) {
+					return nil, fmt.Errorf("conflicting definitions of %s in %s: %q in %s and %q in %s",
+						key, sectionNameMap[sec], prev.Value, definedIn[sec+key], ast.Value, path)
+				}
+				if _, ok := m[sec]; !ok {
+					m[sec] = make(AssertionMap)
+				}
+				m[sec][key] = ast
+				definedIn[sec+key] = path
+			}
+		}
+	}
+
+	ms := make([]string, 0)
+	for _, rs := range requiredSections {
+		if !m.hasSection(rs) {
+			ms = append(ms, sectionNameMap[rs])
+		}
+	}
+	if len(ms) > 0 {
+		return nil, fmt.Errorf("missing required sections: %s", strings.Join(ms, ","))
+	}
+	return m, nil
+}
+
+// sameTokens reports whether two definitions of a request, policy or role definition have the same tokens.
+// The definitions of the other sections have no tokens and always do.
+func sameTokens(ast1 *Assertion, ast2 *Assertion) bool {
+	if len(ast1.Tokens) != len(ast2.Tokens) || len(ast1.ParamsTokens) != len(ast2.ParamsTokens) {
+		return false
+	}
+	for i := range ast1.Tokens {
+		if strings.TrimSpace(ast1.Tokens[i]) != strings.TrimSpace(ast2.Tokens[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // LoadModel loads the model from model CONF file.
 func (model Model) LoadModel(path string) error {
 	cfg, err := config.NewConfig(path)
@@ -230,7 +296,7 @@ func (model Model) PrintModel() {
 
 	var modelInfo [][]string
 	for k, v := range model {
-		if k == "logger" {
+		if k == "logger" || k == optionsSection {
 			continue
 		}
 
@@ -273,7 +339,7 @@ func (model Model) SortPoliciesBySubjectHierarchy() error {
 			return p1 > p2
 		})
 		for i, policy := range assertion.Policy {
-			assertion.PolicyMap[strings.Join(policy, ",")] = i
+			assertion.PolicyMap[assertion.policyKey(policy)] = i
 		}
 	}
 	return nil
@@ -334,27 +400,50 @@ func getNameWithDomain(domain string, name string) string {
 	return domain + defaultSeparator + name
 }
 
+// SortPoliciesByPriority sorts the rules of every policy type with a priority field, see SortPolicyByPriority.
 func (model Model) SortPoliciesByPriority() error {
-	for ptype, assertion := range model["p"] {
-		priorityIndex, err := model.GetFieldIndex(ptype, constant.PriorityIndex)
-		if err != nil {
-			continue
+	for ptype := range model["p"] {
+		if err := model.SortPolicyByPriority(ptype); err != nil {
+			return err
 		}
-		policies := assertion.Policy
-		sort.SliceStable(policies, func(i, j int) bool {
-			p1, err := strconv.Atoi(policies[i][priorityIndex])
-			if err != nil {
-				return true
-			}
-			p2, err := strconv.Atoi(policies[j][priorityIndex])
-			if err != nil {
-				return true
-			}
-			return p1 < p2
-		})
-		for i, policy := range assertion.Policy {
-			assertion.PolicyMap[strings.Join(policy, ",")] = i
+	}
+	return nil
+}
+
+// SortPolicyByPriority sorts the rules of ptype by ascending priority. The sort is stable: rules of the same
+// priority keep their relative order, as AddPolicy inserts a rule after the existing rules of its priority.
+// Rules whose priority is not an integer are placed after the others, in their order.
+// The policy is left as is if ptype has no priority field.
+func (model Model) SortPolicyByPriority(ptype string) error {
+	assertion, err := model.GetAssertion("p", ptype)
+	if err != nil {
+		return err
+	}
+	priorityIndex, err := model.GetFieldIndex(ptype, constant.PriorityIndex)
+	if err != nil {
+		return nil
+	}
+
+	type prioritizedRule struct {
+		rule     []string
+		priority int
+		valid    bool
+	}
+	rules := make([]prioritizedRule, len(assertion.Policy))
+	for i, rule := range assertion.Policy {
+		priority, err := strconv.Atoi(rule[priorityIndex])
+		rules[i] = prioritizedRule{rule: rule, priority: priority, valid: err == nil}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].valid != rules[j].valid {
+			return rules[i].valid
 		}
+		return rules[i].priority < rules[j].priority
+	})
+
+	for i, rule := range rules {
+		assertion.Policy[i] = rule.rule
+		assertion.PolicyMap[assertion.policyKey(rule.rule)] = i
 	}
 	return nil
 }
@@ -376,12 +465,21 @@ func (model Model) ToText() string {
 		tokenPatterns["p_eft"] = "p.eft"
 	}
 	s := strings.Builder{}
+	writeComment := func(comment string) {
+		if comment == "" {
+			return
+		}
+		for _, line := range strings.Split(comment, "\n") {
+			s.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	}
 	writeString := func(sec string) {
 		for ptype := range model[sec] {
 			value := model[sec][ptype].Value
 			for tokenPattern, newToken := range tokenPatterns {
 				value = strings.Replace(value, tokenPattern, newToken, -1)
 			}
+			writeComment(model[sec][ptype].Comment)
 			s.WriteString(fmt.Sprintf("%s = %s\n", sec, value))
 		}
 	}
@@ -392,6 +490,7 @@ func (model Model) ToText() string {
 	if _, ok := model["g"]; ok {
 		s.WriteString("[role_definition]\n")
 		for ptype := range model["g"] {
+			writeComment(model["g"][ptype].Comment)
 			s.WriteString(fmt.Sprintf("%s = %s\n", ptype, model["g"][ptype].Value))
 		}
 	}
@@ -402,10 +501,85 @@ func (model Model) ToText() string {
 	return s.String()
 }
 
+// Structure describes the definitions of a model, keyed by assertion type
+// such as "r", "p2" or "g".
+type Structure struct {
+	RequestDefinitions map[string][]string
+	PolicyDefinitions  map[string][]string
+	RoleDefinitions    map[string]string
+	Effects            map[string]string
+	Matchers           map[string]string
+}
+
+var escapedTokenRegex = regexp.MustCompile(`\b(r|p)[0-9]*_\w+`)
+
+// GetStructure returns the request, policy and role definitions together with
+// the effect and matcher expressions of the model, written as in the model text.
+func (model Model) GetStructure() Structure {
+	structure := Structure{
+		RequestDefinitions: make(map[string][]string),
+		PolicyDefinitions:  make(map[string][]string),
+		RoleDefinitions:    make(map[string]string),
+		Effects:            make(map[string]string),
+		Matchers:           make(map[string]string),
+	}
+
+	escapedTokens := make(map[string]string)
+	for _, sec := range []string{"r", "p"} {
+		for ptype, ast := range model[sec] {
+			for _, token := range ast.Tokens {
+				escapedTokens[token] = ptype + "." + strings.TrimPrefix(token, ptype+"_")
+			}
+			if sec == "p" {
+				escapedTokens[ptype+"_eft"] = ptype + ".eft"
+			}
+		}
+	}
+
+	tokens := func(ptype string, ast *Assertion) []string {
+		res := make([]string, len(ast.Tokens))
+		for i, token := range ast.Tokens {
+			res[i] = strings.TrimPrefix(token, ptype+"_")
+		}
+		return res
+	}
+	unescape := func(s string) string {
+		return escapedTokenRegex.ReplaceAllStringFunc(s, func(token string) string {
+			if newToken, ok := escapedTokens[token]; ok {
+				return newToken
+			}
+			return token
+		})
+	}
+
+	for ptype, ast := range model["r"] {
+		structure.RequestDefinitions[ptype] = tokens(ptype, ast)
+	}
+	for ptype, ast := range model["p"] {
+		structure.PolicyDefinitions[ptype] = tokens(ptype, ast)
+	}
+	for ptype, ast := range model["g"] {
+		structure.RoleDefinitions[ptype] = ast.Value
+	}
+	for ptype, ast := range model["e"] {
+		structure.Effects[ptype] = unescape(ast.Value)
+	}
+	for ptype, ast := range model["m"] {
+		structure.Matchers[ptype] = unescape(ast.Value)
+	}
+
+	return structure
+}
+
+// Copy returns a deep copy of the model with its policy and its options, e.g. the callbacks
+// registered by OnPolicyChanged and the settings of SetStrictPriority and SetRejectRoleCycles.
 func (model Model) Copy() Model {
 	newModel := NewModel()
 
 	for sec, m := range model {
+		if sec == optionsSection {
+			continue
+		}
 		newAstMap := make(AssertionMap)
 		for ptype, ast := range m {
 			newAstMap[ptype] = ast.copy()
@@ -414,6 +588,9 @@ func (model Model) Copy() Model {
 	}
 
 	newModel.SetLogger(model.GetLogger())
+	if options := model.getOptions(); options != nil {
+		*newModel.options() = options.copy()
+	}
 	return newModel
 }
 
@@ -15,13 +15,19 @@
 package model
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/casbin/casbin/v2/config"
 	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/rbac"
+	defaultrolemanager "github.com/casbin/casbin/v2/rbac/default-role-manager"
+	"github.com/casbin/casbin/v2/util"
 )
 
 var (
@@ -159,3 +165,1245 @@ func testModelToText(t *testing.T, mData, mExpected string) {
 		}
 	}
 }
+
+func TestModelGetStructure(t *testing.T) {
+	m := NewModel()
+	m.AddDef("r", "r", "sub, obj, act")
+	m.AddDef("p", "p", "sub, obj, act")
+	m.AddDef("g", "g", "_, _")
+	m.AddDef("e", "e", "some(where (p.eft == allow))")
+	m.AddDef("m", "m", "g(r.sub, p.sub) && r_func(r.obj, p.obj) && r.act == p.act")
+
+	structure := m.GetStructure()
+	if !util.ArrayEquals(structure.RequestDefinitions["r"], []string{"sub", "obj", "act"}) {
+		t.Errorf("request definition: %v", structure.RequestDefinitions["r"])
+	}
+	if !util.ArrayEquals(structure.PolicyDefinitions["p"], []string{"sub", "obj", "act"}) {
+		t.Errorf("policy definition: %v", structure.PolicyDefinitions["p"])
+	}
+	if structure.RoleDefinitions["g"] != "_, _" {
+		t.Errorf("role definition: %s", structure.RoleDefinitions["g"])
+	}
+	if structure.Effects["e"] != "some(where (p.eft == allow))" {
+		t.Errorf("effect: %s", structure.Effects["e"])
+	}
+	if structure.Matchers["m"] != "g(r.sub, p.sub) && r_func(r.obj, p.obj) && r.act == p.act" {
+		t.Errorf("matcher: %s", structure.Matchers["m"])
+	}
+}
+
+func TestSetPolicyKeyFunc(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicy("p", "p", []string{"Alice", "data1", "read"})
+
+	caseFolded := func(rule []string) string {
+		return strings.ToLower(strings.Join(rule, DefaultSep))
+	}
+	if err := m.SetPolicyKeyFunc("p", "p", caseFolded); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "DATA1", "read"}); !ok {
+		t.Error("rule should be found by its case-folded key")
+	}
+	_ = m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	if ok, _ := m.HasPolicy("p", "p", []string{"Bob", "data2", "write"}); !ok {
+		t.Error("added rule should be found by its case-folded key")
+	}
+	if ok, _ := m.RemovePolicy("p", "p", []string{"ALICE", "data1", "read"}); !ok {
+		t.Error("rule should be removed by its case-folded key")
+	}
+	if len(m["p"]["p"].Policy) != 1 {
+		t.Errorf("policy: %v, supposed to have 1 rule", m["p"]["p"].Policy)
+	}
+
+	// The key function is kept when the model is copied, e.g. on LoadPolicy.
+	if ok, _ := m.Copy().HasPolicy("p", "p", []string{"BOB", "data2", "write"}); !ok {
+		t.Error("copied model should keep the key function")
+	}
+
+	if err := m.SetPolicyKeyFunc("p", "p", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"Bob", "data2", "write"}); ok {
+		t.Error("default key should be case sensitive")
+	}
+
+	_ = m.AddPolicy("p", "p", []string{"Bob", "data2", "write"})
+	if err := m.SetPolicyKeyFunc("p", "p", caseFolded); err == nil {
+		t.Error("colliding rules should fail")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"Bob", "data2", "write"}); !ok {
+		t.Error("failed key function should not be applied")
+	}
+}
+
+func TestModelToTextWithComments(t *testing.T) {
+	text := `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+# users inherit the permissions of their roles
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+# the subject must hold the role,
+# objects and actions must match exactly
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+	m, err := NewModelFromString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["m"]["m"].Comment != "the subject must hold the role,\nobjects and actions must match exactly" {
+		t.Errorf("matcher comment: %q", m["m"]["m"].Comment)
+	}
+
+	output := m.ToText()
+	if !strings.Contains(output, "# users inherit the permissions of their roles\ng = _, _\n") {
+		t.Errorf("role definition comment is lost: %s", output)
+	}
+	if !strings.Contains(output, "# the subject must hold the role,\n# objects and actions must match exactly\nm = ") {
+		t.Errorf("matcher comment is lost: %s", output)
+	}
+
+	newM, err := NewModelFromString(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newM["m"]["m"].Comment != m["m"]["m"].Comment || newM["g"]["g"].Comment != m["g"]["g"].Comment {
+		t.Error("comments should survive a ToText round trip")
+	}
+}
+
+func TestNewModelFromFiles(t *testing.T) {
+	definitions := filepath.Join("..", "examples", "rbac_model_definitions.conf")
+	matchers := filepath.Join("..", "examples", "rbac_model_matchers.conf")
+
+	m, err := NewModelFromFiles(definitions, matchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	if m.ToText() != full.ToText() {
+		t.Errorf("merged model:\n%s\nsupposed to be:\n%s", m.ToText(), full.ToText())
+	}
+
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	override := filepath.Join(dir, "override.conf")
+	_ = ioutil.WriteFile(override, []byte("[request_definition]\nr = sub, obj, act\n\n[matchers]\nm = r.sub == p.sub\n"), 0600)
+	m, err = NewModelFromFiles(definitions, matchers, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["m"]["m"].Value != "r_sub == p_sub" {
+		t.Errorf("matcher: %s, supposed to be overridden by the later file", m["m"]["m"].Value)
+	}
+
+	conflict := filepath.Join(dir, "conflict.conf")
+	_ = ioutil.WriteFile(conflict, []byte("[policy_definition]\np = sub, obj\n"), 0600)
+	_, err = NewModelFromFiles(definitions, conflict, matchers)
+	if err == nil || !strings.Contains(err.Error(), "conflicting definitions of p") || !strings.Contains(err.Error(), conflict) {
+		t.Errorf("redefining the tokens of p should fail with a clear error, got %v", err)
+	}
+
+	if _, err = NewModelFromFiles(definitions); err == nil {
+		t.Error("a model without effect and matcher should fail")
+	}
+}
+
+func TestUpdateFilteredPolicies(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"alice", "data2", "write"},
+	})
+
+	oldRules, err := m.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data3", "read"}, {"alice", "data3", "write"}}, 0, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(oldRules, [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}}) {
+		t.Errorf("old rules: %v", oldRules)
+	}
+	expected := [][]string{{"bob", "data2", "write"}, {"alice", "data3", "read"}, {"alice", "data3", "write"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, expected %v", m["p"]["p"].Policy, expected)
+	}
+	for i, rule := range expected {
+		if index, ok := m["p"]["p"].PolicyMap[strings.Join(rule, DefaultSep)]; !ok || index != i {
+			t.Errorf("rule %v should be indexed at %d, got %d", rule, i, index)
+		}
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); ok {
+		t.Error("replaced rule should be removed")
+	}
+
+	if _, err = m.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data4"}}, 0, "alice"); err == nil {
+		t.Error("malformed rule should fail")
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("failed update should leave the policy untouched, got %v", m["p"]["p"].Policy)
+	}
+
+	// Nothing matches the filter, so nothing is replaced.
+	oldRules, err = m.UpdateFilteredPolicies("p", "p", [][]string{{"carol", "data4", "read"}}, 0, "carol")
+	if err != nil || len(oldRules) != 0 {
+		t.Errorf("update without a match: %v, %v, expected no replaced rules", oldRules, err)
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("update without a match should leave the policy untouched, got %v", m["p"]["p"].Policy)
+	}
+}
+
+func TestUpdateFilteredPoliciesStrictPriority(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "priority_model_explicit.conf"))
+	m.SetStrictPriority(true)
+	_ = m.AddPolicies("p", "p", [][]string{{"10", "alice", "data1", "read", "allow"}, {"20", "bob", "data2", "write", "allow"}})
+
+	if _, err := m.UpdateFilteredPolicies("p", "p", [][]string{{"high", "alice", "data1", "write", "allow"}}, 1, "alice"); err == nil {
+		t.Error("new rule without a valid priority should fail")
+	}
+	expected := [][]string{{"10", "alice", "data1", "read", "allow"}, {"20", "bob", "data2", "write", "allow"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("failed update should keep the replaced rules, got %v", m["p"]["p"].Policy)
+	}
+}
+
+func TestBuildFieldIndex(t *testing.T) {
+	scanned, _ := NewModelFromFile(basicExample)
+	indexed, _ := NewModelFromFile(basicExample)
+	if err := indexed.BuildFieldIndex("p", "p", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	subjects := []string{"alice", "bob", "carol", "dave"}
+	objects := []string{"data1", "data2", "data3"}
+	actions := []string{"read", "write"}
+	var rules [][]string
+	for _, sub := range subjects {
+		for _, obj := range objects {
+			for _, act := range actions {
+				rules = append(rules, []string{sub, obj, act})
+			}
+		}
+	}
+
+	testSameFilteredPolicy := func(step string) {
+		t.Helper()
+		for _, sub := range append(subjects, "", "eve") {
+			for _, obj := range append(objects, "") {
+				res, _ := scanned.GetFilteredPolicy("p", "p", 0, sub, obj)
+				indexedRes, _ := indexed.GetFilteredPolicy("p", "p", 0, sub, obj)
+				if !util.Array2DEquals(res, indexedRes) {
+					t.Errorf("%s: filtering %s, %s: %v, supposed to be %v", step, sub, obj, indexedRes, res)
+				}
+			}
+			res, _ := scanned.GetFilteredPolicyLimit("p", "p", 2, 0, sub)
+			indexedRes, _ := indexed.GetFilteredPolicyLimit("p", "p", 2, 0, sub)
+			if !util.Array2DEquals(res, indexedRes) {
+				t.Errorf("%s: filtering %s with limit: %v, supposed to be %v", step, sub, indexedRes, res)
+			}
+		}
+	}
+
+	apply := func(step string, op func(m Model)) {
+		t.Helper()
+		op(scanned)
+		op(indexed)
+		testSameFilteredPolicy(step)
+	}
+
+	apply("add", func(m Model) { _ = m.AddPolicies("p", "p", rules) })
+	apply("remove", func(m Model) { _, _ = m.RemovePolicy("p", "p", []string{"alice", "data1", "read"}) })
+	apply("remove several", func(m Model) {
+		_, _ = m.RemovePolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data1", "read"}})
+	})
+	apply("update", func(m Model) {
+		_, _ = m.UpdatePolicy("p", "p", []string{"bob", "data1", "read"}, []string{"eve", "data1", "read"})
+	})
+	apply("update several", func(m Model) {
+		_, _ = m.UpdatePolicies("p", "p", [][]string{{"dave", "data3", "write"}, {"dave", "data3", "read"}},
+			[][]string{{"alice", "data9", "write"}, {"carol", "data9", "read"}})
+	})
+	apply("failed update", func(m Model) {
+		_, _ = m.UpdatePolicies("p", "p", [][]string{{"alice", "data9", "write"}, {"nobody", "data1", "read"}},
+			[][]string{{"bob", "data9", "write"}, {"bob", "data8", "read"}})
+	})
+	apply("transform", func(m Model) {
+		_ = m.TransformPolicy("p", "p", 10, func(rule []string) []string {
+			return []string{strings.ToUpper(rule[0]), rule[1], rule[2]}
+		})
+	})
+	apply("remove filtered", func(m Model) { _, _, _ = m.RemoveFilteredPolicy("p", "p", 0, "carol") })
+	apply("remove filtered by another field", func(m Model) { _, _, _ = m.RemoveFilteredPolicy("p", "p", 1, "data2") })
+	apply("update filtered", func(m Model) {
+		_, _ = m.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data4", "read"}}, 0, "alice", "data1")
+	})
+
+	scanned = scanned.Copy()
+	indexed = indexed.Copy()
+	testSameFilteredPolicy("copy")
+	if len(indexed["p"]["p"].fieldIndexes) != 1 {
+		t.Error("copied model should keep the field index")
+	}
+
+	apply("clear", func(m Model) { m.ClearPolicy() })
+	apply("add again", func(m Model) { _ = m.AddPolicies("p", "p", rules) })
+
+	if err := indexed.BuildFieldIndex("p", "p", -1); err == nil {
+		t.Error("negative field index should be an error")
+	}
+	if err := indexed.BuildFieldIndex("p", "p9", 0); err == nil {
+		t.Error("unknown policy type should be an error")
+	}
+}
+
+func TestUpdatePoliciesWithAffected(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	})
+
+	affected, err := m.UpdatePoliciesWithAffected("p", "p",
+		[][]string{{"carol", "data3", "read"}, {"alice", "data1", "read"}},
+		[][]string{{"carol", "data3", "write"}, {"alice", "data1", "write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PolicyUpdate{
+		{OldRule: []string{"carol", "data3", "read"}, NewRule: []string{"carol", "data3", "write"}, Index: 2},
+		{OldRule: []string{"alice", "data1", "read"}, NewRule: []string{"alice", "data1", "write"}, Index: 0},
+	}
+	if !reflect.DeepEqual(affected, expected) {
+		t.Errorf("affected: %v, expected %v", affected, expected)
+	}
+	policy := [][]string{{"alice", "data1", "write"}, {"bob", "data2", "write"}, {"carol", "data3", "write"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, policy) {
+		t.Errorf("policy: %v, expected %v", m["p"]["p"].Policy, policy)
+	}
+
+	affected, err = m.UpdatePoliciesWithAffected("p", "p",
+		[][]string{{"bob", "data2", "write"}, {"dave", "data4", "read"}},
+		[][]string{{"bob", "data2", "read"}, {"dave", "data4", "write"}})
+	if err != nil || affected != nil {
+		t.Errorf("update of a missing rule: %v, %v, expected no affected rule", affected, err)
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, policy) {
+		t.Errorf("failed update should be rolled back, got %v", m["p"]["p"].Policy)
+	}
+
+	if ok, err := m.UpdatePolicies("p", "p", nil, nil); !ok || err != nil {
+		t.Errorf("empty update: %t, %v, expected true", ok, err)
+	}
+}
+
+func TestSetMatchingOptions(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	if err := m.SetMatchingOptions(MatchingOptions{CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = m.AddPolicy("p", "p", []string{"Alice", "Data1", "read"})
+	_ = m.AddPolicy("g", "g", []string{"Bob", "Admin"})
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "READ"}); !ok {
+		t.Error("rule should be found whatever its case")
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"BOB", "admin"}); !ok {
+		t.Error("grouping rule should be found whatever its case")
+	}
+	if affected, _ := m.AddPoliciesWithAffected("p", "p", [][]string{{"ALICE", "data1", "Read"}}); len(affected) != 0 {
+		t.Errorf("rule differing only in case should not be added, got %v", affected)
+	}
+	policy, _ := m.GetPolicy("p", "p")
+	if !util.Array2DEquals(policy, [][]string{{"Alice", "Data1", "read"}}) {
+		t.Errorf("policy should keep the case of the added rules, got %v", policy)
+	}
+	if key := m.PolicyKey("p", "p", []string{"ALICE", "DATA1", "READ"}); key != "alice,data1,read" {
+		t.Errorf("policy key: %s, supposed to be alice,data1,read", key)
+	}
+	if ok, _ := m.RemovePolicy("p", "p", []string{"alice", "DATA1", "read"}); !ok {
+		t.Error("rule should be removed whatever its case")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"Alice", "Data1", "read"}); ok {
+		t.Error("removed rule should not be found")
+	}
+
+	if err := m.SetMatchingOptions(MatchingOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"bob", "admin"}); ok {
+		t.Error("rules should be case sensitive again")
+	}
+
+	// Rules colliding once case-folded make the options fail for every policy.
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicy("g", "g", []string{"bob", "admin"})
+	if err := m.SetMatchingOptions(MatchingOptions{CaseInsensitive: true}); err == nil {
+		t.Error("colliding rules should fail")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"ALICE", "data1", "read"}); ok {
+		t.Error("failed options should not be applied to p")
+	}
+	if ok, _ := m.HasPolicy("g", "g", []string{"BOB", "Admin"}); ok {
+		t.Error("failed options should not be applied to g")
+	}
+}
+
+func TestMatchingOptionsSeparator(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicy("p", "p", []string{`{"a":1,"b":2}`, "data1", "read"})
+	if ok, _ := m.HasPolicy("p", "p", []string{`{"a":1`, `"b":2}`, "data1,read"}); !ok {
+		t.Fatal("rules differing by where a comma falls should collide with the default separator")
+	}
+
+	if err := m.SetMatchingOptions(MatchingOptions{Separator: "\x1f"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{`{"a":1`, `"b":2}`, "data1,read"}); ok {
+		t.Error("rules differing by where a comma falls should be distinct")
+	}
+	_ = m.AddPolicy("p", "p", []string{`{"a":1`, `"b":2}`, "data1,read"})
+	if len(m["p"]["p"].Policy) != 2 {
+		t.Errorf("policy: %v, supposed to have 2 rules", m["p"]["p"].Policy)
+	}
+	if ok, _ := m.UpdatePolicy("p", "p", []string{`{"a":1`, `"b":2}`, "data1,read"}, []string{"bob", "data2", "write"}); !ok {
+		t.Error("rule should be updated")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{`{"a":1,"b":2}`, "data1", "read"}); !ok {
+		t.Error("other rule should be kept by the update")
+	}
+	if ok, _ := m.RemovePolicy("p", "p", []string{`{"a":1,"b":2}`, "data1", "read"}); !ok {
+		t.Error("rule should be removed")
+	}
+	_, _, _ = m.RemoveFilteredPolicy("p", "p", 0, "bob")
+	if len(m["p"]["p"].Policy) != 0 || len(m["p"]["p"].PolicyMap) != 0 {
+		t.Errorf("policy: %v, supposed to be empty", m["p"]["p"].Policy)
+	}
+
+	// The separator is combined with case insensitivity.
+	if err := m.SetMatchingOptions(MatchingOptions{Separator: "\x1f", CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	}
+	_ = m.AddPolicy("p", "p", []string{"Alice", "a,b", "read"})
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "A,B", "read"}); !ok {
+		t.Error("rule should be found whatever its case")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice,a", "b", "read"}); ok {
+		t.Error("rules differing by where a comma falls should be distinct")
+	}
+}
+
+func TestHasPoliciesDetailed(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	})
+
+	res, err := m.HasPoliciesDetailed("p", "p", [][]string{
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+		{"alice", "data1", "read"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []bool{true, false, true}) {
+		t.Errorf("result: %v, supposed to be [true false true]", res)
+	}
+
+	if _, err = m.HasPoliciesDetailed("p", "p2", [][]string{{"alice", "data1", "read"}}); err == nil {
+		t.Error("missing assertion should be an error")
+	}
+}
+
+func TestSortPolicyByPriority(t *testing.T) {
+	m, _ := NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = priority, sub, obj, act, eft
+
+[policy_effect]
+e = priority(p_eft) || deny
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	rules := [][]string{
+		{"10", "alice", "data1", "read", "deny"},
+		{"1", "bob", "data2", "write", "allow"},
+		{"x", "carol", "data3", "read", "allow"},
+		{"10", "alice", "data1", "read", "allow"},
+		{"1", "bob", "data2", "write", "deny"},
+		{"10", "alice", "data1", "write", "allow"},
+		{"y", "carol", "data3", "write", "allow"},
+	}
+	m["p"]["p"].Policy = append([][]string{}, rules...)
+	if err := m.SortPolicyByPriority("p"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"1", "bob", "data2", "write", "allow"},
+		{"1", "bob", "data2", "write", "deny"},
+		{"10", "alice", "data1", "read", "deny"},
+		{"10", "alice", "data1", "read", "allow"},
+		{"10", "alice", "data1", "write", "allow"},
+		{"x", "carol", "data3", "read", "allow"},
+		{"y", "carol", "data3", "write", "allow"},
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+	for i, rule := range expected {
+		if index := m["p"]["p"].PolicyMap[strings.Join(rule, DefaultSep)]; index != i {
+			t.Errorf("index of %v: %d, supposed to be %d", rule, index, i)
+		}
+	}
+
+	// Adding the rules one by one gives the same order.
+	m.ClearPolicy()
+	for _, rule := range rules {
+		_ = m.AddPolicy("p", "p", rule)
+	}
+	if err := m.SortPolicyByPriority("p"); err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+
+	if err := m.SortPolicyByPriority("p2"); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+}
+
+func TestRemovePoliciesStrict(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	})
+
+	for _, tc := range []struct {
+		rules [][]string
+		err   string
+	}{
+		{[][]string{{"alice", "data1", "read"}, {"dave", "data4", "read"}}, "does not exist"},
+		{[][]string{{"alice", "data1", "read"}, {"alice", "data1", "read"}}, "given more than once"},
+	} {
+		if err := m.RemovePoliciesStrict("p", "p", tc.rules); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("removing %v: %v, supposed to be an error containing %q", tc.rules, err, tc.err)
+		}
+		if len(m["p"]["p"].Policy) != 3 {
+			t.Errorf("policy: %v, supposed to be unchanged", m["p"]["p"].Policy)
+		}
+	}
+
+	if err := m.RemovePoliciesStrict("p", "p", [][]string{{"carol", "data3", "read"}, {"alice", "data1", "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, [][]string{{"bob", "data2", "write"}}) {
+		t.Errorf("policy: %v, supposed to be [[bob data2 write]]", m["p"]["p"].Policy)
+	}
+	if index := m["p"]["p"].PolicyMap["bob,data2,write"]; index != 0 {
+		t.Errorf("index of [bob data2 write]: %d, supposed to be 0", index)
+	}
+
+	if err := m.RemovePoliciesStrict("p", "p2", [][]string{{"bob", "data2", "write"}}); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+}
+
+func TestAddPoliciesStrict(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}})
+
+	for _, rules := range [][][]string{
+		{{"bob", "data2", "write"}, {"alice", "data1", "read"}},
+		{{"bob", "data2", "write"}, {"bob", "data2", "write"}},
+	} {
+		err := m.AddPoliciesStrict("p", "p", rules)
+		if err == nil {
+			t.Errorf("adding %v should be an error", rules)
+		} else if !strings.Contains(err.Error(), "[alice data1 read]") && !strings.Contains(err.Error(), "[bob data2 write]") {
+			t.Errorf("error %q should identify the rule", err)
+		}
+		if len(m["p"]["p"].Policy) != 1 {
+			t.Errorf("policy: %v, supposed to be unchanged", m["p"]["p"].Policy)
+		}
+	}
+
+	if err := m.AddPoliciesStrict("p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+
+	if err := m.AddPoliciesStrict("p", "p2", [][]string{{"dave", "data4", "read"}}); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+}
+
+func TestClearPolicyForDomain(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_with_domains_model.conf"))
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"admin", "domain1", "data1", "read"},
+		{"admin", "domain2", "data2", "read"},
+		{"admin", "domain1", "data1", "write"},
+	})
+	_ = m.AddPolicies("g", "g", [][]string{
+		{"alice", "admin", "domain1"},
+		{"bob", "admin", "domain2"},
+	})
+
+	if err := m.ClearPolicyForDomain("domain1"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]string{{"admin", "domain2", "data2", "read"}}; !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+	if expected := [][]string{{"bob", "admin", "domain2"}}; !util.Array2DEquals(m["g"]["g"].Policy, expected) {
+		t.Errorf("grouping policy: %v, supposed to be %v", m["g"]["g"].Policy, expected)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"admin", "domain2", "data2", "read"}); !ok {
+		t.Error("the rule of domain2 should still be indexed")
+	}
+
+	m, _ = NewModelFromFile(basicExample)
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := m.ClearPolicyForDomain("data1"); err == nil {
+		t.Error("a model without domain should be an error")
+	}
+	if len(m["p"]["p"].Policy) != 1 {
+		t.Errorf("policy: %v, supposed to be unchanged", m["p"]["p"].Policy)
+	}
+}
+
+func TestGetFilteredValuesForFieldInPolicy(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"admin", "data1", "read"},
+		{"admin", "data2", "write"},
+		{"admin", "data3", "read"},
+		{"alice", "data1", "delete"},
+	})
+
+	values, err := m.GetFilteredValuesForFieldInPolicy("p", "p", 2, 0, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !util.ArrayEquals(values, []string{"read", "write"}) {
+		t.Errorf("values: %v, supposed to be [read write]", values)
+	}
+
+	values, _ = m.GetFilteredValuesForFieldInPolicy("p", "p", 2, 0, "bob")
+	if values == nil || len(values) != 0 {
+		t.Errorf("values: %v, supposed to be empty", values)
+	}
+
+	if _, err = m.GetFilteredValuesForFieldInPolicy("p", "p2", 2, 0, "admin"); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+}
+
+func TestDeduplicatePolicy(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	for _, rule := range [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"alice", "data1", "read"},
+		{"carol", "data3", "read"},
+		{"bob", "data2", "write"},
+		{"alice", "data1", "read"},
+	} {
+		_ = m.AddPolicy("p", "p", rule)
+	}
+
+	duplicates := m.FindDuplicatePolicies("p", "p")
+	if !util.Array2DEquals(duplicates, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}) {
+		t.Errorf("duplicates: %v, supposed to be [[alice data1 read] [bob data2 write]]", duplicates)
+	}
+
+	if removed := m.DeduplicatePolicy("p", "p"); removed != 3 {
+		t.Errorf("removed rules: %d, supposed to be 3", removed)
+	}
+	expected := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+	for i, rule := range expected {
+		if index := m["p"]["p"].PolicyMap[strings.Join(rule, DefaultSep)]; index != i {
+			t.Errorf("index of %v: %d, supposed to be %d", rule, index, i)
+		}
+	}
+
+	if duplicates = m.FindDuplicatePolicies("p", "p"); len(duplicates) != 0 {
+		t.Errorf("duplicates: %v, supposed to be none", duplicates)
+	}
+	if removed := m.DeduplicatePolicy("p", "p"); removed != 0 {
+		t.Errorf("removed rules: %d, supposed to be 0", removed)
+	}
+}
+
+func TestGetPolicyCount(t *testing.T) {
+	m, _ := NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+p2 = sub, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	_ = m.AddPolicy("p", "p2", []string{"carol", "read"})
+	_ = m.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	if count, err := m.GetPolicyCount("p", "p"); err != nil || count != 2 {
+		t.Errorf("count of p: %d, %v, supposed to be 2", count, err)
+	}
+	if count, err := m.GetPolicyCountAllTypes("p"); err != nil || count != 3 {
+		t.Errorf("count of all p types: %d, %v, supposed to be 3", count, err)
+	}
+	if count, err := m.GetPolicyCountAllTypes("g"); err != nil || count != 1 {
+		t.Errorf("count of all g types: %d, %v, supposed to be 1", count, err)
+	}
+	if _, err := m.GetPolicyCount("p", "p3"); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+	if _, err := m.GetPolicyCountAllTypes("x"); err == nil {
+		t.Error("missing section should be an error")
+	}
+}
+
+func TestRangePolicy(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	})
+
+	var visited [][]string
+	err := m.RangePolicy("p", "p", func(rule []string) bool {
+		visited = append(visited, rule)
+		return rule[0] != "bob"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals(visited, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}) {
+		t.Errorf("visited rules: %v, supposed to stop at bob", visited)
+	}
+
+	if err = m.RangePolicy("p", "p2", func([]string) bool { return true }); err == nil {
+		t.Error("missing policy type should be an error")
+	}
+}
+
+func TestBuildIncrementalRoleLinksArity(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	rm := defaultrolemanager.NewRoleManager(10)
+	rmMap := map[string]rbac.RoleManager{"g": rm}
+
+	err := m.BuildIncrementalRoleLinks(rmMap, PolicyAdd, "g", "g", [][]string{{"alice", "admin"}, {"bob"}})
+	if err == nil || !strings.Contains(err.Error(), "[bob]") {
+		t.Errorf("error: %v, supposed to report the rule [bob]", err)
+	}
+	if ok, _ := rm.HasLink("alice", "admin"); ok {
+		t.Error("no link should be added when a rule is too short")
+	}
+
+	// Elements after the ones of the role definition are custom data.
+	err = m.BuildIncrementalRoleLinks(rmMap, PolicyAdd, "g", "g", [][]string{{"alice", "admin", "custom_data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := rm.HasLink("alice", "admin"); !ok {
+		t.Error("link with custom data should be added")
+	}
+
+	err = m.BuildIncrementalRoleLinks(rmMap, PolicyRemove, "g", "g", [][]string{{"alice", "admin"}, {}})
+	if err == nil {
+		t.Error("empty rule should be an error")
+	}
+	if ok, _ := rm.HasLink("alice", "admin"); !ok {
+		t.Error("no link should be deleted when a rule is too short")
+	}
+}
+
+func TestRejectRoleCycles(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	_ = m.AddPolicies("g", "g", [][]string{{"alice", "admin"}, {"admin", "root"}, {"root", "alice"}})
+
+	// Cycles are tolerated by default.
+	if err := m.BuildRoleLinks(map[string]rbac.RoleManager{"g": defaultrolemanager.NewRoleManager(10)}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.SetRejectRoleCycles(true)
+	err := m.BuildRoleLinks(map[string]rbac.RoleManager{"g": defaultrolemanager.NewRoleManager(10)})
+	if err == nil || !strings.Contains(err.Error(), "[root alice]") {
+		t.Errorf("error: %v, supposed to report the rule [root alice]", err)
+	}
+	if !m.Copy().isRejectRoleCycles() {
+		t.Error("the copy of the model should reject role cycles too")
+	}
+
+	m.ClearPolicy()
+	_ = m.AddPolicies("g", "g", [][]string{{"alice", "admin"}, {"admin", "root"}})
+	rm := defaultrolemanager.NewRoleManager(10)
+	rmMap := map[string]rbac.RoleManager{"g": rm}
+	if err = m.BuildRoleLinks(rmMap); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.BuildIncrementalRoleLinks(rmMap, PolicyAdd, "g", "g", [][]string{{"bob", "root"}, {"root", "alice"}})
+	if err == nil || !strings.Contains(err.Error(), "alice already inherits root") {
+		t.Errorf("error: %v, supposed to report the cycle", err)
+	}
+	if ok, _ := rm.HasLink("bob", "root"); ok {
+		t.Error("no link should be added when a rule makes a cycle")
+	}
+
+	if err = m.BuildIncrementalRoleLinks(rmMap, PolicyAdd, "g", "g", [][]string{{"carol", "carol"}}); err == nil {
+		t.Error("a role linked to itself should be a cycle")
+	}
+	if err = m.BuildIncrementalRoleLinks(rmMap, PolicyAdd, "g", "g", [][]string{{"bob", "root"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRoleCycles(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	// A chain longer than the hierarchy level of the role managers.
+	var chain [][]string
+	for i := 0; i < 20; i++ {
+		chain = append(chain, []string{fmt.Sprintf("role%d", i), fmt.Sprintf("role%d", i+1)})
+	}
+	_ = m.AddPolicies("g", "g", chain)
+
+	if err := m.CheckRoleCycles("g", "g", [][]string{{"role20", "role0"}}); err != nil {
+		t.Errorf("cycles should not be checked by default: %v", err)
+	}
+	m.SetRejectRoleCycles(true)
+	if err := m.CheckRoleCycles("g", "g", [][]string{{"role20", "role0"}}); err == nil {
+		t.Error("a cycle through 20 roles should be found")
+	}
+	if err := m.CheckRoleCycles("g", "g", [][]string{{"role20", "role21"}, {"role21", "role0"}}); err == nil {
+		t.Error("a cycle through the added rules should be found")
+	}
+	if err := m.CheckRoleCycles("g", "g", [][]string{{"role0", "role20"}}); err != nil {
+		t.Errorf("a shortcut is not a cycle: %v", err)
+	}
+	if err := m.CheckRoleCycles("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Errorf("policy rules have no cycles: %v", err)
+	}
+}
+
+func TestCopyAndRestorePolicy(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "rbac_model.conf"))
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"admin", "data2", "write"}})
+	_ = m.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	snapshot := m.CopyPolicy()
+	snapshot["p"]["p"][0][0] = "eve"
+	if m["p"]["p"].Policy[0][0] != "alice" {
+		t.Error("changing the snapshot should not change the model")
+	}
+	snapshot["p"]["p"][0][0] = "alice"
+
+	_, _ = m.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicy("p", "p", []string{"bob", "data3", "read"})
+	_ = m.AddPolicy("g", "g", []string{"bob", "admin"})
+	m.AddDef("p", "p2", "sub, act")
+	_ = m.AddPolicy("p", "p2", []string{"carol", "read"})
+
+	m.RestorePolicy(snapshot)
+	if !util.Array2DEquals(m["p"]["p"].Policy, [][]string{{"alice", "data1", "read"}, {"admin", "data2", "write"}}) {
+		t.Errorf("policy: %v, supposed to be restored", m["p"]["p"].Policy)
+	}
+	if !util.Array2DEquals(m["g"]["g"].Policy, [][]string{{"alice", "admin"}}) {
+		t.Errorf("grouping policy: %v, supposed to be restored", m["g"]["g"].Policy)
+	}
+	if len(m["p"]["p2"].Policy) != 0 {
+		t.Errorf("policy of p2: %v, supposed to be empty", m["p"]["p2"].Policy)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("restored rule should be in the policy map")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"bob", "data3", "read"}); ok {
+		t.Error("rule added after the snapshot should not be in the policy map")
+	}
+
+	// The snapshot can be restored again after the model changed.
+	_, _ = m.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	m.RestorePolicy(snapshot)
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Error("rule should be restored from the same snapshot")
+	}
+}
+
+func TestGetMatchingPolicyIndex(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "priority_model_explicit.conf"))
+	_ = m.AddPolicy("p", "p", []string{"10", "alice", "data1", "read", "deny"})
+	_ = m.AddPolicy("p", "p", []string{"20", "bob", "data2", "write", "allow"})
+	// Sorted as when the policy is loaded, the next rule is then inserted before the rules of lower priority.
+	_ = m.SortPoliciesByPriority()
+	_ = m.AddPolicy("p", "p", []string{"1", "alice", "data1", "read", "allow"})
+
+	for rule, expected := range map[string]int{
+		"1,alice,data1,read,allow": 0,
+		"10,alice,data1,read,deny": 1,
+		"20,bob,data2,write,allow": 2,
+	} {
+		index, ok := m.GetMatchingPolicyIndex("p", strings.Split(rule, ","))
+		if !ok || index != expected {
+			t.Errorf("index of %s: %d, %t, supposed to be %d", rule, index, ok, expected)
+		}
+	}
+
+	if _, ok := m.GetMatchingPolicyIndex("p", []string{"1", "bob", "data1", "read", "allow"}); ok {
+		t.Error("missing rule should not be found")
+	}
+	if _, ok := m.GetMatchingPolicyIndex("p2", []string{"1", "alice", "data1", "read", "allow"}); ok {
+		t.Error("rule of a missing policy type should not be found")
+	}
+}
+
+func TestOnPolicyChanged(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+
+	type change struct {
+		op    PolicyOp
+		rules [][]string
+	}
+	var changes []change
+	// current is the model whose changes are reported.
+	current := m
+	m.OnPolicyChanged(func(sec string, ptype string, op PolicyOp, rules [][]string) {
+		if sec != "p" || ptype != "p" {
+			t.Errorf("change of %s.%s, supposed to be p.p", sec, ptype)
+		}
+		// The callback sees the policy after the change.
+		for _, rule := range rules {
+			if ok, _ := current.HasPolicy(sec, ptype, rule); ok != (op == PolicyAdd) {
+				t.Errorf("rule %v is in the policy: %t during the callback of op %d", rule, ok, op)
+			}
+		}
+		changes = append(changes, change{op, rules})
+	})
+	testChanges := func(expected ...change) {
+		t.Helper()
+		if len(changes) != len(expected) {
+			t.Fatalf("changes: %v, supposed to be %v", changes, expected)
+		}
+		for i := range expected {
+			if changes[i].op != expected[i].op || !util.Array2DEquals(changes[i].rules, expected[i].rules) {
+				t.Fatalf("changes: %v, supposed to be %v", changes, expected)
+			}
+		}
+		changes = nil
+	}
+
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data1", "read"}})
+	testChanges(
+		change{PolicyAdd, [][]string{{"alice", "data1", "read"}}},
+		change{PolicyAdd, [][]string{{"bob", "data2", "write"}, {"carol", "data1", "read"}}},
+	)
+
+	_, _ = m.RemovePolicy("p", "p", []string{"dave", "data1", "read"})
+	_, _ = m.RemovePolicies("p", "p", [][]string{{"dave", "data1", "read"}})
+	_, _, _ = m.RemoveFilteredPolicy("p", "p", 0, "dave")
+	_, _ = m.UpdatePolicy("p", "p", []string{"dave", "data1", "read"}, []string{"dave", "data1", "write"})
+	testChanges()
+
+	_, _ = m.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"})
+	testChanges(
+		change{PolicyRemove, [][]string{{"bob", "data2", "write"}}},
+		change{PolicyAdd, [][]string{{"bob", "data2", "read"}}},
+	)
+
+	_, _ = m.RemovePolicy("p", "p", []string{"bob", "data2", "read"})
+	_ = m.BuildFieldIndex("p", "p", 1)
+	_, _, _ = m.RemoveFilteredPolicy("p", "p", 1, "data1")
+	testChanges(
+		change{PolicyRemove, [][]string{{"bob", "data2", "read"}}},
+		change{PolicyRemove, [][]string{{"alice", "data1", "read"}, {"carol", "data1", "read"}}},
+	)
+
+	// Copies keep the callbacks.
+	c := m.Copy()
+	current = c
+	_ = c.AddPolicy("p", "p", []string{"eve", "data3", "read"})
+	testChanges(change{PolicyAdd, [][]string{{"eve", "data3", "read"}}})
+
+	c.SetPolicyChangedMuted(true)
+	_, _ = c.RemovePolicy("p", "p", []string{"eve", "data3", "read"})
+	testChanges()
+	current = m
+	_ = m.AddPolicy("p", "p", []string{"eve", "data3", "read"})
+	testChanges(change{PolicyAdd, [][]string{{"eve", "data3", "read"}}})
+}
+
+func TestOnPolicyChangedReset(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	var resets []string
+	m.OnPolicyChanged(func(sec string, ptype string, op PolicyOp, rules [][]string) {
+		if op == PolicyReset {
+			if rules != nil {
+				t.Errorf("reset rules: %v, supposed to be nil", rules)
+			}
+			resets = append(resets, sec+"."+ptype)
+		}
+	})
+
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	snapshot := m.CopyPolicy()
+	m.ClearPolicy()
+	m.RestorePolicy(snapshot)
+	m["p"]["p"].Policy = append(m["p"]["p"].Policy, []string{"alice", "data1", "read"})
+	m.DeduplicatePolicy("p", "p")
+	m.DeduplicatePolicy("p", "p")
+	m.NotifyPolicyReset()
+
+	if !util.ArrayEquals(resets, []string{".", ".", "p.p", "."}) {
+		t.Errorf("resets: %v, supposed to be [. . p.p .]", resets)
+	}
+}
+
+func TestModelOptionsWithoutLogger(t *testing.T) {
+	m := Model{}
+	m.SetStrictPriority(true)
+	m.SetRejectRoleCycles(true)
+	m.OnPolicyChanged(func(sec string, ptype string, op PolicyOp, rules [][]string) {})
+	if _, ok := m["logger"]; ok {
+		t.Error("setting the options should not install a logger")
+	}
+	if !m.isStrictPriority() || !m.isRejectRoleCycles() {
+		t.Error("options should be set")
+	}
+}
+
+func TestMergePolicyFrom(t *testing.T) {
+	priorityExample := filepath.Join("..", "examples", "priority_model_explicit.conf")
+	m, _ := NewModelFromFile(priorityExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"10", "alice", "data1", "read", "allow"},
+		{"20", "bob", "data2", "write", "allow"},
+	})
+	_ = m.AddPolicy("g", "g", []string{"alice", "admin"})
+
+	other, _ := NewModelFromFile(priorityExample)
+	_ = other.AddPolicies("p", "p", [][]string{
+		{"20", "bob", "data2", "write", "allow"},
+		{"5", "alice", "data1", "read", "deny"},
+		{"1", "carol", "data3", "read", "allow"},
+		{"15", "alice", "data1", "read", "allow"},
+	})
+	_ = other.AddPolicies("g", "g", [][]string{{"alice", "admin"}, {"bob", "admin"}})
+
+	conflicts, err := m.MergePolicyFrom(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Sec != "p" || conflicts[0].PType != "p" ||
+		!util.ArrayEquals(conflicts[0].Existing, []string{"10", "alice", "data1", "read", "allow"}) ||
+		!util.ArrayEquals(conflicts[0].Incoming, []string{"5", "alice", "data1", "read", "deny"}) {
+		t.Errorf("conflicts: %v, supposed to be the deny rule of alice", conflicts)
+	}
+
+	expected := [][]string{
+		{"1", "carol", "data3", "read", "allow"},
+		{"10", "alice", "data1", "read", "allow"},
+		{"15", "alice", "data1", "read", "allow"},
+		{"20", "bob", "data2", "write", "allow"},
+	}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, expected)
+	}
+	for i, rule := range expected {
+		if index := m["p"]["p"].PolicyMap[strings.Join(rule, DefaultSep)]; index != i {
+			t.Errorf("index of %v: %d, supposed to be %d", rule, index, i)
+		}
+	}
+	if !util.Array2DEquals(m["g"]["g"].Policy, [][]string{{"alice", "admin"}, {"bob", "admin"}}) {
+		t.Errorf("grouping policy: %v, supposed to be merged", m["g"]["g"].Policy)
+	}
+
+	basic, _ := NewModelFromFile(basicExample)
+	_ = basic.AddPolicy("p", "p", []string{"dave", "data4", "read"})
+	if _, err = m.MergePolicyFrom(basic); err == nil {
+		t.Error("merging a differently defined policy type should be an error")
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"dave", "data4", "read"}); ok {
+		t.Error("model should be unchanged by a failed merge")
+	}
+}
+
+func TestMergePolicyFromCustomizedPriority(t *testing.T) {
+	customizedExample := filepath.Join("..", "examples", "priority_model_explicit_customized.conf")
+	m, _ := NewModelFromFile(customizedExample)
+	m["p"]["p"].FieldIndexMap[constant.PriorityIndex] = 0
+	_ = m.AddPolicy("p", "p", []string{"10", "data1", "read", "allow", "alice"})
+
+	other, _ := NewModelFromFile(customizedExample)
+	_ = other.AddPolicies("p", "p", [][]string{
+		{"5", "data1", "read", "deny", "alice"},
+		{"1", "data2", "read", "allow", "bob"},
+	})
+
+	conflicts, err := m.MergePolicyFrom(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || !util.ArrayEquals(conflicts[0].Incoming, []string{"5", "data1", "read", "deny", "alice"}) {
+		t.Errorf("conflicts: %v, supposed to be the deny rule of alice", conflicts)
+	}
+	expected := [][]string{{"1", "data2", "read", "allow", "bob"}, {"10", "data1", "read", "allow", "alice"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, expected) {
+		t.Errorf("policy: %v, supposed to be %v sorted by the customized priority", m["p"]["p"].Policy, expected)
+	}
+}
+
+func TestRemoveFilteredPolicyBatch(t *testing.T) {
+	m, _ := NewModelFromFile(basicExample)
+	_ = m.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"alice", "data2", "write"},
+		{"carol", "data3", "read"},
+		{"dave", "data1", "read"},
+		{"bob", "data1", "read"},
+	})
+
+	effects, err := m.RemoveFilteredPolicyBatch("p", "p", 0, [][]string{{"bob"}, {"alice", "data2"}, {"eve"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][][]string{
+		{{"bob", "data2", "write"}, {"bob", "data1", "read"}},
+		{{"alice", "data2", "write"}},
+		nil,
+	}
+	if len(effects) != len(expected) {
+		t.Fatalf("effects: %v, supposed to be %v", effects, expected)
+	}
+	for i := range expected {
+		if !util.Array2DEquals(effects[i], expected[i]) {
+			t.Errorf("effects of set %d: %v, supposed to be %v", i, effects[i], expected[i])
+		}
+	}
+
+	remaining := [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}, {"dave", "data1", "read"}}
+	if !util.Array2DEquals(m["p"]["p"].Policy, remaining) {
+		t.Errorf("policy: %v, supposed to be %v", m["p"]["p"].Policy, remaining)
+	}
+	if len(m["p"]["p"].PolicyMap) != len(remaining) {
+		t.Errorf("policy map: %v, supposed to have %d rules", m["p"]["p"].PolicyMap, len(remaining))
+	}
+	for i, rule := range remaining {
+		if index := m["p"]["p"].PolicyMap[strings.Join(rule, DefaultSep)]; index != i {
+			t.Errorf("index of %v: %d, supposed to be %d", rule, index, i)
+		}
+	}
+}
+
+func TestSetStrictPriority(t *testing.T) {
+	m, _ := NewModelFromFile(filepath.Join("..", "examples", "priority_model_explicit.conf"))
+
+	if err := m.AddPolicy("p", "p", []string{"high", "alice", "data1", "read", "allow"}); err != nil {
+		t.Errorf("invalid priority should be accepted by default: %v", err)
+	}
+
+	m.SetStrictPriority(true)
+	if err := m.AddPolicy("p", "p", []string{"1O", "alice", "data1", "write", "allow"}); err == nil {
+		t.Error("invalid priority should be an error in strict mode")
+	}
+	err := m.AddPolicies("p", "p", [][]string{
+		{"1", "bob", "data2", "read", "allow"},
+		{"", "bob", "data2", "write", "allow"},
+	})
+	if err == nil {
+		t.Error("invalid priority should be an error in strict mode")
+	}
+	if len(m["p"]["p"].Policy) != 1 {
+		t.Errorf("policy: %v, supposed to be unchanged by the failed adds", m["p"]["p"].Policy)
+	}
+
+	if err = m.AddPolicy("p", "p", []string{"10", "bob", "data2", "read", "allow"}); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Copy().isStrictPriority() {
+		t.Error("strict priority should be kept by a copy of the model")
+	}
+
+	// Grouping rules have no priority.
+	if err = m.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetValuesForFieldInPolicyAllTypesByNameWithPtype(t *testing.T) {
+	m, _ := NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+p2 = sub, act
+p3 = obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	_ = m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"alice", "data2", "read"}})
+	_ = m.AddPolicies("p", "p2", [][]string{{"carol", "read"}, {"alice", "write"}})
+	_ = m.AddPolicy("p", "p3", []string{"data3", "read"})
+
+	values, err := m.GetValuesForFieldInPolicyAllTypesByNameWithPtype("p", "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 {
+		t.Errorf("values: %v, supposed to have p and p2 only", values)
+	}
+	if !util.ArrayEquals(values["p"], []string{"alice", "bob"}) {
+		t.Errorf("values of p: %v, supposed to be [alice bob]", values["p"])
+	}
+	if !util.ArrayEquals(values["p2"], []string{"carol", "alice"}) {
+		t.Errorf("values of p2: %v, supposed to be [carol alice]", values["p2"])
+	}
+}
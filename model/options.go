@@ -0,0 +1,70 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// optionsSection is the section and the key of the assertion holding the options of a model,
+// as a Model has no other place for the state that is not part of its definition.
+const optionsSection = "options"
+
+// modelOptions holds the options of a model, which Copy carries over.
+type modelOptions struct {
+	policyChangedCallbacks []PolicyChangedFunc
+	policyChangedMuted     bool
+	strictPriority         bool
+	rejectRoleCycles       bool
+	// matchingOptions are the options set by SetMatchingOptions, nil if not set.
+	matchingOptions *MatchingOptions
+}
+
+func (o modelOptions) copy() modelOptions {
+	o.policyChangedCallbacks = append([]PolicyChangedFunc(nil), o.policyChangedCallbacks...)
+	return o
+}
+
+// SetOptionsFrom sets the options of other to the model, as Copy does, e.g. to keep them when the model
+// of an enforcer is reloaded: the callbacks registered by OnPolicyChanged and the settings of SetStrictPriority,
+// SetRejectRoleCycles and SetMatchingOptions. It fails if the existing rules collide with the matching options.
+func (model Model) SetOptionsFrom(other Model) error {
+	options := other.getOptions()
+	if options == nil {
+		return nil
+	}
+	if options.matchingOptions != nil {
+		if err := model.SetMatchingOptions(*options.matchingOptions); err != nil {
+			return err
+		}
+	}
+	*model.options() = options.copy()
+	return nil
+}
+
+// getOptions returns the options of the model, or nil if none was set.
+func (model Model) getOptions() *modelOptions {
+	holder := model[optionsSection][optionsSection]
+	if holder == nil {
+		return nil
+	}
+	return holder.options
+}
+
+// options returns the options of the model to be changed, creating them if needed.
+func (model Model) options() *modelOptions {
+	if options := model.getOptions(); options != nil {
+		return options
+	}
+	options := &modelOptions{}
+	model[optionsSection] = AssertionMap{optionsSection: &Assertion{Key: optionsSection, options: options}}
+	return options
+}
@@ -15,9 +15,12 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/casbin/casbin/v2/constant"
 	"github.com/casbin/casbin/v2/rbac"
@@ -31,8 +34,14 @@ type (
 const (
 	PolicyAdd PolicyOp = iota
 	PolicyRemove
+	// PolicyReset reports that the rules of a policy type, or of the whole policy if sec and ptype are empty,
+	// were replaced at once. No rules are given, the policy has to be read again.
+	PolicyReset
 )
 
+// PolicyChangedFunc is called with the rules added to or removed from a policy, see Model.OnPolicyChanged.
+type PolicyChangedFunc func(sec string, ptype string, op PolicyOp, rules [][]string)
+
 const DefaultSep = ","
 
 // BuildIncrementalRoleLinks provides incremental build the role inheritance relations.
@@ -42,7 +51,7 @@ func (model Model) BuildIncrementalRoleLinks(rmMap map[string]rbac.RoleManager,
 		if err != nil {
 			return err
 		}
-		return model[sec][ptype].buildIncrementalRoleLinks(rmMap[ptype], op, rules)
+		return model[sec][ptype].buildIncrementalRoleLinks(rmMap[ptype], op, rules, model.isRejectRoleCycles())
 	}
 	return nil
 }
@@ -52,7 +61,7 @@ func (model Model) BuildRoleLinks(rmMap map[string]rbac.RoleManager) error {
 	model.PrintPolicy()
 	for ptype, ast := range model["g"] {
 		if rm := rmMap[ptype]; rm != nil {
-			err := ast.buildRoleLinks(rm)
+			err := ast.buildRoleLinks(rm, model.isRejectRoleCycles())
 			if err != nil {
 				return err
 			}
@@ -120,17 +129,140 @@ func (model Model) PrintPolicy() {
 	model.GetLogger().LogPolicy(policy)
 }
 
+// OnPolicyChanged registers fn to be called after the rules of a policy are added by AddPolicy or AddPolicies,
+// removed by RemovePolicy, RemovePolicies, RemoveFilteredPolicy or RemoveFilteredPolicyBatch,
+// or updated by UpdatePolicy or UpdatePolicies, and by the methods built on them.
+// An update is reported as the removal of the old rules followed by the addition of the new ones.
+// ClearPolicy, RestorePolicy and DeduplicatePolicy are reported as a PolicyReset without rules, as is the
+// replacement of the policy by an enforcer, e.g. by LoadPolicy or the commit of a transaction, see NotifyPolicyReset.
+// Nothing is reported when no rule changed, nor for TransformPolicy or sorting. Copies of the model keep the
+// callbacks, see SetPolicyChangedMuted.
+func (model Model) OnPolicyChanged(fn PolicyChangedFunc) {
+	options := model.options()
+	options.policyChangedCallbacks = append(options.policyChangedCallbacks, fn)
+}
+
+// SetPolicyChangedMuted controls whether the callbacks registered by OnPolicyChanged are kept from being called,
+// e.g. for a copy of the model whose changes are a preview or replace the whole policy.
+func (model Model) SetPolicyChangedMuted(muted bool) {
+	model.options().policyChangedMuted = muted
+}
+
+// notifyPolicyChanged calls the callbacks registered by OnPolicyChanged if any rule changed.
+func (model Model) notifyPolicyChanged(sec string, ptype string, op PolicyOp, rules [][]string) {
+	options := model.getOptions()
+	if options == nil || options.policyChangedMuted || len(rules) == 0 {
+		return
+	}
+	for _, fn := range options.policyChangedCallbacks {
+		fn(sec, ptype, op, rules)
+	}
+}
+
+// NotifyPolicyReset reports a PolicyReset of the whole policy to the callbacks registered by OnPolicyChanged,
+// e.g. after the policy was loaded into a muted copy of the model which then replaced the model.
+func (model Model) NotifyPolicyReset() {
+	model.notifyPolicyReset("", "")
+}
+
+// notifyPolicyReset reports a PolicyReset of ptype, or of the whole policy if sec and ptype are empty.
+func (model Model) notifyPolicyReset(sec string, ptype string) {
+	options := model.getOptions()
+	if options == nil || options.policyChangedMuted {
+		return
+	}
+	for _, fn := range options.policyChangedCallbacks {
+		fn(sec, ptype, PolicyReset, nil)
+	}
+}
+
 // ClearPolicy clears all current policy.
 func (model Model) ClearPolicy() {
 	for _, ast := range model["p"] {
 		ast.Policy = nil
 		ast.PolicyMap = map[string]int{}
+		ast.rebuildFieldIndexes()
 	}
 
 	for _, ast := range model["g"] {
 		ast.Policy = nil
 		ast.PolicyMap = map[string]int{}
+		ast.rebuildFieldIndexes()
+	}
+	model.notifyPolicyReset("", "")
+}
+
+// ClearPolicyForDomain removes the p rules whose domain field, the "dom" token or the one set by
+// SetFieldIndex, equals domain, and the g rules whose domain, their third field, equals domain.
+// The policy types without a domain are left unchanged. It returns an error if no p policy type
+// has a domain. The role links are not rebuilt.
+func (model Model) ClearPolicyForDomain(domain string) error {
+	found := false
+	for ptype := range model["p"] {
+		index, err := model.GetFieldIndex(ptype, constant.DomainIndex)
+		if err != nil {
+			continue
+		}
+		found = true
+		if _, _, err = model.RemoveFilteredPolicy("p", ptype, index, domain); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return errors.New("the model has no domain, please use enforcer.SetFieldIndex() to set its index")
 	}
+
+	for ptype, ast := range model["g"] {
+		if len(ast.Tokens) <= 2 {
+			continue
+		}
+		if _, _, err := model.RemoveFilteredPolicy("g", ptype, 2, domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyPolicy returns a deep copy of the rules of all the p and g policies by section and ptype,
+// to be restored by RestorePolicy.
+func (model Model) CopyPolicy() map[string]map[string][][]string {
+	snapshot := make(map[string]map[string][][]string, 2)
+	for _, sec := range []string{"p", "g"} {
+		snapshot[sec] = make(map[string][][]string, len(model[sec]))
+		for ptype, ast := range model[sec] {
+			snapshot[sec][ptype] = copyRules(ast.Policy)
+		}
+	}
+	return snapshot
+}
+
+// RestorePolicy replaces the rules of all the p and g policies by a copy of the ones of the snapshot,
+// as returned by CopyPolicy. The policy types missing from the snapshot, e.g. added after it was taken,
+// are cleared, the ones of the snapshot missing from the model are ignored. The role links are not rebuilt.
+func (model Model) RestorePolicy(snapshot map[string]map[string][][]string) {
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			ast.Policy = copyRules(snapshot[sec][ptype])
+			ast.PolicyMap = make(map[string]int, len(ast.Policy))
+			for i, rule := range ast.Policy {
+				ast.PolicyMap[ast.policyKey(rule)] = i
+			}
+			ast.rebuildFieldIndexes()
+		}
+	}
+	model.notifyPolicyReset("", "")
+}
+
+// copyRules returns a deep copy of rules.
+func copyRules(rules [][]string) [][]string {
+	if rules == nil {
+		return nil
+	}
+	res := make([][]string, len(rules))
+	for i, rule := range rules {
+		res[i] = append([]string(nil), rule...)
+	}
+	return res
 }
 
 // GetPolicy gets all rules in a policy.
@@ -142,31 +274,111 @@ func (model Model) GetPolicy(sec string, ptype string) ([][]string, error) {
 	return model[sec][ptype].Policy, nil
 }
 
+// RangePolicy calls fn for each rule in a policy, in order, until fn returns false.
+// The rules are not copied, fn must not modify them nor the policy.
+func (model Model) RangePolicy(sec string, ptype string, fn func(rule []string) bool) error {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+	for _, rule := range ast.Policy {
+		if !fn(rule) {
+			break
+		}
+	}
+	return nil
+}
+
+// GetPolicyCount gets the number of rules in a policy.
+func (model Model) GetPolicyCount(sec string, ptype string) (int, error) {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return 0, err
+	}
+	return len(ast.Policy), nil
+}
+
+// GetPolicyCountAllTypes gets the number of rules in the policies of all ptypes of a section.
+func (model Model) GetPolicyCountAllTypes(sec string) (int, error) {
+	if model[sec] == nil {
+		return 0, fmt.Errorf("missing required section %s", sec)
+	}
+	count := 0
+	for _, ast := range model[sec] {
+		count += len(ast.Policy)
+	}
+	return count, nil
+}
+
 // GetFilteredPolicy gets rules based on field filters from a policy.
 func (model Model) GetFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return model.GetFilteredPolicyLimit(sec, ptype, 0, fieldIndex, fieldValues...)
+}
+
+// GetFilteredPolicyLimit gets the first limit rules based on field filters from a policy,
+// stopping the scan as soon as they are found. A limit of 0 means no limit.
+func (model Model) GetFilteredPolicyLimit(sec string, ptype string, limit int, fieldIndex int, fieldValues ...string) ([][]string, error) {
 	_, err := model.GetAssertion(sec, ptype)
 	if err != nil {
 		return nil, err
 	}
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid limit: %d", limit)
+	}
 	res := [][]string{}
 
-	for _, rule := range model[sec][ptype].Policy {
-		matched := true
-		for i, fieldValue := range fieldValues {
-			if fieldValue != "" && rule[fieldIndex+i] != fieldValue {
-				matched = false
+	ast := model[sec][ptype]
+	if positions, ok := ast.filterIndexed(fieldIndex, fieldValues); ok {
+		if limit != 0 && len(positions) > limit {
+			positions = positions[:limit]
+		}
+		for _, i := range positions {
+			res = append(res, ast.Policy[i])
+		}
+		return res, nil
+	}
+
+	for _, rule := range ast.Policy {
+		if matchesFieldFilter(rule, fieldIndex, fieldValues) {
+			res = append(res, rule)
+			if len(res) == limit {
 				break
 			}
 		}
+	}
 
-		if matched {
-			res = append(res, rule)
+	return res, nil
+}
+
+// GetFilteredPolicyExcept gets copies of the rules of a policy that do not match the field filters,
+// i.e. every rule GetFilteredPolicy does not return.
+func (model Model) GetFilteredPolicyExcept(sec string, ptype string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	_, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil, err
+	}
+	res := [][]string{}
+
+	for _, rule := range model[sec][ptype].Policy {
+		if !matchesFieldFilter(rule, fieldIndex, fieldValues) {
+			res = append(res, append([]string(nil), rule...))
 		}
 	}
 
 	return res, nil
 }
 
+// matchesFieldFilter reports whether the fields of a rule from fieldIndex are equal to fieldValues,
+// an empty field value matching any field.
+func matchesFieldFilter(rule []string, fieldIndex int, fieldValues []string) bool {
+	for i, fieldValue := range fieldValues {
+		if fieldValue != "" && rule[fieldIndex+i] != fieldValue {
+			return false
+		}
+	}
+	return true
+}
+
 // HasPolicyEx determines whether a model has the specified policy rule with error.
 func (model Model) HasPolicyEx(sec string, ptype string, rule []string) (bool, error) {
 	assertion, err := model.GetAssertion(sec, ptype)
@@ -194,16 +406,150 @@ func (model Model) HasPolicyEx(sec string, ptype string, rule []string) (bool, e
 	return model.HasPolicy(sec, ptype, rule)
 }
 
+// SetPolicyKeyFunc sets the function computing the key under which the rules of an assertion are stored,
+// e.g. to consider rules differing only in case as the same rule. Rules with the same key are deduplicated
+// by AddPolicy and found by HasPolicy. A nil function restores the default key, which joins the fields.
+// It fails if existing rules of the assertion collide under the new key.
+func (model Model) SetPolicyKeyFunc(sec string, ptype string, fn func([]string) string) error {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+
+	policyMap := make(map[string]int, len(ast.Policy))
+	keyFunc := ast.policyKeyFunc
+	ast.policyKeyFunc = fn
+	for i, rule := range ast.Policy {
+		key := ast.policyKey(rule)
+		if j, ok := policyMap[key]; ok {
+			ast.policyKeyFunc = keyFunc
+			return fmt.Errorf("policy rules %v and %v have the same key %s", ast.Policy[j], rule, key)
+		}
+		policyMap[key] = i
+	}
+	ast.PolicyMap = policyMap
+	ast.rebuildFieldIndexes()
+	return nil
+}
+
+// MatchingOptions are the options of a model for considering policy rules the same, see SetMatchingOptions.
+type MatchingOptions struct {
+	// CaseInsensitive considers the rules differing only in case the same rule, e.g. for AddPolicy, HasPolicy
+	// and RemovePolicy. GetPolicy still returns the rules as they were added. It does not affect the matchers.
+	CaseInsensitive bool
+	// Separator joins the fields of the rules into their keys, DefaultSep if empty. Rules whose fields contain
+	// DefaultSep may collide, e.g. ["a,b", "c"] and ["a", "b,c"], unless it is set to a string never found
+	// in the fields, e.g. "\x1f".
+	Separator string
+}
+
+// SetMatchingOptions sets the options for considering the rules of every policy the same, replacing the key
+// functions set by SetPolicyKeyFunc. It fails, leaving the key functions unchanged, if existing rules collide.
+func (model Model) SetMatchingOptions(options MatchingOptions) error {
+	var fn func([]string) string
+	sep := options.Separator
+	switch {
+	case options.CaseInsensitive && (sep == "" || sep == DefaultSep):
+		fn = caseInsensitivePolicyKey
+	case options.CaseInsensitive:
+		fn = func(rule []string) string {
+			return strings.ToLower(strings.Join(rule, sep))
+		}
+	case sep != "" && sep != DefaultSep:
+		fn = func(rule []string) string {
+			return strings.Join(rule, sep)
+		}
+	}
+
+	var rollbacks []func()
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			sec, ptype, previous := sec, ptype, ast.policyKeyFunc
+			if err := model.SetPolicyKeyFunc(sec, ptype, fn); err != nil {
+				for _, rollback := range rollbacks {
+					rollback()
+				}
+				return err
+			}
+			rollbacks = append(rollbacks, func() { _ = model.SetPolicyKeyFunc(sec, ptype, previous) })
+		}
+	}
+	model.options().matchingOptions = &options
+	return nil
+}
+
+// caseInsensitivePolicyKey is the policy key of the rules with MatchingOptions.CaseInsensitive.
+func caseInsensitivePolicyKey(rule []string) string {
+	return strings.ToLower(strings.Join(rule, DefaultSep))
+}
+
+// PolicyKey returns the key under which a rule of a policy is stored, rules with the same key are considered
+// the same, see SetPolicyKeyFunc.
+func (model Model) PolicyKey(sec string, ptype string, rule []string) string {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return strings.Join(rule, DefaultSep)
+	}
+	return ast.policyKey(rule)
+}
+
 // HasPolicy determines whether a model has the specified policy rule.
 func (model Model) HasPolicy(sec string, ptype string, rule []string) (bool, error) {
 	_, err := model.GetAssertion(sec, ptype)
 	if err != nil {
 		return false, err
 	}
-	_, ok := model[sec][ptype].PolicyMap[strings.Join(rule, DefaultSep)]
+	_, ok := model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(rule)]
 	return ok, nil
 }
 
+// GetMatchingPolicyIndex returns the index in the policy of ptype of the rule matched, e.g. the explanation
+// of a decision returned by EnforceEx, to find the rule that caused it.
+func (model Model) GetMatchingPolicyIndex(ptype string, matched []string) (int, bool) {
+	ast, err := model.GetAssertion("p", ptype)
+	if err != nil {
+		return -1, false
+	}
+	index, ok := ast.PolicyMap[ast.policyKey(matched)]
+	if !ok {
+		return -1, false
+	}
+	return index, true
+}
+
+// GetStoredPolicy returns the rule of the model that is considered the same as rule, which differs from rule
+// when the key func set by SetPolicyKeyFunc ignores some of their differences.
+func (model Model) GetStoredPolicy(sec string, ptype string, rule []string) ([]string, bool, error) {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil, false, err
+	}
+	index, ok := ast.PolicyMap[ast.policyKey(rule)]
+	if !ok {
+		return nil, false, nil
+	}
+	return ast.Policy[index], true, nil
+}
+
+// TransformPolicy replaces each rule of the policy, starting at the index from, by the result of fn.
+func (model Model) TransformPolicy(sec string, ptype string, from int, fn func(rule []string) []string) error {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+	for i := from; i < len(ast.Policy); i++ {
+		rule := fn(ast.Policy[i])
+		if index, ok := ast.PolicyMap[ast.policyKey(ast.Policy[i])]; ok && index == i {
+			delete(ast.PolicyMap, ast.policyKey(ast.Policy[i]))
+			ast.unindexRule(ast.Policy[i])
+		}
+		ast.Policy[i] = rule
+		ast.PolicyMap[ast.policyKey(rule)] = i
+		ast.indexRule(rule)
+	}
+	return nil
+}
+
 // HasPolicies determines whether a model has any of the specified policies. If one is found we return true.
 func (model Model) HasPolicies(sec string, ptype string, rules [][]string) (bool, error) {
 	for i := 0; i < len(rules); i++ {
@@ -219,14 +565,113 @@ func (model Model) HasPolicies(sec string, ptype string, rules [][]string) (bool
 	return false, nil
 }
 
+// HasPoliciesDetailed reports for each of the specified rules whether the model has it,
+// so that the result is parallel to rules.
+func (model Model) HasPoliciesDetailed(sec string, ptype string, rules [][]string) ([]bool, error) {
+	res := make([]bool, len(rules))
+	for i, rule := range rules {
+		ok, err := model.HasPolicy(sec, ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = ok
+	}
+
+	return res, nil
+}
+
+// SetStrictPriority controls whether the rules added to a policy type with a priority field must have
+// an integer priority. When enabled, AddPolicy and AddPolicies return an error for a rule whose priority
+// does not parse as an integer, instead of adding it without ordering it by priority. It is disabled by default.
+func (model Model) SetStrictPriority(strict bool) {
+	model.options().strictPriority = strict
+}
+
+// isStrictPriority returns whether the priorities of the added rules are checked, see SetStrictPriority.
+func (model Model) isStrictPriority() bool {
+	options := model.getOptions()
+	return options != nil && options.strictPriority
+}
+
+// SetRejectRoleCycles controls whether building the role links fails on a grouping rule making a cycle
+// of inheritance, e.g. "g, a, b" with "g, b, a", with an error naming the roles, instead of linking the roles
+// anyway. BuildRoleLinks and BuildIncrementalRoleLinks then link none of the rules. The cycles are found by walking
+// the grouping rules, whatever their length. The enforcer checks the added rules with CheckRoleCycles before
+// persisting or adding them. A rule linking a role to itself is a cycle too.
+// It is disabled by default, as some policies rely on cycles.
+func (model Model) SetRejectRoleCycles(reject bool) {
+	model.options().rejectRoleCycles = reject
+}
+
+// CheckRoleCycles returns an error if adding the grouping rules of ptype would make a cycle of inheritance
+// with the rules of the policy, when role cycles are rejected, see SetRejectRoleCycles. It is meant to be called
+// before the rules are persisted or added, unlike the check made when building the role links.
+func (model Model) CheckRoleCycles(sec string, ptype string, rules [][]string) error {
+	if sec != "g" || !model.isRejectRoleCycles() {
+		return nil
+	}
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return err
+	}
+	count := strings.Count(ast.Value, "_")
+	for _, rule := range rules {
+		if len(rule) < count {
+			return fmt.Errorf("grouping policy rule %v has %d elements, the role definition %s = %s needs %d",
+				rule, len(rule), ast.Key, ast.Value, count)
+		}
+	}
+	return findRoleCycle(ast.Policy, rules, count)
+}
+
+// isRejectRoleCycles returns whether role cycles are rejected, see SetRejectRoleCycles.
+func (model Model) isRejectRoleCycles() bool {
+	options := model.getOptions()
+	return options != nil && options.rejectRoleCycles
+}
+
+// checkPriority returns an error if the priority of a rule is not an integer in strict priority mode.
+func (model Model) checkPriority(sec string, ptype string, rule []string) error {
+	if sec != "p" || !model.isStrictPriority() {
+		return nil
+	}
+	index, err := model.GetFieldIndex(ptype, constant.PriorityIndex)
+	if err != nil {
+		return nil
+	}
+	if index >= len(rule) {
+		return fmt.Errorf("policy rule %v has no priority", rule)
+	}
+	if _, err = strconv.Atoi(rule[index]); err != nil {
+		return fmt.Errorf("priority %q of policy rule %v is not an integer", rule[index], rule)
+	}
+	return nil
+}
+
 // AddPolicy adds a policy rule to the model.
 func (model Model) AddPolicy(sec string, ptype string, rule []string) error {
+	if _, err := model.GetAssertion(sec, ptype); err != nil {
+		return err
+	}
+	if err := model.checkPriority(sec, ptype, rule); err != nil {
+		return err
+	}
+	if err := model.addPolicy(sec, ptype, rule); err != nil {
+		return err
+	}
+	model.notifyPolicyChanged(sec, ptype, PolicyAdd, [][]string{rule})
+	return nil
+}
+
+// addPolicy adds a policy rule to the model without calling the callbacks of OnPolicyChanged.
+func (model Model) addPolicy(sec string, ptype string, rule []string) error {
 	assertion, err := model.GetAssertion(sec, ptype)
 	if err != nil {
 		return err
 	}
 	assertion.Policy = append(assertion.Policy, rule)
-	assertion.PolicyMap[strings.Join(rule, DefaultSep)] = len(model[sec][ptype].Policy) - 1
+	assertion.PolicyMap[assertion.policyKey(rule)] = len(model[sec][ptype].Policy) - 1
+	assertion.indexRule(rule)
 
 	hasPriority := false
 	if _, ok := assertion.FieldIndexMap[constant.PriorityIndex]; ok {
@@ -241,10 +686,10 @@ func (model Model) AddPolicy(sec string, ptype string, rule []string) error {
 					break
 				}
 				assertion.Policy[i] = assertion.Policy[i-1]
-				assertion.PolicyMap[strings.Join(assertion.Policy[i-1], DefaultSep)]++
+				assertion.PolicyMap[assertion.policyKey(assertion.Policy[i-1])]++
 			}
 			assertion.Policy[i] = rule
-			assertion.PolicyMap[strings.Join(rule, DefaultSep)] = i
+			assertion.PolicyMap[assertion.policyKey(rule)] = i
 		}
 	}
 	return nil
@@ -262,22 +707,49 @@ func (model Model) AddPoliciesWithAffected(sec string, ptype string, rules [][]s
 	if err != nil {
 		return nil, err
 	}
+	for _, rule := range rules {
+		if err = model.checkPriority(sec, ptype, rule); err != nil {
+			return nil, err
+		}
+	}
 	var affected [][]string
 	for _, rule := range rules {
-		hashKey := strings.Join(rule, DefaultSep)
+		hashKey := model[sec][ptype].policyKey(rule)
 		_, ok := model[sec][ptype].PolicyMap[hashKey]
 		if ok {
 			continue
 		}
 		affected = append(affected, rule)
-		err = model.AddPolicy(sec, ptype, rule)
+		err = model.addPolicy(sec, ptype, rule)
 		if err != nil {
 			return affected, err
 		}
 	}
+	model.notifyPolicyChanged(sec, ptype, PolicyAdd, affected)
 	return affected, err
 }
 
+// AddPoliciesStrict adds policy rules to the model, all or none: if any of the rules already exists,
+// or is given more than once, an error is returned and the model is left unchanged.
+// It pairs with RemovePoliciesStrict.
+func (model Model) AddPoliciesStrict(sec string, ptype string, rules [][]string) error {
+	seen := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		ok, err := model.HasPolicy(sec, ptype, rule)
+		if err != nil {
+			return err
+		}
+		key := model[sec][ptype].policyKey(rule)
+		if _, duplicate := seen[key]; ok || duplicate {
+			return fmt.Errorf("policy rule %v cannot be added to %s: it already exists", rule, ptype)
+		}
+		seen[key] = struct{}{}
+	}
+
+	_, err := model.AddPoliciesWithAffected(sec, ptype, rules)
+	return err
+}
+
 // RemovePolicy removes a policy rule from the model.
 // Deprecated: Using AddPoliciesWithAffected instead.
 func (model Model) RemovePolicy(sec string, ptype string, rule []string) (bool, error) {
@@ -285,20 +757,23 @@ func (model Model) RemovePolicy(sec string, ptype string, rule []string) (bool,
 	if err != nil {
 		return false, err
 	}
-	key := strings.Join(rule, DefaultSep)
+	key := ast.policyKey(rule)
 	index, ok := ast.PolicyMap[key]
 	if !ok {
 		return false, nil
 	}
 
+	removed := ast.Policy[index]
+	ast.unindexRule(removed)
 	lastIdx := len(ast.Policy) - 1
 	if index != lastIdx {
 		ast.Policy[index] = ast.Policy[lastIdx]
-		lastPolicyKey := strings.Join(ast.Policy[index], DefaultSep)
+		lastPolicyKey := ast.policyKey(ast.Policy[index])
 		ast.PolicyMap[lastPolicyKey] = index
 	}
 	ast.Policy = ast.Policy[:lastIdx]
 	delete(ast.PolicyMap, key)
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, [][]string{removed})
 	return true, nil
 }
 
@@ -308,24 +783,46 @@ func (model Model) UpdatePolicy(sec string, ptype string, oldRule []string, newR
 	if err != nil {
 		return false, err
 	}
-	oldPolicy := strings.Join(oldRule, DefaultSep)
+	oldPolicy := model[sec][ptype].policyKey(oldRule)
 	index, ok := model[sec][ptype].PolicyMap[oldPolicy]
 	if !ok {
 		return false, nil
 	}
 
+	stored := model[sec][ptype].Policy[index]
+	model[sec][ptype].unindexRule(stored)
 	model[sec][ptype].Policy[index] = newRule
 	delete(model[sec][ptype].PolicyMap, oldPolicy)
-	model[sec][ptype].PolicyMap[strings.Join(newRule, DefaultSep)] = index
+	model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(newRule)] = index
+	model[sec][ptype].indexRule(newRule)
 
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, [][]string{stored})
+	model.notifyPolicyChanged(sec, ptype, PolicyAdd, [][]string{newRule})
 	return true, nil
 }
 
 // UpdatePolicies updates a policy rule from the model.
 func (model Model) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) (bool, error) {
+	affected, err := model.UpdatePoliciesWithAffected(sec, ptype, oldRules, newRules)
+	// Updating no rule succeeds.
+	return err == nil && (len(affected) != 0 || len(oldRules) == 0), err
+}
+
+// PolicyUpdate is the replacement of a rule of the policy, see UpdatePoliciesWithAffected.
+type PolicyUpdate struct {
+	OldRule []string
+	NewRule []string
+	// Index is the index of NewRule in the policy once all the rules are replaced.
+	Index int
+}
+
+// UpdatePoliciesWithAffected replaces each of oldRules by the new rule at the same position in newRules,
+// and returns the replacements, in order. Each new rule takes the index in the policy of the rule it
+// replaces. If any of oldRules is not in the policy, nothing is updated and no replacement is returned.
+func (model Model) UpdatePoliciesWithAffected(sec string, ptype string, oldRules, newRules [][]string) ([]PolicyUpdate, error) {
 	_, err := model.GetAssertion(sec, ptype)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	rollbackFlag := false
 	// index -> []{oldIndex, newIndex}
@@ -335,31 +832,45 @@ func (model Model) UpdatePolicies(sec string, ptype string, oldRules, newRules [
 		if rollbackFlag {
 			for index, oldNewIndex := range modifiedRuleIndex {
 				model[sec][ptype].Policy[index] = oldRules[oldNewIndex[0]]
-				oldPolicy := strings.Join(oldRules[oldNewIndex[0]], DefaultSep)
-				newPolicy := strings.Join(newRules[oldNewIndex[1]], DefaultSep)
+				oldPolicy := model[sec][ptype].policyKey(oldRules[oldNewIndex[0]])
+				newPolicy := model[sec][ptype].policyKey(newRules[oldNewIndex[1]])
 				delete(model[sec][ptype].PolicyMap, newPolicy)
 				model[sec][ptype].PolicyMap[oldPolicy] = index
 			}
+			model[sec][ptype].rebuildFieldIndexes()
 		}
 	}()
 
+	var affected []PolicyUpdate
 	newIndex := 0
 	for oldIndex, oldRule := range oldRules {
-		oldPolicy := strings.Join(oldRule, DefaultSep)
+		oldPolicy := model[sec][ptype].policyKey(oldRule)
 		index, ok := model[sec][ptype].PolicyMap[oldPolicy]
 		if !ok {
 			rollbackFlag = true
-			return false, nil
+			return nil, nil
 		}
 
+		stored := model[sec][ptype].Policy[index]
+		model[sec][ptype].unindexRule(stored)
 		model[sec][ptype].Policy[index] = newRules[newIndex]
 		delete(model[sec][ptype].PolicyMap, oldPolicy)
-		model[sec][ptype].PolicyMap[strings.Join(newRules[newIndex], DefaultSep)] = index
+		model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(newRules[newIndex])] = index
+		model[sec][ptype].indexRule(newRules[newIndex])
 		modifiedRuleIndex[index] = []int{oldIndex, newIndex}
+		affected = append(affected, PolicyUpdate{OldRule: stored, NewRule: newRules[newIndex]})
 		newIndex++
 	}
 
-	return true, nil
+	removed := make([][]string, len(affected))
+	added := make([][]string, len(affected))
+	for i, update := range affected {
+		removed[i], added[i] = update.OldRule, update.NewRule
+		affected[i].Index = model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(update.NewRule)]
+	}
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, removed)
+	model.notifyPolicyChanged(sec, ptype, PolicyAdd, added)
+	return affected, nil
 }
 
 // RemovePolicies removes policy rules from the model.
@@ -376,46 +887,165 @@ func (model Model) RemovePoliciesWithAffected(sec string, ptype string, rules []
 	}
 	var affected [][]string
 	for _, rule := range rules {
-		index, ok := model[sec][ptype].PolicyMap[strings.Join(rule, DefaultSep)]
+		index, ok := model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(rule)]
 		if !ok {
 			continue
 		}
 
 		affected = append(affected, rule)
+		model[sec][ptype].unindexRule(model[sec][ptype].Policy[index])
 		model[sec][ptype].Policy = append(model[sec][ptype].Policy[:index], model[sec][ptype].Policy[index+1:]...)
-		delete(model[sec][ptype].PolicyMap, strings.Join(rule, DefaultSep))
+		delete(model[sec][ptype].PolicyMap, model[sec][ptype].policyKey(rule))
 		for i := index; i < len(model[sec][ptype].Policy); i++ {
-			model[sec][ptype].PolicyMap[strings.Join(model[sec][ptype].Policy[i], DefaultSep)] = i
+			model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(model[sec][ptype].Policy[i])] = i
 		}
 	}
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, affected)
 	return affected, nil
 }
 
+// RemovePoliciesStrict removes policy rules from the model, all or none: if any of the rules does not exist,
+// or is given more than once, an error is returned and the model is left unchanged.
+func (model Model) RemovePoliciesStrict(sec string, ptype string, rules [][]string) error {
+	seen := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		ok, err := model.HasPolicy(sec, ptype, rule)
+		if err != nil {
+			return err
+		}
+		key := model[sec][ptype].policyKey(rule)
+		if _, duplicate := seen[key]; duplicate {
+			return fmt.Errorf("policy rule %v cannot be removed from %s: it is given more than once", rule, ptype)
+		}
+		if !ok {
+			return fmt.Errorf("policy rule %v cannot be removed from %s: it does not exist", rule, ptype)
+		}
+		seen[key] = struct{}{}
+	}
+
+	_, err := model.RemovePoliciesWithAffected(sec, ptype, rules)
+	return err
+}
+
+// FindDuplicatePolicies returns the rules of a policy appearing more than once, each once in the order
+// of their first appearance. Rules are the same if they have the same policy key, see SetPolicyKeyFunc.
+func (model Model) FindDuplicatePolicies(sec string, ptype string) [][]string {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil
+	}
+
+	counts := make(map[string]int, len(ast.Policy))
+	var res [][]string
+	for _, rule := range ast.Policy {
+		key := ast.policyKey(rule)
+		counts[key]++
+		if counts[key] == 2 {
+			res = append(res, append([]string(nil), rule...))
+		}
+	}
+	return res
+}
+
+// DeduplicatePolicy removes the rules of a policy appearing more than once but their first occurrence,
+// and returns the number of rules removed.
+func (model Model) DeduplicatePolicy(sec string, ptype string) int {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return 0
+	}
+
+	seen := make(map[string]struct{}, len(ast.Policy))
+	policy := ast.Policy[:0]
+	for _, rule := range ast.Policy {
+		key := ast.policyKey(rule)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		policy = append(policy, rule)
+	}
+	removed := len(ast.Policy) - len(policy)
+	if removed == 0 {
+		return 0
+	}
+
+	for i := len(policy); i < len(ast.Policy); i++ {
+		ast.Policy[i] = nil
+	}
+	ast.Policy = policy
+	for i, rule := range ast.Policy {
+		ast.PolicyMap[ast.policyKey(rule)] = i
+	}
+	model.notifyPolicyReset(sec, ptype)
+	return removed
+}
+
+// RemoveExpiredPolicy removes the policy rules whose "expire" field holds an RFC 3339 time not after now,
+// and returns the number of removed rules. Rules with an empty expiry never expire, and policy types
+// without an "expire" field are left unchanged. Rules with an unparsable expiry are removed as well,
+// so that a malformed rule does not grant access forever, and reported by the returned error.
+func (model Model) RemoveExpiredPolicy(now time.Time) (int, error) {
+	removed := 0
+	var malformed []string
+	for ptype, ast := range model["p"] {
+		index, err := model.GetFieldIndex(ptype, constant.ExpireIndex)
+		if err != nil {
+			continue
+		}
+
+		var expired [][]string
+		for _, rule := range ast.Policy {
+			if index >= len(rule) {
+				continue
+			}
+			if rule[index] == "" {
+				continue
+			}
+			expire, err := time.Parse(time.RFC3339, rule[index])
+			if err != nil {
+				malformed = append(malformed, fmt.Sprintf("%s %v", ptype, rule))
+			}
+			if err != nil || !expire.After(now) {
+				expired = append(expired, rule)
+			}
+		}
+		affected, _ := model.RemovePoliciesWithAffected("p", ptype, expired)
+		removed += len(affected)
+	}
+	if len(malformed) != 0 {
+		sort.Strings(malformed)
+		return removed, fmt.Errorf("invalid expiry of the rules %s, removed as expired", strings.Join(malformed, ", "))
+	}
+	return removed, nil
+}
+
 // RemoveFilteredPolicy removes policy rules based on field filters from the model.
 func (model Model) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) (bool, [][]string, error) {
-	_, err := model.GetAssertion(sec, ptype)
+	ast, err := model.GetAssertion(sec, ptype)
 	if err != nil {
 		return false, nil, err
 	}
+	if positions, ok := ast.filterIndexed(fieldIndex, fieldValues); ok {
+		// The rules are collected before their positions are removed.
+		effects := ast.rulesAt(positions)
+		res := ast.removePositions(positions)
+		model.notifyPolicyChanged(sec, ptype, PolicyRemove, effects)
+		return res, effects, nil
+	}
+
 	var tmp [][]string
 	var effects [][]string
 	res := false
 	model[sec][ptype].PolicyMap = map[string]int{}
 
 	for _, rule := range model[sec][ptype].Policy {
-		matched := true
-		for i, fieldValue := range fieldValues {
-			if fieldValue != "" && rule[fieldIndex+i] != fieldValue {
-				matched = false
-				break
-			}
-		}
-
-		if matched {
+		if matchesFieldFilter(rule, fieldIndex, fieldValues) {
 			effects = append(effects, rule)
+			model[sec][ptype].unindexRule(rule)
 		} else {
 			tmp = append(tmp, rule)
-			model[sec][ptype].PolicyMap[strings.Join(rule, DefaultSep)] = len(tmp) - 1
+			model[sec][ptype].PolicyMap[model[sec][ptype].policyKey(rule)] = len(tmp) - 1
 		}
 	}
 
@@ -424,9 +1054,78 @@ func (model Model) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int
 		res = true
 	}
 
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, effects)
 	return res, effects, nil
 }
 
+// RemoveFilteredPolicyBatch removes in a single scan the policy rules that match any of the field filters
+// of valueSets, each given as the fieldValues of RemoveFilteredPolicy, e.g. one per subject to remove.
+// It returns the removed rules grouped by the first filter they match, in the order of valueSets.
+func (model Model) RemoveFilteredPolicyBatch(sec string, ptype string, fieldIndex int, valueSets [][]string) ([][][]string, error) {
+	ast, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil, err
+	}
+
+	effects := make([][][]string, len(valueSets))
+	var tmp, removed [][]string
+	ast.PolicyMap = map[string]int{}
+	for _, rule := range ast.Policy {
+		matched := false
+		for i, fieldValues := range valueSets {
+			if matchesFieldFilter(rule, fieldIndex, fieldValues) {
+				effects[i] = append(effects[i], rule)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			removed = append(removed, rule)
+			ast.unindexRule(rule)
+			continue
+		}
+		tmp = append(tmp, rule)
+		ast.PolicyMap[ast.policyKey(rule)] = len(tmp) - 1
+	}
+
+	if len(removed) != 0 {
+		ast.Policy = tmp
+	}
+	model.notifyPolicyChanged(sec, ptype, PolicyRemove, removed)
+	return effects, nil
+}
+
+// UpdateFilteredPolicies replaces the policy rules that match the field filters with newRules,
+// and returns the replaced rules. The new rules already in the policy are not added twice.
+// If no rule matches the filters, nothing is replaced and the new rules are not added.
+// If a new rule does not have as many fields as the assertion, or lacks a valid priority under
+// SetStrictPriority, the policy is left untouched.
+func (model Model) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	assertion, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil, err
+	}
+	arity := len(assertion.Tokens) + len(assertion.ParamsTokens)
+	for _, rule := range newRules {
+		if len(rule) != arity {
+			return nil, fmt.Errorf("rule %v should have %d fields to match %s", rule, arity, ptype)
+		}
+		// Adding can then only fail on the priority, which is checked before anything is removed.
+		if err = model.checkPriority(sec, ptype, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	_, oldRules, err := model.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	if err != nil || len(oldRules) == 0 {
+		return nil, err
+	}
+	if err = model.AddPolicies(sec, ptype, newRules); err != nil {
+		return nil, err
+	}
+	return oldRules, nil
+}
+
 // GetValuesForFieldInPolicy gets all values for a field for all rules in a policy, duplicated values are removed.
 func (model Model) GetValuesForFieldInPolicy(sec string, ptype string, fieldIndex int) ([]string, error) {
 	values := []string{}
@@ -445,6 +1144,27 @@ func (model Model) GetValuesForFieldInPolicy(sec string, ptype string, fieldInde
 	return values, nil
 }
 
+// GetFilteredValuesForFieldInPolicy gets all values for a field for the rules in a policy whose field filterIndex
+// is filterValue, e.g. the actions granted to a role, duplicated values are removed.
+func (model Model) GetFilteredValuesForFieldInPolicy(sec string, ptype string, fieldIndex int, filterIndex int, filterValue string) ([]string, error) {
+	values := []string{}
+
+	_, err := model.GetAssertion(sec, ptype)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range model[sec][ptype].Policy {
+		if rule[filterIndex] == filterValue {
+			values = append(values, rule[fieldIndex])
+		}
+	}
+
+	util.ArrayRemoveDuplicates(&values)
+
+	return values, nil
+}
+
 // GetValuesForFieldInPolicyAllTypes gets all values for a field for all rules in a policy of all ptypes, duplicated values are removed.
 func (model Model) GetValuesForFieldInPolicyAllTypes(sec string, fieldIndex int) ([]string, error) {
 	values := []string{}
@@ -483,3 +1203,24 @@ func (model Model) GetValuesForFieldInPolicyAllTypesByName(sec string, field str
 
 	return values, nil
 }
+
+// GetValuesForFieldInPolicyAllTypesByNameWithPtype gets all values for a field for all rules in a policy
+// of all ptypes by ptype, duplicated values are removed. The ptypes without the field are not in the result.
+func (model Model) GetValuesForFieldInPolicyAllTypesByNameWithPtype(sec string, field string) (map[string][]string, error) {
+	values := map[string][]string{}
+
+	for ptype := range model[sec] {
+		// GetFieldIndex will return (-1, err) if field is not found, ignore it
+		index, err := model.GetFieldIndex(ptype, field)
+		if err != nil {
+			continue
+		}
+		v, err := model.GetValuesForFieldInPolicy(sec, ptype, index)
+		if err != nil {
+			return nil, err
+		}
+		values[ptype] = v
+	}
+
+	return values, nil
+}
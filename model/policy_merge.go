@@ -0,0 +1,156 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// PolicyConflict is a rule of another model that was not merged by MergePolicyFrom,
+// as it only differs from an existing rule by its effect, and possibly its priority.
+type PolicyConflict struct {
+	Sec      string
+	PType    string
+	Existing []string
+	Incoming []string
+}
+
+// MergePolicyFrom adds the p and g rules of other to the model, skipping the rules already in the model.
+// A rule of a policy type with an "eft" field that only differs from an existing rule by its effect,
+// and possibly its priority, is not added but returned as a conflict for the caller to resolve.
+// The policy types of other must be defined the same way in the model, otherwise an error is returned
+// and the model is left unchanged. The policy types with a priority field are sorted by priority afterwards.
+func (model Model) MergePolicyFrom(other Model) ([]PolicyConflict, error) {
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range other[sec] {
+			assertion, err := model.GetAssertion(sec, ptype)
+			if err != nil {
+				return nil, err
+			}
+			if !util.ArrayEquals(assertion.Tokens, ast.Tokens) {
+				return nil, fmt.Errorf("%s is defined as %s, which differs from %s in the merged model", ptype, assertion.Value, ast.Value)
+			}
+		}
+	}
+
+	var conflicts []PolicyConflict
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(other[sec]))
+		for ptype := range other[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		for _, ptype := range ptypes {
+			ptypeConflicts, err := model.mergePolicy(sec, ptype, other[sec][ptype].Policy)
+			if err != nil {
+				return conflicts, err
+			}
+			conflicts = append(conflicts, ptypeConflicts...)
+		}
+	}
+	return conflicts, nil
+}
+
+// PolicyConflictKey returns a function giving the policy key of a rule of ptype without its fields named
+// by fields, e.g. constant.PriorityIndex, so that rules only differing by these fields have the same key.
+// The fields are found with GetFieldIndex and follow SetFieldIndex. The returned bool reports whether
+// any of the fields is in ptype; if not, the key is the policy key of the rule.
+func (model Model) PolicyConflictKey(sec string, ptype string, fields ...string) (func(rule []string) string, bool) {
+	ast := model[sec][ptype]
+	var indexes []int
+	if sec == "p" {
+		for _, field := range fields {
+			if index, err := model.GetFieldIndex(ptype, field); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	return func(rule []string) string {
+		if len(indexes) == 0 {
+			return ast.policyKey(rule)
+		}
+		rule = append([]string(nil), rule...)
+		for _, i := range indexes {
+			if i < len(rule) {
+				rule[i] = ""
+			}
+		}
+		return ast.policyKey(rule)
+	}, len(indexes) != 0
+}
+
+// mergePolicy adds the rules to the policy of ptype, except the conflicting ones which are returned.
+func (model Model) mergePolicy(sec string, ptype string, rules [][]string) ([]PolicyConflict, error) {
+	ast := model[sec][ptype]
+	eftIndex, priorityIndex := -1, -1
+	if sec == "p" {
+		if index, err := model.GetFieldIndex(ptype, constant.EffectIndex); err == nil {
+			eftIndex = index
+		}
+		if index, err := model.GetFieldIndex(ptype, constant.PriorityIndex); err == nil {
+			priorityIndex = index
+		}
+	}
+
+	// conflictKey is the key of a rule without its effect and priority.
+	conflictKey, _ := model.PolicyConflictKey(sec, ptype, constant.EffectIndex, constant.PriorityIndex)
+	existing := map[string][][]string{}
+	if eftIndex >= 0 {
+		for _, rule := range ast.Policy {
+			key := conflictKey(rule)
+			existing[key] = append(existing[key], rule)
+		}
+	}
+
+	var conflicts []PolicyConflict
+	var merged [][]string
+	for _, rule := range rules {
+		if _, ok := ast.PolicyMap[ast.policyKey(rule)]; ok {
+			continue
+		}
+		if eftIndex >= 0 && eftIndex < len(rule) {
+			key := conflictKey(rule)
+			if conflict := findEffectConflict(existing[key], rule, eftIndex); conflict != nil {
+				conflicts = append(conflicts, PolicyConflict{Sec: sec, PType: ptype, Existing: conflict, Incoming: rule})
+				continue
+			}
+			existing[key] = append(existing[key], rule)
+		}
+		merged = append(merged, append([]string(nil), rule...))
+	}
+
+	if _, err := model.AddPoliciesWithAffected(sec, ptype, merged); err != nil {
+		return conflicts, err
+	}
+	if sec == "p" && priorityIndex >= 0 {
+		return conflicts, model.SortPolicyByPriority(ptype)
+	}
+	return conflicts, nil
+}
+
+// findEffectConflict returns the first of rules whose effect differs from the one of rule, or nil.
+func findEffectConflict(rules [][]string, rule []string, eftIndex int) []string {
+	for _, r := range rules {
+		if r[eftIndex] != rule[eftIndex] {
+			return r
+		}
+	}
+	return nil
+}
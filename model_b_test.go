@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/casbin/casbin/v2/effector"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -149,6 +150,48 @@ func BenchmarkRBACModelSmall(b *testing.B) {
 	}
 }
 
+func benchmarkBatchEnforce(b *testing.B, concurrent bool) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", false)
+
+	pPolicies := make([][]string, 0)
+	for i := 0; i < 1000; i++ {
+		pPolicies = append(pPolicies, []string{fmt.Sprintf("group%d", i), fmt.Sprintf("data%d", i/10), "read"})
+	}
+	if _, err := e.AddPolicies(pPolicies); err != nil {
+		b.Fatal(err)
+	}
+
+	gPolicies := make([][]string, 0)
+	for i := 0; i < 10000; i++ {
+		gPolicies = append(gPolicies, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("group%d", i/10)})
+	}
+	if _, err := e.AddGroupingPolicies(gPolicies); err != nil {
+		b.Fatal(err)
+	}
+
+	requests := make([][]interface{}, 0, 100)
+	for i := 0; i < 100; i++ {
+		requests = append(requests, []interface{}{fmt.Sprintf("user%d", i*100), fmt.Sprintf("data%d", i), "read"})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if concurrent {
+			_, _ = e.BatchEnforceConcurrent(requests, 0)
+		} else {
+			_, _ = e.BatchEnforce(requests)
+		}
+	}
+}
+
+func BenchmarkBatchEnforce(b *testing.B) {
+	benchmarkBatchEnforce(b, false)
+}
+
+func BenchmarkBatchEnforceConcurrent(b *testing.B) {
+	benchmarkBatchEnforce(b, true)
+}
+
 func BenchmarkRBACModelMedium(b *testing.B) {
 	e, _ := NewEnforcer("examples/rbac_model.conf", false)
 
@@ -289,3 +332,34 @@ func BenchmarkRBACModelWithDomainPatternLarge(b *testing.B) {
 		_, _ = e.Enforce("staffUser1001", "/orgs/1/sites/site001", "App001.Module001.Action1001")
 	}
 }
+
+func BenchmarkAllowOverrideShortCircuit(b *testing.B) {
+	// 100000 rules, the request matching one of the first ones.
+	newEnforcer := func() *Enforcer {
+		e, _ := NewEnforcer("examples/basic_model.conf", false)
+		rules := make([][]string, 0, 100000)
+		for i := 0; i < 100000; i++ {
+			rules = append(rules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i), "read"})
+		}
+		if _, err := e.AddPolicies(rules); err != nil {
+			b.Fatal(err)
+		}
+		return e
+	}
+
+	b.Run("whole-policy-effects", func(b *testing.B) {
+		e := newEnforcer()
+		e.SetEffector(wrappedEffector{effector.NewDefaultEffector()})
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = e.Enforce("user10", "data10", "read")
+		}
+	})
+	b.Run("evaluated-rule-effects", func(b *testing.B) {
+		e := newEnforcer()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = e.Enforce("user10", "data10", "read")
+		}
+	})
+}
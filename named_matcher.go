@@ -0,0 +1,71 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// AddNamedMatcher compiles the matcher and stores it under name for EnforceWithNamedMatcher,
+// so that switching between several matchers at runtime, e.g. a normal and a break-glass one,
+// doesn't parse the matcher on each request. A matcher added under an existing name replaces it.
+// The compiled form is dropped when the matcher functions change, and compiled again on the next use.
+func (e *Enforcer) AddNamedMatcher(name string, matcher string) error {
+	if name == "" {
+		return errors.New("matcher name should not be empty")
+	}
+
+	expString := e.escapeMatcher(matcher)
+	functions := e.matcherFunctions()
+	hasEval := util.HasEval(expString)
+	if hasEval {
+		// The matcher is only checked, eval is bound to the request on each evaluation.
+		functions["eval"] = func(args ...interface{}) (interface{}, error) { return false, nil }
+	}
+	if _, err := e.getAndStoreMatcherExpression(hasEval, expString, functions); err != nil {
+		return fmt.Errorf("invalid matcher %s: %w", name, err)
+	}
+
+	e.namedMatchers.Store(name, matcher)
+	return nil
+}
+
+// EnforceWithNamedMatcher decides whether a "subject" can access a "object" with the operation "action"
+// like EnforceWithMatcher, using the matcher added by AddNamedMatcher under name.
+func (e *Enforcer) EnforceWithNamedMatcher(name string, rvals ...interface{}) (bool, error) {
+	matcher, ok := e.namedMatchers.Load(name)
+	if !ok {
+		return false, fmt.Errorf("matcher %s is not found", name)
+	}
+	return e.enforce(matcher.(string), nil, rvals...)
+}
+
+// escapedMatcherCacheSize is the number of escaped custom matchers memoized by escapeMatcher.
+const escapedMatcherCacheSize = 128
+
+// escapeMatcher returns the matcher escaped for govaluate and without its comments,
+// memoized as custom matchers are usually the same few ones over and over. Only the most recently used
+// ones are kept, so that matchers built per request, e.g. with the request values inlined, don't pile up.
+func (e *Enforcer) escapeMatcher(matcher string) string {
+	if expString, ok := e.escapedMatchers.Get(matcher); ok {
+		return expString.(string)
+	}
+	expString := util.RemoveComments(util.EscapeAssertion(matcher))
+	e.escapedMatchers.Put(matcher, expString)
+	return expString
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnforceWithNamedMatcher(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	if err := e.AddNamedMatcher("normal", "g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddNamedMatcher("break-glass", "r.sub == \"root\" || g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddNamedMatcher("broken", "r.sub == "); err == nil {
+		t.Error("a matcher that doesn't compile should not be added")
+	}
+	if err := e.AddNamedMatcher("", "r.sub == p.sub"); err == nil {
+		t.Error("a matcher without a name should not be added")
+	}
+
+	testEnforceWithNamedMatcher(t, e, "normal", "alice", "data2", "read", true)
+	testEnforceWithNamedMatcher(t, e, "normal", "root", "data1", "read", false)
+	testEnforceWithNamedMatcher(t, e, "break-glass", "root", "data1", "read", true)
+	testEnforceWithNamedMatcher(t, e, "break-glass", "bob", "data1", "read", false)
+
+	if _, err := e.EnforceWithNamedMatcher("missing", "alice", "data1", "read"); err == nil {
+		t.Error("enforcing with a missing matcher should fail")
+	}
+}
+
+func TestEnforceWithNamedMatcherFunctionChange(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	e.AddFunction("allowed", func(args ...interface{}) (interface{}, error) { return args[0] != "bob", nil })
+	matcher := "allowed(r.sub) && r.obj == p.obj && r.act == p.act"
+	if err := e.AddNamedMatcher("custom", matcher); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.matcherMap.Load(e.escapeMatcher(matcher)); !ok {
+		t.Error("the named matcher should be compiled when added")
+	}
+	testEnforceWithNamedMatcher(t, e, "custom", "alice", "data2", "write", true)
+
+	e.AddFunction("denied", func(args ...interface{}) (interface{}, error) { return false, nil })
+	if _, ok := e.matcherMap.Load(e.escapeMatcher(matcher)); ok {
+		t.Error("the compiled matcher should be dropped when the functions change")
+	}
+	testEnforceWithNamedMatcher(t, e, "custom", "alice", "data2", "write", true)
+	testEnforceWithNamedMatcher(t, e, "custom", "bob", "data2", "write", false)
+}
+
+func testEnforceWithNamedMatcher(t *testing.T, e *Enforcer, name string, sub, obj, act interface{}, res bool) {
+	t.Helper()
+	myRes, err := e.EnforceWithNamedMatcher(name, sub, obj, act)
+	if err != nil {
+		t.Errorf("EnforceWithNamedMatcher: %v", err)
+	} else if myRes != res {
+		t.Errorf("%s: %s, %v, %s: %t, supposed to be %t", name, sub, obj, act, myRes, res)
+	}
+}
+
+func TestEscapeMatcherBounded(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	first := `r.sub == "user0" && r.obj == p.obj && r.act == p.act`
+	for i := 0; i <= escapedMatcherCacheSize; i++ {
+		matcher := fmt.Sprintf(`r.sub == "user%d" && r.obj == p.obj && r.act == p.act`, i)
+		if _, err := e.EnforceWithMatcher(matcher, "alice", "data1", "read"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := e.escapedMatchers.Get(first); ok {
+		t.Error("the least recently used matcher should be dropped from the escaped matchers")
+	}
+}
@@ -15,7 +15,9 @@
 package persist
 
 import (
+	"bytes"
 	"encoding/csv"
+	"sort"
 	"strings"
 
 	"github.com/casbin/casbin/v2/model"
@@ -23,12 +25,17 @@ import (
 
 // LoadPolicyLine loads a text line as a policy rule to model.
 func LoadPolicyLine(line string, m model.Model) error {
+	return LoadPolicyLineWithSeparator(line, ',', m)
+}
+
+// LoadPolicyLineWithSeparator loads a text line whose fields are separated by sep, e.g. '\t', as a policy rule to model.
+func LoadPolicyLineWithSeparator(line string, sep rune, m model.Model) error {
 	if line == "" || strings.HasPrefix(line, "#") {
 		return nil
 	}
 
 	r := csv.NewReader(strings.NewReader(line))
-	r.Comma = ','
+	r.Comma = sep
 	r.Comment = '#'
 	r.TrimLeadingSpace = true
 
@@ -60,6 +67,39 @@ func LoadPolicyArray(rule []string, m model.Model) error {
 	return nil
 }
 
+// PolicyToText serializes the policy rules of the model to text, one "ptype, field, ..." line per rule,
+// policy rules first, followed by role inheritance rules.
+func PolicyToText(m model.Model) string {
+	return PolicyToTextWithSeparator(m, ',')
+}
+
+// PolicyToTextWithSeparator serializes the policy rules of the model to text like PolicyToText,
+// with the fields separated by sep, e.g. '\t'. A comma is followed by a space.
+func PolicyToTextWithSeparator(m model.Model, sep rune) string {
+	separator := string(sep)
+	if sep == ',' {
+		separator = ", "
+	}
+
+	var tmp bytes.Buffer
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		for _, ptype := range ptypes {
+			for _, rule := range m[sec][ptype].Policy {
+				tmp.WriteString(ptype + separator)
+				tmp.WriteString(strings.Join(rule, separator))
+				tmp.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimRight(tmp.String(), "\n")
+}
+
 // Adapter is the interface for Casbin adapters.
 type Adapter interface {
 	// LoadPolicy loads all policy rules from the storage.
@@ -0,0 +1,26 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+// PolicyChange is a change of the policy made in memory and not persisted yet, see ChangeAdapter.
+type PolicyChange = PolicyOperation
+
+// ChangeAdapter is the interface for Casbin adapters persisting the policy changes made since the last save
+// at once, so that saving doesn't rewrite the whole policy.
+type ChangeAdapter interface {
+	Adapter
+	// ApplyChanges persists the changes, in the order they were made in memory, all or none.
+	ApplyChanges(changes []PolicyChange) error
+}
@@ -16,20 +16,23 @@ package fileadapter
 
 import (
 	"bufio"
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
-	"github.com/casbin/casbin/v2/util"
 )
 
 // Adapter is the file adapter for Casbin.
 // It can load policy from file or save policy to file.
 type Adapter struct {
 	filePath string
+	// separator separates the fields of the lines of the file, a comma if zero.
+	separator rune
 }
 
 func (a *Adapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
@@ -49,13 +52,31 @@ func NewAdapter(filePath string) *Adapter {
 	return &Adapter{filePath: filePath}
 }
 
+// SetSeparator sets the separator of the fields of the lines of the file, e.g. '\t', a comma by default.
+// It only applies to the file, the rules in the model are the same whatever the separator.
+func (a *Adapter) SetSeparator(sep rune) {
+	a.separator = sep
+}
+
+func (a *Adapter) getSeparator() rune {
+	if a.separator == 0 {
+		return ','
+	}
+	return a.separator
+}
+
+// loadPolicyLine loads a line of the file as a policy rule to model.
+func (a *Adapter) loadPolicyLine(line string, model model.Model) error {
+	return persist.LoadPolicyLineWithSeparator(line, a.getSeparator(), model)
+}
+
 // LoadPolicy loads all policy rules from the storage.
 func (a *Adapter) LoadPolicy(model model.Model) error {
 	if a.filePath == "" {
 		return errors.New("invalid file path, file path cannot be empty")
 	}
 
-	return a.loadPolicyFile(model, persist.LoadPolicyLine)
+	return a.loadPolicyFile(model, a.loadPolicyLine)
 }
 
 // SavePolicy saves all policy rules to the storage.
@@ -64,25 +85,73 @@ func (a *Adapter) SavePolicy(model model.Model) error {
 		return errors.New("invalid file path, file path cannot be empty")
 	}
 
-	var tmp bytes.Buffer
+	return a.savePolicyFile(persist.PolicyToTextWithSeparator(model, a.getSeparator()))
+}
 
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			tmp.WriteString(ptype + ", ")
-			tmp.WriteString(util.ArrayToString(rule))
-			tmp.WriteString("\n")
-		}
+// LoadPolicyVersioned loads all policy rules from the storage and returns the version of the file,
+// which is the SHA-256 digest of its content. The file is digested while it is read line by line,
+// so it is never held in memory as a whole.
+func (a *Adapter) LoadPolicyVersioned(model model.Model) (string, error) {
+	if a.filePath == "" {
+		return "", errors.New("invalid file path, file path cannot be empty")
 	}
 
-	for ptype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			tmp.WriteString(ptype + ", ")
-			tmp.WriteString(util.ArrayToString(rule))
-			tmp.WriteString("\n")
-		}
+	f, err := os.Open(a.filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if err = loadPolicyReader(io.TeeReader(f, h), model, a.loadPolicyLine); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SavePolicyVersioned saves all policy rules to the storage if the file is still at version.
+// The check only guards against changes made before the save, not against a concurrent write.
+func (a *Adapter) SavePolicyVersioned(model model.Model, version string) (string, error) {
+	if a.filePath == "" {
+		return "", errors.New("invalid file path, file path cannot be empty")
+	}
+
+	actual, err := a.currentVersion()
+	if err != nil {
+		return "", err
+	}
+	if actual != version {
+		return "", &persist.VersionConflictError{Expected: version, Actual: actual}
 	}
 
-	return a.savePolicyFile(strings.TrimRight(tmp.String(), "\n"))
+	text := persist.PolicyToTextWithSeparator(model, a.getSeparator())
+	if err = a.savePolicyFile(text); err != nil {
+		return "", err
+	}
+	return fileVersion([]byte(text)), nil
+}
+
+// currentVersion digests the file without reading it into memory, a missing file is at the version of empty content.
+func (a *Adapter) currentVersion() (string, error) {
+	h := sha256.New()
+	f, err := os.Open(a.filePath)
+	if os.IsNotExist(err) {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 func (a *Adapter) loadPolicyFile(model model.Model, handler func(string, model.Model) error) error {
@@ -92,11 +161,15 @@ func (a *Adapter) loadPolicyFile(model model.Model, handler func(string, model.M
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	return loadPolicyReader(f, model, handler)
+}
+
+// loadPolicyReader passes the lines read from r to handler one at a time.
+func loadPolicyReader(r io.Reader, model model.Model, handler func(string, model.Model) error) error {
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		err = handler(line, model)
-		if err != nil {
+		if err := handler(line, model); err != nil {
 			return err
 		}
 	}
@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	"github.com/casbin/casbin/v2/model"
-	"github.com/casbin/casbin/v2/persist"
 )
 
 // FilteredAdapter is the filtered file adapter for Casbin. It can load policy
@@ -41,6 +40,9 @@ type Filter struct {
 	G3 []string
 	G4 []string
 	G5 []string
+	// PTypes are the policy types to load, e.g. p and g2, all of them if empty.
+	// Lines of other types are skipped before the field filters apply.
+	PTypes []string
 }
 
 // NewFilteredAdapter is the constructor for FilteredAdapter.
@@ -57,6 +59,12 @@ func (a *FilteredAdapter) LoadPolicy(model model.Model) error {
 	return a.Adapter.LoadPolicy(model)
 }
 
+// LoadPolicyVersioned loads all policy rules from the storage and returns the version of the file.
+func (a *FilteredAdapter) LoadPolicyVersioned(model model.Model) (string, error) {
+	a.filtered = false
+	return a.Adapter.LoadPolicyVersioned(model)
+}
+
 // LoadFilteredPolicy loads only policy rules that match the filter.
 func (a *FilteredAdapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
 	if filter == nil {
@@ -70,7 +78,7 @@ func (a *FilteredAdapter) LoadFilteredPolicy(model model.Model, filter interface
 	if !ok {
 		return errors.New("invalid filter type")
 	}
-	err := a.loadFilteredPolicyFile(model, filterValue, persist.LoadPolicyLine)
+	err := a.loadFilteredPolicyFile(model, filterValue, a.loadPolicyLine)
 	if err == nil {
 		a.filtered = true
 	}
@@ -88,7 +96,7 @@ func (a *FilteredAdapter) loadFilteredPolicyFile(model model.Model, filter *Filt
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
-		if filterLine(line, filter) {
+		if filterLine(line, filter, a.getSeparator()) {
 			continue
 		}
 
@@ -113,16 +121,28 @@ func (a *FilteredAdapter) SavePolicy(model model.Model) error {
 	return a.Adapter.SavePolicy(model)
 }
 
-func filterLine(line string, filter *Filter) bool {
+// SavePolicyVersioned saves all policy rules to the storage if the file is still at version.
+func (a *FilteredAdapter) SavePolicyVersioned(model model.Model, version string) (string, error) {
+	if a.filtered {
+		return "", errors.New("cannot save a filtered policy")
+	}
+	return a.Adapter.SavePolicyVersioned(model, version)
+}
+
+func filterLine(line string, filter *Filter, sep rune) bool {
 	if filter == nil {
 		return false
 	}
-	p := strings.Split(line, ",")
+	p := strings.Split(line, string(sep))
 	if len(p) == 0 {
 		return true
 	}
+	ptype := strings.TrimSpace(p[0])
+	if len(filter.PTypes) != 0 && !containsPType(filter.PTypes, ptype) {
+		return true
+	}
 	var filterSlice []string
-	switch strings.TrimSpace(p[0]) {
+	switch ptype {
 	case "p":
 		filterSlice = filter.P
 	case "g":
@@ -141,6 +161,15 @@ func filterLine(line string, filter *Filter) bool {
 	return filterWords(p, filterSlice)
 }
 
+func containsPType(ptypes []string, ptype string) bool {
+	for _, t := range ptypes {
+		if t == ptype {
+			return true
+		}
+	}
+	return false
+}
+
 func filterWords(line []string, filter []string) bool {
 	if len(line) < len(filter)+1 {
 		return true
@@ -0,0 +1,40 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+// IncrementalAdapter is the interface for Casbin adapters persisting the policy one rule at a time,
+// e.g. with one INSERT, DELETE or UPDATE statement per rule, without implementing BatchAdapter
+// nor UpdatableAdapter.
+//
+// The enforcer picks the adapter methods in this order:
+//   - with auto-save, the methods of IncrementalAdapter, or else the ones of Adapter, BatchAdapter
+//     and UpdatableAdapter matching the change, e.g. AddPolicy for AddPolicy and AddPolicies for AddPolicies;
+//   - for SavePolicyIncremental, ApplyChanges of ChangeAdapter, or else the methods of IncrementalAdapter,
+//     or else the ones of BatchAdapter and UpdatableAdapter, or else SavePolicy;
+//   - for a committed transaction, the methods of IncrementalAdapter, or else the ones of BatchAdapter
+//     and UpdatableAdapter, or else AddPolicy and RemovePolicy of Adapter.
+//
+// A method returning a "not implemented" error is skipped with auto-save, leaving the change in memory only,
+// and makes SavePolicyIncremental fall back to SavePolicy.
+type IncrementalAdapter interface {
+	Adapter
+
+	// AddPolicyRule adds a policy rule to the storage.
+	AddPolicyRule(sec string, ptype string, rule []string) error
+	// RemovePolicyRule removes a policy rule from the storage.
+	RemovePolicyRule(sec string, ptype string, rule []string) error
+	// UpdatePolicyRule updates a policy rule of the storage.
+	UpdatePolicyRule(sec string, ptype string, oldRule []string, newRule []string) error
+}
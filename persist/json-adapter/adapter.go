@@ -0,0 +1,113 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// Adapter is the JSON adapter for Casbin.
+// It can load policy from JSON or save policy to JSON. The JSON is an object mapping each ptype
+// to the array of its rules, each rule being an array of strings, e.g.
+// {"p": [["alice", "data1", "read"]], "g": [["alice", "admin"]]}.
+type Adapter struct {
+	JSON []byte
+}
+
+// NewAdapter is the constructor for Adapter.
+func NewAdapter(data []byte) *Adapter {
+	return &Adapter{
+		JSON: data,
+	}
+}
+
+// LoadPolicy loads all policy rules from the storage.
+func (a *Adapter) LoadPolicy(model model.Model) error {
+	if len(a.JSON) == 0 {
+		return errors.New("invalid JSON, JSON cannot be empty")
+	}
+
+	var policy map[string][][]string
+	if err := json.Unmarshal(a.JSON, &policy); err != nil {
+		return fmt.Errorf("invalid policy JSON, it should be an object mapping each ptype to an array of rules, each an array of strings: %w", err)
+	}
+
+	ptypes := make([]string, 0, len(policy))
+	for ptype := range policy {
+		ptypes = append(ptypes, ptype)
+	}
+	sort.Strings(ptypes)
+
+	for _, ptype := range ptypes {
+		if ptype == "" {
+			return errors.New("invalid policy JSON, ptype cannot be empty")
+		}
+		for i, rule := range policy[ptype] {
+			if len(rule) == 0 {
+				return fmt.Errorf("invalid policy JSON, rule %d of %s cannot be empty", i, ptype)
+			}
+			if err := persist.LoadPolicyArray(append([]string{ptype}, rule...), model); err != nil {
+				return fmt.Errorf("rule %d of %s: %w", i, ptype, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SavePolicy saves all policy rules to the storage.
+func (a *Adapter) SavePolicy(model model.Model) error {
+	policy := make(map[string][][]string)
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			if len(ast.Policy) != 0 {
+				policy[ptype] = ast.Policy
+			}
+		}
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	a.JSON = data
+	return nil
+}
+
+// GetPolicyJSON returns the policy JSON of the adapter, as loaded or as written by the last SavePolicy.
+func (a *Adapter) GetPolicyJSON() []byte {
+	return a.JSON
+}
+
+// AddPolicy adds a policy rule to the storage.
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return errors.New("not implemented")
+}
+
+// RemovePolicy removes a policy rule from the storage.
+func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return errors.New("not implemented")
+}
+
+// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
+func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errors.New("not implemented")
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonadapter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+const conf = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+g2 = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func Test_JSONRbac(t *testing.T) {
+	a := NewAdapter([]byte(`{
+		"p": [["alice", "data1", "read"], ["data_group_admin", "data, \"3\"", "write"]],
+		"g": [["alice", "data_group_admin"]],
+		"g2": [["alice", "admin", "domain1"]]
+	}`))
+	m, err := model.NewModelFromString(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer(m, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := e.Enforce("alice", "data1", "read"); !res {
+		t.Error("alice should read data1")
+	}
+	if res, _ := e.Enforce("alice", `data, "3"`, "write"); !res {
+		t.Error(`alice should write data, "3"`)
+	}
+	if ok, _ := e.HasNamedGroupingPolicy("g2", "alice", "admin", "domain1"); !ok {
+		t.Error("the g2 rule should be loaded")
+	}
+}
+
+func Test_SavePolicyJSON(t *testing.T) {
+	a := NewAdapter([]byte(`{"p": [["alice", "data1", "read"]]}`))
+	m, _ := model.NewModelFromString(conf)
+	e, _ := casbin.NewEnforcer(m, a)
+	_, _ = e.AddPolicy("bob", "data, 2", `"write"`)
+	_, _ = e.AddGroupingPolicy("bob", "data_group_admin")
+	_, _ = e.AddNamedGroupingPolicy("g2", "bob", "admin", "domain1")
+
+	if err := e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"g":[["bob","data_group_admin"]],"g2":[["bob","admin","domain1"]],` +
+		`"p":[["alice","data1","read"],["bob","data, 2","\"write\""]]}`
+	if string(a.GetPolicyJSON()) != expected {
+		t.Errorf("policy JSON: %s, supposed to be %s", a.GetPolicyJSON(), expected)
+	}
+
+	m, _ = model.NewModelFromString(conf)
+	e2, _ := casbin.NewEnforcer(m, NewAdapter(a.GetPolicyJSON()))
+	if res, _ := e2.Enforce("alice", "data1", "read"); !res {
+		t.Error("alice should read data1 after the round trip")
+	}
+	if res, _ := e2.Enforce("bob", "data, 2", `"write"`); !res {
+		t.Error("bob should write data, 2 after the round trip")
+	}
+	if ok, _ := e2.HasNamedGroupingPolicy("g2", "bob", "admin", "domain1"); !ok {
+		t.Error("the g2 rule should survive the round trip")
+	}
+}
+
+func Test_LoadPolicyMalformedJSON(t *testing.T) {
+	for data, message := range map[string]string{
+		``:                              "JSON cannot be empty",
+		`[["p", "alice", "data1"]]`:     "should be an object",
+		`{"p": ["alice", "data1"]}`:     "should be an object",
+		`{"p": [["alice", 1, "read"]]}`: "should be an object",
+		`{"p": [[]]}`:                   "rule 0 of p cannot be empty",
+		`{"p3": [["alice", "data1"]]}`:  "rule 0 of p3",
+		`{"p": [["alice"`:               "should be an object",
+	} {
+		m, _ := model.NewModelFromString(conf)
+		err := NewAdapter([]byte(data)).LoadPolicy(m)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("loading %s: %v, supposed to contain %q", data, err, message)
+		}
+	}
+}
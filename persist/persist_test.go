@@ -51,3 +51,24 @@ func TestDuplicateRuleInAdapter(t *testing.T) {
 
 	testRuleCount(t, e.GetModel(), 1, "p", "p", "LoadPolicyArray")
 }
+
+func TestLoadPolicyLineWithSeparator(t *testing.T) {
+	e, _ := casbin.NewEnforcer("../examples/basic_model.conf")
+
+	_ = persist.LoadPolicyLineWithSeparator("p\talice\tdata, with comma\tread", '\t', e.GetModel())
+	_ = persist.LoadPolicyLineWithSeparator("p\tbob\tdata2\twrite", '\t', e.GetModel())
+	testRuleCount(t, e.GetModel(), 2, "p", "p", "LoadPolicyLineWithSeparator")
+
+	ok, _ := e.HasPolicy("alice", "data, with comma", "read")
+	if !ok {
+		t.Error("a comma should be part of a field of a tab-separated line")
+	}
+
+	text := persist.PolicyToTextWithSeparator(e.GetModel(), '\t')
+	if text != "p\talice\tdata, with comma\tread\np\tbob\tdata2\twrite" {
+		t.Errorf("unexpected text: %q", text)
+	}
+	if text := persist.PolicyToText(e.GetModel()); text != "p, alice, data, with comma, read\np, bob, data2, write" {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
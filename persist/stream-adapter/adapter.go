@@ -0,0 +1,179 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamadapter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// Adapter is the streaming CSV adapter for Casbin, for policy files too large to be held in memory.
+// It has the same line format as the file adapter, "ptype, field, ...", and parses the stream with encoding/csv,
+// so a quoted field may hold the separator, quotes written twice or line breaks. The rules are added to the model
+// as they are read, and written to the file one at a time, so only the model itself grows with the size of the policy.
+type Adapter struct {
+	filePath string
+	open     func() (io.Reader, error)
+	// separator separates the fields of the lines, a comma if zero.
+	separator rune
+}
+
+// NewAdapter is the constructor for Adapter, loading the policy from and saving it to the file at filePath.
+func NewAdapter(filePath string) *Adapter {
+	return &Adapter{filePath: filePath}
+}
+
+// NewReaderAdapter returns an Adapter loading the policy from the reader returned by open, e.g. a network stream
+// or a decompressing reader. open is called on each load, so that the policy can be reloaded, and the reader
+// is closed after the load if it is an io.Closer. The policy cannot be saved.
+func NewReaderAdapter(open func() (io.Reader, error)) *Adapter {
+	return &Adapter{open: open}
+}
+
+// SetSeparator sets the separator of the fields of the lines, e.g. '\t', a comma by default.
+func (a *Adapter) SetSeparator(sep rune) {
+	a.separator = sep
+}
+
+func (a *Adapter) getSeparator() rune {
+	if a.separator == 0 {
+		return ','
+	}
+	return a.separator
+}
+
+// LoadPolicy loads all policy rules from the storage.
+func (a *Adapter) LoadPolicy(model model.Model) error {
+	if a.open != nil {
+		r, err := a.open()
+		if err != nil {
+			return err
+		}
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		return a.loadPolicyReader(r, model)
+	}
+	if a.filePath == "" {
+		return errors.New("invalid file path, file path cannot be empty")
+	}
+
+	f, err := os.Open(a.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return a.loadPolicyReader(f, model)
+}
+
+// loadPolicyReader adds the rules read from r to model one at a time.
+func (a *Adapter) loadPolicyReader(r io.Reader, model model.Model) error {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.Comma = a.getSeparator()
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	for {
+		rule, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Like the file adapter, which trims the lines, ignore the trailing spaces, the blank lines
+		// and the indented comments.
+		last := len(rule) - 1
+		rule[last] = strings.TrimRightFunc(rule[last], unicode.IsSpace)
+		if (last == 0 && rule[0] == "") || strings.HasPrefix(rule[0], "#") {
+			continue
+		}
+		if err = persist.LoadPolicyArray(rule, model); err != nil {
+			return err
+		}
+	}
+}
+
+// SavePolicy saves all policy rules to the storage.
+func (a *Adapter) SavePolicy(model model.Model) error {
+	if a.open != nil {
+		return errors.New("cannot save policy to a reader")
+	}
+	if a.filePath == "" {
+		return errors.New("invalid file path, file path cannot be empty")
+	}
+
+	f, err := os.Create(a.filePath)
+	if err != nil {
+		return err
+	}
+	if err = a.savePolicyWriter(f, model); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// savePolicyWriter writes the rules of model to w one at a time, policy rules first,
+// followed by role inheritance rules, quoting the fields as needed.
+func (a *Adapter) savePolicyWriter(w io.Writer, model model.Model) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = a.getSeparator()
+
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(model[sec]))
+		for ptype := range model[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		for _, ptype := range ptypes {
+			for _, rule := range model[sec][ptype].Policy {
+				if err := cw.Write(append([]string{ptype}, rule...)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AddPolicy adds a policy rule to the storage.
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return errors.New("not implemented")
+}
+
+// RemovePolicy removes a policy rule from the storage.
+func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return errors.New("not implemented")
+}
+
+// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
+func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errors.New("not implemented")
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamadapter
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+const conf = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+// policyGenerator generates a large policy on the fly, mostly copies of a single rule so that the model stays small,
+// with a distinct rule every 1000 lines. It is never held in memory as a whole, and fails the test if the rules
+// it has generated are not added to the model before it generates more, i.e. if the adapter buffers the policy.
+type policyGenerator struct {
+	t       *testing.T
+	m       model.Model
+	size    int
+	written int
+	lines   int
+	pending string
+}
+
+func (g *policyGenerator) Read(p []byte) (int, error) {
+	// Allow for the distinct rule being read, and the one buffered ahead.
+	if distinct := g.lines/1000 + 1; len(g.m["p"]["p"].Policy) < distinct-2 {
+		g.t.Fatalf("%d rules loaded after generating %d distinct ones, the policy is buffered", len(g.m["p"]["p"].Policy), distinct)
+	}
+	for g.pending == "" {
+		if g.written >= g.size {
+			return 0, io.EOF
+		}
+		if g.lines%1000 == 0 {
+			g.pending = fmt.Sprintf("p, \"user, %d\", \"data\n%d\", read\n", g.lines, g.lines)
+		} else {
+			g.pending = fmt.Sprintf("p, alice, \"%s, 1\", read\n", strings.Repeat("data", 50))
+		}
+		g.lines++
+	}
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	g.written += n
+	return n, nil
+}
+
+func TestLoadLargePolicy(t *testing.T) {
+	m, err := model.NewModelFromString(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &policyGenerator{t: t, m: m, size: 16 << 20}
+	open := func() (io.Reader, error) { return g, nil }
+	if err = NewReaderAdapter(open).LoadPolicy(m); err != nil {
+		t.Fatal(err)
+	}
+
+	distinct := (g.lines + 999) / 1000
+	if len(m["p"]["p"].Policy) != distinct+1 {
+		t.Fatalf("%d rules loaded, supposed to be %d", len(m["p"]["p"].Policy), distinct+1)
+	}
+	if rule := m["p"]["p"].Policy[0]; !reflect.DeepEqual(rule, []string{"user, 0", "data\n0", "read"}) {
+		t.Errorf("first rule: %q, supposed to be [\"user, 0\" \"data\\n0\" \"read\"]", rule)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.csv")
+	policy := `# a comment
+p, alice, data1, read
+
+p, bob, "data, 2", "write"
+  # an indented comment
+p, data_admin, "multi
+line", "say ""hi"""
+g, alice, data_admin
+`
+	if err = ioutil.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := model.NewModelFromString(conf)
+	e, err := casbin.NewEnforcer(m, NewAdapter(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data, 2", "write"},
+		{"data_admin", "multi\nline", `say "hi"`},
+	}
+	if policy, _ := e.GetPolicy(); !reflect.DeepEqual(policy, expected) {
+		t.Errorf("policy: %q, supposed to be %q", policy, expected)
+	}
+	if ok, _ := e.Enforce("alice", "multi\nline", `say "hi"`); !ok {
+		t.Error("alice should be allowed through data_admin")
+	}
+
+	// The saved policy loads back the same, quoted fields included.
+	if err = e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ := e.GetPolicy(); !reflect.DeepEqual(policy, expected) {
+		t.Errorf("policy after a save: %q, supposed to be %q", policy, expected)
+	}
+	if grouping, _ := e.GetGroupingPolicy(); !reflect.DeepEqual(grouping, [][]string{{"alice", "data_admin"}}) {
+		t.Errorf("grouping policy after a save: %q, supposed to be [[alice data_admin]]", grouping)
+	}
+
+	opened := 0
+	ra := NewReaderAdapter(func() (io.Reader, error) {
+		opened++
+		return strings.NewReader(policy), nil
+	})
+	if err = ra.SavePolicy(m); err == nil {
+		t.Error("saving to a reader should fail")
+	}
+	e, _ = casbin.NewEnforcer(m, ra)
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ := e.GetPolicy(); opened != 2 || !reflect.DeepEqual(policy, expected) {
+		t.Errorf("policy reloaded from %d readers: %q, supposed to be %q from 2", opened, policy, expected)
+	}
+	if err = NewReaderAdapter(func() (io.Reader, error) { return nil, io.ErrUnexpectedEOF }).LoadPolicy(m); err != io.ErrUnexpectedEOF {
+		t.Errorf("error: %v, supposed to be the error of the opener", err)
+	}
+	if err = NewAdapter("").LoadPolicy(m); err == nil {
+		t.Error("loading without a file path should fail")
+	}
+}
@@ -15,13 +15,11 @@
 package stringadapter
 
 import (
-	"bytes"
 	"errors"
 	"strings"
 
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
-	"github.com/casbin/casbin/v2/util"
 )
 
 // Adapter is the string adapter for Casbin.
@@ -55,26 +53,16 @@ func (a *Adapter) LoadPolicy(model model.Model) error {
 
 // SavePolicy saves all policy rules to the storage.
 func (a *Adapter) SavePolicy(model model.Model) error {
-	var tmp bytes.Buffer
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			tmp.WriteString(ptype + ", ")
-			tmp.WriteString(util.ArrayToString(rule))
-			tmp.WriteString("\n")
-		}
-	}
-
-	for ptype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			tmp.WriteString(ptype + ", ")
-			tmp.WriteString(util.ArrayToString(rule))
-			tmp.WriteString("\n")
-		}
-	}
-	a.Line = strings.TrimRight(tmp.String(), "\n")
+	a.Line = persist.PolicyToText(model)
 	return nil
 }
 
+// GetPolicyText returns the policy text of the adapter, as loaded or as written by the last SavePolicy.
+// It has the same format as the policy file written by the file adapter.
+func (a *Adapter) GetPolicyText() string {
+	return a.Line
+}
+
 // AddPolicy adds a policy rule to the storage.
 func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
 	return errors.New("not implemented")
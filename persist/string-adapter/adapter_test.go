@@ -99,3 +99,50 @@ g, alice, data_group_admin
 		t.Error("unexpected enforce result")
 	}
 }
+
+func Test_SavePolicyText(t *testing.T) {
+	conf := `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+p2 = sub, act
+
+[role_definition]
+g = _ , _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+	line := `
+p2, alice, read
+p, alice, data1, read
+g, alice, data_group_admin
+`
+	a := NewAdapter(line)
+	m := model.NewModel()
+	err := m.LoadModelFromText(conf)
+	if err != nil {
+		t.Errorf("load model from text failed: %v", err.Error())
+		return
+	}
+	e, _ := casbin.NewEnforcer(m, a)
+	_, _ = e.AddPolicy("bob", "data2", "write")
+	_, _ = e.RemoveGroupingPolicy("alice", "data_group_admin")
+	_, _ = e.AddGroupingPolicy("bob", "data_group_admin")
+
+	if err = e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `p, alice, data1, read
+p, bob, data2, write
+p2, alice, read
+g, bob, data_group_admin`
+	if a.GetPolicyText() != expected {
+		t.Errorf("policy text: %q, supposed to be %q", a.GetPolicyText(), expected)
+	}
+}
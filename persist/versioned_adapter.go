@@ -0,0 +1,44 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// VersionedAdapter is the interface for Casbin adapters supporting optimistic concurrency:
+// the storage has a version token that changes with every write, so that a save can detect
+// the policy being changed by someone else since it was loaded.
+type VersionedAdapter interface {
+	Adapter
+	// LoadPolicyVersioned loads all policy rules from the storage and returns the version of the storage.
+	LoadPolicyVersioned(model model.Model) (version string, err error)
+	// SavePolicyVersioned saves all policy rules to the storage if the storage is still at version,
+	// otherwise it fails with a *VersionConflictError. It returns the new version of the storage.
+	SavePolicyVersioned(model model.Model, version string) (newVersion string, err error)
+}
+
+// VersionConflictError is returned by SavePolicyVersioned when the storage changed since the policy was loaded.
+// The caller should reload the policy, reapply its changes and retry.
+type VersionConflictError struct {
+	Expected string // The version the policy was loaded at.
+	Actual   string // The current version of the storage.
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("policy version conflict: loaded version %q, storage is at version %q", e.Expected, e.Actual)
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// PolicyChangeEvent describes a change of the policy in memory, see SubscribePolicyChanges.
+type PolicyChangeEvent struct {
+	Op    persist.OperationType
+	Sec   string
+	Ptype string
+	// Rules are the added or removed rules, or the new rules of an update.
+	Rules [][]string
+	// OldRules are the rules replaced by an update.
+	OldRules [][]string
+	// Reset reports that the whole policy was replaced, e.g. by LoadPolicy, ClearPolicy or the commit
+	// of a transaction. The other fields are then empty, and the policy has to be read again.
+	Reset bool
+}
+
+// policySubscriber is a function subscribed by SubscribePolicyChanges.
+type policySubscriber struct {
+	id int
+	fn func(ev PolicyChangeEvent)
+}
+
+// SubscribePolicyChanges registers fn to be called synchronously after each change of the policy in memory
+// by the management API, with the affected rules, and returns the function unsubscribing it.
+// Subscribers are called in registration order, and must not modify the rules of the event.
+// With a SyncedEnforcer, they are called while it is locked, so they must not call its methods.
+// Unlike a Watcher, which propagates changes to other processes, subscribers are local to the enforcer.
+// Reloading or clearing the whole policy, e.g. by LoadPolicy or ClearPolicy, is reported as a Reset event.
+func (e *Enforcer) SubscribePolicyChanges(fn func(ev PolicyChangeEvent)) func() {
+	e.subscribersMutex.Lock()
+	defer e.subscribersMutex.Unlock()
+	e.nextSubscriberID++
+	id := e.nextSubscriberID
+	e.policySubscribers = append(e.policySubscribers, policySubscriber{id: id, fn: fn})
+
+	return func() {
+		e.subscribersMutex.Lock()
+		defer e.subscribersMutex.Unlock()
+		for i, s := range e.policySubscribers {
+			if s.id == id {
+				// Copy, as publishChange may be ranging over the previous slice.
+				e.policySubscribers = append(e.policySubscribers[:i:i], e.policySubscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publishChange is called after rules of the policy in memory changed. It records the change for
+// SavePolicyIncremental if it was not persisted by auto-save, updates the subject index,
+// and calls the subscribers of the policy changes.
+func (e *Enforcer) publishChange(opType persist.OperationType, sec string, ptype string, rules [][]string, oldRules [][]string) {
+	if len(rules) == 0 {
+		return
+	}
+	e.recordChange(opType, sec, ptype, rules, oldRules)
+	e.policyRevision++
+	e.updateSubjectIndex(opType, sec, ptype, rules, oldRules)
+	e.publishEvent(PolicyChangeEvent{Op: opType, Sec: sec, Ptype: ptype, Rules: rules, OldRules: oldRules})
+}
+
+// publishReset is called after the policy in memory was replaced as a whole by a new model, e.g. by LoadPolicy
+// or the commit of a transaction. It invalidates the policy indexes, and reports the reset to the callbacks
+// of model.OnPolicyChanged and to the subscribers of the policy changes.
+func (e *Enforcer) publishReset() {
+	e.invalidatePolicyIndexes()
+	e.model.NotifyPolicyReset()
+	e.publishEvent(PolicyChangeEvent{Reset: true})
+}
+
+// recordChange records a policy change that was not persisted by auto-save,
+// so that SavePolicyIncremental can write it to the adapter later.
+// Without change tracking, only the need for a full save is recorded.
+func (e *Enforcer) recordChange(opType persist.OperationType, sec string, ptype string, rules [][]string, oldRules [][]string) {
+	if e.adapter == nil || e.autoSave {
+		return
+	}
+	if !e.changeTracking {
+		e.pendingFullSave = true
+		return
+	}
+	e.pendingChanges = append(e.pendingChanges, persist.PolicyOperation{
+		Type:       opType,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      rules,
+		OldRules:   oldRules,
+	})
+}
+
+// publishEvent calls the subscribers of the policy changes with ev.
+func (e *Enforcer) publishEvent(ev PolicyChangeEvent) {
+	e.subscribersMutex.Lock()
+	subscribers := e.policySubscribers
+	e.subscribersMutex.Unlock()
+
+	for _, s := range subscribers {
+		s.fn(ev)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestSubscribePolicyChanges(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	var events []PolicyChangeEvent
+	var order []int
+	unsubscribe := e.SubscribePolicyChanges(func(ev PolicyChangeEvent) {
+		events = append(events, ev)
+		order = append(order, 1)
+	})
+	e.SubscribePolicyChanges(func(ev PolicyChangeEvent) {
+		order = append(order, 2)
+	})
+
+	_, _ = e.AddPolicy("carol", "data3", "read")
+	_, _ = e.AddPolicy("carol", "data3", "read")
+	_, _ = e.UpdatePolicy([]string{"carol", "data3", "read"}, []string{"carol", "data3", "write"})
+	_, _ = e.RemoveFilteredPolicy(0, "carol")
+	_, _ = e.AddGroupingPolicy("carol", "data2_admin")
+
+	expected := []PolicyChangeEvent{
+		{Op: persist.OperationAdd, Sec: "p", Ptype: "p", Rules: [][]string{{"carol", "data3", "read"}}},
+		{Op: persist.OperationUpdate, Sec: "p", Ptype: "p", Rules: [][]string{{"carol", "data3", "write"}}, OldRules: [][]string{{"carol", "data3", "read"}}},
+		{Op: persist.OperationRemove, Sec: "p", Ptype: "p", Rules: [][]string{{"carol", "data3", "write"}}},
+		{Op: persist.OperationAdd, Sec: "g", Ptype: "g", Rules: [][]string{{"carol", "data2_admin"}}},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("events: %v, supposed to be %v", events, expected)
+	}
+	for i, ev := range events {
+		if ev.Op != expected[i].Op || ev.Sec != expected[i].Sec || ev.Ptype != expected[i].Ptype ||
+			!util.Array2DEquals(ev.Rules, expected[i].Rules) || !util.Array2DEquals(ev.OldRules, expected[i].OldRules) {
+			t.Errorf("event %d: %v, supposed to be %v", i, ev, expected[i])
+		}
+	}
+	if fmt.Sprint(order) != "[1 2 1 2 1 2 1 2]" {
+		t.Errorf("subscribers should be called in registration order, got %v", order)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	_, _ = e.AddPolicy("dave", "data3", "read")
+	if len(events) != len(expected) {
+		t.Error("an unsubscribed function should not be called")
+	}
+	if len(order) != 9 || order[8] != 2 {
+		t.Errorf("the other subscriber should still be called, got %v", order)
+	}
+}
+
+func TestSubscribePolicyChangesRoleLinks(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	var allowed bool
+	e.SubscribePolicyChanges(func(ev PolicyChangeEvent) {
+		allowed, _ = e.Enforce("carol", "data2", "read")
+	})
+	_, _ = e.AddGroupingPolicy("carol", "data2_admin")
+	if !allowed {
+		t.Error("role links should be updated before the subscribers are called")
+	}
+}
+
+func TestSubscribePolicyChangesReset(t *testing.T) {
+	e, _ := NewTransactionalEnforcer("examples/rbac_model.conf", NewMockTransactionalAdapter())
+
+	resets, modelResets := 0, 0
+	e.SubscribePolicyChanges(func(ev PolicyChangeEvent) {
+		if ev.Reset {
+			if ev.Sec != "" || ev.Ptype != "" || ev.Rules != nil {
+				t.Errorf("reset event: %v, supposed to be empty", ev)
+			}
+			resets++
+		}
+	})
+	e.GetModel().OnPolicyChanged(func(sec string, ptype string, op model.PolicyOp, rules [][]string) {
+		if op == model.PolicyReset {
+			modelResets++
+		}
+	})
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ReloadPolicyAtomicSwap(); err != nil {
+		t.Fatal(err)
+	}
+	e.ClearPolicy()
+
+	tx, err := e.BeginTransaction(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tx.AddPolicy("alice", "data1", "read")
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if resets != 4 || modelResets != 4 {
+		t.Errorf("resets: %d to the subscribers and %d to the model, supposed to be 4", resets, modelResets)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultImportBatchSize is the number of rules added at once by ImportPolicyStream by default.
+const defaultImportBatchSize = 1000
+
+// ImportOptions configures ImportPolicyStream.
+type ImportOptions struct {
+	// Separator separates the fields of a line, ',' if zero.
+	Separator rune
+	// BatchSize is the maximum number of rules added at once, 1000 if zero.
+	BatchSize int
+	// Transform, if not nil, is called with each rule read and returns the rule to add,
+	// nil to skip the rule, or an error to abort the import.
+	Transform func(ptype string, rule []string) ([]string, error)
+	// Progress, if not nil, is called after each batch with the number of rules read so far, not counting
+	// the skipped ones, and the number of those added, i.e. not already in the policy.
+	Progress func(read int, added int)
+}
+
+// ImportPolicyStream adds the rules read from r, in the format of the file adapter, i.e. one "ptype, field, ..." rule
+// per line, to the current policy. The rules are read incrementally and added in batches of consecutive rules
+// of the same type, so that the import of a large policy aborts at the first invalid rule without reading the rest.
+// The rules added before the error are kept. Rules already in the policy are skipped.
+// It returns the number of rules added; the watcher, if any, is notified once at the end.
+func (e *Enforcer) ImportPolicyStream(r io.Reader, opts ImportOptions) (int, error) {
+	if opts.Separator == 0 {
+		opts.Separator = ','
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatchSize
+	}
+
+	var (
+		read, added int
+		ptype       string
+		batch       [][]string
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		before := len(e.model[ptype[:1]][ptype].Policy)
+		if _, err := e.addPoliciesWithoutNotify(ptype[:1], ptype, batch, true); err != nil {
+			return err
+		}
+		added += len(e.model[ptype[:1]][ptype].Policy) - before
+		batch = nil
+		if opts.Progress != nil {
+			opts.Progress(read, added)
+		}
+		return nil
+	}
+
+	err := e.readPolicyStream(r, opts, func(rulePtype string, rule []string) error {
+		if rulePtype != ptype || len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			ptype = rulePtype
+		}
+		read++
+		batch = append(batch, rule)
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	if added != 0 && e.shouldNotify() {
+		if notifyErr := e.notifySavePolicy(); err == nil {
+			err = notifyErr
+		}
+	}
+	return added, err
+}
+
+// readPolicyStream parses the rules of r and calls handle with each valid rule after transform.
+func (e *Enforcer) readPolicyStream(r io.Reader, opts ImportOptions, handle func(ptype string, rule []string) error) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.Comma = opts.Separator
+		reader.TrimLeadingSpace = true
+		tokens, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		ptype, rule := tokens[0], tokens[1:]
+		if ptype == "" {
+			return fmt.Errorf("line %d: missing policy type", n)
+		}
+		if sec := ptype[:1]; sec != "p" && sec != "g" {
+			return fmt.Errorf("line %d: %s is not a policy or grouping policy type", n, ptype)
+		}
+		if _, err = e.model.GetAssertion(ptype[:1], ptype); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if opts.Transform != nil {
+			if rule, err = opts.Transform(ptype, rule); err != nil {
+				return fmt.Errorf("line %d: %w", n, err)
+			}
+			if rule == nil {
+				continue
+			}
+		}
+		if err = e.validateMergedRules(ptype, [][]string{rule}); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if err = handle(ptype, rule); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportPolicyStream(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf")
+
+	var progress [][2]int
+	added, err := e.ImportPolicyStream(strings.NewReader(`
+# comment
+p, Alice, data1, read
+p, BOB, data2, write
+p, alice, data1, read
+p, carol, data3, read
+g, Alice, data2_admin
+
+p, data2_admin, data2, read
+`), ImportOptions{
+		BatchSize: 2,
+		Transform: func(ptype string, rule []string) ([]string, error) {
+			if rule[0] == "carol" {
+				return nil, nil
+			}
+			for i := range rule {
+				rule[i] = strings.ToLower(rule[i])
+			}
+			return rule, nil
+		},
+		Progress: func(read int, added int) {
+			progress = append(progress, [2]int{read, added})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 4 {
+		t.Errorf("added rules: %d, supposed to be 4", added)
+	}
+	expected := [][2]int{{2, 2}, {3, 2}, {4, 3}, {5, 4}}
+	if len(progress) != len(expected) {
+		t.Fatalf("progress: %v, supposed to be %v", progress, expected)
+	}
+	for i := range expected {
+		if progress[i] != expected[i] {
+			t.Fatalf("progress: %v, supposed to be %v", progress, expected)
+		}
+	}
+	testEnforce(t, e, "alice", "data2", "read", true)
+	testEnforce(t, e, "bob", "data2", "write", true)
+	testEnforce(t, e, "carol", "data3", "read", false)
+}
+
+func TestImportPolicyStreamAbort(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf")
+
+	invalid := errors.New("invalid rule")
+	added, err := e.ImportPolicyStream(strings.NewReader(`p, alice, data1, read
+p, bob, data2, write
+p, eve, data1, read
+p, carol, data3, read
+`), ImportOptions{
+		BatchSize: 2,
+		Transform: func(ptype string, rule []string) ([]string, error) {
+			if rule[0] == "eve" {
+				return nil, invalid
+			}
+			return rule, nil
+		},
+	})
+	if !errors.Is(err, invalid) || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("error: %v, supposed to be the transform error at line 3", err)
+	}
+	if added != 0 {
+		t.Errorf("added rules: %d, supposed to be 0", added)
+	}
+
+	_, err = e.ImportPolicyStream(strings.NewReader("p, alice, data1\n"), ImportOptions{})
+	if err == nil {
+		t.Error("rule of a wrong size should be an error")
+	}
+	_, err = e.ImportPolicyStream(strings.NewReader("p2, alice, data1, read\n"), ImportOptions{})
+	if err == nil {
+		t.Error("unknown policy type should be an error")
+	}
+	_, err = e.ImportPolicyStream(strings.NewReader("p, alice, data1, read\nr, alice, data1, read\n"), ImportOptions{})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("error: %v, supposed to be the request definition error at line 2", err)
+	}
+	if len(e.GetModel()["r"]["r"].Policy) != 0 {
+		t.Errorf("request definition rules: %v, supposed to be empty", e.GetModel()["r"]["r"].Policy)
+	}
+}
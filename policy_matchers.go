@@ -0,0 +1,236 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/effector"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// EnablePolicyMatchers enables or disables evaluating each policy type with its own matcher,
+// m for p, m2 for p2 and so on, so that one enforcer can handle heterogeneous permission schemes,
+// e.g. RESTful rules in p matched with keyMatch and exact rules in p2.
+//
+// When enabled, Enforce evaluates the rules of every policy type against the request "r" with the matcher of the
+// same suffix, and the top-level effect "e" merges the effects of all of them as if they were a single policy:
+// the rules of p come first, then the ones of p2 and so on. For example, with "some(where (p.eft == allow))"
+// a request is allowed if any policy type allows it, and with "!some(where (p.eft == deny))" it is denied if any
+// policy type denies it. An EnforceContext or a custom matcher bypasses the pairing.
+//
+// Every policy type must have a matcher and every matcher a policy type, and a matcher must not reference
+// the tokens of another policy type. The pairing is checked when it is enabled and when the model is loaded.
+func (e *Enforcer) EnablePolicyMatchers(enable bool) error {
+	if enable {
+		if _, err := e.policyMatcherTypes(); err != nil {
+			return err
+		}
+	}
+	e.policyMatchers = enable
+	return nil
+}
+
+// policyMatcherTypes checks the pairing of the policy types with the matchers and returns the policy types in order.
+func (e *Enforcer) policyMatcherTypes() ([]string, error) {
+	pTypes := make([]string, 0, len(e.model["p"]))
+	for pType := range e.model["p"] {
+		pTypes = append(pTypes, pType)
+	}
+	sort.Slice(pTypes, func(i, j int) bool {
+		if len(pTypes[i]) != len(pTypes[j]) {
+			return len(pTypes[i]) < len(pTypes[j])
+		}
+		return pTypes[i] < pTypes[j]
+	})
+
+	for _, pType := range pTypes {
+		mType := "m" + strings.TrimPrefix(pType, "p")
+		assertion, ok := e.model["m"][mType]
+		if !ok {
+			return nil, fmt.Errorf("policy type %s has no matcher %s", pType, mType)
+		}
+		if joined := e.getJoinedPolicyTypes(assertion.Value, pType); len(joined) != 0 {
+			sort.Strings(joined)
+			return nil, fmt.Errorf("matcher %s of policy type %s references policy type %s", mType, pType, strings.Join(joined, ", "))
+		}
+	}
+	for mType := range e.model["m"] {
+		pType := "p" + strings.TrimPrefix(mType, "m")
+		if _, ok := e.model["p"][pType]; !ok {
+			return nil, fmt.Errorf("matcher %s has no policy type %s", mType, pType)
+		}
+	}
+	return pTypes, nil
+}
+
+// enforceWithPolicyMatchers decides whether the request is allowed by evaluating every policy type
+// with its own matcher, see EnablePolicyMatchers.
+func (e *Enforcer) enforceWithPolicyMatchers(ctx context.Context, explains *[]string, matched *[][]string, effects *[]effector.Effect, rvals ...interface{}) (bool, error) {
+	pTypes, err := e.policyMatcherTypes()
+	if err != nil {
+		return false, err
+	}
+
+	rAssertion, err := e.model.GetAssertion("r", "r")
+	if err != nil {
+		return false, err
+	}
+	if len(rAssertion.Tokens) != len(rvals) {
+		return false, fmt.Errorf(
+			"invalid request size: expected %d, got %d, rvals: %v",
+			len(rAssertion.Tokens),
+			len(rvals),
+			rvals)
+	}
+	rTokens := make(map[string]int, len(rAssertion.Tokens))
+	for i, token := range rAssertion.Tokens {
+		rTokens[token] = i
+	}
+	e.parseJsonRequest(rvals)
+
+	matchers := make([]string, len(pTypes))
+	for i, pType := range pTypes {
+		matchers[i] = e.model["m"]["m"+strings.TrimPrefix(pType, "p")].Value
+	}
+	expString := strings.Join(matchers, "; ")
+	if e.isDenyOverridden(rTokens, rvals) {
+		e.logger.LogEnforce(expString, rvals, false, nil)
+		return false, nil
+	}
+
+	policyLen := 0
+	for _, pType := range pTypes {
+		policyLen += len(e.model["p"][pType].Policy)
+	}
+	effectExpr := e.model["e"]["e"].Value
+
+	var effect effector.Effect
+	explainIndex := -1
+	if policyLen == 0 {
+		// Without any rule, the decision is the one of the effect when no rule matches.
+		effect, explainIndex, err = e.eft.MergeEffects(effectExpr, []effector.Effect{effector.Indeterminate}, []float64{0}, 0, 1)
+		if err != nil {
+			return false, err
+		}
+		if effects != nil {
+			*effects = append(*effects, effector.Indeterminate)
+		}
+	}
+
+	rules := make([][]string, 0, policyLen)
+	policyEffects := make([]effector.Effect, policyLen)
+	matcherResults := make([]float64, policyLen)
+	decided := false
+	functions := e.matcherFunctions()
+	policyIndex := 0
+
+	for i, pType := range pTypes {
+		if decided && matched == nil {
+			break
+		}
+		assertion := e.model["p"][pType]
+		pTokens := make(map[string]int, len(assertion.Tokens))
+		for j, token := range assertion.Tokens {
+			pTokens[token] = j
+		}
+		hasEval := util.HasEval(matchers[i])
+		parameters := enforceParameters{
+			ctx:          ctx,
+			rTokens:      rTokens,
+			rVals:        rvals,
+			pTokens:      pTokens,
+			subjectRoles: e.newSubjectRoles(matchers[i], hasEval, "r", rTokens, rvals),
+		}
+		if hasEval {
+			functions["eval"] = generateEvalFunction(functions, &parameters)
+		}
+		expression, err := e.getContextMatcherExpression(ctx, hasEval, matchers[i], functions)
+		if err != nil {
+			return false, err
+		}
+
+		for _, pvals := range assertion.Policy {
+			if decided && matched == nil {
+				break
+			}
+			if len(assertion.Tokens) != len(pvals) {
+				return false, fmt.Errorf(
+					"invalid policy size: expected %d, got %d, pvals: %v",
+					len(assertion.Tokens),
+					len(pvals),
+					pvals)
+			}
+			rules = append(rules, pvals)
+
+			result, err := evalWithJoinedPolicies(expression, &parameters, pvals, nil)
+			if err != nil {
+				return false, err
+			}
+			switch result := result.(type) {
+			case bool:
+				if result {
+					matcherResults[policyIndex] = 1
+				}
+			case float64:
+				if result != 0 {
+					matcherResults[policyIndex] = 1
+				}
+			default:
+				return false, errors.New("matcher result should be bool, int or float")
+			}
+
+			if matched != nil && matcherResults[policyIndex] == 1 {
+				*matched = append(*matched, pvals)
+			}
+			if !decided {
+				policyEffects[policyIndex] = effector.Allow
+				if j, ok := pTokens[pType+"_eft"]; ok {
+					switch pvals[j] {
+					case "allow":
+					case "deny":
+						policyEffects[policyIndex] = effector.Deny
+					default:
+						policyEffects[policyIndex] = effector.Indeterminate
+					}
+				}
+				if effects != nil {
+					*effects = append(*effects, matchedEffect(policyEffects[policyIndex], matcherResults[policyIndex]))
+				}
+
+				effect, explainIndex, err = e.eft.MergeEffects(effectExpr, policyEffects, matcherResults, policyIndex, policyLen)
+				if err != nil {
+					return false, err
+				}
+				decided = effect != effector.Indeterminate
+			}
+			policyIndex++
+		}
+	}
+
+	var logExplains [][]string
+	if explains != nil && explainIndex != -1 && explainIndex < len(rules) {
+		*explains = rules[explainIndex]
+		logExplains = append(logExplains, *explains)
+	}
+
+	result := effect == effector.Allow
+	e.logger.LogEnforce(expString, rvals, result, logExplains)
+	return result, nil
+}
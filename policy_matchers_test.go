@@ -0,0 +1,99 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestPolicyMatchers(t *testing.T) {
+	e, _ := NewEnforcer("examples/policy_matchers_model.conf", "examples/policy_matchers_policy.csv")
+
+	// Only m and p are used by default.
+	testEnforce(t, e, "alice", "/alice_data/1", "GET", true)
+	testEnforce(t, e, "bob", "/reports/2026", "read", false)
+
+	if err := e.EnablePolicyMatchers(true); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "alice", "/alice_data/1", "GET", true)
+	testEnforce(t, e, "carol", "/data/1", "POST", true)
+	testEnforce(t, e, "bob", "/reports/2026", "read", true)
+	testEnforce(t, e, "bob", "/reports/2025", "read", false)
+	// p2 rules are matched exactly, with no pattern.
+	testEnforce(t, e, "alice", "/reports/2026", "read", false)
+	testEnforce(t, e, "alice", "/reports/*", "read", true)
+
+	ok, explain, err := e.EnforceEx("bob", "/reports/2026", "read")
+	if err != nil || !ok || !util.ArrayEquals(explain, []string{"bob", "/reports/2026", "read"}) {
+		t.Errorf("EnforceEx: %t, %v, %v", ok, explain, err)
+	}
+
+	// An EnforceContext bypasses the pairing.
+	ok, err = e.Enforce(EnforceContext{RType: "r", PType: "p", EType: "e", MType: "m"}, "bob", "/reports/2026", "read")
+	if err != nil || ok {
+		t.Errorf("enforcing with an EnforceContext should only use p and m: %t, %v", ok, err)
+	}
+}
+
+func TestPolicyMatchersEffect(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+p2 = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && r.act == p.act
+m2 = r.sub == p2.sub && r.obj == p2.obj && r.act == p2.act
+`)
+	e, _ := NewEnforcer(m)
+	_ = e.EnablePolicyMatchers(true)
+	_, _ = e.AddPolicy("alice", "/data/*", "read", "allow")
+	_, _ = e.AddNamedPolicy("p2", "alice", "/data/secret", "read", "deny")
+
+	testEnforce(t, e, "alice", "/data/public", "read", true)
+	// A deny of p2 overrides an allow of p.
+	testEnforce(t, e, "alice", "/data/secret", "read", false)
+	testEnforce(t, e, "bob", "/data/public", "read", false)
+}
+
+func TestPolicyMatchersPairing(t *testing.T) {
+	e, _ := NewEnforcer("examples/multiple_policy_definitions_model.conf", "examples/multiple_policy_definitions_policy.csv")
+	if err := e.EnablePolicyMatchers(true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.GetModel().AddDef("p", "p2", "sub, obj, act")
+	if err := e.EnablePolicyMatchers(true); err == nil {
+		t.Error("policy type without a matcher should be an error")
+	}
+
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.GetModel().AddDef("p", "p2", "sub, obj, act")
+	e.GetModel().AddDef("m", "m2", "r.sub == p2.sub && r.obj == p.obj")
+	if err := e.EnablePolicyMatchers(true); err == nil {
+		t.Error("matcher referencing another policy type should be an error")
+	}
+}
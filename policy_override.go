@@ -0,0 +1,107 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/rbac"
+)
+
+// EnforceWithPolicyOverride decides whether the request would be allowed if the policy types in rules,
+// e.g. "p" or "g", had the given rules instead of the loaded ones, to preview a policy change.
+// The other policy types keep their loaded rules. The enforcer is not modified: the role links of the
+// override are built into fresh role managers, which must implement rbac.RoleManagerCloner.
+// Conditional role managers are not supported.
+func (e *Enforcer) EnforceWithPolicyOverride(rules map[string][][]string, rvals ...interface{}) (bool, error) {
+	preview, err := e.policyOverride(rules)
+	if err != nil {
+		return false, err
+	}
+	return preview.Enforce(rvals...)
+}
+
+// policyOverride returns a transient enforcer sharing the settings of e, with the rules of the policy types
+// in rules replaced, see EnforceWithPolicyOverride.
+func (e *Enforcer) policyOverride(rules map[string][][]string) (*Enforcer, error) {
+	if len(e.condRmMap) != 0 {
+		return nil, fmt.Errorf("policy override is not supported with conditional role managers")
+	}
+
+	m := e.model.Copy()
+	m.SetPolicyChangedMuted(true)
+	for ptype, ptypeRules := range rules {
+		sec := ""
+		for _, s := range []string{"p", "g"} {
+			if _, ok := m[s][ptype]; ok {
+				sec = s
+			}
+		}
+		if sec == "" {
+			return nil, fmt.Errorf("policy type %s is not in the model", ptype)
+		}
+
+		if _, _, err := m.RemoveFilteredPolicy(sec, ptype, 0); err != nil {
+			return nil, err
+		}
+		if err := m.AddPolicies(sec, ptype, ptypeRules); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.SortPoliciesBySubjectHierarchy(); err != nil {
+		return nil, err
+	}
+	if err := m.SortPoliciesByPriority(); err != nil {
+		return nil, err
+	}
+
+	rmMap := make(map[string]rbac.RoleManager, len(e.rmMap))
+	for ptype, rm := range e.rmMap {
+		cloner, ok := rm.(rbac.RoleManagerCloner)
+		if _, conditional := rm.(rbac.ConditionalRoleManager); conditional || !ok {
+			return nil, fmt.Errorf("policy override is not supported with the role manager of %s: %T", ptype, rm)
+		}
+		rmMap[ptype] = cloner.CloneEmpty()
+	}
+	if err := m.BuildRoleLinks(rmMap); err != nil {
+		return nil, err
+	}
+
+	return e.withPolicy(m, rmMap), nil
+}
+
+// withPolicy returns a transient enforcer evaluating the requests against the model m and the role managers
+// rmMap with the enforcement settings of e. The settings affecting Enforce are all copied here.
+func (e *Enforcer) withPolicy(m model.Model, rmMap map[string]rbac.RoleManager) *Enforcer {
+	return &Enforcer{
+		model:             m,
+		fm:                e.fm,
+		contextFunctions:  e.contextFunctions,
+		eft:               e.eft,
+		rmMap:             rmMap,
+		enabled:           e.enabled,
+		acceptJsonRequest: e.acceptJsonRequest,
+		failurePolicy:     e.failurePolicy,
+		escapedMatchers:   e.escapedMatchers,
+		clock:             e.clock,
+		policyMatchers:    e.policyMatchers,
+		policyJoin:        e.policyJoin,
+		denyOverrideSets:  e.denyOverrideSets,
+		preMatcher:        e.preMatcher,
+		logger:            e.logger,
+		modelLogger:       e.modelLogger,
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestEnforceWithPolicyOverride(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	testOverride := func(rules map[string][][]string, sub, obj, act string, res bool) {
+		t.Helper()
+		myRes, err := e.EnforceWithPolicyOverride(rules, sub, obj, act)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if myRes != res {
+			t.Errorf("%s, %s, %s with %v: %t, supposed to be %t", sub, obj, act, rules, myRes, res)
+		}
+	}
+
+	policy, _ := e.GetPolicy()
+	addedRule := append([][]string{{"bob", "data1", "read"}}, policy...)
+	testOverride(map[string][][]string{"p": addedRule}, "bob", "data1", "read", true)
+	testOverride(map[string][][]string{"p": addedRule}, "alice", "data2", "read", true)
+	testOverride(map[string][][]string{"g": {}}, "alice", "data2", "read", false)
+	testOverride(map[string][][]string{"g": {{"bob", "data2_admin"}}}, "bob", "data2", "read", true)
+	testOverride(map[string][][]string{"p": {}}, "alice", "data1", "read", false)
+
+	// The enforcer itself is not modified.
+	testEnforce(t, e, "bob", "data1", "read", false)
+	testEnforce(t, e, "alice", "data2", "read", true)
+	testEnforce(t, e, "bob", "data2", "read", false)
+	if ok, _ := e.HasPolicy("bob", "data1", "read"); ok {
+		t.Error("override should not be added to the policy")
+	}
+
+	if _, err := e.EnforceWithPolicyOverride(map[string][][]string{"p9": {}}, "alice", "data1", "read"); err == nil {
+		t.Error("unknown policy type should be an error")
+	}
+}
+
+func TestEnforceWithPolicyOverrideSettings(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, start, end
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && allowed(r.obj) && r.act == p.act && timeMatch(p.start, p.end)
+`)
+	e, err := NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddContextFunction("allowed", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		if args[0] == "broken" {
+			return nil, errors.New("lookup failed")
+		}
+		return args[0] == "data1", nil
+	})
+	if err = e.ValidateMatchers(); err != nil {
+		t.Errorf("context functions should be known to the validation, got %v", err)
+	}
+	e.SetClock(func() time.Time { return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC) })
+	e.SetFailurePolicy(FailClosed)
+
+	rules := map[string][][]string{"p": {{"alice", "data1", "read", "2019-12-31T00:00:00Z", "2020-01-02T00:00:00Z"}}}
+	if ok, err := e.EnforceWithPolicyOverride(rules, "alice", "data1", "read"); err != nil || !ok {
+		t.Errorf("preview with the context function and clock of the enforcer: %t, %v, supposed to be true", ok, err)
+	}
+	if ok, err := e.EnforceWithPolicyOverride(rules, "alice", "broken", "read"); err != nil || ok {
+		t.Errorf("preview with the failure policy of the enforcer: %t, %v, supposed to be false without error", ok, err)
+	}
+}
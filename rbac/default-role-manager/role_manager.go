@@ -202,6 +202,7 @@ type RoleManagerImpl struct {
 	maxHierarchyLevel  int
 	matchingFunc       rbac.MatchingFunc
 	domainMatchingFunc rbac.MatchingFunc
+	domainCaptureFunc  rbac.DomainCaptureFunc
 	logger             log.Logger
 	matchingFuncCache  *util.SyncLRUCache
 	mutex              sync.Mutex
@@ -315,11 +316,40 @@ func (rm *RoleManagerImpl) AddDomainMatchingFunc(name string, fn rbac.MatchingFu
 	rm.domainMatchingFunc = fn
 }
 
+// SetDomainCaptureFunc restricts HasLink called with a domain to the roles of that domain,
+// for roles that encode their domain in the name: a role whose captured domain differs from
+// the requested one is neither matched nor inherited through. Roles without a captured domain are not restricted.
+func (rm *RoleManagerImpl) SetDomainCaptureFunc(fn rbac.DomainCaptureFunc) {
+	rm.domainCaptureFunc = fn
+}
+
+// inCapturedDomain checks whether the role belongs to the requested domain according to the domain capture func.
+func (rm *RoleManagerImpl) inCapturedDomain(name string, domains []string) bool {
+	if rm.domainCaptureFunc == nil || len(domains) == 0 {
+		return true
+	}
+	domain, ok := rm.domainCaptureFunc(name)
+	return !ok || domain == domains[0]
+}
+
 // SetLogger sets role manager's logger.
 func (rm *RoleManagerImpl) SetLogger(logger log.Logger) {
 	rm.logger = logger
 }
 
+// CloneEmpty returns a role manager configured like rm, without any link.
+func (rm *RoleManagerImpl) CloneEmpty() rbac.RoleManager {
+	return rm.cloneEmpty()
+}
+
+func (rm *RoleManagerImpl) cloneEmpty() *RoleManagerImpl {
+	c := newRoleManagerWithMatchingFunc(rm.maxHierarchyLevel, rm.matchingFunc)
+	c.domainMatchingFunc = rm.domainMatchingFunc
+	c.domainCaptureFunc = rm.domainCaptureFunc
+	c.logger = rm.logger
+	return c
+}
+
 // Clear clears all stored data and resets the role manager to the initial state.
 func (rm *RoleManagerImpl) Clear() error {
 	rm.matchingFuncCache = util.NewSyncLRUCache(100)
@@ -347,10 +377,24 @@ func (rm *RoleManagerImpl) DeleteLink(name1 string, name2 string, domains ...str
 
 // HasLink determines whether role: name1 inherits role: name2.
 func (rm *RoleManagerImpl) HasLink(name1 string, name2 string, domains ...string) (bool, error) {
+	if !rm.inCapturedDomain(name2, domains) {
+		return false, nil
+	}
+
 	if name1 == name2 || (rm.matchingFunc != nil && rm.Match(name1, name2)) {
 		return true, nil
 	}
 
+	if rm.matchingFunc == nil {
+		// Without pattern matching, a role that doesn't exist has no link, so the link is checked without
+		// creating any role nor locking, and concurrent checks, e.g. enforcements, don't serialize.
+		user, ok := rm.load(name1)
+		if !ok {
+			return false, nil
+		}
+		return rm.hasLinkHelper(name2, map[string]*Role{user.name: user}, rm.maxHierarchyLevel, domains), nil
+	}
+
 	// Lock to prevent race conditions between getRole and removeRole
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
@@ -365,10 +409,10 @@ func (rm *RoleManagerImpl) HasLink(name1 string, name2 string, domains ...string
 		defer rm.removeRole(role.name)
 	}
 
-	return rm.hasLinkHelper(role.name, map[string]*Role{user.name: user}, rm.maxHierarchyLevel), nil
+	return rm.hasLinkHelper(role.name, map[string]*Role{user.name: user}, rm.maxHierarchyLevel, domains), nil
 }
 
-func (rm *RoleManagerImpl) hasLinkHelper(targetName string, roles map[string]*Role, level int) bool {
+func (rm *RoleManagerImpl) hasLinkHelper(targetName string, roles map[string]*Role, level int, domains []string) bool {
 	if level < 0 || len(roles) == 0 {
 		return false
 	}
@@ -379,12 +423,48 @@ func (rm *RoleManagerImpl) hasLinkHelper(targetName string, roles map[string]*Ro
 			return true
 		}
 		role.rangeRoles(func(key, value interface{}) bool {
-			nextRoles[key.(string)] = value.(*Role)
+			if rm.inCapturedDomain(key.(string), domains) {
+				nextRoles[key.(string)] = value.(*Role)
+			}
 			return true
 		})
 	}
 
-	return rm.hasLinkHelper(targetName, nextRoles, level-1)
+	return rm.hasLinkHelper(targetName, nextRoles, level-1, domains)
+}
+
+// CountRoles counts the roles that a user inherits.
+func (rm *RoleManagerImpl) CountRoles(name string, domains ...string) (int, error) {
+	if rm.matchingFunc != nil {
+		// Roles reached through several patterns must only be counted once.
+		roles, err := rm.GetRoles(name, domains...)
+		return len(roles), err
+	}
+
+	user, created := rm.getRole(name)
+	if created {
+		defer rm.removeRole(user.name)
+	}
+	count := 0
+	user.roles.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count, nil
+}
+
+// CountUsers counts the users that inherits a role.
+func (rm *RoleManagerImpl) CountUsers(name string, domains ...string) (int, error) {
+	role, created := rm.getRole(name)
+	if created {
+		defer rm.removeRole(role.name)
+	}
+	count := 0
+	role.rangeUsers(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count, nil
 }
 
 // GetRoles gets the roles that a user inherits.
@@ -601,6 +681,19 @@ func (dm *DomainManager) rebuild() {
 	})
 }
 
+// CloneEmpty returns a role manager configured like dm, without any link.
+func (dm *DomainManager) CloneEmpty() rbac.RoleManager {
+	return dm.cloneEmpty()
+}
+
+func (dm *DomainManager) cloneEmpty() *DomainManager {
+	c := NewDomainManager(dm.maxHierarchyLevel)
+	c.matchingFunc = dm.matchingFunc
+	c.domainMatchingFunc = dm.domainMatchingFunc
+	c.logger = dm.logger
+	return c
+}
+
 // Clear clears all stored data and resets the role manager to the initial state.
 func (dm *DomainManager) Clear() error {
 	dm.rmMap = &sync.Map{}
@@ -734,6 +827,26 @@ func (dm *DomainManager) GetUsers(name string, domains ...string) ([]string, err
 	return rm.GetUsers(name, domains...)
 }
 
+// CountRoles counts the roles that a subject inherits.
+func (dm *DomainManager) CountRoles(name string, domains ...string) (int, error) {
+	domain, err := dm.getDomain(domains...)
+	if err != nil {
+		return 0, err
+	}
+	rm := dm.getRoleManager(domain, false)
+	return rm.CountRoles(name, domains...)
+}
+
+// CountUsers counts the users that inherits a subject.
+func (dm *DomainManager) CountUsers(name string, domains ...string) (int, error) {
+	domain, err := dm.getDomain(domains...)
+	if err != nil {
+		return 0, err
+	}
+	rm := dm.getRoleManager(domain, false)
+	return rm.CountUsers(name, domains...)
+}
+
 // GetImplicitRoles gets the implicit roles that a subject inherits, respecting maxHierarchyLevel.
 func (dm *DomainManager) GetImplicitRoles(name string, domains ...string) ([]string, error) {
 	domain, err := dm.getDomain(domains...)
@@ -828,6 +941,11 @@ func NewRoleManager(maxHierarchyLevel int) *RoleManager {
 	return rm
 }
 
+// CloneEmpty returns a role manager configured like rm, without any link.
+func (rm *RoleManager) CloneEmpty() rbac.RoleManager {
+	return &RoleManager{DomainManager: rm.DomainManager.cloneEmpty()}
+}
+
 // DeleteDomain does nothing for RoleManagerImpl (no domain concept).
 func (rm *RoleManagerImpl) DeleteDomain(domain string) error {
 	return errors.New("DeleteDomain is not supported by RoleManagerImpl (no domain concept)")
@@ -835,6 +953,7 @@ func (rm *RoleManagerImpl) DeleteDomain(domain string) error {
 
 type ConditionalRoleManager struct {
 	RoleManagerImpl
+	defaultLinkConditionFunc rbac.LinkConditionFunc
 }
 
 func (crm *ConditionalRoleManager) copyFrom(other *ConditionalRoleManager) {
@@ -851,6 +970,16 @@ func newConditionalRoleManagerWithMatchingFunc(maxHierarchyLevel int, fn rbac.Ma
 	return rm
 }
 
+// CloneEmpty returns a role manager configured like crm, without any link nor link condition.
+func (crm *ConditionalRoleManager) CloneEmpty() rbac.RoleManager {
+	c := newConditionalRoleManagerWithMatchingFunc(crm.maxHierarchyLevel, crm.matchingFunc)
+	c.domainMatchingFunc = crm.domainMatchingFunc
+	c.domainCaptureFunc = crm.domainCaptureFunc
+	c.defaultLinkConditionFunc = crm.defaultLinkConditionFunc
+	c.logger = crm.logger
+	return c
+}
+
 // NewConditionalRoleManager is the constructor for creating an instance of the
 // ConditionalRoleManager implementation.
 func NewConditionalRoleManager(maxHierarchyLevel int) *ConditionalRoleManager {
@@ -927,17 +1056,37 @@ func (crm *ConditionalRoleManager) checkLinkCondition(name1, name2 string, domai
 		if linkConditionFunc, existLinkCondition := crm.GetLinkConditionFunc(name1, name2); existLinkCondition {
 			params, _ := crm.GetLinkConditionFuncParams(name1, name2)
 			passLinkConditionFunc, err = linkConditionFunc(params...)
+		} else if crm.defaultLinkConditionFunc != nil {
+			if params, _ := crm.GetLinkConditionFuncParams(name1, name2); len(params) != 0 {
+				passLinkConditionFunc, err = crm.defaultLinkConditionFunc(params...)
+			}
 		}
 	} else {
 		if linkConditionFunc, existLinkCondition := crm.GetDomainLinkConditionFunc(name1, name2, domain[0]); existLinkCondition {
 			params, _ := crm.GetLinkConditionFuncParams(name1, name2, domain[0])
 			passLinkConditionFunc, err = linkConditionFunc(params...)
+		} else if crm.defaultLinkConditionFunc != nil {
+			if params, _ := crm.GetLinkConditionFuncParams(name1, name2, domain[0]); len(params) != 0 {
+				passLinkConditionFunc, err = crm.defaultLinkConditionFunc(params...)
+			}
 		}
 	}
 
 	return passLinkConditionFunc, err
 }
 
+// CountRoles counts the roles that a user inherits through links whose conditions hold.
+func (crm *ConditionalRoleManager) CountRoles(name string, domains ...string) (int, error) {
+	roles, err := crm.GetRoles(name, domains...)
+	return len(roles), err
+}
+
+// CountUsers counts the users that inherits a role through links whose conditions hold.
+func (crm *ConditionalRoleManager) CountUsers(name string, domains ...string) (int, error) {
+	users, err := crm.GetUsers(name, domains...)
+	return len(users), err
+}
+
 func (crm *ConditionalRoleManager) GetRoles(name string, domains ...string) ([]string, error) {
 	user, created := crm.getRole(name)
 	if created {
@@ -1139,6 +1288,14 @@ func (crm *ConditionalRoleManager) AddDomainLinkConditionFunc(userName, roleName
 	user.addLinkConditionFunc(role, domain, fn)
 }
 
+// SetDefaultLinkConditionFunc sets the LinkConditionFunc applied to every link that carries
+// parameters but has no LinkConditionFunc of its own. Unlike the per-link functions, it is
+// kept when the links are rebuilt, so a condition declared by the g assertion columns holds
+// across policy reloads. Passing nil removes it.
+func (crm *ConditionalRoleManager) SetDefaultLinkConditionFunc(fn rbac.LinkConditionFunc) {
+	crm.defaultLinkConditionFunc = fn
+}
+
 // SetLinkConditionFuncParams sets parameters of LinkConditionFunc based on userName, roleName, domain.
 func (crm *ConditionalRoleManager) SetLinkConditionFuncParams(userName, roleName string, params ...string) {
 	crm.SetDomainLinkConditionFuncParams(userName, roleName, defaultDomain, params...)
@@ -1181,6 +1338,7 @@ func (cdm *ConditionalDomainManager) getConditionalRoleManager(domain string, st
 
 	if rm, ok = cdm.load(domain); !ok {
 		rm = newConditionalRoleManagerWithMatchingFunc(cdm.maxHierarchyLevel, cdm.matchingFunc)
+		rm.defaultLinkConditionFunc = cdm.defaultLinkConditionFunc
 		if store {
 			cdm.rmMap.Store(domain, rm)
 		}
@@ -1208,6 +1366,18 @@ func (cdm *ConditionalDomainManager) HasLink(name1 string, name2 string, domains
 	return rm.HasLink(name1, name2, domains...)
 }
 
+// CountRoles counts the roles that a user inherits through links whose conditions hold.
+func (cdm *ConditionalDomainManager) CountRoles(name string, domains ...string) (int, error) {
+	roles, err := cdm.GetRoles(name, domains...)
+	return len(roles), err
+}
+
+// CountUsers counts the users that inherits a role through links whose conditions hold.
+func (cdm *ConditionalDomainManager) CountUsers(name string, domains ...string) (int, error) {
+	users, err := cdm.GetUsers(name, domains...)
+	return len(users), err
+}
+
 func (cdm *ConditionalDomainManager) GetRoles(name string, domains ...string) ([]string, error) {
 	domain, err := cdm.getDomain(domains...)
 	if err != nil {
@@ -1292,6 +1462,16 @@ func (cdm *ConditionalDomainManager) AddDomainLinkConditionFunc(userName, roleNa
 	})
 }
 
+// SetDefaultLinkConditionFunc sets the LinkConditionFunc applied to every link that carries
+// parameters but has no LinkConditionFunc of its own, in all domains.
+func (cdm *ConditionalDomainManager) SetDefaultLinkConditionFunc(fn rbac.LinkConditionFunc) {
+	cdm.defaultLinkConditionFunc = fn
+	cdm.rmMap.Range(func(key, value interface{}) bool {
+		value.(*ConditionalRoleManager).SetDefaultLinkConditionFunc(fn)
+		return true
+	})
+}
+
 // SetLinkConditionFuncParams sets parameters of LinkConditionFunc based on userName, roleName.
 func (cdm *ConditionalDomainManager) SetLinkConditionFuncParams(userName, roleName string, params ...string) {
 	cdm.rmMap.Range(func(key, value interface{}) bool {
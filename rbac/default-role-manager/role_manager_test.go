@@ -389,6 +389,35 @@ func TestMaxHierarchyLevel(t *testing.T) {
 	testRole(t, rm, "level1", "level3", true)
 }
 
+func TestDomainCaptureFunc(t *testing.T) {
+	rm := NewRoleManagerImpl(10)
+	capture, err := util.RegexDomainCaptureFunc(`^role:(?P<domain>[^:]+):`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm.SetDomainCaptureFunc(capture)
+
+	_ = rm.AddLink("alice", "role:project42:admin")
+	_ = rm.AddLink("role:project42:admin", "reader")
+	_ = rm.AddLink("bob", "role:project7:admin")
+	_ = rm.AddLink("role:project7:admin", "reader")
+
+	testDomainRole(t, rm, "alice", "role:project42:admin", "project42", true)
+	testDomainRole(t, rm, "alice", "role:project42:admin", "project7", false)
+	testDomainRole(t, rm, "alice", "reader", "project42", true)
+	testDomainRole(t, rm, "alice", "reader", "project7", false)
+	testDomainRole(t, rm, "bob", "reader", "project7", true)
+	testDomainRole(t, rm, "bob", "reader", "project42", false)
+
+	// Without a domain, the captured domain isn't checked.
+	testRole(t, rm, "alice", "role:project42:admin", true)
+	testRole(t, rm, "bob", "reader", true)
+
+	if _, err = util.RegexDomainCaptureFunc(`^role:[^:]+:`); err == nil {
+		t.Error("a regular expression without a group should be rejected")
+	}
+}
+
 // TestConcurrentHasLink tests concurrent HasLink calls for race conditions.
 // This test verifies that concurrent HasLink calls with matching functions
 // don't produce inconsistent results due to temporary role creation/deletion races.
@@ -431,3 +460,36 @@ func TestConcurrentHasLink(t *testing.T) {
 			inconsistencies, numGoroutines*numIterations)
 	}
 }
+
+func TestCloneEmpty(t *testing.T) {
+	rm := NewRoleManagerImpl(1)
+	rm.AddMatchingFunc("keyMatch2", util.KeyMatch2)
+	_ = rm.AddLink("/book/:id", "book_group")
+	_ = rm.AddLink("u1", "g1")
+	_ = rm.AddLink("g1", "g2")
+
+	c := rm.CloneEmpty()
+	testRole(t, c, "/book/1", "book_group", false)
+	_ = c.AddLink("/book/:id", "book_group")
+	testRole(t, c, "/book/1", "book_group", true)
+	// The maximum hierarchy level is kept.
+	_ = c.AddLink("u1", "g1")
+	_ = c.AddLink("g1", "g2")
+	testRole(t, c, "u1", "g2", false)
+	// The links of the clone are apart from the ones of rm.
+	_ = c.AddLink("u2", "g1")
+	testRole(t, rm, "u2", "g1", false)
+
+	dm := NewRoleManager(10)
+	dm.AddDomainMatchingFunc("keyMatch2", util.KeyMatch2)
+	_ = dm.AddLink("u1", "g1", "*")
+	dc := dm.CloneEmpty()
+	testDomainRole(t, dc, "u1", "g1", "domain1", false)
+	_ = dc.AddLink("u1", "g1", "*")
+	testDomainRole(t, dc, "u1", "g1", "domain1", true)
+
+	crm := NewConditionalRoleManager(10)
+	if _, ok := crm.CloneEmpty().(*ConditionalRoleManager); !ok {
+		t.Error("the clone of a conditional role manager should be a conditional role manager")
+	}
+}
@@ -20,6 +20,10 @@ type MatchingFunc func(arg1 string, arg2 string) bool
 
 type LinkConditionFunc = func(args ...string) (bool, error)
 
+// DomainCaptureFunc captures the domain encoded in a role name, e.g. "project42" of "role:project42:admin".
+// ok is false when the role name doesn't encode a domain.
+type DomainCaptureFunc func(role string) (domain string, ok bool)
+
 // RoleManager provides interface to define the operations for managing roles.
 type RoleManager interface {
 	// Clear clears all stored data and resets the role manager to the initial state.
@@ -65,6 +69,23 @@ type RoleManager interface {
 	DeleteDomain(domain string) error
 }
 
+// RoleCounter is implemented by role managers able to count the roles of a user
+// and the users of a role without listing them.
+type RoleCounter interface {
+	// CountRoles counts the roles that a user inherits, like len(GetRoles(name, domain...)).
+	CountRoles(name string, domain ...string) (int, error)
+	// CountUsers counts the users that inherits a role, like len(GetUsers(name, domain...)).
+	CountUsers(name string, domain ...string) (int, error)
+}
+
+// RoleManagerCloner is implemented by role managers able to create an empty role manager
+// with the same configuration, e.g. to build the role links of a reloaded policy aside.
+type RoleManagerCloner interface {
+	// CloneEmpty returns a role manager configured like this one, e.g. with the same maximum hierarchy level
+	// and matching functions, but without any link.
+	CloneEmpty() RoleManager
+}
+
 // ConditionalRoleManager provides interface to define the operations for managing roles.
 // Link with conditions is supported.
 type ConditionalRoleManager interface {
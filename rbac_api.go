@@ -16,12 +16,14 @@ package casbin
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/casbin/casbin/v2/rbac"
 
 	"github.com/casbin/casbin/v2/constant"
 	"github.com/casbin/casbin/v2/errors"
+	"github.com/casbin/casbin/v2/persist"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -45,6 +47,34 @@ func (e *Enforcer) GetUsersForRole(name string, domain ...string) ([]string, err
 	return res, err
 }
 
+// CountRolesForUser counts the roles that a user has, without listing them if the role manager
+// implements rbac.RoleCounter.
+func (e *Enforcer) CountRolesForUser(name string, domain ...string) (int, error) {
+	rm := e.GetRoleManager()
+	if rm == nil {
+		return 0, fmt.Errorf("role manager is not initialized")
+	}
+	if counter, ok := rm.(rbac.RoleCounter); ok {
+		return counter.CountRoles(name, domain...)
+	}
+	res, err := rm.GetRoles(name, domain...)
+	return len(res), err
+}
+
+// CountUsersForRole counts the users that has a role, without listing them if the role manager
+// implements rbac.RoleCounter.
+func (e *Enforcer) CountUsersForRole(name string, domain ...string) (int, error) {
+	rm := e.GetRoleManager()
+	if rm == nil {
+		return 0, fmt.Errorf("role manager is not initialized")
+	}
+	if counter, ok := rm.(rbac.RoleCounter); ok {
+		return counter.CountUsers(name, domain...)
+	}
+	res, err := rm.GetUsers(name, domain...)
+	return len(res), err
+}
+
 // HasRoleForUser determines whether a user has a role.
 func (e *Enforcer) HasRoleForUser(name string, role string, domain ...string) (bool, error) {
 	roles, err := e.GetRolesForUser(name, domain...)
@@ -143,6 +173,123 @@ func (e *Enforcer) DeleteRole(role string) (bool, error) {
 	return res1 || res2 || res3, err
 }
 
+// RenameRole renames a role in the grouping policies, both where it is a role and where it is a member
+// of another role, and where it is the subject of the policies, then notifies the watcher once.
+// With a domain, only the rules of this domain are renamed. A rule that would be the same as an
+// existing rule once renamed is removed instead. Renaming a role to a name matched as the same one,
+// e.g. with case-insensitive matching, leaves the rules unchanged. If a change fails, the changes
+// already made are reverted.
+// Returns false if the role does not exist (aka not affected).
+func (e *Enforcer) RenameRole(oldRole string, newRole string, domain ...string) (bool, error) {
+	if len(domain) > 1 {
+		return false, errors.ErrDomainParameter
+	}
+
+	// All the policy types are checked before any of them is changed.
+	var ops []persist.PolicyOperation
+	for _, sec := range []string{"g", "p"} {
+		ptypes := make([]string, 0, len(e.model[sec]))
+		for ptype := range e.model[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			renameOps, err := e.planRoleRename(sec, ptype, oldRole, newRole, domain)
+			if err != nil {
+				return false, err
+			}
+			ops = append(ops, renameOps...)
+		}
+	}
+	if len(ops) == 0 {
+		return false, nil
+	}
+
+	rolesChanged := false
+	for i, op := range ops {
+		if err := e.applyPolicyOperation(op); err != nil {
+			// Revert the changes already made, the last one first.
+			for j := i - 1; j >= 0; j-- {
+				_ = e.applyPolicyOperation(invertPolicyOperation(ops[j]))
+			}
+			return false, err
+		}
+		rolesChanged = rolesChanged || op.Section == "g"
+	}
+
+	if rolesChanged && e.autoBuildRoleLinks {
+		if err := e.RebuildRoleLinks(); err != nil {
+			return true, err
+		}
+	}
+	if e.shouldNotify() {
+		return true, e.watcher.Update()
+	}
+	return true, nil
+}
+
+// planRoleRename computes the operations renaming a role in the rules of the policy type ptype without applying them.
+// The policy types of the policy section without a subject field are left unchanged, except for p.
+func (e *Enforcer) planRoleRename(sec string, ptype string, oldRole string, newRole string, domain []string) ([]persist.PolicyOperation, error) {
+	fields, domainIndex := []int{0, 1}, 2
+	if sec == "p" {
+		subIndex, err := e.GetFieldIndex(ptype, constant.SubjectIndex)
+		if err == nil && len(domain) != 0 {
+			domainIndex, err = e.GetFieldIndex(ptype, constant.DomainIndex)
+		}
+		if err != nil {
+			if ptype == "p" {
+				return nil, err
+			}
+			return nil, nil
+		}
+		fields = []int{subIndex}
+	}
+
+	var oldRules, newRules, duplicates [][]string
+	renamed := map[string]struct{}{}
+	for index, rule := range e.model[sec][ptype].Policy {
+		if len(domain) != 0 && (domainIndex >= len(rule) || rule[domainIndex] != domain[0]) {
+			continue
+		}
+		var newRule []string
+		for _, i := range fields {
+			if i < len(rule) && rule[i] == oldRole {
+				if newRule == nil {
+					newRule = deepCopyPolicy(rule)
+				}
+				newRule[i] = newRole
+			}
+		}
+		if newRule == nil {
+			continue
+		}
+
+		key := e.model.PolicyKey(sec, ptype, newRule)
+		existing, exists := e.model[sec][ptype].PolicyMap[key]
+		if exists && existing == index {
+			// The renamed rule is the same rule, e.g. for a case-only rename with case-insensitive matching.
+			continue
+		}
+		if _, seen := renamed[key]; seen || exists {
+			duplicates = append(duplicates, rule)
+			continue
+		}
+		renamed[key] = struct{}{}
+		oldRules = append(oldRules, rule)
+		newRules = append(newRules, newRule)
+	}
+
+	var ops []persist.PolicyOperation
+	if len(oldRules) != 0 {
+		ops = append(ops, persist.PolicyOperation{Type: persist.OperationUpdate, Section: sec, PolicyType: ptype, Rules: newRules, OldRules: oldRules})
+	}
+	if len(duplicates) != 0 {
+		ops = append(ops, persist.PolicyOperation{Type: persist.OperationRemove, Section: sec, PolicyType: ptype, Rules: duplicates})
+	}
+	return ops, nil
+}
+
 // DeletePermission deletes a permission.
 // Returns false if the permission does not exist (aka not affected).
 func (e *Enforcer) DeletePermission(permission ...string) (bool, error) {
@@ -308,6 +455,38 @@ func (e *Enforcer) GetImplicitPermissionsForUser(user string, domain ...string)
 	return e.GetNamedImplicitPermissionsForUser("p", "g", user, domain...)
 }
 
+// GetImplicitPermissionsForUserGrouped gets implicit permissions for a user or role, partitioned by the effect
+// of the rules. Rules without an eft field are allowed, rules with an effect other than allow or deny are left out.
+// For example:
+// p, admin, data1, read, allow
+// p, alice, data1, write, deny
+// g, alice, admin
+//
+// GetImplicitPermissionsForUserGrouped("alice") will get: [["admin", "data1", "read", "allow"]] as allowed
+// and [["alice", "data1", "write", "deny"]] as denied.
+func (e *Enforcer) GetImplicitPermissionsForUserGrouped(user string, domain ...string) ([][]string, [][]string, error) {
+	permissions, err := e.GetImplicitPermissionsForUser(user, domain...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eftIndex, err := e.GetFieldIndex("p", "eft")
+	if err != nil {
+		return permissions, [][]string{}, nil
+	}
+
+	allow, deny := make([][]string, 0), make([][]string, 0)
+	for _, rule := range permissions {
+		switch rule[eftIndex] {
+		case "allow":
+			allow = append(allow, rule)
+		case "deny":
+			deny = append(deny, rule)
+		}
+	}
+	return allow, deny, nil
+}
+
 // GetNamedImplicitPermissionsForUser gets implicit permissions for a user or role by named policy.
 // Compared to GetNamedPermissionsForUser(), this function retrieves permissions for inherited roles.
 // For example:
@@ -318,16 +497,55 @@ func (e *Enforcer) GetImplicitPermissionsForUser(user string, domain ...string)
 // GetImplicitPermissionsForUser("alice") can only get: [["admin", "data1", "read"]], whose policy is default policy "p"
 // But you can specify the named policy "p2" to get: [["admin", "create"]] by    GetNamedImplicitPermissionsForUser("p2","alice").
 func (e *Enforcer) GetNamedImplicitPermissionsForUser(ptype string, gtype string, user string, domain ...string) ([][]string, error) {
+	key := implicitPermissionsKey{ptype: ptype, gtype: gtype, user: user, domain: strings.Join(domain, "\x00")}
+	if permission, ok := e.getCachedImplicitPermissions(key); ok {
+		return permission, nil
+	}
+
 	permission := make([][]string, 0)
+	err := e.RangeNamedImplicitPermissionsForUser(ptype, gtype, user, func(perm []string) bool {
+		permission = append(permission, perm)
+		return true
+	}, domain...)
+	if err != nil {
+		return nil, err
+	}
+	e.cacheImplicitPermissions(key, permission)
+	return permission, nil
+}
+
+// RangeImplicitPermissionsForUser calls fn for each implicit permission of a user or role, in the order of
+// GetImplicitPermissionsForUser, without collecting them. It stops as soon as fn returns false.
+// The enforcer must not be modified from fn.
+// For example, to check whether alice has any write permission:
+//
+//	canWrite := false
+//	err := e.RangeImplicitPermissionsForUser("alice", func(perm []string) bool {
+//		canWrite = perm[2] == "write"
+//		return !canWrite
+//	})
+func (e *Enforcer) RangeImplicitPermissionsForUser(user string, fn func(perm []string) bool, domain ...string) error {
+	return e.RangeNamedImplicitPermissionsForUser("p", "g", user, fn, domain...)
+}
+
+// RangeNamedImplicitPermissionsForUser calls fn for each implicit permission of a user or role by named policy,
+// see RangeImplicitPermissionsForUser.
+func (e *Enforcer) RangeNamedImplicitPermissionsForUser(ptype string, gtype string, user string, fn func(perm []string) bool, domain ...string) error {
 	rm := e.GetNamedRoleManager(gtype)
 	if rm == nil {
-		return nil, fmt.Errorf("role manager %s is not initialized", gtype)
+		return fmt.Errorf("role manager %s is not initialized", gtype)
 	}
 
 	roles, err := e.GetNamedImplicitRolesForUser(gtype, user, domain...)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return e.rangePermissionsForRoles(ptype, rm, user, roles, fn, domain...)
+}
+
+// rangePermissionsForRoles calls fn for each rule of ptype whose subject is the user or one of the roles,
+// see RangeNamedImplicitPermissionsForUser.
+func (e *Enforcer) rangePermissionsForRoles(ptype string, rm rbac.RoleManager, user string, roles []string, fn func(perm []string) bool, domain ...string) error {
 	policyRoles := make(map[string]struct{}, len(roles)+1)
 	policyRoles[user] = struct{}{}
 	for _, r := range roles {
@@ -338,15 +556,17 @@ func (e *Enforcer) GetNamedImplicitPermissionsForUser(ptype string, gtype string
 	for _, rule := range e.model["p"][ptype].Policy {
 		if len(domain) == 0 {
 			if _, ok := policyRoles[rule[0]]; ok {
-				permission = append(permission, deepCopyPolicy(rule))
+				if !fn(deepCopyPolicy(rule)) {
+					return nil
+				}
 			}
 			continue
 		}
 		if len(domain) > 1 {
-			return nil, errors.ErrDomainParameter
+			return errors.ErrDomainParameter
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		d := domain[0]
 		matched := rm.Match(d, rule[domainIndex])
@@ -356,12 +576,75 @@ func (e *Enforcer) GetNamedImplicitPermissionsForUser(ptype string, gtype string
 		if _, ok := policyRoles[rule[0]]; ok {
 			newRule := deepCopyPolicy(rule)
 			newRule[domainIndex] = d
-			permission = append(permission, newRule)
+			if !fn(newRule) {
+				return nil
+			}
 		}
 	}
+	return nil
+}
+
+// GetImplicitPermissionsForUserWithDepth gets implicit permissions for a user or role like
+// GetImplicitPermissionsForUser, only through the roles inherited within maxDepth levels:
+// 0 only gets the permissions of the user, 1 those of its roles too, and so on.
+// A role reached through several paths, e.g. with diamond-shaped inheritance, counts once at its shortest depth.
+// For example:
+// p, admin, data1, read
+// p, root, data2, read
+// g, alice, admin
+// g, admin, root
+//
+// GetImplicitPermissionsForUserWithDepth("alice", 1) will get: [["admin", "data1", "read"]].
+func (e *Enforcer) GetImplicitPermissionsForUserWithDepth(user string, maxDepth int, domain ...string) ([][]string, error) {
+	if maxDepth < 0 {
+		return nil, fmt.Errorf("maxDepth should not be negative, got %d", maxDepth)
+	}
+	rm := e.GetNamedRoleManager("g")
+	if rm == nil {
+		return nil, fmt.Errorf("role manager %s is not initialized", "g")
+	}
+
+	roles, err := implicitRolesWithDepth(rm, user, maxDepth, domain...)
+	if err != nil {
+		return nil, err
+	}
+	permission := make([][]string, 0)
+	err = e.rangePermissionsForRoles("p", rm, user, roles, func(perm []string) bool {
+		permission = append(permission, perm)
+		return true
+	}, domain...)
+	if err != nil {
+		return nil, err
+	}
 	return permission, nil
 }
 
+// implicitRolesWithDepth gets the roles the user inherits within maxDepth levels, each once, level by level.
+func implicitRolesWithDepth(rm rbac.RoleManager, user string, maxDepth int, domain ...string) ([]string, error) {
+	var res []string
+	visited := map[string]struct{}{user: {}}
+	level := []string{user}
+	for depth := 0; depth < maxDepth && len(level) != 0; depth++ {
+		var next []string
+		for _, name := range level {
+			roles, err := rm.GetRoles(name, domain...)
+			if err != nil {
+				return nil, err
+			}
+			for _, role := range roles {
+				if _, ok := visited[role]; ok {
+					continue
+				}
+				visited[role] = struct{}{}
+				next = append(next, role)
+			}
+		}
+		res = append(res, next...)
+		level = next
+	}
+	return res, nil
+}
+
 // GetImplicitUsersForPermission gets implicit users for a permission.
 // For example:
 // p, admin, data1, read
@@ -405,22 +688,74 @@ func (e *Enforcer) GetImplicitUsersForPermission(permission ...string) ([]string
 	return res, nil
 }
 
-// GetDomainsForUser gets all domains.
+// GetDomainsForUser gets the domains in which a user has a role or a permission, directly or through a role,
+// without duplicates. The domain of a permission is taken from the "dom" field of the policy definition.
+// For example:
+// p, admin, domain1, data1, read
+// p, bob, domain3, data3, read
+// g, alice, admin, domain1
+// g, bob, admin, domain2
+//
+// GetDomainsForUser("bob") will get: ["domain2", "domain3"].
 func (e *Enforcer) GetDomainsForUser(user string) ([]string, error) {
 	var domains []string
+	seen := make(map[string]struct{})
+	add := func(domain string) {
+		if _, ok := seen[domain]; !ok {
+			seen[domain] = struct{}{}
+			domains = append(domains, domain)
+		}
+	}
+
 	for _, rm := range e.rmMap {
 		domain, err := rm.GetDomains(user)
 		if err != nil {
 			return nil, err
 		}
-		domains = append(domains, domain...)
+		for _, d := range domain {
+			add(d)
+		}
 	}
 	for _, crm := range e.condRmMap {
 		domain, err := crm.GetDomains(user)
 		if err != nil {
 			return nil, err
 		}
-		domains = append(domains, domain...)
+		for _, d := range domain {
+			add(d)
+		}
+	}
+
+	rm := e.GetRoleManager()
+	for ptype, ast := range e.model["p"] {
+		domainIndex, err := e.GetFieldIndex(ptype, constant.DomainIndex)
+		if err != nil {
+			continue
+		}
+		subIndex, err := e.GetFieldIndex(ptype, constant.SubjectIndex)
+		if err != nil {
+			subIndex = 0
+		}
+		for _, rule := range ast.Policy {
+			domain := rule[domainIndex]
+			if _, ok := seen[domain]; ok {
+				continue
+			}
+			if rule[subIndex] == user {
+				add(domain)
+				continue
+			}
+			if rm == nil {
+				continue
+			}
+			ok, err := rm.HasLink(user, rule[subIndex], domain)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				add(domain)
+			}
+		}
 	}
 	return domains, nil
 }
@@ -464,6 +799,63 @@ func (e *Enforcer) GetImplicitResourcesForUser(user string, domain ...string) ([
 	return res, nil
 }
 
+// GetImplicitResourcesForRole gets the permissions of a role, including the inherited ones, with the object
+// of each expanded to the resources in the object group it names by the resource role definition g2.
+// For example:
+// p, data_group_admin, data_group, write
+// g2, data1, data_group
+// g2, data2, data_group
+//
+// GetImplicitResourcesForRole("data_group_admin") will get: [["data_group_admin", "data_group", "write"],
+// ["data_group_admin", "data1", "write"], ["data_group_admin", "data2", "write"]].
+// The same permission is only returned once. Without g2, the permissions are returned as is.
+// The domain is also passed to g2 if it has one.
+func (e *Enforcer) GetImplicitResourcesForRole(role string, domain ...string) ([][]string, error) {
+	permissions, err := e.GetImplicitPermissionsForUser(role, domain...)
+	if err != nil {
+		return nil, err
+	}
+	objIndex, err := e.GetFieldIndex("p", constant.ObjectIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	rm := e.rmMap["g2"]
+	var resourceDomain []string
+	if rm != nil && len(e.model["g"]["g2"].Tokens) > 2 {
+		resourceDomain = domain
+	}
+
+	res := [][]string{}
+	seen := map[string]struct{}{}
+	add := func(permission []string, resource string) {
+		rule := deepCopyPolicy(permission)
+		rule[objIndex] = resource
+		key := e.model.PolicyKey("p", "p", rule)
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			res = append(res, rule)
+		}
+	}
+	for _, permission := range permissions {
+		if objIndex >= len(permission) {
+			continue
+		}
+		add(permission, permission[objIndex])
+		if rm == nil {
+			continue
+		}
+		resources, err := rm.GetImplicitUsers(permission[objIndex], resourceDomain...)
+		if err != nil && err.Error() != "error: name does not exist" {
+			return nil, err
+		}
+		for _, resource := range resources {
+			add(permission, resource)
+		}
+	}
+	return res, nil
+}
+
 // deepCopyPolicy returns a deepcopy version of the policy to prevent changing policies through returned slice.
 func deepCopyPolicy(src []string) []string {
 	newRule := make([]string, len(src))
@@ -28,6 +28,20 @@ func (e *SyncedEnforcer) GetUsersForRole(name string, domain ...string) ([]strin
 	return e.Enforcer.GetUsersForRole(name, domain...)
 }
 
+// CountRolesForUser counts the roles that a user has.
+func (e *SyncedEnforcer) CountRolesForUser(name string, domain ...string) (int, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.CountRolesForUser(name, domain...)
+}
+
+// CountUsersForRole counts the users that has a role.
+func (e *SyncedEnforcer) CountUsersForRole(name string, domain ...string) (int, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.CountUsersForRole(name, domain...)
+}
+
 // HasRoleForUser determines whether a user has a role.
 func (e *SyncedEnforcer) HasRoleForUser(name string, role string, domain ...string) (bool, error) {
 	e.m.RLock()
@@ -83,6 +97,14 @@ func (e *SyncedEnforcer) DeleteRole(role string) (bool, error) {
 	return e.Enforcer.DeleteRole(role)
 }
 
+// RenameRole renames a role in the grouping policy and in the policy, then notifies the watcher once.
+// Returns false if the role does not exist (aka not affected).
+func (e *SyncedEnforcer) RenameRole(oldRole string, newRole string, domain ...string) (bool, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.RenameRole(oldRole, newRole, domain...)
+}
+
 // DeletePermission deletes a permission.
 // Returns false if the permission does not exist (aka not affected).
 func (e *SyncedEnforcer) DeletePermission(permission ...string) (bool, error) {
@@ -158,6 +180,13 @@ func (e *SyncedEnforcer) GetImplicitRolesForUser(name string, domain ...string)
 	return e.Enforcer.GetImplicitRolesForUser(name, domain...)
 }
 
+// GetNamedImplicitRolesForUser gets implicit roles that a user has by named role definition.
+func (e *SyncedEnforcer) GetNamedImplicitRolesForUser(ptype string, name string, domain ...string) ([]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetNamedImplicitRolesForUser(ptype, name, domain...)
+}
+
 // GetImplicitPermissionsForUser gets implicit permissions for a user or role.
 // Compared to GetPermissionsForUser(), this function retrieves permissions for inherited roles.
 // For example:
@@ -173,6 +202,37 @@ func (e *SyncedEnforcer) GetImplicitPermissionsForUser(user string, domain ...st
 	return e.Enforcer.GetImplicitPermissionsForUser(user, domain...)
 }
 
+// GetImplicitPermissionsForUserWithDepth gets implicit permissions for a user or role,
+// only through the roles inherited within maxDepth levels.
+func (e *SyncedEnforcer) GetImplicitPermissionsForUserWithDepth(user string, maxDepth int, domain ...string) ([][]string, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Enforcer.GetImplicitPermissionsForUserWithDepth(user, maxDepth, domain...)
+}
+
+// GetImplicitPermissionsForUserGrouped gets implicit permissions for a user or role, partitioned by the effect of the rules.
+func (e *SyncedEnforcer) GetImplicitPermissionsForUserGrouped(user string, domain ...string) ([][]string, [][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetImplicitPermissionsForUserGrouped(user, domain...)
+}
+
+// RangeImplicitPermissionsForUser calls fn for each implicit permission of a user or role until it returns false.
+// The enforcer must not be modified from fn.
+func (e *SyncedEnforcer) RangeImplicitPermissionsForUser(user string, fn func(perm []string) bool, domain ...string) error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.RangeImplicitPermissionsForUser(user, fn, domain...)
+}
+
+// RangeNamedImplicitPermissionsForUser calls fn for each implicit permission of a user or role by named policy
+// until it returns false. The enforcer must not be modified from fn.
+func (e *SyncedEnforcer) RangeNamedImplicitPermissionsForUser(ptype string, gtype string, user string, fn func(perm []string) bool, domain ...string) error {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.RangeNamedImplicitPermissionsForUser(ptype, gtype, user, fn, domain...)
+}
+
 // GetNamedImplicitPermissionsForUser gets implicit permissions for a user or role by named policy.
 // Compared to GetNamedPermissionsForUser(), this function retrieves permissions for inherited roles.
 // For example:
@@ -202,6 +262,14 @@ func (e *SyncedEnforcer) GetImplicitUsersForPermission(permission ...string) ([]
 	return e.Enforcer.GetImplicitUsersForPermission(permission...)
 }
 
+// GetImplicitResourcesForRole gets the permissions of a role, including the inherited ones,
+// with the object of each expanded to the resources in its object group by g2.
+func (e *SyncedEnforcer) GetImplicitResourcesForRole(role string, domain ...string) ([][]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetImplicitResourcesForRole(role, domain...)
+}
+
 // GetImplicitObjectPatternsForUser returns all object patterns (with wildcards) that a user has for a given domain and action.
 // For example:
 // p, admin, chronicle/123, location/*, read
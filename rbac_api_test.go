@@ -22,6 +22,7 @@ import (
 
 	"github.com/casbin/casbin/v2/constant"
 	"github.com/casbin/casbin/v2/errors"
+	"github.com/casbin/casbin/v2/model"
 	defaultrolemanager "github.com/casbin/casbin/v2/rbac/default-role-manager"
 	"github.com/casbin/casbin/v2/util"
 )
@@ -129,6 +130,45 @@ func TestRoleAPI(t *testing.T) {
 	testEnforce(t, e, "bob", "data2", "write", true)
 }
 
+func testCountRoles(t *testing.T, e *Enforcer, name string, domain ...string) {
+	t.Helper()
+	roles, _ := e.GetRolesForUser(name, domain...)
+	count, err := e.CountRolesForUser(name, domain...)
+	if err != nil || count != len(roles) {
+		t.Errorf("role count of %s in %v: %d, %v, supposed to be %d", name, domain, count, err, len(roles))
+	}
+
+	users, _ := e.GetUsersForRole(name, domain...)
+	count, err = e.CountUsersForRole(name, domain...)
+	if err != nil || count != len(users) {
+		t.Errorf("user count of %s in %v: %d, %v, supposed to be %d", name, domain, count, err, len(users))
+	}
+}
+
+func TestCountRolesAndUsers(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	_, _ = e.AddRoleForUser("alice", "data1_admin")
+	_, _ = e.AddRoleForUser("bob", "data2_admin")
+	for _, name := range []string{"alice", "bob", "data2_admin", "non_exist"} {
+		testCountRoles(t, e, name)
+	}
+	if count, _ := e.CountUsersForRole("data2_admin"); count != 2 {
+		t.Errorf("user count of data2_admin: %d, supposed to be 2", count)
+	}
+
+	e, _ = NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	for _, name := range []string{"alice", "bob", "admin", "non_exist"} {
+		testCountRoles(t, e, name, "domain1")
+		testCountRoles(t, e, name, "domain2")
+	}
+
+	e, _ = NewEnforcer("examples/rbac_with_pattern_model.conf", "examples/rbac_with_pattern_policy.csv")
+	e.AddNamedMatchingFunc("g", "KeyMatch2", util.KeyMatch2)
+	for _, name := range []string{"alice", "bob", "book_group", "/book/1", "/pen/:id"} {
+		testCountRoles(t, e, name)
+	}
+}
+
 func TestRoleAPI_Domains(t *testing.T) {
 	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
 
@@ -307,6 +347,38 @@ func testGetImplicitRolesInDomain(t *testing.T, e *Enforcer, name string, domain
 	}
 }
 
+func testGetNamedImplicitRoles(t *testing.T, e *Enforcer, ptype string, name string, res []string) {
+	t.Helper()
+	myRes, err := e.GetNamedImplicitRolesForUser(ptype, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(ptype, " implicit roles for ", name, ": ", myRes)
+
+	if !util.SetEquals(res, myRes) {
+		t.Error(ptype, " implicit roles for ", name, ": ", myRes, ", supposed to be ", res)
+	}
+}
+
+func TestNamedImplicitRolesForUser(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_resource_roles_model.conf", "examples/rbac_with_resource_roles_policy.csv")
+	_, _ = e.AddNamedGroupingPolicy("g2", "data_group", "all_data")
+	_, _ = e.AddNamedGroupingPolicy("g2", "/book/:id", "book_group")
+	e.AddNamedMatchingFunc("g2", "KeyMatch2", util.KeyMatch2)
+
+	// Resource hierarchies are traversed independently from user roles.
+	testGetNamedImplicitRoles(t, e, "g2", "data1", []string{"data_group", "all_data"})
+	testGetNamedImplicitRoles(t, e, "g", "data1", []string{})
+	testGetNamedImplicitRoles(t, e, "g", "alice", []string{"data_group_admin"})
+	testGetNamedImplicitRoles(t, e, "g2", "alice", []string{})
+	// The matching function of the g2 role manager is honored.
+	testGetNamedImplicitRoles(t, e, "g2", "/book/1", []string{"book_group"})
+
+	if _, err := e.GetNamedImplicitRolesForUser("g3", "alice"); err == nil {
+		t.Error("an unknown ptype should fail")
+	}
+}
+
 func TestImplicitRoleAPI(t *testing.T) {
 	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_with_hierarchy_policy.csv")
 
@@ -388,11 +460,72 @@ func TestImplicitPermissionAPI(t *testing.T) {
 	testGetNamedImplicitPermissions(t, e, "p2", "g2", "alice", [][]string{{"user", "view"}})
 }
 
+func testGetImplicitPermissionsWithDepth(t *testing.T, e *Enforcer, name string, maxDepth int, res [][]string, domain ...string) {
+	t.Helper()
+	myRes, err := e.GetImplicitPermissionsForUserWithDepth(name, maxDepth, domain...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(myRes) != len(res) || !util.Set2DEquals(res, myRes) {
+		t.Error("Implicit permissions for ", name, " within ", maxDepth, " levels: ", myRes, ", supposed to be ", res)
+	}
+}
+
+func TestImplicitPermissionsForUserWithDepth(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_with_hierarchy_policy.csv")
+	// data1_admin and data2_admin both inherit base, which is reached twice.
+	_, _ = e.AddGroupingPolicies([][]string{{"data1_admin", "base"}, {"data2_admin", "base"}})
+	_, _ = e.AddPolicy("base", "data3", "read")
+
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 0, [][]string{{"alice", "data1", "read"}})
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 1, [][]string{{"alice", "data1", "read"}})
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 2, [][]string{{"alice", "data1", "read"},
+		{"data1_admin", "data1", "read"}, {"data1_admin", "data1", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 3, [][]string{{"alice", "data1", "read"},
+		{"data1_admin", "data1", "read"}, {"data1_admin", "data1", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"},
+		{"base", "data3", "read"}})
+	testGetImplicitPermissionsWithDepth(t, e, "admin", 2, [][]string{
+		{"data1_admin", "data1", "read"}, {"data1_admin", "data1", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"},
+		{"base", "data3", "read"}})
+
+	if _, err := e.GetImplicitPermissionsForUserWithDepth("alice", -1); err == nil {
+		t.Error("a negative depth should be an error")
+	}
+
+	e, _ = NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_hierarchy_with_domains_policy.csv")
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 1, [][]string{{"alice", "domain1", "data2", "read"}}, "domain1")
+	testGetImplicitPermissionsWithDepth(t, e, "alice", 2, [][]string{{"alice", "domain1", "data2", "read"},
+		{"role:reader", "domain1", "data1", "read"}, {"role:writer", "domain1", "data1", "write"}}, "domain1")
+}
+
 func TestImplicitPermissionAPIWithDomain(t *testing.T) {
 	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_hierarchy_with_domains_policy.csv")
 	testGetImplicitPermissionsWithDomain(t, e, "alice", "domain1", [][]string{{"alice", "domain1", "data2", "read"}, {"role:reader", "domain1", "data1", "read"}, {"role:writer", "domain1", "data1", "write"}})
 }
 
+func TestImplicitPermissionsForUserGrouped(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_deny_model.conf", "examples/rbac_with_deny_policy.csv")
+
+	allow, deny, err := e.GetImplicitPermissionsForUserGrouped("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAllow := [][]string{{"alice", "data1", "read", "allow"}, {"data2_admin", "data2", "read", "allow"}, {"data2_admin", "data2", "write", "allow"}}
+	if !util.Set2DEquals(expectedAllow, allow) {
+		t.Error("Allowed permissions for alice: ", allow, ", supposed to be ", expectedAllow)
+	}
+	expectedDeny := [][]string{{"alice", "data2", "write", "deny"}}
+	if !util.Set2DEquals(expectedDeny, deny) {
+		t.Error("Denied permissions for alice: ", deny, ", supposed to be ", expectedDeny)
+	}
+
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	allow, deny, _ = e.GetImplicitPermissionsForUserGrouped("bob")
+	if !util.Set2DEquals([][]string{{"bob", "data2", "write"}}, allow) || len(deny) != 0 {
+		t.Error("Permissions for bob: ", allow, deny, ", supposed to be all allowed")
+	}
+}
+
 func testGetImplicitUsers(t *testing.T, e *Enforcer, res []string, permission ...string) {
 	t.Helper()
 	myRes, _ := e.GetImplicitUsersForPermission(permission...)
@@ -881,3 +1014,352 @@ func TestGetImplicitObjectPatternsForUser(t *testing.T) {
 	// Test case 5: non-existent action
 	testGetImplicitObjectPatternsForUser(t, e, "admin", "domain1", "non_existent", []string{})
 }
+
+func TestDomainCaptureFunc(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.obj == p.obj && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+	capture, err := util.RegexDomainCaptureFunc(`^role:([^:]+):`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.SetNamedDomainCaptureFunc("g", capture) {
+		t.Fatal("the default role manager should support domain capture")
+	}
+
+	_, _ = e.AddPolicy("role:project42:admin", "data1", "write")
+	_, _ = e.AddGroupingPolicy("alice", "role:project42:admin")
+
+	testDomainEnforce(t, e, "alice", "project42", "data1", "write", true)
+	testDomainEnforce(t, e, "alice", "project7", "data1", "write", false)
+}
+
+func TestRangeImplicitPermissionsForUser(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_with_hierarchy_policy.csv")
+
+	var perms [][]string
+	err := e.RangeImplicitPermissionsForUser("alice", func(perm []string) bool {
+		perms = append(perms, perm)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := e.GetImplicitPermissionsForUser("alice")
+	if !util.Array2DEquals(expected, perms) {
+		t.Errorf("implicit permissions: %v, supposed to be %v", perms, expected)
+	}
+
+	visited := 0
+	err = e.RangeImplicitPermissionsForUser("alice", func(perm []string) bool {
+		visited++
+		return perm[2] != "write"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited != 3 {
+		t.Errorf("visited permissions: %d, expected to stop at the first write permission, the 3rd", visited)
+	}
+
+	e, _ = NewEnforcer("examples/rbac_with_domain_pattern_model.conf", "examples/rbac_with_domain_pattern_policy.csv")
+	err = e.RangeImplicitPermissionsForUser("admin", func(perm []string) bool { return true }, "domain1", "domain2")
+	if err == nil {
+		t.Error("RangeImplicitPermissionsForUser should not support multiple domains")
+	}
+}
+
+func TestImplicitPermissionsCache(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.EnableImplicitPermissionsCache(true)
+
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	// The cached result is a copy.
+	perms, _ := e.GetImplicitPermissionsForUser("alice")
+	perms[0][0] = "mallory"
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	// A change of the model bypassing the enforcer is not noticed.
+	_ = e.GetModel().AddPolicy("p", "p", []string{"alice", "data3", "read"})
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	_, _ = e.GetModel().RemovePolicy("p", "p", []string{"alice", "data3", "read"})
+
+	_, _ = e.AddPolicy("data2_admin", "data3", "read")
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"data2_admin", "data3", "read"}})
+
+	_, _ = e.DeleteRoleForUser("alice", "data2_admin")
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}})
+
+	_ = e.LoadPolicy()
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	e.ClearPolicy()
+	testGetImplicitPermissions(t, e, "alice", [][]string{})
+
+	e.SetImplicitPermissionsCacheSize(1)
+	_ = e.LoadPolicy()
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testGetImplicitPermissions(t, e, "bob", [][]string{{"bob", "data2", "write"}})
+	testGetImplicitPermissions(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestGetImplicitResourcesForRole(t *testing.T) {
+	testResources := func(e *Enforcer, role string, res [][]string) {
+		t.Helper()
+		myRes, err := e.GetImplicitResourcesForRole(role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !util.Set2DEquals(res, myRes) {
+			t.Errorf("Implicit resources for role %s: %v, supposed to be %v", role, myRes, res)
+		}
+	}
+
+	e, _ := NewEnforcer("examples/rbac_with_resource_roles_model.conf", "examples/rbac_with_resource_roles_policy.csv")
+	testResources(e, "data_group_admin", [][]string{
+		{"data_group_admin", "data_group", "write"},
+		{"data_group_admin", "data1", "write"},
+		{"data_group_admin", "data2", "write"},
+	})
+	testResources(e, "alice", [][]string{
+		{"alice", "data1", "read"},
+		{"data_group_admin", "data_group", "write"},
+		{"data_group_admin", "data1", "write"},
+		{"data_group_admin", "data2", "write"},
+	})
+	testResources(e, "nobody", [][]string{})
+
+	// A resource reached through several groups is only returned once.
+	_, _ = e.AddNamedGroupingPolicy("g2", "data_group", "all_data")
+	_, _ = e.AddPolicy("data_group_admin", "all_data", "write")
+	testResources(e, "data_group_admin", [][]string{
+		{"data_group_admin", "data_group", "write"},
+		{"data_group_admin", "data1", "write"},
+		{"data_group_admin", "data2", "write"},
+		{"data_group_admin", "all_data", "write"},
+	})
+
+	// Without g2, the permissions are returned as is.
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	testResources(e, "data2_admin", [][]string{
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
+
+func TestRenameRole(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	_, _ = e.AddGroupingPolicy("data2_admin", "super_admin")
+	_, _ = e.AddGroupingPolicy("alice", "data_admin")
+
+	notifications := 0
+	_ = e.SetWatcher(&SampleWatcher{})
+	_ = e.watcher.SetUpdateCallback(func(string) { notifications++ })
+
+	ok, err := e.RenameRole("data2_admin", "data_admin")
+	if err != nil || !ok {
+		t.Fatalf("RenameRole: %t, %v, supposed to be true", ok, err)
+	}
+	if notifications != 1 {
+		t.Errorf("watcher notifications: %d, supposed to be 1", notifications)
+	}
+
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data_admin", "data2", "read"},
+		{"data_admin", "data2", "write"},
+	})
+	// alice was already a member of data_admin, so the renamed rule is removed.
+	testGetGroupingPolicy(t, e, [][]string{{"data_admin", "super_admin"}, {"alice", "data_admin"}})
+	testGetImplicitRoles(t, e, "alice", []string{"data_admin", "super_admin"})
+	testEnforce(t, e, "alice", "data2", "write", true)
+
+	ok, _ = e.RenameRole("data2_admin", "data_admin")
+	if ok || notifications != 1 {
+		t.Errorf("renaming a missing role: %t with %d notifications, supposed to be false with 1", ok, notifications)
+	}
+}
+
+func TestRenameRoleToSameRole(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	ok, err := e.RenameRole("data2_admin", "data2_admin")
+	if err != nil || ok {
+		t.Errorf("RenameRole to itself: %t, %v, supposed to be false", ok, err)
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "data2_admin"}})
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	if err = e.GetModel().SetMatchingOptions(model.MatchingOptions{CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = e.RenameRole("data2_admin", "DATA2_ADMIN")
+	if err != nil || ok {
+		t.Errorf("case-only RenameRole: %t, %v, supposed to be false", ok, err)
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "data2_admin"}})
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestRenameRoleAtomic(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = usr, obj, act
+
+[policy_definition]
+p = usr, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.usr, p.usr) && r.obj == p.obj && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddGroupingPolicy("alice", "admin")
+	_, _ = e.AddPolicy("admin", "data1", "read")
+
+	if _, err := e.RenameRole("admin", "owner"); err == nil {
+		t.Error("RenameRole should fail without a subject field in p")
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "admin"}})
+}
+
+func TestRenameRoleInDomain(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+
+	ok, err := e.RenameRole("admin", "owner", "domain1")
+	if err != nil || !ok {
+		t.Fatalf("RenameRole: %t, %v, supposed to be true", ok, err)
+	}
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "read", true)
+	testDomainEnforce(t, e, "bob", "domain2", "data2", "read", true)
+	if ok, _ := e.HasGroupingPolicy("alice", "owner", "domain1"); !ok {
+		t.Error("role should be renamed in domain1")
+	}
+	if ok, _ := e.HasGroupingPolicy("bob", "admin", "domain2"); !ok {
+		t.Error("role should not be renamed in domain2")
+	}
+	if ok, _ := e.HasPolicy("owner", "domain1", "data1", "read"); !ok {
+		t.Error("permission should be renamed in domain1")
+	}
+	if ok, _ := e.HasPolicy("admin", "domain2", "data2", "read"); !ok {
+		t.Error("permission should not be renamed in domain2")
+	}
+
+	if _, err = e.RenameRole("admin", "owner", "domain1", "domain2"); err == nil {
+		t.Error("several domains should be an error")
+	}
+}
+
+const renameRoleModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+p2 = sub, act
+
+[role_definition]
+g = _, _
+g2 = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func TestRenameRoleInEveryPolicyType(t *testing.T) {
+	m, _ := model.NewModelFromString(renameRoleModel)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddGroupingPolicies([][]string{{"alice", "admin"}, {"admin", "user"}})
+	_, _ = e.AddNamedGroupingPolicy("g2", "alice", "user")
+	_, _ = e.AddPolicy("user", "data1", "read")
+	_, _ = e.AddNamedPolicy("p2", "user", "view")
+
+	ok, err := e.RenameRole("user", "member")
+	if err != nil || !ok {
+		t.Fatalf("RenameRole: %t, %v, supposed to be true", ok, err)
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "admin"}, {"admin", "member"}})
+	rules, _ := e.GetNamedGroupingPolicy("g2")
+	if !util.Array2DEquals([][]string{{"alice", "member"}}, rules) {
+		t.Errorf("g2: %v, supposed to be [[alice member]]", rules)
+	}
+	testGetPolicy(t, e, [][]string{{"member", "data1", "read"}})
+	rules, _ = e.GetNamedPolicy("p2")
+	if !util.Array2DEquals([][]string{{"member", "view"}}, rules) {
+		t.Errorf("p2: %v, supposed to be [[member view]]", rules)
+	}
+	testEnforce(t, e, "alice", "data1", "read", true)
+}
+
+// failingUpdateAdapter fails to update the rules of the policy type p.
+type failingUpdateAdapter struct {
+	incrementalAdapter
+}
+
+func (a *failingUpdateAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	if ptype == "p" {
+		return fmt.Errorf("storage unavailable")
+	}
+	return a.incrementalAdapter.UpdatePolicies(sec, ptype, oldRules, newRules)
+}
+
+func TestRenameRoleRollback(t *testing.T) {
+	m, _ := model.NewModelFromString(renameRoleModel)
+	a := &failingUpdateAdapter{}
+	e, _ := NewEnforcer(m, a)
+	_, _ = e.AddGroupingPolicies([][]string{{"alice", "admin"}, {"bob", "owner"}})
+	_, _ = e.AddPolicy("admin", "data1", "read")
+
+	if _, err := e.RenameRole("admin", "owner"); err == nil {
+		t.Fatal("RenameRole should fail when the policy cannot be updated")
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "admin"}, {"bob", "owner"}})
+	testGetPolicy(t, e, [][]string{{"admin", "data1", "read"}})
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "bob", "data1", "read", false)
+	if !util.Array2DEquals([][]string{{"alice", "admin"}}, a.updated[len(a.updated)-1:]) {
+		t.Errorf("adapter updates: %v, the rename of g supposed to be reverted", a.updated)
+	}
+}
+
+func TestAddGroupingPolicyRoleCycle(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewEnforcer("examples/rbac_model.conf", a)
+	e.GetModel().SetRejectRoleCycles(true)
+	_, _ = e.AddGroupingPolicy("alice", "admin")
+
+	if ok, err := e.AddGroupingPolicy("admin", "alice"); ok || err == nil {
+		t.Errorf("adding a cycle: %t, %v, supposed to be false with an error", ok, err)
+	}
+	if ok, err := e.AddGroupingPolicies([][]string{{"bob", "admin"}, {"admin", "bob"}}); ok || err == nil {
+		t.Errorf("adding rules making a cycle: %t, %v, supposed to be false with an error", ok, err)
+	}
+	testGetGroupingPolicy(t, e, [][]string{{"alice", "admin"}})
+	if !util.Array2DEquals([][]string{{"alice", "admin"}}, a.added) {
+		t.Errorf("persisted rules: %v, supposed to be [[alice admin]]", a.added)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+}
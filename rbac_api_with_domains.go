@@ -29,6 +29,55 @@ func (e *Enforcer) GetUsersForRoleInDomain(name string, domain string) []string
 	return res
 }
 
+// GetImplicitUsersForRoleInDomain gets the users that inherit a role inside a domain, directly or through
+// intermediate roles. Only leaf subjects are returned, that is subjects that are not a role in any grouping policy.
+// For example:
+// g, alice, role:user, domain1
+// g, role:admin, role:user, domain1
+// g, bob, role:admin, domain1
+//
+// GetImplicitUsersForRoleInDomain("role:user", "domain1") will get: ["alice", "bob"].
+func (e *Enforcer) GetImplicitUsersForRoleInDomain(name string, domain string) ([]string, error) {
+	rm := e.GetRoleManager()
+	if rm == nil {
+		return nil, fmt.Errorf("role manager is not initialized")
+	}
+
+	roles, err := e.model.GetValuesForFieldInPolicy("g", "g", 1)
+	if err != nil {
+		return nil, err
+	}
+	roleSet := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		roleSet[role] = true
+	}
+
+	res := []string{}
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) != 0 {
+		users, err := rm.GetUsers(queue[0], domain)
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+
+		for _, user := range users {
+			if visited[user] {
+				continue
+			}
+			visited[user] = true
+			if roleSet[user] {
+				queue = append(queue, user)
+			} else {
+				res = append(res, user)
+			}
+		}
+	}
+
+	return res, nil
+}
+
 // GetRolesForUserInDomain gets the roles that a user has inside a domain.
 func (e *Enforcer) GetRolesForUserInDomain(name string, domain string) []string {
 	if rm := e.GetRoleManager(); rm != nil {
@@ -199,3 +248,40 @@ func (e *Enforcer) GetAllRolesByDomain(domain string) ([]string, error) {
 
 	return roles, nil
 }
+
+// GetAllObjectsByDomain gets the distinct objects of the policy rules applying to the domain,
+// i.e. whose domain is the domain or a pattern matching it by a domain matching function.
+func (e *Enforcer) GetAllObjectsByDomain(domain string) ([]string, error) {
+	return e.getAllValuesByDomain(constant.ObjectIndex, domain)
+}
+
+// GetAllActionsByDomain gets the distinct actions of the policy rules applying to the domain,
+// i.e. whose domain is the domain or a pattern matching it by a domain matching function.
+func (e *Enforcer) GetAllActionsByDomain(domain string) ([]string, error) {
+	return e.getAllValuesByDomain(constant.ActionIndex, domain)
+}
+
+// getAllValuesByDomain gets the distinct values of the field of the policy rules applying to the domain.
+func (e *Enforcer) getAllValuesByDomain(field string, domain string) ([]string, error) {
+	domainIndex, err := e.GetFieldIndex("p", constant.DomainIndex)
+	if err != nil {
+		return []string{}, err
+	}
+	index, err := e.GetFieldIndex("p", field)
+	if err != nil {
+		return []string{}, err
+	}
+
+	values := make([]string, 0)
+	existMap := make(map[string]struct{}) // remove duplicates
+	for _, rule := range e.model["p"]["p"].Policy {
+		if !e.matchDomain(domainIndex, domain, rule) {
+			continue
+		}
+		if _, ok := existMap[rule[index]]; !ok {
+			values = append(values, rule[index])
+			existMap[rule[index]] = struct{}{}
+		}
+	}
+	return values, nil
+}
@@ -66,3 +66,24 @@ func (e *SyncedEnforcer) DeleteDomains(domains ...string) (bool, error) {
 	defer e.m.Unlock()
 	return e.Enforcer.DeleteDomains(domains...)
 }
+
+// GetDomainsForUser gets the domains in which a user has a role or a permission, directly or through a role.
+func (e *SyncedEnforcer) GetDomainsForUser(user string) ([]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetDomainsForUser(user)
+}
+
+// GetAllObjectsByDomain gets the distinct objects of the policy rules applying to the domain.
+func (e *SyncedEnforcer) GetAllObjectsByDomain(domain string) ([]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetAllObjectsByDomain(domain)
+}
+
+// GetAllActionsByDomain gets the distinct actions of the policy rules applying to the domain.
+func (e *SyncedEnforcer) GetAllActionsByDomain(domain string) ([]string, error) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return e.Enforcer.GetAllActionsByDomain(domain)
+}
@@ -16,8 +16,10 @@ package casbin
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/util"
 )
 
@@ -52,6 +54,32 @@ func TestGetImplicitRolesForDomainUser(t *testing.T) {
 	testGetImplicitRolesInDomain(t, e, "alice", "domain1", []string{"role:global_admin", "role:reader", "role:writer"})
 }
 
+func testGetImplicitUsersForRoleInDomain(t *testing.T, e *Enforcer, name string, domain string, res []string) {
+	t.Helper()
+	myRes, err := e.GetImplicitUsersForRoleInDomain(name, domain)
+	if err != nil {
+		t.Error(err)
+	}
+	t.Log("Implicit users for ", name, " under ", domain, ": ", myRes)
+
+	if !util.SetEquals(res, myRes) {
+		t.Error("Implicit users for ", name, " under ", domain, ": ", myRes, ", supposed to be ", res)
+	}
+}
+
+func TestGetImplicitUsersForRoleInDomain(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_hierarchy_with_domains_policy.csv")
+
+	testGetImplicitUsersForRoleInDomain(t, e, "role:reader", "domain1", []string{"alice"})
+	testGetImplicitUsersForRoleInDomain(t, e, "role:global_admin", "domain1", []string{"alice"})
+	testGetImplicitUsersForRoleInDomain(t, e, "role:reader", "domain2", []string{})
+
+	_, _ = e.AddRoleForUserInDomain("bob", "role:writer", "domain1")
+	_, _ = e.AddRoleForUserInDomain("role:reader", "role:global_admin", "domain1")
+	testGetImplicitUsersForRoleInDomain(t, e, "role:reader", "domain1", []string{"alice"})
+	testGetImplicitUsersForRoleInDomain(t, e, "role:writer", "domain1", []string{"alice", "bob"})
+}
+
 // TestUserAPIWithDomains: Add by Gordon.
 func TestUserAPIWithDomains(t *testing.T) {
 	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
@@ -208,6 +236,18 @@ func TestGetDomainsForUser(t *testing.T) {
 	testGetDomainsForUser(t, e, []string{"domain1", "domain2"}, "alice")
 	testGetDomainsForUser(t, e, []string{"domain2", "domain3"}, "bob")
 	testGetDomainsForUser(t, e, []string{"domain3"}, "user")
+
+	// Domains of the permissions of the user and of its roles.
+	_, _ = e.AddPolicy("carol", "domain4", "data4", "read")
+	_, _ = e.AddPolicy("user", "domain5", "data5", "read")
+	_, _ = e.AddGroupingPolicy("carol", "user", "domain5")
+	testGetDomainsForUser(t, e, []string{"domain4", "domain5"}, "carol")
+	testGetDomainsForUser(t, e, []string{"domain2", "domain3"}, "bob")
+
+	myRes, _ := e.GetDomainsForUser("alice")
+	if len(myRes) != 2 {
+		t.Errorf("domains for user alice should not be duplicated, got %v", myRes)
+	}
 }
 
 func testGetAllUsersByDomain(t *testing.T, e *Enforcer, domain string, expected []string) {
@@ -423,3 +463,79 @@ func TestGetRolesForUserInDomainWithConditionalFunctions(t *testing.T) {
 		}
 	})
 }
+
+func TestDomainOptionalRoleDefinition(t *testing.T) {
+	text := `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _, _?
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+	scoped := "g(r.sub, p.sub, r.dom) && r.obj == p.obj && r.act == p.act"
+
+	m, _ := model.NewModelFromString(text)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("admin", "data1", "read")
+	_, _ = e.AddGroupingPolicy("alice", "admin", "domain1")
+
+	// Without a domain, the role granted in any domain counts.
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "read", true)
+	testDomainEnforce(t, e, "alice", "domain2", "data1", "read", true)
+	testDomainEnforce(t, e, "bob", "domain1", "data1", "read", false)
+
+	// With a domain, the check stays scoped to it.
+	if res, _ := e.EnforceWithMatcher(scoped, "alice", "domain1", "data1", "read"); !res {
+		t.Error("alice should be admin in domain1")
+	}
+	if res, _ := e.EnforceWithMatcher(scoped, "alice", "domain2", "data1", "read"); res {
+		t.Error("alice should not be admin in domain2")
+	}
+
+	// The domain is only optional when the role definition opts in.
+	m, _ = model.NewModelFromString(strings.Replace(text, "_?", "_", 1))
+	e, _ = NewEnforcer(m)
+	_, _ = e.AddPolicy("admin", "data1", "read")
+	_, _ = e.AddGroupingPolicy("alice", "admin", "domain1")
+	testDomainEnforce(t, e, "alice", "domain1", "data1", "read", false)
+}
+
+func TestGetAllObjectsAndActionsByDomain(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domain_pattern_model.conf", "examples/rbac_with_domain_pattern_policy.csv")
+
+	objects, _ := e.GetAllObjectsByDomain("domain1")
+	if !util.ArrayEquals(objects, []string{"data1"}) {
+		t.Errorf("objects of domain1: %v, supposed to be [data1]", objects)
+	}
+	if objects, _ = e.GetAllObjectsByDomain("domain4"); objects == nil || len(objects) != 0 {
+		t.Errorf("objects of domain4: %#v, supposed to be empty", objects)
+	}
+
+	e.AddNamedDomainMatchingFunc("g", "KeyMatch", util.KeyMatch)
+	objects, _ = e.GetAllObjectsByDomain("domain1")
+	if !util.ArrayEquals(objects, []string{"data1", "data3"}) {
+		t.Errorf("objects of domain1: %v, supposed to be [data1 data3]", objects)
+	}
+	actions, _ := e.GetAllActionsByDomain("domain2")
+	if !util.ArrayEquals(actions, []string{"read", "write"}) {
+		t.Errorf("actions of domain2: %v, supposed to be [read write]", actions)
+	}
+	actions, _ = e.GetAllActionsByDomain("domain3")
+	if !util.ArrayEquals(actions, []string{"read"}) {
+		t.Errorf("actions of domain3: %v, supposed to be [read]", actions)
+	}
+
+	e, _ = NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if _, err := e.GetAllObjectsByDomain("domain1"); err == nil {
+		t.Error("model without domains should be an error")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// requestTagName is the struct tag mapping a field to a request token, e.g. `casbin:"sub"`.
+const requestTagName = "casbin"
+
+// requestBinding maps the fields of a registered struct type to the tokens of a request definition.
+type requestBinding struct {
+	ctx    EnforceContext
+	fields []int // fields[i] is the index of the struct field for the i-th request token.
+}
+
+// RegisterRequestType registers a struct type whose fields build the requests of the request definition rType
+// for EnforceTyped. Every token of the request definition must be bound to exactly one exported field
+// by the "casbin" struct tag, for example:
+//
+//	type Request struct {
+//		User   string `casbin:"sub"`
+//		Path   string `casbin:"obj"`
+//		Method string `casbin:"act"`
+//	}
+//
+//	err := e.RegisterRequestType("r", Request{})
+//
+// The requests are evaluated with the policy, effect and matcher definitions of the same suffix,
+// e.g. p2, e2 and m2 for r2, which must exist. Use RegisterRequestTypeWithContext to pair
+// the request definition with other definitions.
+func (e *Enforcer) RegisterRequestType(rType string, req interface{}) error {
+	return e.RegisterRequestTypeWithContext(NewEnforceContext(strings.TrimPrefix(rType, "r")), req)
+}
+
+// RegisterRequestTypeWithContext registers a struct type whose fields build the requests of the request definition
+// ctx.RType for EnforceTyped, which evaluates them with the policy, effect and matcher definitions of ctx.
+// The fields are bound as by RegisterRequestType.
+func (e *Enforcer) RegisterRequestTypeWithContext(ctx EnforceContext, req interface{}) error {
+	rType := ctx.RType
+	t := reflect.TypeOf(req)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("request type should be a struct, got %v", t)
+	}
+
+	assertion, err := e.model.GetAssertion("r", rType)
+	if err != nil {
+		return err
+	}
+	for sec, key := range map[string]string{"p": ctx.PType, "e": ctx.EType, "m": ctx.MType} {
+		if _, err := e.model.GetAssertion(sec, key); err != nil {
+			return fmt.Errorf("request definition %s cannot be paired with %s: %w", rType, key, err)
+		}
+	}
+
+	tokens := make(map[string]int, len(assertion.Tokens))
+	for i, token := range assertion.Tokens {
+		tokens[strings.TrimPrefix(token, rType+"_")] = i
+	}
+
+	fields := make([]int, len(assertion.Tokens))
+	for i := range fields {
+		fields[i] = -1
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup(requestTagName)
+		if !ok || name == "-" {
+			continue
+		}
+		index, ok := tokens[name]
+		if !ok {
+			return fmt.Errorf("field %s of %v is bound to %s.%s, which is not in the request definition", field.Name, t, rType, name)
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf("field %s of %v is bound to %s.%s but is not exported", field.Name, t, rType, name)
+		}
+		if fields[index] != -1 {
+			return fmt.Errorf("%s.%s is bound to more than one field of %v", rType, name, t)
+		}
+		fields[index] = i
+	}
+	for name, index := range tokens {
+		if fields[index] == -1 {
+			return fmt.Errorf("%s.%s is not bound to any field of %v", rType, name, t)
+		}
+	}
+
+	e.requestTypes.Store(t, requestBinding{ctx: ctx, fields: fields})
+	return nil
+}
+
+// EnforceTyped decides whether the request built from a struct registered by RegisterRequestType is allowed.
+func (e *Enforcer) EnforceTyped(req interface{}) (bool, error) {
+	rvals, err := e.typedRequestValues(req)
+	if err != nil {
+		return false, err
+	}
+	return e.Enforce(rvals...)
+}
+
+// typedRequestValues builds the request values from a registered request struct.
+func (e *Enforcer) typedRequestValues(req interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(req)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("request should not be nil")
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("request should not be nil")
+		}
+		v = v.Elem()
+	}
+
+	value, ok := e.requestTypes.Load(v.Type())
+	if !ok {
+		return nil, fmt.Errorf("request type %v is not registered", v.Type())
+	}
+	binding := value.(requestBinding)
+
+	rvals := make([]interface{}, 0, len(binding.fields)+1)
+	if binding.ctx != NewEnforceContext("") {
+		rvals = append(rvals, binding.ctx)
+	}
+	for _, index := range binding.fields {
+		rvals = append(rvals, v.Field(index).Interface())
+	}
+	return rvals, nil
+}
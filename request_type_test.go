@@ -0,0 +1,133 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+type testTypedRequest struct {
+	Method  string `casbin:"act"`
+	Subject string `casbin:"sub"`
+	Object  string `casbin:"obj"`
+	Comment string
+}
+
+func testEnforceTyped(t *testing.T, e IEnforcer, req interface{}, res bool) {
+	t.Helper()
+	myRes, err := e.EnforceTyped(req)
+	if err != nil {
+		t.Errorf("EnforceTyped Error: %s", err)
+	} else if myRes != res {
+		t.Errorf("%v: %t, supposed to be %t", req, myRes, res)
+	}
+}
+
+func TestEnforceTyped(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	if err := e.RegisterRequestType("r", testTypedRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	testEnforceTyped(t, e, testTypedRequest{Subject: "alice", Object: "data1", Method: "read"}, true)
+	testEnforceTyped(t, e, &testTypedRequest{Subject: "alice", Object: "data1", Method: "write"}, false)
+	testEnforceTyped(t, e, testTypedRequest{Subject: "bob", Object: "data2", Method: "write"}, true)
+
+	if _, err := e.EnforceTyped(struct{ Sub string }{"alice"}); err == nil {
+		t.Error("unregistered request type should fail")
+	}
+
+	se, _ := NewSyncedEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	if err := se.RegisterRequestType("r", &testTypedRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	testEnforceTyped(t, se, testTypedRequest{Subject: "alice", Object: "data1", Method: "read"}, true)
+}
+
+func TestRegisterRequestTypeError(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+
+	if err := e.RegisterRequestType("r", "alice"); err == nil {
+		t.Error("non-struct request type should fail")
+	}
+	if err := e.RegisterRequestType("r2", testTypedRequest{}); err == nil {
+		t.Error("unknown request definition should fail")
+	}
+
+	type missingToken struct {
+		Sub string `casbin:"sub"`
+		Obj string `casbin:"obj"`
+	}
+	if err := e.RegisterRequestType("r", missingToken{}); err == nil {
+		t.Error("request type missing a token should fail")
+	}
+
+	type unknownToken struct {
+		Sub string `casbin:"sub"`
+		Obj string `casbin:"obj"`
+		Act string `casbin:"action"`
+	}
+	if err := e.RegisterRequestType("r", unknownToken{}); err == nil {
+		t.Error("request type with an unknown token should fail")
+	}
+
+	type duplicateToken struct {
+		Sub  string `casbin:"sub"`
+		Obj  string `casbin:"obj"`
+		Act  string `casbin:"act"`
+		Verb string `casbin:"act"`
+	}
+	if err := e.RegisterRequestType("r", duplicateToken{}); err == nil {
+		t.Error("request type binding a token twice should fail")
+	}
+}
+
+func TestRegisterRequestTypeWithContext(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+r2 = sub, obj
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+m2 = r2.sub == p.sub && r2.obj == p.obj
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("alice", "data1", "read")
+
+	type objectRequest struct {
+		Sub string `casbin:"sub"`
+		Obj string `casbin:"obj"`
+	}
+	if err := e.RegisterRequestType("r2", objectRequest{}); err == nil {
+		t.Error("request definition without p2 and e2 should fail")
+	}
+	if err := e.RegisterRequestTypeWithContext(EnforceContext{RType: "r2", PType: "p", EType: "e", MType: "m3"}, objectRequest{}); err == nil {
+		t.Error("unknown matcher definition should fail")
+	}
+	if err := e.RegisterRequestTypeWithContext(EnforceContext{RType: "r2", PType: "p", EType: "e", MType: "m2"}, objectRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	testEnforceTyped(t, e, objectRequest{Sub: "alice", Obj: "data1"}, true)
+	testEnforceTyped(t, e, objectRequest{Sub: "alice", Obj: "data2"}, false)
+}
@@ -0,0 +1,386 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// RuleCodecFunc transforms a rule of the policy type ptype in the section sec, see SetRuleCodec.
+type RuleCodecFunc func(sec string, ptype string, rule []string) []string
+
+// SetRuleCodec sets the functions transforming the rules between their encoding in the storage and the model:
+// decode is applied to every rule loaded from the adapter, by LoadPolicy, LoadFilteredPolicy and
+// LoadIncrementalFilteredPolicy, and encode to every rule written to the adapter, by SavePolicy and Auto-Save.
+// Both must keep the number of fields of a rule. The field values of filtered removals are encoded as
+// the fields of a rule, except empty values, which match any value. A nil function leaves the rules unchanged.
+func (e *Enforcer) SetRuleCodec(decode RuleCodecFunc, encode RuleCodecFunc) {
+	e.ruleDecoder = decode
+	e.ruleEncoder = encode
+	e.adapter = e.wrapRuleCodec(unwrapRuleCodec(e.adapter))
+}
+
+// wrapRuleCodec wraps the adapter to apply the rule codec, if any.
+func (e *Enforcer) wrapRuleCodec(adapter persist.Adapter) persist.Adapter {
+	if adapter == nil || e.ruleDecoder == nil && e.ruleEncoder == nil {
+		return adapter
+	}
+	return &codecAdapter{Adapter: adapter, decode: e.ruleDecoder, encode: e.ruleEncoder}
+}
+
+// unwrapRuleCodec returns the adapter wrapped by wrapRuleCodec.
+func unwrapRuleCodec(adapter persist.Adapter) persist.Adapter {
+	if a, ok := adapter.(*codecAdapter); ok {
+		return a.Adapter
+	}
+	return adapter
+}
+
+// The codecAdapter has the methods of all the optional adapter interfaces, so whether the storage supports one
+// is checked on the adapter it wraps by the functions below, which return the adapter as that interface if so.
+
+func asIncrementalAdapter(adapter persist.Adapter) (persist.IncrementalAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.IncrementalAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.IncrementalAdapter)
+	return a, ok
+}
+
+func asChangeAdapter(adapter persist.Adapter) (persist.ChangeAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.ChangeAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.ChangeAdapter)
+	return a, ok
+}
+
+func asUpdatableAdapter(adapter persist.Adapter) (persist.UpdatableAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.UpdatableAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.UpdatableAdapter)
+	return a, ok
+}
+
+func asFilteredAdapter(adapter persist.Adapter) (persist.FilteredAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.FilteredAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.FilteredAdapter)
+	return a, ok
+}
+
+func asVersionedAdapter(adapter persist.Adapter) (persist.VersionedAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.VersionedAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.VersionedAdapter)
+	return a, ok
+}
+
+func asTransactionalAdapter(adapter persist.Adapter) (persist.TransactionalAdapter, bool) {
+	if _, ok := unwrapRuleCodec(adapter).(persist.TransactionalAdapter); !ok {
+		return nil, false
+	}
+	a, ok := adapter.(persist.TransactionalAdapter)
+	return a, ok
+}
+
+// codecAdapter decodes the rules loaded from the underlying adapter and encodes the rules written to it.
+// It falls back to the methods of persist.Adapter when the underlying adapter does not support a batch,
+// and returns a "not implemented" error from the methods of the other interfaces the underlying adapter lacks.
+type codecAdapter struct {
+	persist.Adapter
+	decode RuleCodecFunc
+	encode RuleCodecFunc
+}
+
+func (a *codecAdapter) decodeRules(sec string, ptype string, rules [][]string) [][]string {
+	if a.decode == nil || rules == nil {
+		return rules
+	}
+	res := make([][]string, len(rules))
+	for i, rule := range rules {
+		res[i] = a.decode(sec, ptype, rule)
+	}
+	return res
+}
+
+func (a *codecAdapter) encodeRule(sec string, ptype string, rule []string) []string {
+	if a.encode == nil {
+		return rule
+	}
+	return a.encode(sec, ptype, rule)
+}
+
+func (a *codecAdapter) encodeRules(sec string, ptype string, rules [][]string) [][]string {
+	if a.encode == nil || rules == nil {
+		return rules
+	}
+	res := make([][]string, len(rules))
+	for i, rule := range rules {
+		res[i] = a.encode(sec, ptype, rule)
+	}
+	return res
+}
+
+// encodeFieldValues encodes the values of a filter as the fields of a rule, leaving the empty values as they are.
+func (a *codecAdapter) encodeFieldValues(sec string, ptype string, fieldIndex int, fieldValues []string) []string {
+	if a.encode == nil {
+		return fieldValues
+	}
+	rule := make([]string, fieldIndex+len(fieldValues))
+	copy(rule[fieldIndex:], fieldValues)
+	encoded := a.encode(sec, ptype, rule)
+	res := make([]string, len(fieldValues))
+	for i, value := range fieldValues {
+		if value != "" && fieldIndex+i < len(encoded) {
+			res[i] = encoded[fieldIndex+i]
+		}
+	}
+	return res
+}
+
+// policySizes returns the number of rules of each policy type of the model.
+func policySizes(m model.Model) map[string]map[string]int {
+	sizes := map[string]map[string]int{}
+	for _, sec := range []string{"p", "g"} {
+		sizes[sec] = map[string]int{}
+		for ptype, ast := range m[sec] {
+			sizes[sec][ptype] = len(ast.Policy)
+		}
+	}
+	return sizes
+}
+
+// decodePolicy decodes the rules of the model after the ones counted in from, or all rules if from is nil.
+func (a *codecAdapter) decodePolicy(m model.Model, from map[string]map[string]int) {
+	if a.decode == nil {
+		return
+	}
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range m[sec] {
+			sec, ptype := sec, ptype
+			_ = m.TransformPolicy(sec, ptype, from[sec][ptype], func(rule []string) []string {
+				return a.decode(sec, ptype, rule)
+			})
+		}
+	}
+}
+
+// encodePolicy returns a copy of the model with encoded rules.
+func (a *codecAdapter) encodePolicy(m model.Model) model.Model {
+	if a.encode == nil || m == nil {
+		return m
+	}
+	encoded := m.Copy()
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range encoded[sec] {
+			sec, ptype := sec, ptype
+			_ = encoded.TransformPolicy(sec, ptype, 0, func(rule []string) []string {
+				return a.encode(sec, ptype, rule)
+			})
+		}
+	}
+	return encoded
+}
+
+// LoadPolicy loads all policy rules from the underlying adapter and decodes them.
+func (a *codecAdapter) LoadPolicy(m model.Model) error {
+	err := a.Adapter.LoadPolicy(m)
+	a.decodePolicy(m, nil)
+	return err
+}
+
+// SavePolicy encodes all policy rules and saves them to the underlying adapter.
+func (a *codecAdapter) SavePolicy(m model.Model) error {
+	return a.Adapter.SavePolicy(a.encodePolicy(m))
+}
+
+// LoadPolicyVersioned loads all policy rules from the underlying persist.VersionedAdapter
+// and decodes them, along with the version of the storage.
+func (a *codecAdapter) LoadPolicyVersioned(m model.Model) (string, error) {
+	va, ok := a.Adapter.(persist.VersionedAdapter)
+	if !ok {
+		return "", errors.New(notImplemented)
+	}
+	version, err := va.LoadPolicyVersioned(m)
+	a.decodePolicy(m, nil)
+	return version, err
+}
+
+// SavePolicyVersioned encodes all policy rules and saves them to the underlying persist.VersionedAdapter.
+func (a *codecAdapter) SavePolicyVersioned(m model.Model, version string) (string, error) {
+	va, ok := a.Adapter.(persist.VersionedAdapter)
+	if !ok {
+		return "", errors.New(notImplemented)
+	}
+	return va.SavePolicyVersioned(a.encodePolicy(m), version)
+}
+
+// LoadFilteredPolicy loads the policy rules matching the filter from the underlying persist.FilteredAdapter
+// and decodes them, leaving the rules already in the model unchanged.
+func (a *codecAdapter) LoadFilteredPolicy(m model.Model, filter interface{}) error {
+	fa, ok := a.Adapter.(persist.FilteredAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	from := policySizes(m)
+	err := fa.LoadFilteredPolicy(m, filter)
+	a.decodePolicy(m, from)
+	return err
+}
+
+// IsFiltered returns true if the underlying adapter has loaded a filtered policy.
+func (a *codecAdapter) IsFiltered() bool {
+	fa, ok := a.Adapter.(persist.FilteredAdapter)
+	return ok && fa.IsFiltered()
+}
+
+// AddPolicy encodes a policy rule and adds it to the underlying adapter.
+func (a *codecAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.Adapter.AddPolicy(sec, ptype, a.encodeRule(sec, ptype, rule))
+}
+
+// RemovePolicy encodes a policy rule and removes it from the underlying adapter.
+func (a *codecAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.Adapter.RemovePolicy(sec, ptype, a.encodeRule(sec, ptype, rule))
+}
+
+// RemoveFilteredPolicy encodes the field values and removes the matching rules from the underlying adapter.
+func (a *codecAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.Adapter.RemoveFilteredPolicy(sec, ptype, fieldIndex, a.encodeFieldValues(sec, ptype, fieldIndex, fieldValues)...)
+}
+
+// AddPolicies encodes policy rules and adds them to the underlying adapter.
+func (a *codecAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return applyAddOperation(a.Adapter, persist.PolicyOperation{
+		Type:       persist.OperationAdd,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      a.encodeRules(sec, ptype, rules),
+	})
+}
+
+// RemovePolicies encodes policy rules and removes them from the underlying adapter.
+func (a *codecAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return applyRemoveOperation(a.Adapter, persist.PolicyOperation{
+		Type:       persist.OperationRemove,
+		Section:    sec,
+		PolicyType: ptype,
+		Rules:      a.encodeRules(sec, ptype, rules),
+	})
+}
+
+// UpdatePolicy encodes policy rules and updates them in the underlying persist.UpdatableAdapter.
+func (a *codecAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	ua, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	return ua.UpdatePolicy(sec, ptype, a.encodeRule(sec, ptype, oldRule), a.encodeRule(sec, ptype, newRule))
+}
+
+// UpdatePolicies encodes policy rules and updates them in the underlying persist.UpdatableAdapter.
+func (a *codecAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	ua, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	return ua.UpdatePolicies(sec, ptype, a.encodeRules(sec, ptype, oldRules), a.encodeRules(sec, ptype, newRules))
+}
+
+// UpdateFilteredPolicies encodes the new rules and the field values, replaces the matching rules
+// in the underlying persist.UpdatableAdapter and returns the decoded old rules.
+func (a *codecAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	ua, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return nil, errors.New(notImplemented)
+	}
+	oldRules, err := ua.UpdateFilteredPolicies(sec, ptype, a.encodeRules(sec, ptype, newRules), fieldIndex, a.encodeFieldValues(sec, ptype, fieldIndex, fieldValues)...)
+	return a.decodeRules(sec, ptype, oldRules), err
+}
+
+// AddPolicyRule encodes a policy rule and adds it to the underlying persist.IncrementalAdapter.
+func (a *codecAdapter) AddPolicyRule(sec string, ptype string, rule []string) error {
+	ia, ok := a.Adapter.(persist.IncrementalAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	return ia.AddPolicyRule(sec, ptype, a.encodeRule(sec, ptype, rule))
+}
+
+// RemovePolicyRule encodes a policy rule and removes it from the underlying persist.IncrementalAdapter.
+func (a *codecAdapter) RemovePolicyRule(sec string, ptype string, rule []string) error {
+	ia, ok := a.Adapter.(persist.IncrementalAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	return ia.RemovePolicyRule(sec, ptype, a.encodeRule(sec, ptype, rule))
+}
+
+// UpdatePolicyRule encodes policy rules and updates them in the underlying persist.IncrementalAdapter.
+func (a *codecAdapter) UpdatePolicyRule(sec string, ptype string, oldRule []string, newRule []string) error {
+	ia, ok := a.Adapter.(persist.IncrementalAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	return ia.UpdatePolicyRule(sec, ptype, a.encodeRule(sec, ptype, oldRule), a.encodeRule(sec, ptype, newRule))
+}
+
+// ApplyChanges encodes the rules of the changes and persists them with the underlying persist.ChangeAdapter.
+func (a *codecAdapter) ApplyChanges(changes []persist.PolicyChange) error {
+	ca, ok := a.Adapter.(persist.ChangeAdapter)
+	if !ok {
+		return errors.New(notImplemented)
+	}
+	encoded := make([]persist.PolicyChange, len(changes))
+	for i, change := range changes {
+		change.Rules = a.encodeRules(change.Section, change.PolicyType, change.Rules)
+		change.OldRules = a.encodeRules(change.Section, change.PolicyType, change.OldRules)
+		encoded[i] = change
+	}
+	return ca.ApplyChanges(encoded)
+}
+
+// BeginTransaction starts a transaction of the underlying persist.TransactionalAdapter,
+// whose adapter also applies the rule codec.
+func (a *codecAdapter) BeginTransaction(ctx context.Context) (persist.TransactionContext, error) {
+	ta, ok := a.Adapter.(persist.TransactionalAdapter)
+	if !ok {
+		return nil, errors.New(notImplemented)
+	}
+	txContext, err := ta.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &codecTransactionContext{TransactionContext: txContext, codec: a}, nil
+}
+
+// codecTransactionContext is a transaction context whose adapter applies the rule codec.
+type codecTransactionContext struct {
+	persist.TransactionContext
+	codec *codecAdapter
+}
+
+// GetAdapter returns the adapter of the transaction, applying the rule codec.
+func (c *codecTransactionContext) GetAdapter() persist.Adapter {
+	return &codecAdapter{Adapter: c.TransactionContext.GetAdapter(), decode: c.codec.decode, encode: c.codec.encode}
+}
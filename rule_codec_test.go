@@ -0,0 +1,217 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/persist"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func urlDecodeRule(sec string, ptype string, rule []string) []string {
+	res := make([]string, len(rule))
+	for i, value := range rule {
+		res[i], _ = url.QueryUnescape(value)
+	}
+	return res
+}
+
+func urlEncodeRule(sec string, ptype string, rule []string) []string {
+	res := make([]string, len(rule))
+	for i, value := range rule {
+		res[i] = url.QueryEscape(value)
+	}
+	return res
+}
+
+// testWritePolicyFile writes a policy file into dir and returns its path.
+func testWritePolicyFile(t *testing.T, dir string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "policy.csv")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRuleCodecLoadAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := testWritePolicyFile(t, dir, "p, alice, %2Fdata%2Fsecret, read\np, bob, %2Fdata, write\n")
+	a := fileadapter.NewAdapter(path)
+	e, _ := NewEnforcer("examples/basic_model.conf", a)
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+	if e.GetAdapter() != a {
+		t.Error("GetAdapter should return the adapter without the codec")
+	}
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testEnforce(t, e, "alice", "/data/secret", "read", true)
+	testEnforce(t, e, "bob", "/data", "write", true)
+
+	_, _ = e.AddPolicy("carol", "/a b", "read")
+	if err := e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(content), "p, carol, %2Fa+b, read") {
+		t.Errorf("saved policy should be encoded, got %s", content)
+	}
+	policy, _ := e.GetPolicy()
+	if !util.Array2DEquals([][]string{{"alice", "/data/secret", "read"}, {"bob", "/data", "write"}, {"carol", "/a b", "read"}}, policy) {
+		t.Errorf("the policy in memory should stay decoded, got %v", policy)
+	}
+}
+
+func TestRuleCodecFilteredPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := testWritePolicyFile(t, dir, "p, alice, %2Fdata1, read\np, bob, %2Fdata2, write\n")
+	e, _ := NewEnforcer("examples/basic_model.conf", fileadapter.NewFilteredAdapter(path))
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+
+	if err := e.LoadFilteredPolicy(&fileadapter.Filter{P: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadIncrementalFilteredPolicy(&fileadapter.Filter{P: []string{"bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ := e.GetPolicy()
+	if !util.Array2DEquals([][]string{{"alice", "/data1", "read"}, {"bob", "/data2", "write"}}, policy) {
+		t.Errorf("each loaded rule should be decoded once, got %v", policy)
+	}
+}
+
+func TestRuleCodecAutoSave(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewEnforcer("examples/basic_model.conf", a)
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+
+	_, _ = e.AddPolicy("alice", "/a b", "read")
+	_, _ = e.AddPolicies([][]string{{"bob", "/c&d", "write"}})
+	_, _ = e.RemovePolicy("alice", "/a b", "read")
+
+	if !util.Array2DEquals([][]string{{"alice", "%2Fa+b", "read"}, {"bob", "%2Fc%26d", "write"}}, a.added) {
+		t.Errorf("added rules should be encoded, got %v", a.added)
+	}
+	if !util.Array2DEquals([][]string{{"alice", "%2Fa+b", "read"}}, a.removed) {
+		t.Errorf("removed rules should be encoded, got %v", a.removed)
+	}
+
+	e.SetRuleCodec(nil, nil)
+	if e.adapter != a {
+		t.Error("unsetting the codec should restore the adapter")
+	}
+}
+
+func TestBufferedEnforcerRuleCodec(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+
+	_, _ = e.AddPolicy("alice", "/a b", "read")
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !util.Array2DEquals([][]string{{"alice", "%2Fa+b", "read"}}, a.added) {
+		t.Errorf("flushed rules should be encoded, got %v", a.added)
+	}
+	if e.GetAdapter() != a {
+		t.Error("GetAdapter should return the adapter without the codec")
+	}
+}
+
+func TestRuleCodecIncrementalAdapter(t *testing.T) {
+	a := &ruleAdapter{}
+	e, _ := NewEnforcer("examples/basic_model.conf", a)
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+
+	if _, err := e.AddPolicy("alice", "/a b", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RemovePolicy("alice", "/a b", "read"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"add p [alice %2Fa+b read]", "remove p [alice %2Fa+b read]"}
+	if !util.ArrayEquals(expected, a.calls) {
+		t.Errorf("incremental calls: %v, supposed to be %v", a.calls, expected)
+	}
+
+	// The pending changes of a ChangeAdapter are encoded as well.
+	c := &changeAdapter{}
+	e, _ = NewEnforcer("examples/basic_model.conf", c)
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+	e.EnableAutoSave(false)
+	e.EnableChangeTracking(true)
+	_, _ = e.AddPolicy("alice", "/a b", "read")
+	if err := e.SavePolicyIncremental(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.changes) != 1 || !util.Array2DEquals([][]string{{"alice", "%2Fa+b", "read"}}, c.changes[0][0].Rules) {
+		t.Errorf("applied changes: %v, supposed to be encoded", c.changes)
+	}
+}
+
+func TestRuleCodecAdapterInterfaces(t *testing.T) {
+	a := &recordingAdapter{}
+	e, _ := NewEnforcer("examples/basic_model.conf", a)
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+
+	if err := e.LoadFilteredPolicy(nil); err == nil {
+		t.Error("loading a filtered policy should fail when the adapter doesn't support it")
+	}
+	if err := e.SavePolicyVersioned(); err == nil {
+		t.Error("saving a versioned policy should fail when the adapter doesn't support it")
+	}
+
+	te, _ := NewTransactionalEnforcer("examples/basic_model.conf", a)
+	te.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+	if _, err := te.BeginTransaction(context.Background()); err == nil {
+		t.Error("beginning a transaction should fail when the adapter doesn't support it")
+	}
+}
+
+func TestBufferedEnforcerRuleCodecFlushError(t *testing.T) {
+	a := &recordingAdapter{fail: true}
+	e, _ := NewBufferedEnforcer("examples/basic_model.conf", a)
+	defer e.Close()
+	var reported error
+	e.SetFlushErrorCallback(func(ops []persist.PolicyOperation, err error) {
+		reported = err
+	})
+
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	e.SetRuleCodec(urlDecodeRule, urlEncodeRule)
+	if reported == nil {
+		t.Error("the failed flush should be reported through the flush error callback")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/constant"
+	Err "github.com/casbin/casbin/v2/errors"
+)
+
+// SetShadowDetection controls whether the rules added to a priority model are checked for being shadowed.
+// When enabled, adding a rule whose own request is decided by an existing rule of higher priority logs
+// an ErrShadowedPolicy error through the logger, as the new rule has no effect on that request.
+// The request of a rule is made of its values for the fields named like the request tokens,
+// rules lacking such a field are not checked. The rule is added either way.
+func (e *Enforcer) SetShadowDetection(enable bool) {
+	e.shadowDetection = enable
+}
+
+// detectShadowedRules logs the added rules of ptype that are shadowed by a rule of higher priority,
+// see SetShadowDetection.
+func (e *Enforcer) detectShadowedRules(sec string, ptype string, rules [][]string) {
+	if !e.shadowDetection || sec != "p" {
+		return
+	}
+
+	suffix := strings.TrimPrefix(ptype, "p")
+	eAssertion, ok := e.model["e"]["e"+suffix]
+	if !ok || (eAssertion.Value != constant.PriorityEffect && eAssertion.Value != constant.SubjectPriorityEffect) {
+		return
+	}
+	rAssertion, ok := e.model["r"]["r"+suffix]
+	if !ok {
+		return
+	}
+
+	fields := make([]int, len(rAssertion.Tokens))
+	for i, token := range rAssertion.Tokens {
+		index, err := e.GetFieldIndex(ptype, strings.TrimPrefix(token, "r"+suffix+"_"))
+		if err != nil {
+			return
+		}
+		fields[i] = index
+	}
+
+	for _, rule := range rules {
+		rvals := make([]interface{}, 0, len(fields)+1)
+		if suffix != "" {
+			rvals = append(rvals, NewEnforceContext(suffix))
+		}
+		for _, index := range fields {
+			rvals = append(rvals, rule[index])
+		}
+
+		var explain []string
+		if _, err := e.enforce("", &explain, rvals...); err != nil || len(explain) == 0 {
+			continue
+		}
+		if e.model.PolicyKey(sec, ptype, explain) != e.model.PolicyKey(sec, ptype, rule) {
+			e.logger.LogError(fmt.Errorf("%w: %v by %v", Err.ErrShadowedPolicy, rule, explain))
+		}
+	}
+}
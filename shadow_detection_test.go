@@ -0,0 +1,62 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"testing"
+
+	Err "github.com/casbin/casbin/v2/errors"
+	"github.com/casbin/casbin/v2/log"
+)
+
+// errorLogger records the errors logged to it.
+type errorLogger struct {
+	log.DefaultLogger
+	errs []error
+}
+
+func (l *errorLogger) LogError(err error, msg ...string) {
+	l.errs = append(l.errs, err)
+}
+
+func TestShadowDetection(t *testing.T) {
+	e, _ := NewEnforcer("examples/priority_model_explicit.conf", "examples/priority_policy_explicit.csv")
+	logger := &errorLogger{}
+	e.SetLogger(logger)
+
+	_, _ = e.AddPolicy("20", "alice", "data1", "read", "deny")
+	if len(logger.errs) != 0 {
+		t.Errorf("errors: %v, supposed to be none while shadow detection is disabled", logger.errs)
+	}
+
+	e.SetShadowDetection(true)
+	_, _ = e.AddPolicy("20", "alice", "data1", "write", "deny")
+	if len(logger.errs) != 1 || !errors.Is(logger.errs[0], Err.ErrShadowedPolicy) {
+		t.Fatalf("errors: %v, supposed to be one shadowed rule", logger.errs)
+	}
+	if ok, _ := e.HasPolicy("20", "alice", "data1", "write", "deny"); !ok {
+		t.Error("shadowed rule should still be added")
+	}
+
+	// Rules deciding their own request are not reported.
+	_, _ = e.AddPolicies([][]string{
+		{"0", "alice", "data1", "read", "deny"},
+		{"20", "carol", "data3", "read", "allow"},
+	})
+	if len(logger.errs) != 1 {
+		t.Errorf("errors: %v, supposed to be one shadowed rule", logger.errs)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// subjectIndex maps the subjects of the "p" policy to their rules, see EnableSubjectIndex.
+type subjectIndex struct {
+	// stale is set when the policy was replaced as a whole, so that the index is rebuilt on its next use.
+	stale      bool
+	fieldIndex int
+	// key is the policy key of the model, see model.SetPolicyKeyFunc.
+	key   func(rule []string) string
+	rules map[string][][]string
+}
+
+// EnableSubjectIndex enables or disables the index of the "p" policy by subject,
+// which makes HasPolicyForSubject O(1) and GetPolicyForSubject O(k) for the k rules of the subject,
+// instead of a scan of the whole policy.
+// Subjects are compared by the policy key of the model, e.g. case-insensitively with
+// model.MatchingOptions.CaseInsensitive, so the index must be enabled again after changing the matching options.
+// The index is kept up to date by the management API, and rebuilt after the policy is loaded, cleared
+// or its role links are rebuilt.
+// It costs one map entry per subject and one slice header per rule, as the rules themselves are shared
+// with the model, so it is worth enabling for large policies queried by subject.
+func (e *Enforcer) EnableSubjectIndex(enable bool) {
+	if !enable {
+		e.subjectIndex = nil
+		return
+	}
+	e.subjectIndex = &subjectIndex{stale: true}
+}
+
+// HasPolicyForSubject determines whether the "p" policy has any rule for the subject.
+func (e *Enforcer) HasPolicyForSubject(subject string) bool {
+	if e.subjectIndex == nil {
+		return len(e.policyForSubject(subject)) != 0
+	}
+	e.buildSubjectIndex()
+	return len(e.subjectIndex.rules[e.subjectIndex.key([]string{subject})]) != 0
+}
+
+// GetPolicyForSubject gets the rules of the "p" policy for the subject.
+func (e *Enforcer) GetPolicyForSubject(subject string) [][]string {
+	if e.subjectIndex == nil {
+		return e.policyForSubject(subject)
+	}
+	e.buildSubjectIndex()
+	rules := e.subjectIndex.rules[e.subjectIndex.key([]string{subject})]
+	res := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, append([]string(nil), rule...))
+	}
+	return res
+}
+
+// policyForSubject scans the "p" policy for the rules of the subject, without the index.
+func (e *Enforcer) policyForSubject(subject string) [][]string {
+	res := [][]string{}
+	assertion, ok := e.model["p"]["p"]
+	if !ok {
+		return res
+	}
+	fieldIndex, key := e.subjectFieldIndex(), e.model.PolicyKey("p", "p", []string{subject})
+	for _, rule := range assertion.Policy {
+		if fieldIndex < len(rule) && e.model.PolicyKey("p", "p", rule[fieldIndex:fieldIndex+1]) == key {
+			res = append(res, append([]string(nil), rule...))
+		}
+	}
+	return res
+}
+
+// subjectFieldIndex returns the index of the subject field of the "p" policy, the first one by default.
+func (e *Enforcer) subjectFieldIndex() int {
+	index, err := e.GetFieldIndex("p", constant.SubjectIndex)
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+// invalidatePolicyIndexes is called when the policy was replaced as a whole. It makes the subject index
+// be rebuilt on its next use and discards the cached implicit permissions.
+func (e *Enforcer) invalidatePolicyIndexes() {
+	e.policyRevision++
+	if e.subjectIndex != nil {
+		e.subjectIndex.stale = true
+	}
+}
+
+// buildSubjectIndex rebuilds the subject index from the policy if it is stale.
+func (e *Enforcer) buildSubjectIndex() {
+	idx := e.subjectIndex
+	if !idx.stale {
+		return
+	}
+	idx.fieldIndex = e.subjectFieldIndex()
+	idx.key = func(rule []string) string { return e.model.PolicyKey("p", "p", rule) }
+	idx.rules = map[string][][]string{}
+	if assertion, ok := e.model["p"]["p"]; ok {
+		for _, rule := range assertion.Policy {
+			idx.add(rule)
+		}
+	}
+	idx.stale = false
+}
+
+// updateSubjectIndex applies a change of the "p" policy to the subject index.
+func (e *Enforcer) updateSubjectIndex(opType persist.OperationType, sec string, ptype string, rules [][]string, oldRules [][]string) {
+	idx := e.subjectIndex
+	if idx == nil || idx.stale || sec != "p" || ptype != "p" {
+		return
+	}
+	switch opType {
+	case persist.OperationAdd:
+		for _, rule := range rules {
+			idx.add(rule)
+		}
+	case persist.OperationRemove:
+		for _, rule := range rules {
+			idx.remove(rule)
+		}
+	case persist.OperationUpdate:
+		for _, rule := range oldRules {
+			idx.remove(rule)
+		}
+		for _, rule := range rules {
+			idx.add(rule)
+		}
+	}
+}
+
+func (idx *subjectIndex) add(rule []string) {
+	if idx.fieldIndex >= len(rule) {
+		return
+	}
+	sub := idx.key(rule[idx.fieldIndex : idx.fieldIndex+1])
+	idx.rules[sub] = append(idx.rules[sub], rule)
+}
+
+func (idx *subjectIndex) remove(rule []string) {
+	if idx.fieldIndex >= len(rule) {
+		return
+	}
+	sub, key := idx.key(rule[idx.fieldIndex:idx.fieldIndex+1]), idx.key(rule)
+	rules := idx.rules[sub]
+	for i, r := range rules {
+		if idx.key(r) == key {
+			rules = append(rules[:i:i], rules[i+1:]...)
+			break
+		}
+	}
+	if len(rules) == 0 {
+		delete(idx.rules, sub)
+		return
+	}
+	idx.rules[sub] = rules
+}
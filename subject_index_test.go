@@ -0,0 +1,101 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func testPolicyForSubject(t *testing.T, e *Enforcer, subject string, res [][]string) {
+	t.Helper()
+	rules := e.GetPolicyForSubject(subject)
+	if !util.Set2DEquals(res, rules) {
+		t.Errorf("policy for %s: %v, supposed to be %v", subject, rules, res)
+	}
+	if e.HasPolicyForSubject(subject) != (len(res) != 0) {
+		t.Errorf("HasPolicyForSubject(%s): %t, supposed to be %t", subject, !(len(res) != 0), len(res) != 0)
+	}
+}
+
+func TestSubjectIndex(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.EnableAutoSave(false)
+	e.EnableSubjectIndex(true)
+
+	testPolicyForSubject(t, e, "alice", [][]string{{"alice", "data1", "read"}})
+	testPolicyForSubject(t, e, "data2_admin", [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testPolicyForSubject(t, e, "carol", nil)
+
+	_, _ = e.AddPolicies([][]string{{"carol", "data1", "read"}, {"alice", "data2", "write"}})
+	testPolicyForSubject(t, e, "carol", [][]string{{"carol", "data1", "read"}})
+	testPolicyForSubject(t, e, "alice", [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}})
+
+	_, _ = e.RemovePolicy("alice", "data1", "read")
+	testPolicyForSubject(t, e, "alice", [][]string{{"alice", "data2", "write"}})
+
+	_, _ = e.UpdatePolicy([]string{"carol", "data1", "read"}, []string{"dave", "data1", "read"})
+	testPolicyForSubject(t, e, "carol", nil)
+	testPolicyForSubject(t, e, "dave", [][]string{{"dave", "data1", "read"}})
+
+	_, _ = e.RemoveFilteredPolicy(1, "data2")
+	testPolicyForSubject(t, e, "alice", nil)
+	testPolicyForSubject(t, e, "data2_admin", nil)
+
+	e.ClearPolicy()
+	testPolicyForSubject(t, e, "dave", nil)
+
+	_ = e.LoadPolicy()
+	testPolicyForSubject(t, e, "alice", [][]string{{"alice", "data1", "read"}})
+
+	e.EnableSubjectIndex(false)
+	testPolicyForSubject(t, e, "bob", [][]string{{"bob", "data2", "write"}})
+}
+
+func TestSubjectIndexCaseInsensitive(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.EnableAutoSave(false)
+	if err := e.GetModel().SetMatchingOptions(model.MatchingOptions{CaseInsensitive: true}); err != nil {
+		t.Fatal(err)
+	}
+	testPolicyForSubject(t, e, "ALICE", [][]string{{"alice", "data1", "read"}})
+
+	e.EnableSubjectIndex(true)
+	testPolicyForSubject(t, e, "ALICE", [][]string{{"alice", "data1", "read"}})
+
+	_, _ = e.AddPolicy("Carol", "data1", "read")
+	testPolicyForSubject(t, e, "carol", [][]string{{"Carol", "data1", "read"}})
+
+	_, _ = e.RemovePolicy("CAROL", "DATA1", "READ")
+	testPolicyForSubject(t, e, "Carol", nil)
+}
+
+func TestSubjectIndexFilteredPolicy(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", fileadapter.NewFilteredAdapter("examples/rbac_with_domains_policy.csv"))
+	e.EnableSubjectIndex(true)
+	testPolicyForSubject(t, e, "admin", nil)
+
+	_ = e.LoadPolicy()
+	testPolicyForSubject(t, e, "admin", [][]string{
+		{"admin", "domain1", "data1", "read"}, {"admin", "domain1", "data1", "write"},
+		{"admin", "domain2", "data2", "read"}, {"admin", "domain2", "data2", "write"},
+	})
+
+	_ = e.LoadFilteredPolicy(&fileadapter.Filter{P: []string{"", "domain1"}})
+	testPolicyForSubject(t, e, "admin", [][]string{{"admin", "domain1", "data1", "read"}, {"admin", "domain1", "data1", "write"}})
+}
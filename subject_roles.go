@@ -0,0 +1,104 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/govaluate"
+)
+
+// subjectRolesRegex matches the roles of the subject of a request in an escaped matcher, e.g. r_sub.roles.
+var subjectRolesRegex = regexp.MustCompile(`\b(r[0-9]*_sub)\.roles\b`)
+
+// escapeSubjectRoles replaces the roles of the subject of a request in an escaped matcher, e.g. r_sub.roles,
+// by a parameter resolved by subjectRoles, e.g. r_sub_roles.
+func escapeSubjectRoles(expString string) string {
+	if !strings.Contains(expString, "_sub.roles") {
+		return expString
+	}
+	return subjectRolesRegex.ReplaceAllString(expString, "${1}_roles")
+}
+
+// subjectRoles lazily resolves the implicit roles of the subject of a request for the matcher,
+// which can use them as r.sub.roles, e.g. "admin" in r.sub.roles.
+// The roles are resolved once per request, on their first use by the matcher.
+// If the subject is not a string, e.g. a JSON object or a struct, r.sub.roles accesses its "roles" field instead.
+// As the expression evaluation spreads a leading list into the arguments of a function,
+// r.sub.roles must not be the first of several arguments, e.g. containsAny("a,b", r.sub.roles).
+type subjectRoles struct {
+	// token is the parameter of the roles in the escaped matcher, e.g. r_sub_roles.
+	token    string
+	resolve  func() (interface{}, error)
+	resolved bool
+	roles    interface{}
+	err      error
+}
+
+func (s *subjectRoles) get() (interface{}, error) {
+	if !s.resolved {
+		s.roles, s.err = s.resolve()
+		s.resolved = true
+	}
+	return s.roles, s.err
+}
+
+// subjectField accesses the "roles" field of a subject that is not a string, as the matcher would without
+// resolving the roles of the subject.
+func subjectField(sub interface{}) (interface{}, error) {
+	expression, err := govaluate.NewEvaluableExpression("sub.roles")
+	if err != nil {
+		return nil, err
+	}
+	return expression.Evaluate(map[string]interface{}{"sub": sub})
+}
+
+// newSubjectRoles returns the roles of the subject of the request for the matcher,
+// or nil if the matcher cannot reference them.
+func (e *Enforcer) newSubjectRoles(expString string, hasEval bool, rType string, rTokens map[string]int, rvals []interface{}) *subjectRoles {
+	if !hasEval && !strings.Contains(expString, "_sub.roles") {
+		return nil
+	}
+	return &subjectRoles{
+		token: rType + "_sub_roles",
+		resolve: func() (interface{}, error) {
+			i, ok := rTokens[rType+"_sub"]
+			if !ok || i >= len(rvals) {
+				return nil, errors.New("r.sub.roles requires the request definition to have a sub field")
+			}
+			sub, ok := rvals[i].(string)
+			if !ok {
+				return subjectField(rvals[i])
+			}
+			if _, ok := e.rmMap["g"]; !ok {
+				return nil, errors.New("r.sub.roles requires the role definition g")
+			}
+			var domain []string
+			if j, ok := rTokens[rType+"_dom"]; ok && j < len(rvals) {
+				if dom, ok := rvals[j].(string); ok {
+					domain = append(domain, dom)
+				}
+			}
+			roles, err := e.GetImplicitRolesForUser(sub, domain...)
+			res := make([]interface{}, len(roles))
+			for i, role := range roles {
+				res[i] = role
+			}
+			return res, err
+		},
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestSubjectRolesMatcher(t *testing.T) {
+	m, _ := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = (g(r.sub, p.sub) || "superuser" in r.sub.roles) && r.obj == p.obj && r.act == p.act
+`)
+	e, _ := NewEnforcer(m)
+	_, _ = e.AddPolicy("data1_admin", "data1", "read")
+	_, _ = e.AddGroupingPolicies([][]string{{"alice", "admin"}, {"admin", "superuser"}, {"bob", "data1_admin"}})
+
+	testEnforce(t, e, "alice", "data1", "read", true)
+	testEnforce(t, e, "bob", "data1", "read", true)
+	testEnforce(t, e, "carol", "data1", "read", false)
+
+	ok, err := e.EnforceWithMatcher(`containsAny("superuser,data1_admin", r.sub.roles)`, "bob", "data1", "read")
+	if err != nil || !ok {
+		t.Errorf("containsAny on the roles of bob: %t, %v", ok, err)
+	}
+	ok, err = e.EnforceWithMatcher(`containsAny("superuser,data1_admin", r.sub.roles)`, "carol", "data1", "read")
+	if err != nil || ok {
+		t.Errorf("containsAny on the roles of carol: %t, %v", ok, err)
+	}
+}
+
+func TestSubjectRolesMatcherWithDomains(t *testing.T) {
+	e, _ := NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	testDomainRoles := func(sub string, dom string, res bool) {
+		t.Helper()
+		ok, err := e.EnforceWithMatcher(`"admin" in r.sub.roles`, sub, dom, "data1", "read")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != res {
+			t.Errorf("%s, %s: %t, supposed to be %t", sub, dom, ok, res)
+		}
+	}
+	testDomainRoles("alice", "domain1", true)
+	testDomainRoles("alice", "domain2", false)
+	testDomainRoles("bob", "domain2", true)
+}
+
+func TestSubjectRolesWithoutRoleDefinition(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	if _, err := e.EnforceWithMatcher(`"admin" in r.sub.roles`, "alice", "data1", "read"); err == nil {
+		t.Error("r.sub.roles without a role definition should be an error")
+	}
+}
+
+func TestSubjectRolesOfObjectSubject(t *testing.T) {
+	e, _ := NewEnforcer("examples/basic_model.conf", "examples/basic_policy.csv")
+	e.EnableAcceptJsonRequest(true)
+
+	ok, err := e.EnforceWithMatcher(`"admin" in r.sub.roles && r.obj == "data1"`, `{"name": "alice", "roles": ["admin"]}`, "data1", "read")
+	if err != nil || !ok {
+		t.Errorf("roles field of a JSON subject: %t, %v", ok, err)
+	}
+	ok, err = e.EnforceWithMatcher(`"admin" in r.sub.roles`, map[string]interface{}{"roles": []interface{}{"user"}}, "data1", "read")
+	if err != nil || ok {
+		t.Errorf("roles field of a map subject: %t, %v", ok, err)
+	}
+}
@@ -33,9 +33,12 @@ type TransactionBuffer struct {
 // NewTransactionBuffer creates a new transaction buffer with a model snapshot.
 // The snapshot represents the state of the model at the beginning of the transaction.
 func NewTransactionBuffer(baseModel model.Model) *TransactionBuffer {
+	// The changes applied to the snapshot and its copies are previews, they are not reported.
+	snapshot := baseModel.Copy()
+	snapshot.SetPolicyChangedMuted(true)
 	return &TransactionBuffer{
 		operations:    make([]persist.PolicyOperation, 0),
-		modelSnapshot: baseModel.Copy(),
+		modelSnapshot: snapshot,
 	}
 }
 
@@ -101,15 +101,15 @@ func (tx *Transaction) applyOperationsToDatabase() error {
 	for _, op := range operations {
 		switch op.Type {
 		case persist.OperationAdd:
-			if err := tx.applyAddOperationToDatabase(txAdapter, op); err != nil {
+			if err := applyAddOperation(txAdapter, op); err != nil {
 				return err
 			}
 		case persist.OperationRemove:
-			if err := tx.applyRemoveOperationToDatabase(txAdapter, op); err != nil {
+			if err := applyRemoveOperation(txAdapter, op); err != nil {
 				return err
 			}
 		case persist.OperationUpdate:
-			if err := tx.applyUpdateOperationToDatabase(txAdapter, op); err != nil {
+			if err := applyUpdateOperation(txAdapter, op); err != nil {
 				return err
 			}
 		}
@@ -118,8 +118,11 @@ func (tx *Transaction) applyOperationsToDatabase() error {
 	return nil
 }
 
-// applyAddOperationToDatabase applies an add operation to the database.
-func (tx *Transaction) applyAddOperationToDatabase(adapter persist.Adapter, op persist.PolicyOperation) error {
+// applyAddOperation applies an add operation to the adapter.
+func applyAddOperation(adapter persist.Adapter, op persist.PolicyOperation) error {
+	if ia, ok := asIncrementalAdapter(adapter); ok {
+		return applyIncrementalOperation(ia, op)
+	}
 	if batchAdapter, ok := adapter.(persist.BatchAdapter); ok {
 		// Use batch operation if available.
 		return batchAdapter.AddPolicies(op.Section, op.PolicyType, op.Rules)
@@ -134,8 +137,11 @@ func (tx *Transaction) applyAddOperationToDatabase(adapter persist.Adapter, op p
 	return nil
 }
 
-// applyRemoveOperationToDatabase applies a remove operation to the database.
-func (tx *Transaction) applyRemoveOperationToDatabase(adapter persist.Adapter, op persist.PolicyOperation) error {
+// applyRemoveOperation applies a remove operation to the adapter.
+func applyRemoveOperation(adapter persist.Adapter, op persist.PolicyOperation) error {
+	if ia, ok := asIncrementalAdapter(adapter); ok {
+		return applyIncrementalOperation(ia, op)
+	}
 	if batchAdapter, ok := adapter.(persist.BatchAdapter); ok {
 		// Use batch operation if available.
 		return batchAdapter.RemovePolicies(op.Section, op.PolicyType, op.Rules)
@@ -150,9 +156,12 @@ func (tx *Transaction) applyRemoveOperationToDatabase(adapter persist.Adapter, o
 	return nil
 }
 
-// applyUpdateOperationToDatabase applies an update operation to the database.
-func (tx *Transaction) applyUpdateOperationToDatabase(adapter persist.Adapter, op persist.PolicyOperation) error {
-	if updateAdapter, ok := adapter.(persist.UpdatableAdapter); ok {
+// applyUpdateOperation applies an update operation to the adapter.
+func applyUpdateOperation(adapter persist.Adapter, op persist.PolicyOperation) error {
+	if ia, ok := asIncrementalAdapter(adapter); ok {
+		return applyIncrementalOperation(ia, op)
+	}
+	if updateAdapter, ok := asUpdatableAdapter(adapter); ok {
 		// Use update operation if available.
 		return updateAdapter.UpdatePolicies(op.Section, op.PolicyType, op.OldRules, op.Rules)
 	}
@@ -173,6 +182,34 @@ func (tx *Transaction) applyUpdateOperationToDatabase(adapter persist.Adapter, o
 	return nil
 }
 
+// applyIncrementalOperation applies an operation to the adapter one rule at a time.
+func applyIncrementalOperation(adapter persist.IncrementalAdapter, op persist.PolicyOperation) error {
+	switch op.Type {
+	case persist.OperationAdd:
+		for _, rule := range op.Rules {
+			if err := adapter.AddPolicyRule(op.Section, op.PolicyType, rule); err != nil {
+				return err
+			}
+		}
+	case persist.OperationRemove:
+		for _, rule := range op.Rules {
+			if err := adapter.RemovePolicyRule(op.Section, op.PolicyType, rule); err != nil {
+				return err
+			}
+		}
+	case persist.OperationUpdate:
+		for i, oldRule := range op.OldRules {
+			if i >= len(op.Rules) {
+				continue
+			}
+			if err := adapter.UpdatePolicyRule(op.Section, op.PolicyType, oldRule, op.Rules[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // applyOperationsToModel applies all buffered operations to the in-memory model.
 func (tx *Transaction) applyOperationsToModel() error {
 	// Create new model with all operations applied.
@@ -181,9 +218,11 @@ func (tx *Transaction) applyOperationsToModel() error {
 		return err
 	}
 
-	// Replace the enforcer's model.
+	// Replace the enforcer's model, which reports its later changes again.
+	newModel.SetPolicyChangedMuted(false)
 	tx.enforcer.model = newModel
 	tx.enforcer.invalidateMatcherMap()
+	tx.enforcer.invalidatePolicyIndexes()
 
 	// Rebuild role links if necessary.
 	if tx.enforcer.autoBuildRoleLinks {
@@ -205,6 +244,7 @@ func (tx *Transaction) applyOperationsToModel() error {
 		}
 	}
 
+	tx.enforcer.publishReset()
 	return nil
 }
 
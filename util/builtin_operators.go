@@ -15,10 +15,12 @@
 package util
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -384,6 +386,40 @@ func IPMatchFunc(args ...interface{}) (interface{}, error) {
 	return IPMatch(ip1, ip2), nil
 }
 
+// IPMatchCIDR determines whether the range of the CIDR cidr1 is entirely inside the range of the CIDR cidr2,
+// for both IPv4 and IPv6. For example, "192.168.2.0/25" is inside "192.168.0.0/16".
+// A CIDR of one family is never inside a CIDR of the other.
+func IPMatchCIDR(cidr1 string, cidr2 string) bool {
+	_, net1, err := net.ParseCIDR(cidr1)
+	if err != nil {
+		panic("invalid argument: cidr1 in IPMatchCIDR() function is not a CIDR.")
+	}
+	_, net2, err := net.ParseCIDR(cidr2)
+	if err != nil {
+		panic("invalid argument: cidr2 in IPMatchCIDR() function is not a CIDR.")
+	}
+
+	// ParseCIDR returns 4-byte networks for IPv4 and 16-byte ones for IPv6.
+	if len(net1.IP) != len(net2.IP) {
+		return false
+	}
+	ones1, _ := net1.Mask.Size()
+	ones2, _ := net2.Mask.Size()
+	return ones1 >= ones2 && net2.Contains(net1.IP)
+}
+
+// IPMatchCIDRFunc is the wrapper for IPMatchCIDR.
+func IPMatchCIDRFunc(args ...interface{}) (interface{}, error) {
+	if err := validateVariadicArgs(2, args...); err != nil {
+		return false, fmt.Errorf("%s: %w", "ipMatchCIDR", err)
+	}
+
+	cidr1 := args[0].(string)
+	cidr2 := args[1].(string)
+
+	return IPMatchCIDR(cidr1, cidr2), nil
+}
+
 // GlobMatch determines whether key1 matches the pattern of key2 using glob pattern.
 func GlobMatch(key1 string, key2 string) (bool, error) {
 	return doublestar.Match(key2, key1)
@@ -401,6 +437,749 @@ func GlobMatchFunc(args ...interface{}) (interface{}, error) {
 	return GlobMatch(name1, name2)
 }
 
+// SemverCompare compares two semantic versions and returns -1, 0 or 1 if v1 is lower than, equal to or greater than v2.
+// Precedence follows the semver 2.0.0 spec: pre-release versions are lower than the associated normal version
+// and build metadata is ignored. A leading "v" is accepted and missing minor or patch numbers are treated as 0,
+// so "1.10" is greater than "1.9".
+func SemverCompare(v1 string, v2 string) (int, error) {
+	ver1, err := parseSemver(v1)
+	if err != nil {
+		return 0, err
+	}
+	ver2, err := parseSemver(v2)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range ver1.core {
+		if ver1.core[i] != ver2.core[i] {
+			if ver1.core[i] < ver2.core[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+
+	return comparePrerelease(ver1.prerelease, ver2.prerelease), nil
+}
+
+// SemverCompareFunc is the wrapper for SemverCompare.
+func SemverCompareFunc(args ...interface{}) (interface{}, error) {
+	if err := validateVariadicArgs(2, args...); err != nil {
+		return nil, fmt.Errorf("%s: %w", "semverCompare", err)
+	}
+
+	v1 := args[0].(string)
+	v2 := args[1].(string)
+
+	res, err := SemverCompare(v1, v2)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", "semverCompare", err)
+	}
+	return float64(res), nil
+}
+
+// SemverGte determines whether semantic version v1 is greater than or equal to v2.
+// For example, "1.10.0" is greater than "1.9.0".
+func SemverGte(v1 string, v2 string) (bool, error) {
+	res, err := SemverCompare(v1, v2)
+	if err != nil {
+		return false, err
+	}
+	return res >= 0, nil
+}
+
+// SemverGteFunc is the wrapper for SemverGte.
+func SemverGteFunc(args ...interface{}) (interface{}, error) {
+	if err := validateVariadicArgs(2, args...); err != nil {
+		return false, fmt.Errorf("%s: %w", "semverGte", err)
+	}
+
+	v1 := args[0].(string)
+	v2 := args[1].(string)
+
+	res, err := SemverGte(v1, v2)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", "semverGte", err)
+	}
+	return res, nil
+}
+
+// JsonGet returns the value at path in the JSON document doc, or nil if the path is missing.
+// doc is a JSON text or an already decoded document such as a map[string]interface{}. path is made of dot and
+// bracket selectors and may start with "$", e.g. "$.user.roles[0]" or "user['first name']".
+// Numbers are returned as float64, as decoded by encoding/json.
+func JsonGet(doc interface{}, path string) (interface{}, error) {
+	selectors, err := parseJsonPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := doc
+	if text, ok := doc.(string); ok {
+		if err = json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, selector := range selectors {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			key, ok := selector.(string)
+			if !ok {
+				key = strconv.Itoa(selector.(int))
+			}
+			if value, ok = current[key]; !ok {
+				return nil, nil
+			}
+		case []interface{}:
+			index, ok := selector.(int)
+			if !ok || index < 0 || index >= len(current) {
+				return nil, nil
+			}
+			value = current[index]
+		default:
+			return nil, nil
+		}
+	}
+	return value, nil
+}
+
+// parseJsonPath splits a path of JsonGet into its selectors, a string for a key or an int for an index.
+func parseJsonPath(path string) ([]interface{}, error) {
+	var selectors []interface{}
+	rest := strings.TrimPrefix(path, "$")
+	for len(rest) != 0 {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSON path %s: unclosed bracket", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				selectors = append(selectors, inner[1:len(inner)-1])
+			} else if index, err := strconv.Atoi(inner); err == nil {
+				selectors = append(selectors, index)
+			} else {
+				return nil, fmt.Errorf("invalid JSON path %s: bad selector [%s]", path, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			if rest[0] == '.' {
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %s: empty key", path)
+			}
+			selectors = append(selectors, rest[:end])
+			rest = rest[end:]
+		}
+	}
+	return selectors, nil
+}
+
+// JsonGetFunc is the wrapper for JsonGet.
+func JsonGetFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s: expected 2 arguments, but got %d", "jsonGet", len(args))
+	}
+	path, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: path must be a string", "jsonGet")
+	}
+
+	res, err := JsonGet(args[0], path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", "jsonGet", err)
+	}
+	return res, nil
+}
+
+// toNumber converts a matcher argument to a number: a Go number, or a string holding one such as a policy field.
+func toNumber(arg interface{}) (float64, error) {
+	switch v := arg.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%v is not a number", arg)
+	}
+}
+
+// Between determines whether value is within the inclusive bounds lo and hi.
+// The arguments are numbers, or strings holding numbers such as policy fields.
+func Between(value interface{}, lo interface{}, hi interface{}) (bool, error) {
+	v, err := toNumber(value)
+	if err != nil {
+		return false, err
+	}
+	l, err := toNumber(lo)
+	if err != nil {
+		return false, err
+	}
+	h, err := toNumber(hi)
+	if err != nil {
+		return false, err
+	}
+	return l <= v && v <= h, nil
+}
+
+// BetweenFunc is the wrapper for Between.
+func BetweenFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 {
+		return false, fmt.Errorf("%s: expected 3 arguments, but got %d", "between", len(args))
+	}
+
+	res, err := Between(args[0], args[1], args[2])
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", "between", err)
+	}
+	return res, nil
+}
+
+// InRange determines whether value is within the inclusive range written as "lo..hi" in a single policy field,
+// e.g. "10..100". Either bound may be omitted for an open range, e.g. "..100" or "10..".
+func InRange(value interface{}, valueRange string) (bool, error) {
+	bounds := strings.Split(valueRange, "..")
+	if len(bounds) != 2 {
+		return false, fmt.Errorf("%q is not a range of the form lo..hi", valueRange)
+	}
+
+	v, err := toNumber(value)
+	if err != nil {
+		return false, err
+	}
+	if lo := strings.TrimSpace(bounds[0]); lo != "" {
+		l, err := toNumber(lo)
+		if err != nil {
+			return false, err
+		}
+		if v < l {
+			return false, nil
+		}
+	}
+	if hi := strings.TrimSpace(bounds[1]); hi != "" {
+		h, err := toNumber(hi)
+		if err != nil {
+			return false, err
+		}
+		if v > h {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// InRangeFunc is the wrapper for InRange.
+func InRangeFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return false, fmt.Errorf("%s: expected 2 arguments, but got %d", "inRange", len(args))
+	}
+	valueRange, ok := args[1].(string)
+	if !ok {
+		return false, fmt.Errorf("%s: range must be a string", "inRange")
+	}
+
+	res, err := InRange(args[0], valueRange)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", "inRange", err)
+	}
+	return res, nil
+}
+
+// ContainsAny determines whether list contains any value of required. It is false if required is empty.
+func ContainsAny(list []string, required []string) bool {
+	values := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		values[v] = struct{}{}
+	}
+	for _, v := range required {
+		if _, ok := values[v]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll determines whether list contains every value of required. It is true if required is empty.
+func ContainsAll(list []string, required []string) bool {
+	values := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		values[v] = struct{}{}
+	}
+	for _, v := range required {
+		if _, ok := values[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// toStringList converts a matcher argument to a list of strings: a comma-separated string such as a policy field,
+// e.g. "read,write", or a slice such as a request field.
+func toStringList(arg interface{}) ([]string, error) {
+	switch v := arg.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return []string{}, nil
+		}
+		list := strings.Split(v, ",")
+		for i := range list {
+			list[i] = strings.TrimSpace(list[i])
+		}
+		return list, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a string", item)
+			}
+			list[i] = s
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("%v is neither a comma-separated string nor a list of strings", arg)
+	}
+}
+
+// ContainsAnyFunc is the wrapper for ContainsAny. Both arguments are comma-separated strings or lists of strings.
+func ContainsAnyFunc(args ...interface{}) (interface{}, error) {
+	list, required, err := validateListArgs("containsAny", args...)
+	if err != nil {
+		return false, err
+	}
+	return ContainsAny(list, required), nil
+}
+
+// ContainsAllFunc is the wrapper for ContainsAll. Both arguments are comma-separated strings or lists of strings.
+func ContainsAllFunc(args ...interface{}) (interface{}, error) {
+	list, required, err := validateListArgs("containsAll", args...)
+	if err != nil {
+		return false, err
+	}
+	return ContainsAll(list, required), nil
+}
+
+func validateListArgs(name string, args ...interface{}) ([]string, []string, error) {
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("%s: expected 2 arguments, but got %d", name, len(args))
+	}
+	list, err := toStringList(args[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	required, err := toStringList(args[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return list, required, nil
+}
+
+var locationCache sync.Map
+
+// toLocalTime converts a matcher argument to a time: a time.Time, or a string in the RFC 3339 format
+// such as a request field, e.g. "2026-10-16T09:30:00Z". If zone is not empty, the time is converted to
+// the IANA time zone it names, e.g. "Europe/Paris", otherwise it keeps its own.
+func toLocalTime(arg interface{}, zone string) (time.Time, error) {
+	var t time.Time
+	switch v := arg.(type) {
+	case time.Time:
+		t = v
+	case string:
+		var err error
+		if t, err = time.Parse(time.RFC3339, v); err != nil {
+			return t, err
+		}
+	default:
+		return t, fmt.Errorf("%v is neither a time nor an RFC 3339 string", arg)
+	}
+	if zone == "" {
+		return t, nil
+	}
+
+	loc, ok := locationCache.Load(zone)
+	if !ok {
+		l, err := time.LoadLocation(zone)
+		if err != nil {
+			return t, err
+		}
+		loc, _ = locationCache.LoadOrStore(zone, l)
+	}
+	return t.In(loc.(*time.Location)), nil
+}
+
+// parseClock parses a clock time of the form "15:04" or "15:04:05" into the duration since midnight.
+func parseClock(clock string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if c, err := time.Parse(layout, strings.TrimSpace(clock)); err == nil {
+			return time.Duration(c.Hour())*time.Hour + time.Duration(c.Minute())*time.Minute + time.Duration(c.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a clock time of the form 15:04 or 15:04:05", clock)
+}
+
+// InTimeRange determines whether the clock time of now is within [start, end), written as "15:04" or "15:04:05",
+// e.g. inTimeRange(r.time, "09:00", "17:30"). If start is after end, the range spans midnight, e.g. "22:00" to "06:00".
+// now is a time.Time or an RFC 3339 string, taken in the IANA time zone if one is given, e.g. "America/New_York".
+func InTimeRange(now interface{}, start string, end string, zone ...string) (bool, error) {
+	if len(zone) > 1 {
+		return false, fmt.Errorf("expected at most 1 time zone, but got %d", len(zone))
+	}
+	t, err := toLocalTime(now, strings.Join(zone, ""))
+	if err != nil {
+		return false, err
+	}
+	s, err := parseClock(start)
+	if err != nil {
+		return false, err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return false, err
+	}
+
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if s <= e {
+		return s <= clock && clock < e, nil
+	}
+	return clock >= s || clock < e, nil
+}
+
+// InTimeRangeFunc is the wrapper for InTimeRange. It takes an optional time zone as the fourth argument.
+// An empty time argument stands for the current time, e.g. inTimeRange("", "09:00", "17:30").
+func InTimeRangeFunc(args ...interface{}) (interface{}, error) {
+	return GenerateInTimeRangeFunc(time.Now)(args...)
+}
+
+// GenerateInTimeRangeFunc returns an InTimeRangeFunc that reads the current time from now instead of time.Now.
+func GenerateInTimeRangeFunc(now func() time.Time) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 && len(args) != 4 {
+			return false, fmt.Errorf("%s: expected 3 or 4 arguments, but got %d", "inTimeRange", len(args))
+		}
+		if err := validateVariadicArgs(len(args)-1, args[1:]...); err != nil {
+			return false, fmt.Errorf("%s: %w", "inTimeRange", err)
+		}
+		zone := []string{}
+		if len(args) == 4 {
+			zone = append(zone, args[3].(string))
+		}
+
+		res, err := InTimeRange(timeOrNow(args[0], now), args[1].(string), args[2].(string), zone...)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", "inTimeRange", err)
+		}
+		return res, nil
+	}
+}
+
+// timeOrNow returns the current time read from now for an empty time argument, and the argument otherwise.
+func timeOrNow(arg interface{}, now func() time.Time) interface{} {
+	if arg == "" {
+		return now()
+	}
+	return arg
+}
+
+// parseWeekday parses the English name of a day of the week, or its first three letters, case-insensitively.
+func parseWeekday(day string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(day))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a day of the week", day)
+}
+
+// WeekdayMatch determines whether the day of the week of now is in days, a list of days or ranges of days
+// separated by "|" or ",", e.g. "Mon-Fri" or "Sat|Sun". A range may wrap around the week, e.g. "Fri-Mon".
+// now is a time.Time or an RFC 3339 string, taken in the IANA time zone if one is given, e.g. "America/New_York".
+func WeekdayMatch(now interface{}, days string, zone ...string) (bool, error) {
+	if len(zone) > 1 {
+		return false, fmt.Errorf("expected at most 1 time zone, but got %d", len(zone))
+	}
+	t, err := toLocalTime(now, strings.Join(zone, ""))
+	if err != nil {
+		return false, err
+	}
+
+	res := false
+	for _, item := range strings.FieldsFunc(days, func(r rune) bool { return r == '|' || r == ',' }) {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return false, fmt.Errorf("%q is not a day or a range of days", item)
+		}
+		first, err := parseWeekday(bounds[0])
+		if err != nil {
+			return false, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parseWeekday(bounds[1]); err != nil {
+				return false, err
+			}
+		}
+
+		day := t.Weekday()
+		if first <= last {
+			res = res || (first <= day && day <= last)
+		} else {
+			res = res || day >= first || day <= last
+		}
+	}
+	return res, nil
+}
+
+// WeekdayMatchFunc is the wrapper for WeekdayMatch. It takes an optional time zone as the third argument.
+// An empty time argument stands for the current time, e.g. weekdayMatch("", "Mon-Fri").
+func WeekdayMatchFunc(args ...interface{}) (interface{}, error) {
+	return GenerateWeekdayMatchFunc(time.Now)(args...)
+}
+
+// GenerateWeekdayMatchFunc returns a WeekdayMatchFunc that reads the current time from now instead of time.Now.
+func GenerateWeekdayMatchFunc(now func() time.Time) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return false, fmt.Errorf("%s: expected 2 or 3 arguments, but got %d", "weekdayMatch", len(args))
+		}
+		if err := validateVariadicArgs(len(args)-1, args[1:]...); err != nil {
+			return false, fmt.Errorf("%s: %w", "weekdayMatch", err)
+		}
+		zone := []string{}
+		if len(args) == 3 {
+			zone = append(zone, args[2].(string))
+		}
+
+		res, err := WeekdayMatch(timeOrNow(args[0], now), args[1].(string), zone...)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", "weekdayMatch", err)
+		}
+		return res, nil
+	}
+}
+
+// TimeWindowMatch determines whether now is within [start, end), written in RFC 3339, e.g. "2026-01-01T09:00:00Z".
+// An empty start or end leaves the window unbounded on that side.
+func TimeWindowMatch(now time.Time, start string, end string) (bool, error) {
+	if start != "" {
+		s, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return false, err
+		}
+		if now.Before(s) {
+			return false, nil
+		}
+	}
+	if end != "" {
+		e, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return false, err
+		}
+		if !now.Before(e) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// TimeWindowMatchFunc is the wrapper for TimeWindowMatch at the current time, registered as timeMatch,
+// e.g. timeMatch(p.start, p.end).
+func TimeWindowMatchFunc(args ...interface{}) (interface{}, error) {
+	return GenerateTimeWindowMatchFunc(time.Now)(args...)
+}
+
+// GenerateTimeWindowMatchFunc returns a TimeWindowMatchFunc that reads the current time from now instead of time.Now.
+func GenerateTimeWindowMatchFunc(now func() time.Time) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if err := validateVariadicArgs(2, args...); err != nil {
+			return false, fmt.Errorf("%s: %w", "timeMatch", err)
+		}
+
+		res, err := TimeWindowMatch(now(), args[0].(string), args[1].(string))
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", "timeMatch", err)
+		}
+		return res, nil
+	}
+}
+
+type semver struct {
+	core       [3]uint64
+	prerelease []string
+}
+
+func parseSemver(v string) (semver, error) {
+	var res semver
+	s := strings.TrimPrefix(v, "v")
+	if i := strings.Index(s, "+"); i != -1 {
+		if err := validateSemverIdentifiers(s[i+1:], false); err != nil {
+			return res, fmt.Errorf("invalid semantic version %q: %w", v, err)
+		}
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i != -1 {
+		if err := validateSemverIdentifiers(s[i+1:], true); err != nil {
+			return res, fmt.Errorf("invalid semantic version %q: %w", v, err)
+		}
+		res.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return res, fmt.Errorf("invalid semantic version %q", v)
+	}
+	for i, part := range parts {
+		if !isSemverNumeric(part) || len(part) > 1 && part[0] == '0' {
+			return res, fmt.Errorf("invalid semantic version %q", v)
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return res, fmt.Errorf("invalid semantic version %q: %w", v, err)
+		}
+		res.core[i] = n
+	}
+	return res, nil
+}
+
+func validateSemverIdentifiers(s string, prerelease bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return errors.New("empty identifier")
+		}
+		for _, c := range id {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return fmt.Errorf("invalid identifier %q", id)
+			}
+		}
+		if prerelease && len(id) > 1 && id[0] == '0' && isSemverNumeric(id) {
+			return fmt.Errorf("numeric identifier %q has leading zeros", id)
+		}
+	}
+	return nil
+}
+
+func isSemverNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// comparePrerelease compares pre-release identifiers, a version without pre-release has the higher precedence.
+func comparePrerelease(p1 []string, p2 []string) int {
+	if len(p1) == 0 || len(p2) == 0 {
+		switch {
+		case len(p1) == len(p2):
+			return 0
+		case len(p1) == 0:
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	for i := 0; i < len(p1) && i < len(p2); i++ {
+		if p1[i] == p2[i] {
+			continue
+		}
+		num1, num2 := isSemverNumeric(p1[i]), isSemverNumeric(p2[i])
+		switch {
+		case num1 && num2:
+			n1, _ := strconv.ParseUint(p1[i], 10, 64)
+			n2, _ := strconv.ParseUint(p2[i], 10, 64)
+			if n1 < n2 {
+				return -1
+			}
+			return 1
+		case num1:
+			return -1
+		case num2:
+			return 1
+		case p1[i] < p2[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch {
+	case len(p1) < len(p2):
+		return -1
+	case len(p1) > len(p2):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RegexDomainCaptureFunc returns a DomainCaptureFunc capturing the domain of a role name by the regular expression
+// pattern, from its group named "domain" if any, or else from its first group, e.g. `^role:([^:]+):` for "role:project42:admin".
+func RegexDomainCaptureFunc(pattern string) (rbac.DomainCaptureFunc, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	group := 1
+	for i, name := range re.SubexpNames() {
+		if name == "domain" {
+			group = i
+		}
+	}
+	if group > re.NumSubexp() {
+		return nil, fmt.Errorf("regular expression %s has no group capturing the domain", pattern)
+	}
+	return func(role string) (string, bool) {
+		matches := re.FindStringSubmatch(role)
+		if matches == nil {
+			return "", false
+		}
+		return matches[group], true
+	}, nil
+}
+
 // GenerateGFunction is the factory method of the g(_, _[, _]) function.
 func GenerateGFunction(rm rbac.RoleManager) govaluate.ExpressionFunction {
 	// Calculate cache size dynamically based on system memory
@@ -446,6 +1225,28 @@ func GenerateGFunction(rm rbac.RoleManager) govaluate.ExpressionFunction {
 	}
 }
 
+// GenerateDomainOptionalGFunction is the factory method of the g(_, _[, _]) function of a role definition
+// with an optional domain: g(_, _) without a domain holds if the link exists in any domain of the first name.
+func GenerateDomainOptionalGFunction(rm rbac.RoleManager) govaluate.ExpressionFunction {
+	g := GenerateGFunction(rm)
+	return func(args ...interface{}) (interface{}, error) {
+		if rm == nil || len(args) != 2 {
+			return g(args...)
+		}
+
+		domains, err := rm.GetDomains(args[0].(string))
+		if err != nil {
+			return false, err
+		}
+		for _, domain := range domains {
+			if v, _ := g(args[0], args[1], domain); v.(bool) {
+				return true, nil
+			}
+		}
+		return g(args...)
+	}
+}
+
 // GenerateConditionalGFunction is the factory method of the g(_, _[, _]) function with conditions.
 func GenerateConditionalGFunction(crm rbac.ConditionalRoleManager) govaluate.ExpressionFunction {
 	return func(args ...interface{}) (interface{}, error) {
@@ -476,6 +1277,18 @@ func TimeMatchFunc(args ...string) (bool, error) {
 	return TimeMatch(args[0], args[1])
 }
 
+// GenerateTimeWindowLinkConditionFunc returns a LinkConditionFunc checking with TimeWindowMatch that the time
+// read from now is within the window given by the link condition parameters, e.g. to evaluate temporal roles
+// against a fake clock.
+func GenerateTimeWindowLinkConditionFunc(now func() time.Time) func(args ...string) (bool, error) {
+	return func(args ...string) (bool, error) {
+		if err := validateVariadicStringArgs(2, args...); err != nil {
+			return false, fmt.Errorf("%s: %w", "timeMatch", err)
+		}
+		return TimeWindowMatch(now(), args[0], args[1])
+	}
+}
+
 // TimeMatch determines whether the current time is between startTime and endTime.
 // You can use "_" to indicate that the parameter is ignored.
 func TimeMatch(startTime, endTime string) (bool, error) {
@@ -16,6 +16,7 @@ package util
 
 import (
 	"testing"
+	"time"
 )
 
 func testKeyMatch(t *testing.T, key1 string, key2 string, res bool) {
@@ -314,6 +315,48 @@ func TestIPMatch(t *testing.T) {
 	testIPMatch(t, "11.0.0.123", "10.0.0.0/8", false)
 }
 
+func testIPMatchCIDR(t *testing.T, cidr1 string, cidr2 string, res bool) {
+	t.Helper()
+	myRes := IPMatchCIDR(cidr1, cidr2)
+	t.Logf("%s < %s: %t", cidr1, cidr2, myRes)
+
+	if myRes != res {
+		t.Errorf("%s < %s: %t, supposed to be %t", cidr1, cidr2, !res, res)
+	}
+}
+
+func TestIPMatchCIDR(t *testing.T) {
+	testIPMatchCIDR(t, "192.168.2.0/25", "192.168.2.0/24", true)
+	testIPMatchCIDR(t, "192.168.2.128/25", "192.168.0.0/16", true)
+	testIPMatchCIDR(t, "192.168.2.0/24", "192.168.2.0/24", true)
+	testIPMatchCIDR(t, "192.168.2.0/23", "192.168.2.0/24", false)
+	testIPMatchCIDR(t, "192.168.3.0/24", "192.168.2.0/24", false)
+	testIPMatchCIDR(t, "10.1.2.3/32", "10.0.0.0/8", true)
+	testIPMatchCIDR(t, "2001:db8:1::/48", "2001:db8::/32", true)
+	testIPMatchCIDR(t, "2001:db8::/32", "2001:db8:1::/48", false)
+	testIPMatchCIDR(t, "2001:db9::/32", "2001:db8::/32", false)
+	testIPMatchCIDR(t, "::1/128", "::/0", true)
+	testIPMatchCIDR(t, "10.0.0.0/8", "::/0", false)
+	testIPMatchCIDR(t, "::ffff:10.0.0.0/104", "10.0.0.0/8", false)
+	testIPMatchCIDR(t, "2001:db8::/32", "0.0.0.0/0", false)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("an address instead of a CIDR should panic")
+		}
+	}()
+	IPMatchCIDR("192.168.2.1", "192.168.2.0/24")
+}
+
+func TestIPMatchCIDRFunc(t *testing.T) {
+	if _, err := IPMatchCIDRFunc("192.168.2.0/25"); err == nil || err.Error() != "ipMatchCIDR: expected 2 arguments, but got 1" {
+		t.Errorf("error: %v, supposed to be about the number of arguments", err)
+	}
+	if res, err := IPMatchCIDRFunc("192.168.2.0/25", "192.168.2.0/24"); res != true || err != nil {
+		t.Errorf("ipMatchCIDR: %v, %v, supposed to be true", res, err)
+	}
+}
+
 func testRegexMatchFunc(t *testing.T, res bool, err string, args ...interface{}) {
 	t.Helper()
 	myRes, myErr := RegexMatchFunc(args...)
@@ -662,3 +705,314 @@ func TestTestMatch(t *testing.T) {
 	testTimeMatch(t, "0000-01-01 00:00:00", "_", true)
 	testTimeMatch(t, "9999-12-30 00:00:00", "_", false)
 }
+
+func testSemverCompare(t *testing.T, v1 string, v2 string, res int) {
+	t.Helper()
+	myRes, err := SemverCompare(v1, v2)
+	if err != nil {
+		t.Errorf("%s <=> %s: %v", v1, v2, err)
+		return
+	}
+	t.Logf("%s <=> %s: %d", v1, v2, myRes)
+
+	if myRes != res {
+		t.Errorf("%s <=> %s: %d, supposed to be %d", v1, v2, myRes, res)
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	testSemverCompare(t, "1.10", "1.9", 1)
+	testSemverCompare(t, "1.9.0", "1.10.0", -1)
+	testSemverCompare(t, "1.2.3", "1.2.3", 0)
+	testSemverCompare(t, "v1.2.3", "1.2.3", 0)
+	testSemverCompare(t, "1", "1.0.0", 0)
+	testSemverCompare(t, "2.0.0", "1.99.99", 1)
+	testSemverCompare(t, "1.0.0+build.1", "1.0.0+build.2", 0)
+	testSemverCompare(t, "1.0.0-alpha", "1.0.0", -1)
+	testSemverCompare(t, "1.0.0-alpha", "1.0.0-alpha.1", -1)
+	testSemverCompare(t, "1.0.0-alpha.1", "1.0.0-alpha.beta", -1)
+	testSemverCompare(t, "1.0.0-alpha.beta", "1.0.0-beta", -1)
+	testSemverCompare(t, "1.0.0-beta.2", "1.0.0-beta.11", -1)
+	testSemverCompare(t, "1.0.0-beta.11", "1.0.0-rc.1", -1)
+	testSemverCompare(t, "1.0.0-rc.1+build.5", "1.0.0", -1)
+}
+
+func TestSemverCompareError(t *testing.T) {
+	for _, v := range []string{"", "1.2.3.4", "1.x.0", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+", "1.2.3-a..b", "1.2.3-a_b"} {
+		if _, err := SemverCompare(v, "1.0.0"); err == nil {
+			t.Errorf("%q should be an invalid semantic version", v)
+		}
+	}
+}
+
+func TestSemverFunc(t *testing.T) {
+	res, err := SemverCompareFunc("1.10.0", "1.9.0")
+	if err != nil || res != float64(1) {
+		t.Errorf("semverCompare(1.10.0, 1.9.0): %v, %v, supposed to be 1", res, err)
+	}
+
+	res, err = SemverGteFunc("1.9.0", "1.10.0")
+	if err != nil || res != false {
+		t.Errorf("semverGte(1.9.0, 1.10.0): %v, %v, supposed to be false", res, err)
+	}
+
+	if _, err = SemverGteFunc("1.9.0"); err == nil {
+		t.Error("semverGte with 1 argument should return an error")
+	}
+
+	if _, err = SemverGteFunc("latest", "1.0.0"); err == nil {
+		t.Error("semverGte with an invalid version should return an error")
+	}
+}
+
+func testJsonGet(t *testing.T, doc interface{}, path string, res interface{}) {
+	t.Helper()
+	myRes, err := JsonGet(doc, path)
+	if err != nil {
+		t.Errorf("jsonGet(%v, %s): %v", doc, path, err)
+	} else if myRes != res {
+		t.Errorf("jsonGet(%v, %s): %v, supposed to be %v", doc, path, myRes, res)
+	}
+}
+
+func TestJsonGet(t *testing.T) {
+	doc := `{"user": {"name": "alice", "age": 30, "roles": ["admin", "dev"], "first name": "Alice"}, "0": "zero"}`
+
+	testJsonGet(t, doc, "user.name", "alice")
+	testJsonGet(t, doc, "$.user.name", "alice")
+	testJsonGet(t, doc, "$['user']['name']", "alice")
+	testJsonGet(t, doc, `user["first name"]`, "Alice")
+	testJsonGet(t, doc, "user.age", float64(30))
+	testJsonGet(t, doc, "user.roles[1]", "dev")
+	testJsonGet(t, doc, "$[0]", "zero")
+
+	// Missing paths give nil.
+	testJsonGet(t, doc, "user.email", nil)
+	testJsonGet(t, doc, "user.roles[2]", nil)
+	testJsonGet(t, doc, "user.name.first", nil)
+	testJsonGet(t, doc, "user.roles.admin", nil)
+
+	// A decoded document is used as is.
+	testJsonGet(t, map[string]interface{}{"dept": map[string]interface{}{"id": "d1"}}, "dept.id", "d1")
+
+	for _, path := range []string{"user..name", "user[name]", "user.roles[0"} {
+		if _, err := JsonGet(doc, path); err == nil {
+			t.Errorf("jsonGet path %s should be invalid", path)
+		}
+	}
+	if _, err := JsonGet("{", "user"); err == nil {
+		t.Error("jsonGet of an invalid JSON document should return an error")
+	}
+	if _, err := JsonGetFunc(doc); err == nil {
+		t.Error("jsonGet with 1 argument should return an error")
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		value, lo, hi interface{}
+		res           bool
+	}{
+		{50, "10", "100", true},
+		{10, "10", "100", true},
+		{100, "10", "100", true},
+		{100.5, "10", "100", false},
+		{9.99, 10, 100, false},
+		{"42", "0.5", "42", true},
+		{int64(-3), "-5", "-1", true},
+		{uint8(7), float64(7), float64(7), true},
+	}
+	for _, test := range tests {
+		res, err := BetweenFunc(test.value, test.lo, test.hi)
+		if err != nil || res != test.res {
+			t.Errorf("between(%v, %v, %v): %v, %v, supposed to be %t", test.value, test.lo, test.hi, res, err, test.res)
+		}
+	}
+
+	if _, err := BetweenFunc("abc", "1", "2"); err == nil {
+		t.Error("between with a non-numeric value should return an error")
+	}
+	if _, err := BetweenFunc(1, "1", true); err == nil {
+		t.Error("between with a non-numeric bound should return an error")
+	}
+	if _, err := BetweenFunc(1, "1"); err == nil {
+		t.Error("between with 2 arguments should return an error")
+	}
+}
+
+func TestInRange(t *testing.T) {
+	tests := []struct {
+		value      interface{}
+		valueRange string
+		res        bool
+	}{
+		{50, "10..100", true},
+		{10, "10..100", true},
+		{101, "10..100", false},
+		{-1000, "..100", true},
+		{1e9, "10..", true},
+		{5, "10..", false},
+		{"2.5", "0.5..2.5", true},
+		{7, "..", true},
+	}
+	for _, test := range tests {
+		res, err := InRangeFunc(test.value, test.valueRange)
+		if err != nil || res != test.res {
+			t.Errorf("inRange(%v, %s): %v, %v, supposed to be %t", test.value, test.valueRange, res, err, test.res)
+		}
+	}
+
+	for _, valueRange := range []string{"10", "1..2..3", "a..b"} {
+		if _, err := InRangeFunc(5, valueRange); err == nil {
+			t.Errorf("inRange with range %s should return an error", valueRange)
+		}
+	}
+	if _, err := InRangeFunc("x", "1..2"); err == nil {
+		t.Error("inRange with a non-numeric value should return an error")
+	}
+}
+
+func TestContainsAnyAndAll(t *testing.T) {
+	tests := []struct {
+		list, required interface{}
+		any, all       bool
+	}{
+		{"read,write", "write", true, true},
+		{"read, write", "write,delete", true, false},
+		{"read,write", "delete", false, false},
+		{[]string{"read", "write"}, "read,write", true, true},
+		{[]interface{}{"read"}, []string{"read", "write"}, true, false},
+		{"read", "", false, true},
+		{"", "read", false, false},
+		{[]string{}, []string{}, false, true},
+	}
+	for _, test := range tests {
+		res, err := ContainsAnyFunc(test.list, test.required)
+		if err != nil || res != test.any {
+			t.Errorf("containsAny(%v, %v): %v, %v, supposed to be %t", test.list, test.required, res, err, test.any)
+		}
+		res, err = ContainsAllFunc(test.list, test.required)
+		if err != nil || res != test.all {
+			t.Errorf("containsAll(%v, %v): %v, %v, supposed to be %t", test.list, test.required, res, err, test.all)
+		}
+	}
+
+	if _, err := ContainsAnyFunc(1, "read"); err == nil {
+		t.Error("containsAny with a number should return an error")
+	}
+	if _, err := ContainsAllFunc([]interface{}{"read", 1}, "read"); err == nil {
+		t.Error("containsAll with a non-string list item should return an error")
+	}
+	if _, err := ContainsAllFunc("read"); err == nil {
+		t.Error("containsAll with 1 argument should return an error")
+	}
+}
+
+func TestInTimeRange(t *testing.T) {
+	tests := []struct {
+		now        interface{}
+		start, end string
+		zone       []string
+		res        bool
+	}{
+		{"2026-10-16T09:00:00Z", "09:00", "17:30", nil, true},
+		{"2026-10-16T17:30:00Z", "09:00", "17:30", nil, false},
+		{"2026-10-16T08:59:59Z", "09:00", "17:30", nil, false},
+		{"2026-10-16T12:00:00+02:00", "11:00:30", "12:00:01", nil, true},
+		{"2026-10-16T23:15:00Z", "22:00", "06:00", nil, true},
+		{"2026-10-16T05:59:00Z", "22:00", "06:00", nil, true},
+		{"2026-10-16T12:00:00Z", "22:00", "06:00", nil, false},
+		{"2026-10-16T14:00:00Z", "09:00", "17:00", []string{"America/New_York"}, true},
+		{"2026-10-16T22:00:00Z", "09:00", "17:00", []string{"America/New_York"}, false},
+	}
+	for _, test := range tests {
+		args := []interface{}{test.now, test.start, test.end}
+		for _, zone := range test.zone {
+			args = append(args, zone)
+		}
+		res, err := InTimeRangeFunc(args...)
+		if err != nil || res != test.res {
+			t.Errorf("inTimeRange(%v): %v, %v, supposed to be %t", args, res, err, test.res)
+		}
+	}
+
+	for _, args := range [][]interface{}{
+		{"2026-10-16T09:00:00Z", "9h", "17:00"},
+		{"2026-10-16 09:00:00", "09:00", "17:00"},
+		{"2026-10-16T09:00:00Z", "09:00", "17:00", "Mars/Olympus_Mons"},
+		{"2026-10-16T09:00:00Z", "09:00"},
+	} {
+		if _, err := InTimeRangeFunc(args...); err == nil {
+			t.Errorf("inTimeRange(%v) should return an error", args)
+		}
+	}
+}
+
+func TestWeekdayMatch(t *testing.T) {
+	// 2026-10-16 is a Friday.
+	tests := []struct {
+		now  interface{}
+		days string
+		zone []string
+		res  bool
+	}{
+		{"2026-10-16T12:00:00Z", "Mon-Fri", nil, true},
+		{"2026-10-17T12:00:00Z", "Mon-Fri", nil, false},
+		{"2026-10-17T12:00:00Z", "sat|Sunday", nil, true},
+		{"2026-10-19T12:00:00Z", "Fri-Mon", nil, true},
+		{"2026-10-21T12:00:00Z", "Fri-Mon", nil, false},
+		{"2026-10-21T12:00:00Z", "Mon,Wed", nil, true},
+		{"2026-10-17T02:00:00Z", "Mon-Fri", []string{"America/New_York"}, true},
+	}
+	for _, test := range tests {
+		args := []interface{}{test.now, test.days}
+		for _, zone := range test.zone {
+			args = append(args, zone)
+		}
+		res, err := WeekdayMatchFunc(args...)
+		if err != nil || res != test.res {
+			t.Errorf("weekdayMatch(%v): %v, %v, supposed to be %t", args, res, err, test.res)
+		}
+	}
+
+	for _, days := range []string{"Mon-Tue-Wed", "Funday", "Mon-"} {
+		if _, err := WeekdayMatchFunc("2026-10-16T12:00:00Z", days); err == nil {
+			t.Errorf("weekdayMatch with days %s should return an error", days)
+		}
+	}
+}
+
+func TestTimeWindowMatch(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2026-10-16T12:00:00Z")
+	tests := []struct {
+		start string
+		end   string
+		res   bool
+	}{
+		{"2026-10-16T09:00:00Z", "2026-10-16T17:00:00Z", true},
+		{"2026-10-16T12:00:00Z", "2026-10-16T17:00:00Z", true},
+		{"2026-10-16T09:00:00Z", "2026-10-16T12:00:00Z", false},
+		{"2026-10-16T13:00:00Z", "", false},
+		{"2026-10-16T13:00:00+02:00", "", true},
+		{"", "2026-10-17T00:00:00Z", true},
+		{"", "2026-10-16T00:00:00Z", false},
+		{"", "", true},
+	}
+	fn := GenerateTimeWindowMatchFunc(func() time.Time { return now })
+	for _, test := range tests {
+		res, err := fn(test.start, test.end)
+		if err != nil || res != test.res {
+			t.Errorf("timeMatch(%q, %q): %v, %v, supposed to be %t", test.start, test.end, res, err, test.res)
+		}
+	}
+
+	if _, err := fn("2026-10-16 09:00:00", ""); err == nil {
+		t.Error("timeMatch with a start not in RFC 3339 should return an error")
+	}
+	if _, err := fn("2026-10-16T09:00:00Z"); err == nil || err.Error() != "timeMatch: expected 2 arguments, but got 1" {
+		t.Errorf("error: %v, supposed to be about the number of arguments", err)
+	}
+	if res, err := TimeWindowMatchFunc("", "2999-01-01T00:00:00Z"); res != true || err != nil {
+		t.Errorf("timeMatch at the current time: %v, %v, supposed to be true", res, err)
+	}
+}
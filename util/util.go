@@ -240,6 +240,25 @@ func HasEval(s string) bool {
 	return evalReg.MatchString(s)
 }
 
+// HasToken determines whether the matcher references the token as a whole identifier, e.g. "p2_sub".
+func HasToken(s string, token string) bool {
+	isIdentifierChar := func(c byte) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	for start := 0; ; {
+		i := strings.Index(s[start:], token)
+		if i == -1 {
+			return false
+		}
+		i += start
+		end := i + len(token)
+		if (i == 0 || !isIdentifierChar(s[i-1])) && (end == len(s) || !isIdentifierChar(s[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
 // ReplaceEval replace function eval with the value of its parameters.
 func ReplaceEval(s string, rule string) string {
 	return evalReg.ReplaceAllString(s, "("+rule+")")
@@ -134,6 +134,24 @@ func TestContainEval(t *testing.T) {
 	testContainEval(t, "xeval() && a && b && c", false)
 }
 
+func testHasToken(t *testing.T, s string, token string, res bool) {
+	t.Helper()
+	myRes := HasToken(s, token)
+	if myRes != res {
+		t.Errorf("%s contains %s: %t, supposed to be %t", s, token, myRes, res)
+	}
+}
+
+func TestHasToken(t *testing.T) {
+	testHasToken(t, "r_sub == p2_sub", "p2_sub", true)
+	testHasToken(t, "r_sub == p2_sub.Name", "p2_sub", true)
+	testHasToken(t, "keyMatch(r_obj, p_obj)", "p_obj", true)
+	testHasToken(t, "r_sub == p2_sub", "p_sub", false)
+	testHasToken(t, "r_sub == xp2_sub", "p2_sub", false)
+	testHasToken(t, "r_sub == p2_sub2", "p2_sub", false)
+	testHasToken(t, "p2_sub2 == p2_sub", "p2_sub", true)
+}
+
 func testReplaceEval(t *testing.T, s string, rule string, res string) {
 	t.Helper()
 	myRes := ReplaceEval(s, rule)